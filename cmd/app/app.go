@@ -7,6 +7,7 @@ import (
 	"merch_shop/internal/config"
 	"merch_shop/internal/db"
 	"merch_shop/internal/handlers"
+	"merch_shop/internal/jobs"
 	"merch_shop/internal/service"
 	"merch_shop/pkg/cryptor"
 	"merch_shop/pkg/middleware"
@@ -19,8 +20,9 @@ import (
 const AppName string = "merch-shop service"
 
 type App struct {
-	cfg    *config.Config
-	server *http.Server
+	cfg       *config.Config
+	server    *http.Server
+	scheduler *jobs.Scheduler
 }
 
 func New(cfg *config.Config, logger *slog.Logger) (*App, error) {
@@ -32,24 +34,108 @@ func New(cfg *config.Config, logger *slog.Logger) (*App, error) {
 		return nil, err
 	}
 
-	service := service.New(storage, logger, cryptor, tokenizer)
+	service := service.New(storage, logger, cryptor, tokenizer, cfg.Shop)
 
 	controller := handlers.New(service)
 
+	scheduler := jobs.New(logger)
+	scheduler.Schedule("statements", jobs.NextMonthStart, jobs.Statements(storage))
+	scheduler.Schedule("wishlists", jobs.Every(cfg.Shop.WishlistCheckInterval), jobs.Wishlists(storage))
+	scheduler.Schedule("approvals", jobs.Every(cfg.Shop.ApprovalCheckInterval),
+		jobs.Approvals(storage, cfg.Shop.ApprovalTimeout))
+	scheduler.Schedule("auctions", jobs.Every(cfg.Shop.AuctionCheckInterval), jobs.Auctions(storage))
+	scheduler.Schedule("collections", jobs.Every(cfg.Shop.CollectionCheckInterval), jobs.Collections(storage))
+
 	router := mux.NewRouter()
 	router.Use(middleware.RpsLimit(cfg.RPS))
-	router.Use(middleware.ResponseTimeLimit(cfg.ResponseTime))
 	router.Use(middleware.Logging(logger))
 
 	authRouter := router.PathPrefix("/api/auth").Subrouter()
+	authRouter.Use(middleware.ResponseTimeLimit(cfg.ResponseTime))
 	authRouter.HandleFunc("", controller.Auth()).Methods(http.MethodPost)
 
+	catalogRouter := router.PathPrefix("/api/items").Subrouter()
+	catalogRouter.Use(middleware.ResponseTimeLimit(cfg.ResponseTime))
+	catalogRouter.HandleFunc("", controller.GetItems()).Methods(http.MethodGet)
+	catalogRouter.HandleFunc("/{id:[0-9]+}", controller.GetItem()).Methods(http.MethodGet)
+	catalogRouter.HandleFunc("/{id:[0-9]+}/prices", controller.GetPriceHistory()).Methods(http.MethodGet)
+	catalogRouter.HandleFunc("/{id:[0-9]+}/reviews", controller.GetItemReviews()).Methods(http.MethodGet)
+
+	bundlesRouter := router.PathPrefix("/api/bundles").Subrouter()
+	bundlesRouter.Use(middleware.ResponseTimeLimit(cfg.ResponseTime))
+	bundlesRouter.HandleFunc("", controller.GetBundles()).Methods(http.MethodGet)
+
+	// Exports stream the whole requested period, so they are not bound by the response time limit.
+	exportRouter := router.PathPrefix("/api").Subrouter()
+	exportRouter.Use(middleware.Auth(tokenizer))
+
+	exportRouter.HandleFunc("/history/export", controller.ExportHistory()).Methods(http.MethodGet)
+	exportRouter.HandleFunc("/admin/history/export", controller.ExportAllHistory()).Methods(http.MethodGet)
+
 	businessRouter := router.PathPrefix("/api").Subrouter()
+	businessRouter.Use(middleware.ResponseTimeLimit(cfg.ResponseTime))
 	businessRouter.Use(middleware.Auth(tokenizer))
 
 	businessRouter.HandleFunc("/info", controller.GetInfo()).Methods(http.MethodGet)
-	businessRouter.HandleFunc("/buy/{item:[0-9]+}", controller.BuyItem()).Methods(http.MethodGet)
+	businessRouter.HandleFunc("/history", controller.GetHistory()).Methods(http.MethodGet)
+	businessRouter.HandleFunc("/buy/{item}", controller.BuyItem()).Methods(http.MethodPost)
+	businessRouter.HandleFunc("/gifts", controller.BuyGift()).Methods(http.MethodPost)
+	businessRouter.HandleFunc("/bundles/{id:[0-9]+}/buy", controller.BuyBundle()).Methods(http.MethodPost)
 	businessRouter.HandleFunc("/sendCoin", controller.SendCoin()).Methods(http.MethodPost)
+	businessRouter.HandleFunc("/giveItem", controller.GiveItem()).Methods(http.MethodPost)
+	businessRouter.HandleFunc("/cart", controller.GetCart()).Methods(http.MethodGet)
+	businessRouter.HandleFunc("/cart/items", controller.AddCartItem()).Methods(http.MethodPost)
+	businessRouter.HandleFunc("/cart/checkout", controller.CheckoutCart()).Methods(http.MethodPost)
+	businessRouter.HandleFunc("/orders", controller.GetOrders()).Methods(http.MethodGet)
+	businessRouter.HandleFunc("/orders/{id:[0-9]+}/cancel", controller.CancelOrder()).Methods(http.MethodPost)
+	businessRouter.HandleFunc("/approvals", controller.GetApprovals()).Methods(http.MethodGet)
+	businessRouter.HandleFunc("/approvals/{id:[0-9]+}/approve", controller.ApproveOrder()).Methods(http.MethodPost)
+	businessRouter.HandleFunc("/approvals/{id:[0-9]+}/reject", controller.RejectOrder()).Methods(http.MethodPost)
+	businessRouter.HandleFunc("/auctions", controller.GetAuctions()).Methods(http.MethodGet)
+	businessRouter.HandleFunc("/auctions/{id:[0-9]+}", controller.GetAuction()).Methods(http.MethodGet)
+	businessRouter.HandleFunc("/auctions/{id:[0-9]+}/bids", controller.PlaceBid()).Methods(http.MethodPost)
+	businessRouter.HandleFunc("/collections", controller.GetCollections()).Methods(http.MethodGet)
+	businessRouter.HandleFunc("/collections", controller.CreateCollection()).Methods(http.MethodPost)
+	businessRouter.HandleFunc("/collections/{id:[0-9]+}", controller.GetCollection()).Methods(http.MethodGet)
+	businessRouter.HandleFunc("/collections/{id:[0-9]+}/contributions", controller.Contribute()).Methods(http.MethodPost)
+	businessRouter.HandleFunc("/reviews", controller.PostReview()).Methods(http.MethodPost)
+	businessRouter.HandleFunc("/wishlist", controller.GetWishlist()).Methods(http.MethodGet)
+	businessRouter.HandleFunc("/wishlist/items", controller.AddWishlistItem()).Methods(http.MethodPost)
+	businessRouter.HandleFunc("/wishlist/items/{id:[0-9]+}", controller.RemoveWishlistItem()).Methods(http.MethodDelete)
+	businessRouter.HandleFunc("/notifications", controller.GetNotifications()).Methods(http.MethodGet)
+	businessRouter.HandleFunc("/notifications/{id:[0-9]+}/read", controller.MarkNotificationRead()).Methods(http.MethodPost)
+	businessRouter.HandleFunc("/statements/{month}", controller.GetStatement()).Methods(http.MethodGet)
+
+	businessRouter.HandleFunc("/admin/items", controller.GetAllItems()).Methods(http.MethodGet)
+	businessRouter.HandleFunc("/admin/items", controller.CreateItem()).Methods(http.MethodPost)
+	businessRouter.HandleFunc("/admin/items/{id:[0-9]+}", controller.UpdateItem()).Methods(http.MethodPut)
+	businessRouter.HandleFunc("/admin/items/{id:[0-9]+}/retire", controller.RetireItem()).Methods(http.MethodPost)
+	businessRouter.HandleFunc("/admin/items/{id:[0-9]+}/restore", controller.RestoreItem()).Methods(http.MethodPost)
+	businessRouter.HandleFunc("/admin/items/{id:[0-9]+}/variants", controller.CreateVariant()).Methods(http.MethodPost)
+	businessRouter.HandleFunc("/admin/items/{id:[0-9]+}/prices", controller.GetItemPrices()).Methods(http.MethodGet)
+	businessRouter.HandleFunc("/admin/items/{id:[0-9]+}/prices", controller.SchedulePrice()).Methods(http.MethodPost)
+	businessRouter.HandleFunc("/admin/prices/{id:[0-9]+}", controller.CancelScheduledPrice()).Methods(http.MethodDelete)
+	businessRouter.HandleFunc("/admin/variants/{id:[0-9]+}", controller.UpdateVariant()).Methods(http.MethodPut)
+	businessRouter.HandleFunc("/admin/variants/{id:[0-9]+}/restock", controller.RestockVariant()).Methods(http.MethodPost)
+	businessRouter.HandleFunc("/admin/variants/low-stock", controller.GetLowStockVariants()).Methods(http.MethodGet)
+	businessRouter.HandleFunc("/admin/promos", controller.GetPromos()).Methods(http.MethodGet)
+	businessRouter.HandleFunc("/admin/promos", controller.CreatePromo()).Methods(http.MethodPost)
+	businessRouter.HandleFunc("/admin/promos/{id:[0-9]+}/deactivate", controller.DeactivatePromo()).Methods(http.MethodPost)
+	businessRouter.HandleFunc("/admin/bundles", controller.GetAllBundles()).Methods(http.MethodGet)
+	businessRouter.HandleFunc("/admin/bundles", controller.CreateBundle()).Methods(http.MethodPost)
+	businessRouter.HandleFunc("/admin/bundles/{id:[0-9]+}/deactivate", controller.DeactivateBundle()).Methods(http.MethodPost)
+	businessRouter.HandleFunc("/admin/auctions", controller.GetAllAuctions()).Methods(http.MethodGet)
+	businessRouter.HandleFunc("/admin/auctions", controller.CreateAuction()).Methods(http.MethodPost)
+	businessRouter.HandleFunc("/admin/collections", controller.GetAllCollections()).Methods(http.MethodGet)
+	businessRouter.HandleFunc("/admin/reviews", controller.GetAllReviews()).Methods(http.MethodGet)
+	businessRouter.HandleFunc("/admin/reviews/{id:[0-9]+}/hide", controller.HideReview()).Methods(http.MethodPost)
+	businessRouter.HandleFunc("/admin/reviews/{id:[0-9]+}/unhide", controller.ShowReview()).Methods(http.MethodPost)
+	businessRouter.HandleFunc("/admin/orders", controller.GetAllOrders()).Methods(http.MethodGet)
+	businessRouter.HandleFunc("/admin/orders/{id:[0-9]+}/advance", controller.AdvanceOrder()).Methods(http.MethodPost)
+	businessRouter.HandleFunc("/admin/orders/{id:[0-9]+}/refund", controller.RefundOrder()).Methods(http.MethodPost)
+	businessRouter.HandleFunc("/admin/approvers", controller.GetApprovers()).Methods(http.MethodGet)
+	businessRouter.HandleFunc("/admin/approvers", controller.AddApprover()).Methods(http.MethodPost)
+	businessRouter.HandleFunc("/admin/approvers/{username}", controller.RemoveApprover()).Methods(http.MethodDelete)
 
 	return &App{
 		cfg: cfg,
@@ -57,6 +143,7 @@ func New(cfg *config.Config, logger *slog.Logger) (*App, error) {
 			Addr:    fmt.Sprintf("%s:%s", cfg.Server.Host, cfg.Server.Port),
 			Handler: router,
 		},
+		scheduler: scheduler,
 	}, nil
 }
 
@@ -66,5 +153,7 @@ func (app *App) Run() error {
 }
 
 func (app *App) Shutdown() error {
-	return app.server.Shutdown(context.Background())
+	err := app.server.Shutdown(context.Background())
+	app.scheduler.Stop()
+	return err
 }
@@ -10,6 +10,7 @@ type Config struct {
 	SecretKey string `env:"SECRET" env-required:"true"`
 	Server    `yaml:"server"`
 	DB        `yaml:"db"`
+	Shop      `yaml:"shop"`
 }
 
 type Server struct {
@@ -24,6 +25,18 @@ type DB struct {
 	MaxOpenConns int    `yaml:"max_open_conns" env-default:"15"`
 }
 
+type Shop struct {
+	CatalogCacheTTL         time.Duration `yaml:"catalog_cache_ttl" env-default:"1m"`
+	LowStockThreshold       int           `yaml:"low_stock_threshold" env-default:"5"`
+	CancellationWindow      time.Duration `yaml:"cancellation_window" env-default:"30m"`
+	WishlistCheckInterval   time.Duration `yaml:"wishlist_check_interval" env-default:"1m"`
+	ApprovalThreshold       int           `yaml:"approval_threshold" env-default:"0"`
+	ApprovalTimeout         time.Duration `yaml:"approval_timeout" env-default:"72h"`
+	ApprovalCheckInterval   time.Duration `yaml:"approval_check_interval" env-default:"1m"`
+	AuctionCheckInterval    time.Duration `yaml:"auction_check_interval" env-default:"1m"`
+	CollectionCheckInterval time.Duration `yaml:"collection_check_interval" env-default:"1m"`
+}
+
 func New(path string) (*Config, error) {
 	var cfg Config
 
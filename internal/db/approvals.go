@@ -0,0 +1,335 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"merch_shop/internal/models"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+)
+
+// assignApprover picks the approver with the fewest pending orders, who is neither the payer nor the recipient.
+// Without such an approver the order is left to admins.
+func assignApprover(ctx context.Context, tx *sql.Tx, userID int, recipientID *int) (*int, error) {
+	column := func(table, column string) string {
+		return fmt.Sprintf("%s.%s", table, column)
+	}
+
+	selectApprover := sq.Select(column(approversTable, approversUserIDColumn)).
+		From(approversTable).
+		LeftJoin(fmt.Sprintf("%s ON %s = %s AND %s = ?", ordersTable,
+			column(ordersTable, ordersApproverIDColumn), column(approversTable, approversUserIDColumn),
+			column(ordersTable, ordersStatusColumn)), models.OrderStatusPendingApproval).
+		Where(sq.NotEq{column(approversTable, approversUserIDColumn): userID})
+	if recipientID != nil {
+		selectApprover = selectApprover.Where(sq.NotEq{column(approversTable, approversUserIDColumn): *recipientID})
+	}
+
+	selectApproverQuery, approverArgs, err := selectApprover.
+		GroupBy(column(approversTable, approversUserIDColumn)).
+		OrderBy(fmt.Sprintf("COUNT(%s)", column(ordersTable, ordersIDColumn)), column(approversTable, approversUserIDColumn)).
+		Limit(1).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var approverID int
+	err = tx.QueryRowContext(ctx, selectApproverQuery, approverArgs...).Scan(&approverID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &approverID, nil
+}
+
+// ApproveOrder places an order pending approval and puts its lines into the inventory of the owner.
+// The held coins are kept as the payment.
+func (s *storage) ApproveOrder(ctx context.Context, orderID int, approval *models.OrderApproval) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	err = approveOrderTx(ctx, tx, orderID, approval)
+	if err != nil {
+		txErr := tx.Rollback()
+		if txErr != nil {
+			log.Printf("tx rollback error: %s", txErr.Error())
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func approveOrderTx(ctx context.Context, tx *sql.Tx, orderID int, approval *models.OrderApproval) error {
+	ownerID, err := decideOrderTx(ctx, tx, orderID, approval)
+	if err != nil {
+		return err
+	}
+
+	placeOrderQuery, orderUpdArgs, err := sq.Update(ordersTable).
+		Set(ordersStatusColumn, models.OrderStatusPlaced).
+		Set(ordersStatusUpdatedAtColumn, time.Now()).
+		Where(sq.Eq{ordersIDColumn: orderID}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, placeOrderQuery, orderUpdArgs...)
+	if err != nil {
+		return err
+	}
+
+	lines, err := selectOrderLines(ctx, tx, orderID)
+	if err != nil {
+		return err
+	}
+
+	return addUserItems(ctx, tx, ownerID, lines)
+}
+
+// RejectOrder cancels an order pending approval, releasing the held coins, reserved stock and promo code use.
+func (s *storage) RejectOrder(ctx context.Context, orderID int, approval *models.OrderApproval) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	err = rejectOrderTx(ctx, tx, orderID, approval)
+	if err != nil {
+		txErr := tx.Rollback()
+		if txErr != nil {
+			log.Printf("tx rollback error: %s", txErr.Error())
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func rejectOrderTx(ctx context.Context, tx *sql.Tx, orderID int, approval *models.OrderApproval) error {
+	_, err := decideOrderTx(ctx, tx, orderID, approval)
+	if err != nil {
+		return err
+	}
+
+	return cancelOrderTx(ctx, tx, orderID, &models.OrderCancellation{Status: models.OrderStatusPendingApproval})
+}
+
+// decideOrderTx locks an order pending approval and records who decided on it, returning the owner of its items.
+// Orders the user may not decide on look missing, those decided already are not pending.
+func decideOrderTx(ctx context.Context, tx *sql.Tx, orderID int, approval *models.OrderApproval) (int, error) {
+	decideOrder := sq.Update(ordersTable).
+		Set(ordersApproverIDColumn, approval.UserID).
+		Where(sq.Eq{ordersIDColumn: orderID}).
+		Where(sq.NotEq{ordersUserIDColumn: approval.UserID}).
+		Where(sq.Or{sq.Eq{ordersRecipientIDColumn: nil}, sq.NotEq{ordersRecipientIDColumn: approval.UserID}}).
+		Where(sq.Eq{ordersStatusColumn: models.OrderStatusPendingApproval})
+
+	exists := sq.Eq{ordersIDColumn: orderID}
+	if !approval.Admin {
+		decideOrder = decideOrder.Where(sq.Eq{ordersApproverIDColumn: approval.UserID})
+		exists[ordersApproverIDColumn] = approval.UserID
+	}
+
+	decideOrderQuery, orderUpdArgs, err := decideOrder.
+		Suffix(fmt.Sprintf("RETURNING COALESCE(%s, %s)", ordersRecipientIDColumn, ordersUserIDColumn)).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	var ownerID int
+	err = tx.QueryRowContext(ctx, decideOrderQuery, orderUpdArgs...).Scan(&ownerID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, orderNotChangedError(ctx, tx, exists, ErrNotPending)
+		}
+		return 0, err
+	}
+
+	return ownerID, nil
+}
+
+// ExpireApprovals rejects orders that have been pending approval since before placedBefore.
+// Every order is cancelled in its own transaction, orders decided meanwhile are skipped.
+func (s *storage) ExpireApprovals(ctx context.Context, placedBefore time.Time) error {
+	selectExpiredQuery, expiredArgs, err := sq.Select(ordersIDColumn).
+		From(ordersTable).
+		Where(sq.Eq{ordersStatusColumn: models.OrderStatusPendingApproval}).
+		Where(sq.Lt{ordersCreatedAtColumn: placedBefore}).
+		OrderBy(ordersIDColumn).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	rows, err := s.db.QueryContext(ctx, selectExpiredQuery, expiredArgs...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	orderIDs := make([]int, 0)
+	for rows.Next() {
+		var orderID int
+		err := rows.Scan(&orderID)
+		if err != nil {
+			return err
+		}
+		orderIDs = append(orderIDs, orderID)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, orderID := range orderIDs {
+		err := s.CancelOrder(ctx, orderID, &models.OrderCancellation{Status: models.OrderStatusPendingApproval})
+		if err != nil && err != ErrNotCancellable {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetApprovers lists approvers by username with the number of orders waiting for them.
+func (s *storage) GetApprovers(ctx context.Context) ([]models.Approver, error) {
+	column := func(table, column string) string {
+		return fmt.Sprintf("%s.%s", table, column)
+	}
+
+	selectApproversQuery, approversArgs, err := sq.Select(
+		column(usersTable, usersNameColumn),
+		fmt.Sprintf("COUNT(%s)", column(ordersTable, ordersIDColumn)),
+		column(approversTable, approversCreatedAtColumn)).
+		From(approversTable).
+		Join(fmt.Sprintf("%s ON %s = %s", usersTable,
+			column(approversTable, approversUserIDColumn), column(usersTable, userIDColumn))).
+		LeftJoin(fmt.Sprintf("%s ON %s = %s AND %s = ?", ordersTable,
+			column(ordersTable, ordersApproverIDColumn), column(approversTable, approversUserIDColumn),
+			column(ordersTable, ordersStatusColumn)), models.OrderStatusPendingApproval).
+		GroupBy(column(usersTable, usersNameColumn), column(approversTable, approversCreatedAtColumn)).
+		OrderBy(column(usersTable, usersNameColumn)).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, selectApproversQuery, approversArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	approvers := make([]models.Approver, 0)
+	for rows.Next() {
+		var approver models.Approver
+		err := rows.Scan(&approver.Username, &approver.Pending, &approver.AddedAt)
+		if err != nil {
+			return nil, err
+		}
+		approvers = append(approvers, approver)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return approvers, nil
+}
+
+// AddApprover makes a user an approver, new orders pending approval may be assigned to them.
+func (s *storage) AddApprover(ctx context.Context, username string) error {
+	userID, _, err := s.GetUser(ctx, username)
+	if err != nil {
+		return err
+	}
+
+	insertApproverQuery, approverInsArgs, err := sq.Insert(approversTable).
+		Columns(approversUserIDColumn, approversCreatedAtColumn).
+		Values(*userID, time.Now()).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx, insertApproverQuery, approverInsArgs...)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "unique_violation" {
+			return ErrApproverExists
+		}
+		return err
+	}
+
+	return nil
+}
+
+// RemoveApprover takes a user off the list. Orders still waiting for them are left to admins.
+func (s *storage) RemoveApprover(ctx context.Context, username string) error {
+	userID, _, err := s.GetUser(ctx, username)
+	if err != nil {
+		if err == ErrNoUser {
+			return ErrNoApprover
+		}
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	err = removeApproverTx(ctx, tx, *userID)
+	if err != nil {
+		txErr := tx.Rollback()
+		if txErr != nil {
+			log.Printf("tx rollback error: %s", txErr.Error())
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func removeApproverTx(ctx context.Context, tx *sql.Tx, userID int) error {
+	deleteApproverQuery, approverDelArgs, err := sq.Delete(approversTable).
+		Where(sq.Eq{approversUserIDColumn: userID}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, deleteApproverQuery, approverDelArgs...)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrNoApprover
+	}
+
+	unassignQuery, orderUpdArgs, err := sq.Update(ordersTable).
+		Set(ordersApproverIDColumn, nil).
+		Where(sq.Eq{ordersApproverIDColumn: userID, ordersStatusColumn: models.OrderStatusPendingApproval}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, unassignQuery, orderUpdArgs...)
+	return err
+}
@@ -0,0 +1,194 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"log"
+	"merch_shop/internal/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	assignApproverQueryRegexp = `
+		SELECT approvers.user_id FROM approvers
+		LEFT JOIN orders ON orders.approver_id = approvers.user_id AND orders.status = \$1
+		WHERE approvers.user_id <> \$2 GROUP BY approvers.user_id
+		ORDER BY COUNT\(orders.id\), approvers.user_id LIMIT 1
+	`
+	decideOrderQueryRegexp = `
+		UPDATE orders SET approver_id = \$1
+		WHERE id = \$2 AND user_id <> \$3 AND \(recipient_id IS NULL OR recipient_id <> \$4\) AND status = \$5
+		(AND approver_id = \$6 )?RETURNING COALESCE\(recipient_id, user_id\)
+	`
+	placeOrderQueryRegexp          = `UPDATE orders SET status = \$1, status_updated_at = \$2 WHERE id = \$3`
+	rejectOrderQueryRegexp         = `UPDATE orders SET (.*) WHERE id = \$4 AND status <> \$5 AND status = \$6 RETURNING (.*)`
+	selectExpiredQueryRegexp       = `SELECT id FROM orders WHERE status = \$1 AND created_at < \$2 ORDER BY id`
+	approverOrderExistsQueryRegexp = `SELECT EXISTS \( SELECT 1 FROM orders WHERE approver_id = \$1 AND id = \$2 \)`
+)
+
+func TestBuyItemsPendingApproval(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer mockDB.Close()
+
+	db := storage{db: mockDB}
+
+	userID, approverID := 1, 4
+	hoody := models.CartLine{VariantID: 2, Quantity: 2}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(selectItemsForPurchaseQueryRegexp).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), hoody.VariantID, true).
+		WillReturnRows(sqlmock.NewRows(purchasedItemColumns).AddRow(2, 1, "hoody", "clothes", "m", 300, nil, nil, nil))
+	mock.ExpectQuery(updateBalanceQueryRegexp).WithArgs(600, userID).
+		WillReturnRows(sqlmock.NewRows([]string{usersBalanceColumn}).AddRow(400))
+	mock.ExpectQuery(assignApproverQueryRegexp).WithArgs(models.OrderStatusPendingApproval, userID).
+		WillReturnRows(sqlmock.NewRows([]string{approversUserIDColumn}).AddRow(approverID))
+	mock.ExpectQuery(insertOrderQueryRegexp).WithArgs(userID, 600, models.OrderStatusPendingApproval,
+		sqlmock.AnyArg(), sqlmock.AnyArg(), nil, 0, nil, nil, nil, approverID).
+		WillReturnRows(sqlmock.NewRows([]string{ordersIDColumn}).AddRow(7))
+	// The lines reach the inventory only on approval.
+	mock.ExpectExec(insertPurchasesQueryRegexp).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	summary, err := db.BuyItems(context.Background(), userID, []models.CartLine{hoody}, "", 500)
+	assert.NoError(t, err)
+	assert.Equal(t, models.OrderStatusPendingApproval, summary.Status)
+	assert.Equal(t, 400, summary.Balance)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestApproveOrder(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer mockDB.Close()
+
+	db := storage{db: mockDB}
+
+	orderID, approverID, ownerID := 7, 4, 2
+	approval := &models.OrderApproval{UserID: approverID}
+	decideArgs := []driver.Value{approverID, orderID, approverID, approverID, models.OrderStatusPendingApproval, approverID}
+	lineColumns := []string{purchasesVariantIDColumn, purchasesQuantityColumn}
+
+	t.Run("lines are put into the inventory of the owner", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(decideOrderQueryRegexp).WithArgs(decideArgs...).
+			WillReturnRows(sqlmock.NewRows([]string{"owner_id"}).AddRow(ownerID))
+		mock.ExpectExec(placeOrderQueryRegexp).WithArgs(models.OrderStatusPlaced, sqlmock.AnyArg(), orderID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(selectOrderLinesQueryRegexp).WithArgs(orderID).
+			WillReturnRows(sqlmock.NewRows(lineColumns).AddRow(2, 2).AddRow(4, 1))
+		mock.ExpectExec(upsertUserItemsQueryRegexp).WithArgs(ownerID, 2, 2, ownerID, 4, 1).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		err := db.ApproveOrder(context.Background(), orderID, approval)
+		assert.NoError(t, err)
+	})
+
+	t.Run("order decided already", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(decideOrderQueryRegexp).WithArgs(decideArgs...).
+			WillReturnRows(sqlmock.NewRows([]string{"owner_id"}))
+		mock.ExpectQuery(approverOrderExistsQueryRegexp).WithArgs(approverID, orderID).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectRollback()
+
+		err := db.ApproveOrder(context.Background(), orderID, approval)
+		assert.Equal(t, ErrNotPending, err)
+	})
+
+	t.Run("order assigned to another approver", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(decideOrderQueryRegexp).WithArgs(decideArgs...).
+			WillReturnRows(sqlmock.NewRows([]string{"owner_id"}))
+		mock.ExpectQuery(approverOrderExistsQueryRegexp).WithArgs(approverID, orderID).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectRollback()
+
+		err := db.ApproveOrder(context.Background(), orderID, approval)
+		assert.Equal(t, ErrNoOrder, err)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRejectOrder(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer mockDB.Close()
+
+	db := storage{db: mockDB}
+
+	orderID, adminID, userID := 7, 9, 1
+	orderColumns := []string{ordersUserIDColumn, "owner_id", ordersTotalColumn, ordersPromoIDColumn}
+	lineColumns := []string{purchasesVariantIDColumn, purchasesQuantityColumn}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(decideOrderQueryRegexp).
+		WithArgs(adminID, orderID, adminID, adminID, models.OrderStatusPendingApproval).
+		WillReturnRows(sqlmock.NewRows([]string{"owner_id"}).AddRow(userID))
+	mock.ExpectQuery(rejectOrderQueryRegexp).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), orderID,
+		models.OrderStatusCancelled, models.OrderStatusPendingApproval).
+		WillReturnRows(sqlmock.NewRows(orderColumns).AddRow(userID, userID, 600, nil))
+	mock.ExpectQuery(selectOrderLinesQueryRegexp).WithArgs(orderID).
+		WillReturnRows(sqlmock.NewRows(lineColumns).AddRow(2, 2))
+	mock.ExpectExec(returnStockQueryRegexp).WithArgs(2, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+	// The held coins are released, nothing is taken out of the inventory.
+	mock.ExpectExec(refundBalanceQueryRegexp).WithArgs(600, userID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err = db.RejectOrder(context.Background(), orderID, &models.OrderApproval{UserID: adminID, Admin: true})
+	assert.NoError(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExpireApprovals(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer mockDB.Close()
+
+	db := storage{db: mockDB}
+
+	userID := 1
+	placedBefore := time.Date(2025, 2, 5, 12, 30, 0, 0, time.UTC)
+	orderColumns := []string{ordersUserIDColumn, "owner_id", ordersTotalColumn, ordersPromoIDColumn}
+	lineColumns := []string{purchasesVariantIDColumn, purchasesQuantityColumn}
+
+	mock.ExpectQuery(selectExpiredQueryRegexp).WithArgs(models.OrderStatusPendingApproval, placedBefore).
+		WillReturnRows(sqlmock.NewRows([]string{ordersIDColumn}).AddRow(7).AddRow(8))
+	// Order 7 has been approved after it was selected.
+	mock.ExpectBegin()
+	mock.ExpectQuery(rejectOrderQueryRegexp).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), 7,
+		models.OrderStatusCancelled, models.OrderStatusPendingApproval).
+		WillReturnRows(sqlmock.NewRows(orderColumns))
+	mock.ExpectQuery(orderExistsQueryRegexp).WithArgs(7).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectQuery(rejectOrderQueryRegexp).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), 8,
+		models.OrderStatusCancelled, models.OrderStatusPendingApproval).
+		WillReturnRows(sqlmock.NewRows(orderColumns).AddRow(userID, userID, 600, nil))
+	mock.ExpectQuery(selectOrderLinesQueryRegexp).WithArgs(8).WillReturnRows(sqlmock.NewRows(lineColumns).AddRow(2, 2))
+	mock.ExpectExec(returnStockQueryRegexp).WithArgs(2, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(refundBalanceQueryRegexp).WithArgs(600, userID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err = db.ExpireApprovals(context.Background(), placedBefore)
+	assert.NoError(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,528 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"merch_shop/internal/models"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+)
+
+const leadingBidsAlias = "leading_bids"
+
+// heldBid is the highest bid of an open auction, its coins are held until it is released.
+type heldBid struct {
+	id     int
+	userID int
+	amount int
+}
+
+// CreateAuction reserves one unit of the variant and starts an auction for it.
+func (s *storage) CreateAuction(ctx context.Context, variantID int, auction *models.AuctionRequest) (*models.Auction, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := createAuctionTx(ctx, tx, variantID, auction)
+	if err != nil {
+		txErr := tx.Rollback()
+		if txErr != nil {
+			log.Printf("tx rollback error: %s", txErr.Error())
+		}
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+func createAuctionTx(ctx context.Context, tx *sql.Tx, variantID int, auction *models.AuctionRequest) (*models.Auction, error) {
+	err := reserveUnit(ctx, tx, variantID)
+	if err != nil {
+		return nil, err
+	}
+
+	insertAuctionQuery, auctionInsArgs, err := sq.Insert(auctionsTable).
+		Columns(auctionsVariantIDColumn, auctionsStartingBidColumn, auctionsMinIncrementColumn, auctionsCreatedAtColumn,
+			auctionsEndsAtColumn).
+		Values(variantID, auction.StartingBid, auction.MinIncrement, time.Now(), auction.EndsAt).
+		Suffix("RETURNING " + auctionsIDColumn).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var auctionID int
+	err = tx.QueryRowContext(ctx, insertAuctionQuery, auctionInsArgs...).Scan(&auctionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return getAuction(ctx, tx, auctionID)
+}
+
+// reserveUnit takes one unit of the variant off the shelf for a lot or a group gift until it is sold or returned.
+// Untracked variants have no stock to reserve.
+func reserveUnit(ctx context.Context, tx *sql.Tx, variantID int) error {
+	reserveStockQuery, stockUpdArgs, err := sq.Update(itemVariantsTable).
+		Set(itemVariantsStockColumn, sq.Expr(fmt.Sprintf("%s - 1", itemVariantsStockColumn))).
+		Where(sq.Eq{itemVariantsIDColumn: variantID}).
+		Where(sq.Or{sq.Eq{itemVariantsStockColumn: nil}, sq.GtOrEq{itemVariantsStockColumn: 1}}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, reserveStockQuery, stockUpdArgs...)
+	if err != nil {
+		return err
+	}
+
+	reserved, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if reserved == 0 {
+		return ErrOutOfStock
+	}
+
+	return nil
+}
+
+// GetAuctions returns auctions ending first first, openOnly skips closed ones.
+func (s *storage) GetAuctions(ctx context.Context, openOnly bool) ([]models.Auction, error) {
+	selectAuctions := selectAuctions()
+	if openOnly {
+		selectAuctions = selectAuctions.Where(sq.Eq{fmt.Sprintf("%s.%s", auctionsTable, auctionsClosedAtColumn): nil})
+	}
+
+	selectAuctionsQuery, auctionsArgs, err := selectAuctions.PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, selectAuctionsQuery, auctionsArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	auctions := make([]models.Auction, 0)
+	for rows.Next() {
+		auction, err := scanAuction(rows)
+		if err != nil {
+			return nil, err
+		}
+		auctions = append(auctions, *auction)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return auctions, nil
+}
+
+func (s *storage) GetAuction(ctx context.Context, auctionID int) (*models.Auction, error) {
+	return getAuction(ctx, s.db, auctionID)
+}
+
+func getAuction(ctx context.Context, q rowQuerier, auctionID int) (*models.Auction, error) {
+	selectAuctionQuery, auctionArgs, err := selectAuctions().
+		Where(sq.Eq{fmt.Sprintf("%s.%s", auctionsTable, auctionsIDColumn): auctionID}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	auction, err := scanAuction(q.QueryRowContext(ctx, selectAuctionQuery, auctionArgs...))
+	if err == sql.ErrNoRows {
+		return nil, ErrNoAuction
+	}
+
+	return auction, err
+}
+
+// selectAuctions selects auctions with their highest bid, which is the winning one once an auction is closed.
+func selectAuctions() sq.SelectBuilder {
+	column := func(table, column string) string {
+		return fmt.Sprintf("%s.%s", table, column)
+	}
+
+	return sq.Select(
+		column(auctionsTable, auctionsIDColumn),
+		column(itemsTable, itemsTypeColumn),
+		column(itemVariantsTable, itemVariantsNameColumn),
+		column(auctionsTable, auctionsStartingBidColumn),
+		column(auctionsTable, auctionsMinIncrementColumn),
+		fmt.Sprintf("COALESCE(%s, 0)", column(leadingBidsAlias, bidsAmountColumn)),
+		fmt.Sprintf("COALESCE(%s, '')", column(usersTable, usersNameColumn)),
+		fmt.Sprintf("(SELECT COUNT(*) FROM %s WHERE %s = %s)", bidsTable,
+			column(bidsTable, bidsAuctionIDColumn), column(auctionsTable, auctionsIDColumn)),
+		column(auctionsTable, auctionsCreatedAtColumn),
+		column(auctionsTable, auctionsEndsAtColumn),
+		column(auctionsTable, auctionsClosedAtColumn)).
+		From(auctionsTable).
+		Join(fmt.Sprintf("%s ON %s = %s", itemVariantsTable,
+			column(auctionsTable, auctionsVariantIDColumn), column(itemVariantsTable, itemVariantsIDColumn))).
+		Join(fmt.Sprintf("%s ON %s = %s", itemsTable,
+			column(itemVariantsTable, itemVariantsItemIDColumn), column(itemsTable, itemsIDColumn))).
+		LeftJoin(fmt.Sprintf("LATERAL (SELECT %s, %s FROM %s WHERE %s = %s ORDER BY %s DESC LIMIT 1) AS %s ON true",
+			column(bidsTable, bidsUserIDColumn), column(bidsTable, bidsAmountColumn), bidsTable,
+			column(bidsTable, bidsAuctionIDColumn), column(auctionsTable, auctionsIDColumn),
+			column(bidsTable, bidsAmountColumn), leadingBidsAlias)).
+		LeftJoin(fmt.Sprintf("%s ON %s = %s", usersTable,
+			column(leadingBidsAlias, bidsUserIDColumn), column(usersTable, userIDColumn))).
+		OrderBy(column(auctionsTable, auctionsEndsAtColumn), column(auctionsTable, auctionsIDColumn))
+}
+
+// scanAuction reads a row selected with selectAuctions.
+func scanAuction(row interface{ Scan(dest ...any) error }) (*models.Auction, error) {
+	auction := &models.Auction{}
+	err := row.Scan(&auction.ID, &auction.Item, &auction.Variant, &auction.StartingBid, &auction.MinIncrement,
+		&auction.HighestBid, &auction.Leader, &auction.Bids, &auction.CreatedAt, &auction.EndsAt, &auction.ClosedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	auction.MinBid = auction.StartingBid
+	if auction.Bids > 0 {
+		auction.MinBid = auction.HighestBid + auction.MinIncrement
+	}
+
+	return auction, nil
+}
+
+// PlaceBid holds the coins of a new highest bid and releases the coins of the bid it outbids.
+func (s *storage) PlaceBid(ctx context.Context, userID, auctionID, amount int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	err = placeBidTx(ctx, tx, userID, auctionID, amount)
+	if err != nil {
+		txErr := tx.Rollback()
+		if txErr != nil {
+			log.Printf("tx rollback error: %s", txErr.Error())
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// placeBidTx locks the auction row first, so concurrent bids on an auction are checked one after another
+// against the bid they actually outbid.
+func placeBidTx(ctx context.Context, tx *sql.Tx, userID, auctionID, amount int) error {
+	timing := time.Now()
+	selectAuctionQuery, auctionArgs, err := sq.Select(auctionsStartingBidColumn, auctionsMinIncrementColumn).
+		Column(fmt.Sprintf("%s IS NULL AND %s > ?", auctionsClosedAtColumn, auctionsEndsAtColumn), timing).
+		From(auctionsTable).
+		Where(sq.Eq{auctionsIDColumn: auctionID}).
+		Suffix("FOR UPDATE").
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	var startingBid, minIncrement int
+	var open bool
+	err = tx.QueryRowContext(ctx, selectAuctionQuery, auctionArgs...).Scan(&startingBid, &minIncrement, &open)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNoAuction
+		}
+		return err
+	}
+	if !open {
+		return ErrAuctionClosed
+	}
+
+	leading, err := selectLeadingBid(ctx, tx, auctionID)
+	if err != nil {
+		return err
+	}
+
+	minBid := startingBid
+	if leading != nil {
+		if leading.userID == userID {
+			return ErrAlreadyLeading
+		}
+		minBid = leading.amount + minIncrement
+	}
+	if amount < minBid {
+		return ErrBidTooLow
+	}
+
+	// User rows are locked in ascending id order, so bids of two users on different auctions never deadlock.
+	if leading != nil && leading.userID < userID {
+		err := releaseBid(ctx, tx, leading, timing)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = adjustBalance(ctx, tx, userID, -amount)
+	if err != nil {
+		return err
+	}
+
+	if leading != nil && leading.userID > userID {
+		err := releaseBid(ctx, tx, leading, timing)
+		if err != nil {
+			return err
+		}
+	}
+
+	insertBidQuery, bidInsArgs, err := sq.Insert(bidsTable).
+		Columns(bidsAuctionIDColumn, bidsUserIDColumn, bidsAmountColumn, bidsCreatedAtColumn).
+		Values(auctionID, userID, amount, timing).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, insertBidQuery, bidInsArgs...)
+	return err
+}
+
+// selectLeadingBid returns the only bid of the auction not released yet, nil if there are no bids.
+func selectLeadingBid(ctx context.Context, tx *sql.Tx, auctionID int) (*heldBid, error) {
+	selectBidQuery, bidArgs, err := sq.Select(bidsIDColumn, bidsUserIDColumn, bidsAmountColumn).
+		From(bidsTable).
+		Where(sq.Eq{bidsAuctionIDColumn: auctionID, bidsReleasedAtColumn: nil}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	bid := &heldBid{}
+	err = tx.QueryRowContext(ctx, selectBidQuery, bidArgs...).Scan(&bid.id, &bid.userID, &bid.amount)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return bid, nil
+}
+
+// releaseBid returns the held coins of an outbid bid to the bidder.
+func releaseBid(ctx context.Context, tx *sql.Tx, bid *heldBid, at time.Time) error {
+	err := markBidReleased(ctx, tx, bid.id, at)
+	if err != nil {
+		return err
+	}
+
+	return adjustBalance(ctx, tx, bid.userID, bid.amount)
+}
+
+func markBidReleased(ctx context.Context, tx *sql.Tx, bidID int, at time.Time) error {
+	releaseBidQuery, bidUpdArgs, err := sq.Update(bidsTable).
+		Set(bidsReleasedAtColumn, at).
+		Where(sq.Eq{bidsIDColumn: bidID}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, releaseBidQuery, bidUpdArgs...)
+	return err
+}
+
+func adjustBalance(ctx context.Context, tx *sql.Tx, userID, delta int) error {
+	updateBalanceQuery, balanceUpdArgs, err := sq.Update(usersTable).
+		Set(usersBalanceColumn, sq.Expr(fmt.Sprintf("%s + ?", usersBalanceColumn), delta)).
+		Where(sq.Eq{userIDColumn: userID}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, updateBalanceQuery, balanceUpdArgs...)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			// From http://www.postgresql.org/docs/9.3/static/errcodes-appendix.html
+			if pqErr.Code.Name() == "check_violation" {
+				return ErrNotEnoughCoins
+			}
+		}
+		return err
+	}
+
+	return nil
+}
+
+// CloseAuctions closes auctions ended by the given time. Every auction is closed in its own transaction:
+// the highest bidder gets an order paid with the held coins, an auction without bids returns its unit to stock.
+func (s *storage) CloseAuctions(ctx context.Context, at time.Time) error {
+	selectEndedQuery, endedArgs, err := sq.Select(auctionsIDColumn).
+		From(auctionsTable).
+		Where(sq.Eq{auctionsClosedAtColumn: nil}).
+		Where(sq.LtOrEq{auctionsEndsAtColumn: at}).
+		OrderBy(auctionsIDColumn).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	rows, err := s.db.QueryContext(ctx, selectEndedQuery, endedArgs...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	auctionIDs := make([]int, 0)
+	for rows.Next() {
+		var auctionID int
+		err := rows.Scan(&auctionID)
+		if err != nil {
+			return err
+		}
+		auctionIDs = append(auctionIDs, auctionID)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, auctionID := range auctionIDs {
+		err := s.closeAuction(ctx, auctionID, at)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *storage) closeAuction(ctx context.Context, auctionID int, at time.Time) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	err = closeAuctionTx(ctx, tx, auctionID, at)
+	if err != nil {
+		txErr := tx.Rollback()
+		if txErr != nil {
+			log.Printf("tx rollback error: %s", txErr.Error())
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// closeAuctionTx skips an auction closed meanwhile. The winning bid is released and charged as the order
+// at once, so the balance of the winner does not change.
+func closeAuctionTx(ctx context.Context, tx *sql.Tx, auctionID int, at time.Time) error {
+	column := func(table, column string) string {
+		return fmt.Sprintf("%s.%s", table, column)
+	}
+
+	selectAuctionQuery, auctionArgs, err := sq.Select(
+		column(auctionsTable, auctionsVariantIDColumn),
+		column(itemVariantsTable, itemVariantsItemIDColumn)).
+		From(auctionsTable).
+		Join(fmt.Sprintf("%s ON %s = %s", itemVariantsTable,
+			column(auctionsTable, auctionsVariantIDColumn), column(itemVariantsTable, itemVariantsIDColumn))).
+		Where(sq.Eq{column(auctionsTable, auctionsIDColumn): auctionID, column(auctionsTable, auctionsClosedAtColumn): nil}).
+		Suffix("FOR UPDATE OF " + auctionsTable).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	var variantID, itemID int
+	err = tx.QueryRowContext(ctx, selectAuctionQuery, auctionArgs...).Scan(&variantID, &itemID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+
+	leading, err := selectLeadingBid(ctx, tx, auctionID)
+	if err != nil {
+		return err
+	}
+
+	var orderID *int
+	if leading == nil {
+		err := returnStock(ctx, tx, models.CartLine{VariantID: variantID, Quantity: 1})
+		if err != nil {
+			return err
+		}
+	} else {
+		orderID, err = chargeWinningBid(ctx, tx, leading, itemID, variantID, at)
+		if err != nil {
+			return err
+		}
+	}
+
+	closeAuctionQuery, auctionUpdArgs, err := sq.Update(auctionsTable).
+		Set(auctionsClosedAtColumn, at).
+		Set(auctionsOrderIDColumn, orderID).
+		Where(sq.Eq{auctionsIDColumn: auctionID}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, closeAuctionQuery, auctionUpdArgs...)
+	return err
+}
+
+// chargeWinningBid places the order of the winner for the amount of the bid and puts the unit into their inventory.
+func chargeWinningBid(ctx context.Context, tx *sql.Tx, bid *heldBid, itemID, variantID int, at time.Time) (*int, error) {
+	err := markBidReleased(ctx, tx, bid.id, at)
+	if err != nil {
+		return nil, err
+	}
+
+	insertOrderQuery, orderInsArgs, err := sq.Insert(ordersTable).
+		Columns(ordersUserIDColumn, ordersTotalColumn, ordersStatusColumn, ordersCreatedAtColumn, ordersStatusUpdatedAtColumn).
+		Values(bid.userID, bid.amount, models.OrderStatusPlaced, at, at).
+		Suffix("RETURNING " + ordersIDColumn).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var orderID int
+	err = tx.QueryRowContext(ctx, insertOrderQuery, orderInsArgs...).Scan(&orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	insertPurchaseQuery, purchaseInsArgs, err := sq.Insert(purchasesTable).
+		Columns(purchasesOrderIDColumn, purchasesUserIDColumn, purchasesItemIDColumn, purchasesVariantIDColumn,
+			purchasesPriceColumn, purchasesQuantityColumn, purchasesDiscountColumn, purchasesTimeColumn).
+		Values(orderID, bid.userID, itemID, variantID, bid.amount, 1, 0, at).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, insertPurchaseQuery, purchaseInsArgs...)
+	if err != nil {
+		return nil, err
+	}
+
+	err = addUserItems(ctx, tx, bid.userID, []models.CartLine{{VariantID: variantID, Quantity: 1}})
+	if err != nil {
+		return nil, err
+	}
+
+	return &orderID, nil
+}
@@ -0,0 +1,201 @@
+package db
+
+import (
+	"context"
+	"log"
+	"merch_shop/internal/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	lockAuctionQueryRegexp = `
+		SELECT starting_bid, min_increment, closed_at IS NULL AND ends_at > \$1 FROM auctions WHERE id = \$2 FOR UPDATE
+	`
+	selectLeadingBidQueryRegexp = `SELECT id, user_id, amount FROM bids WHERE auction_id = \$1 AND released_at IS NULL`
+	releaseBidQueryRegexp       = `UPDATE bids SET released_at = \$1 WHERE id = \$2`
+	insertBidQueryRegexp        = `INSERT INTO bids \(auction_id,user_id,amount,created_at\) VALUES \(\$1,\$2,\$3,\$4\)`
+	selectEndedAuctionsRegexp   = `SELECT id FROM auctions WHERE closed_at IS NULL AND ends_at <= \$1 ORDER BY id`
+	lockEndedAuctionQueryRegexp = `
+		SELECT auctions.variant_id, item_variants.item_id FROM auctions JOIN item_variants ON (.*)
+		WHERE auctions.closed_at IS NULL AND auctions.id = \$1 FOR UPDATE OF auctions
+	`
+	closeAuctionQueryRegexp = `UPDATE auctions SET closed_at = \$1, order_id = \$2 WHERE id = \$3`
+)
+
+func TestPlaceBid(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer mockDB.Close()
+
+	db := storage{db: mockDB}
+
+	auctionID, userID := 3, 5
+	auctionColumns := []string{auctionsStartingBidColumn, auctionsMinIncrementColumn, "open"}
+	bidColumns := []string{bidsIDColumn, bidsUserIDColumn, bidsAmountColumn}
+
+	testCases := []struct {
+		name       string
+		amount     int
+		dbBehavior func()
+
+		expectedErr error
+	}{
+		{
+			name:   "first bid",
+			amount: 100,
+			dbBehavior: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockAuctionQueryRegexp).WithArgs(sqlmock.AnyArg(), auctionID).
+					WillReturnRows(sqlmock.NewRows(auctionColumns).AddRow(100, 10, true))
+				mock.ExpectQuery(selectLeadingBidQueryRegexp).WithArgs(auctionID).WillReturnRows(sqlmock.NewRows(bidColumns))
+				mock.ExpectExec(refundBalanceQueryRegexp).WithArgs(-100, userID).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(insertBidQueryRegexp).WithArgs(auctionID, userID, 100, sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			// The outbid user has the smaller id, so their row is updated first.
+			name:   "outbid coins are released",
+			amount: 120,
+			dbBehavior: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockAuctionQueryRegexp).WithArgs(sqlmock.AnyArg(), auctionID).
+					WillReturnRows(sqlmock.NewRows(auctionColumns).AddRow(100, 10, true))
+				mock.ExpectQuery(selectLeadingBidQueryRegexp).WithArgs(auctionID).
+					WillReturnRows(sqlmock.NewRows(bidColumns).AddRow(8, 2, 110))
+				mock.ExpectExec(releaseBidQueryRegexp).WithArgs(sqlmock.AnyArg(), 8).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(refundBalanceQueryRegexp).WithArgs(110, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(refundBalanceQueryRegexp).WithArgs(-120, userID).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(insertBidQueryRegexp).WithArgs(auctionID, userID, 120, sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(9, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:   "bid below the increment",
+			amount: 115,
+			dbBehavior: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockAuctionQueryRegexp).WithArgs(sqlmock.AnyArg(), auctionID).
+					WillReturnRows(sqlmock.NewRows(auctionColumns).AddRow(100, 10, true))
+				mock.ExpectQuery(selectLeadingBidQueryRegexp).WithArgs(auctionID).
+					WillReturnRows(sqlmock.NewRows(bidColumns).AddRow(8, 2, 110))
+				mock.ExpectRollback()
+			},
+			expectedErr: ErrBidTooLow,
+		},
+		{
+			name:   "leading already",
+			amount: 150,
+			dbBehavior: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockAuctionQueryRegexp).WithArgs(sqlmock.AnyArg(), auctionID).
+					WillReturnRows(sqlmock.NewRows(auctionColumns).AddRow(100, 10, true))
+				mock.ExpectQuery(selectLeadingBidQueryRegexp).WithArgs(auctionID).
+					WillReturnRows(sqlmock.NewRows(bidColumns).AddRow(8, userID, 110))
+				mock.ExpectRollback()
+			},
+			expectedErr: ErrAlreadyLeading,
+		},
+		{
+			name:   "not enough coins",
+			amount: 100,
+			dbBehavior: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockAuctionQueryRegexp).WithArgs(sqlmock.AnyArg(), auctionID).
+					WillReturnRows(sqlmock.NewRows(auctionColumns).AddRow(100, 10, true))
+				mock.ExpectQuery(selectLeadingBidQueryRegexp).WithArgs(auctionID).WillReturnRows(sqlmock.NewRows(bidColumns))
+				mock.ExpectExec(refundBalanceQueryRegexp).WithArgs(-100, userID).WillReturnError(&pq.Error{Code: "23514"})
+				mock.ExpectRollback()
+			},
+			expectedErr: ErrNotEnoughCoins,
+		},
+		{
+			name:   "auction ended",
+			amount: 100,
+			dbBehavior: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockAuctionQueryRegexp).WithArgs(sqlmock.AnyArg(), auctionID).
+					WillReturnRows(sqlmock.NewRows(auctionColumns).AddRow(100, 10, false))
+				mock.ExpectRollback()
+			},
+			expectedErr: ErrAuctionClosed,
+		},
+		{
+			name:   "no auction",
+			amount: 100,
+			dbBehavior: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockAuctionQueryRegexp).WithArgs(sqlmock.AnyArg(), auctionID).
+					WillReturnRows(sqlmock.NewRows(auctionColumns))
+				mock.ExpectRollback()
+			},
+			expectedErr: ErrNoAuction,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.dbBehavior()
+
+			err := db.PlaceBid(context.Background(), userID, auctionID, tc.amount)
+			assert.Equal(t, tc.expectedErr, err)
+		})
+	}
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCloseAuctions(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer mockDB.Close()
+
+	db := storage{db: mockDB}
+
+	at := time.Date(2025, 2, 5, 12, 30, 0, 0, time.UTC)
+	winnerID := 5
+	auctionColumns := []string{auctionsVariantIDColumn, itemVariantsItemIDColumn}
+	bidColumns := []string{bidsIDColumn, bidsUserIDColumn, bidsAmountColumn}
+
+	mock.ExpectQuery(selectEndedAuctionsRegexp).WithArgs(at).
+		WillReturnRows(sqlmock.NewRows([]string{auctionsIDColumn}).AddRow(3).AddRow(4).AddRow(6))
+	// Auction 3 is won: the held bid pays for the order.
+	mock.ExpectBegin()
+	mock.ExpectQuery(lockEndedAuctionQueryRegexp).WithArgs(3).WillReturnRows(sqlmock.NewRows(auctionColumns).AddRow(7, 2))
+	mock.ExpectQuery(selectLeadingBidQueryRegexp).WithArgs(3).WillReturnRows(sqlmock.NewRows(bidColumns).AddRow(9, winnerID, 300))
+	mock.ExpectExec(releaseBidQueryRegexp).WithArgs(at, 9).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(insertOrderQueryRegexp).WithArgs(winnerID, 300, models.OrderStatusPlaced, at, at).
+		WillReturnRows(sqlmock.NewRows([]string{ordersIDColumn}).AddRow(11))
+	mock.ExpectExec(insertPurchasesQueryRegexp).WithArgs(11, winnerID, 2, 7, 300, 1, 0, at).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(upsertUserItemsQueryRegexp).WithArgs(winnerID, 7, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(closeAuctionQueryRegexp).WithArgs(at, 11, 3).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	// Auction 4 has no bids, its unit goes back to stock.
+	mock.ExpectBegin()
+	mock.ExpectQuery(lockEndedAuctionQueryRegexp).WithArgs(4).WillReturnRows(sqlmock.NewRows(auctionColumns).AddRow(8, 2))
+	mock.ExpectQuery(selectLeadingBidQueryRegexp).WithArgs(4).WillReturnRows(sqlmock.NewRows(bidColumns))
+	mock.ExpectExec(returnStockQueryRegexp).WithArgs(1, 8).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(closeAuctionQueryRegexp).WithArgs(at, nil, 4).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	// Auction 6 has been closed by another instance meanwhile.
+	mock.ExpectBegin()
+	mock.ExpectQuery(lockEndedAuctionQueryRegexp).WithArgs(6).WillReturnRows(sqlmock.NewRows(auctionColumns))
+	mock.ExpectCommit()
+
+	err = db.CloseAuctions(context.Background(), at)
+	assert.NoError(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
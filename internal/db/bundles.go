@@ -0,0 +1,353 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"merch_shop/internal/models"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+)
+
+// orderBundle is a bundle being bought, price is charged for all bundles of the order.
+type orderBundle struct {
+	id    int
+	name  string
+	price int
+}
+
+// CreateBundle adds a bundle with its components in one transaction.
+func (s *storage) CreateBundle(ctx context.Context, bundle *models.BundleRequest,
+	lines []models.CartLine) (*models.Bundle, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := createBundleTx(ctx, tx, bundle, lines)
+	if err != nil {
+		txErr := tx.Rollback()
+		if txErr != nil {
+			log.Printf("tx rollback error: %s", txErr.Error())
+		}
+		return nil, bundleWriteError(err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+func createBundleTx(ctx context.Context, tx *sql.Tx, bundle *models.BundleRequest,
+	lines []models.CartLine) (*models.Bundle, error) {
+	insertBundleQuery, bundleInsArgs, err := sq.Insert(bundlesTable).
+		Columns(bundlesNameColumn, bundlesDescriptionColumn, bundlesPriceColumn).
+		Values(bundle.Name, bundle.Description, bundle.Price).
+		Suffix("RETURNING " + bundlesIDColumn).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var bundleID int
+	err = tx.QueryRowContext(ctx, insertBundleQuery, bundleInsArgs...).Scan(&bundleID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lines are merged, so a variant listed twice becomes one component.
+	insertBundleItems := sq.Insert(bundleItemsTable).
+		Columns(bundleItemsBundleIDColumn, bundleItemsVariantIDColumn, bundleItemsQuantityColumn)
+	for _, line := range mergeCartLines(lines) {
+		insertBundleItems = insertBundleItems.Values(bundleID, line.VariantID, line.Quantity)
+	}
+
+	insertBundleItemsQuery, bundleItemsInsArgs, err := insertBundleItems.PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, insertBundleItemsQuery, bundleItemsInsArgs...)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := getBundle(ctx, tx, bundleID)
+	if err != nil {
+		return nil, err
+	}
+	if created.Price >= created.RegularPrice {
+		return nil, ErrBundleDearer
+	}
+
+	return created, nil
+}
+
+// GetBundles returns all bundles including inactive ones.
+func (s *storage) GetBundles(ctx context.Context) ([]models.Bundle, error) {
+	selectBundlesQuery, bundlesArgs, err := selectBundles(time.Now()).PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, selectBundlesQuery, bundlesArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bundles := make([]models.Bundle, 0)
+	for rows.Next() {
+		bundle, err := scanBundle(rows)
+		if err != nil {
+			return nil, err
+		}
+		bundles = append(bundles, *bundle)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return bundles, nil
+}
+
+// SetBundleActive stops or resumes selling a bundle. Bundles are never deleted, orders keep referencing them.
+func (s *storage) SetBundleActive(ctx context.Context, bundleID int, active bool) (*models.Bundle, error) {
+	updateBundleQuery, bundleUpdArgs, err := sq.Update(bundlesTable).
+		Set(bundlesActiveColumn, active).
+		Where(sq.Eq{bundlesIDColumn: bundleID}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.db.ExecContext(ctx, updateBundleQuery, bundleUpdArgs...)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if updated == 0 {
+		return nil, ErrNoBundle
+	}
+
+	return getBundle(ctx, s.db, bundleID)
+}
+
+// getBundle reads one bundle as GetBundles does, q is either the storage or a transaction.
+func getBundle(ctx context.Context, q rowQuerier, bundleID int) (*models.Bundle, error) {
+	selectBundleQuery, bundleArgs, err := selectBundles(time.Now()).
+		Where(sq.Eq{fmt.Sprintf("%s.%s", bundlesTable, bundlesIDColumn): bundleID}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	bundle, err := scanBundle(q.QueryRowContext(ctx, selectBundleQuery, bundleArgs...))
+	if err == sql.ErrNoRows {
+		return nil, ErrNoBundle
+	}
+
+	return bundle, err
+}
+
+// selectBundles selects bundles with components aggregated into a JSON array and priced at the given time.
+func selectBundles(at time.Time) sq.SelectBuilder {
+	column := func(table, column string) string {
+		return fmt.Sprintf("%s.%s", table, column)
+	}
+	quantity := column(bundleItemsTable, bundleItemsQuantityColumn)
+	stock := column(itemVariantsTable, itemVariantsStockColumn)
+
+	query := sq.Select(
+		column(bundlesTable, bundlesIDColumn),
+		column(bundlesTable, bundlesNameColumn),
+		column(bundlesTable, bundlesDescriptionColumn),
+		column(bundlesTable, bundlesPriceColumn),
+		column(bundlesTable, bundlesActiveColumn),
+		fmt.Sprintf("SUM(COALESCE(%s, %s) * %s)",
+			column(itemVariantsTable, itemVariantsPriceColumn), column(currentPricesAlias, itemPricesPriceColumn), quantity),
+		fmt.Sprintf("bool_and(%s AND (%s IS NULL OR %s >= %s))", column(itemsTable, itemsActiveColumn), stock, stock, quantity),
+		fmt.Sprintf("json_agg(json_build_object('itemId', %s, 'item', %s, 'variantId', %s, 'variant', %s, "+
+			"'quantity', %s) ORDER BY %s)",
+			column(itemsTable, itemsIDColumn), column(itemsTable, itemsTypeColumn),
+			column(itemVariantsTable, itemVariantsIDColumn), column(itemVariantsTable, itemVariantsNameColumn),
+			quantity, column(itemVariantsTable, itemVariantsIDColumn))).
+		From(bundlesTable).
+		Join(fmt.Sprintf("%s ON %s = %s", bundleItemsTable,
+			column(bundleItemsTable, bundleItemsBundleIDColumn), column(bundlesTable, bundlesIDColumn))).
+		Join(fmt.Sprintf("%s ON %s = %s", itemVariantsTable,
+			column(bundleItemsTable, bundleItemsVariantIDColumn), column(itemVariantsTable, itemVariantsIDColumn))).
+		Join(fmt.Sprintf("%s ON %s = %s", itemsTable,
+			column(itemVariantsTable, itemVariantsItemIDColumn), column(itemsTable, itemsIDColumn)))
+
+	return joinCurrentPrice(query, at).
+		GroupBy(column(bundlesTable, bundlesIDColumn)).
+		OrderBy(column(bundlesTable, bundlesIDColumn))
+}
+
+// scanBundle reads a row selected with selectBundles.
+func scanBundle(row interface{ Scan(dest ...any) error }) (*models.Bundle, error) {
+	bundle := &models.Bundle{}
+	var itemsAvailable bool
+	var items []byte
+	err := row.Scan(&bundle.ID, &bundle.Name, &bundle.Description, &bundle.Price, &bundle.Active,
+		&bundle.RegularPrice, &itemsAvailable, &items)
+	if err != nil {
+		return nil, err
+	}
+	bundle.Available = bundle.Active && itemsAvailable && bundle.Price <= bundle.RegularPrice
+
+	err = json.Unmarshal(items, &bundle.Items)
+	if err != nil {
+		return nil, err
+	}
+
+	return bundle, nil
+}
+
+func bundleWriteError(err error) error {
+	if pqErr, ok := err.(*pq.Error); ok {
+		switch pqErr.Code.Name() {
+		case "unique_violation":
+			return ErrBundleExists
+		case "foreign_key_violation":
+			return ErrNoVariant
+		}
+	}
+	return err
+}
+
+// BuyBundle buys the given number of bundles as one order of their components.
+func (s *storage) BuyBundle(ctx context.Context, userID, bundleID, quantity,
+	approvalThreshold int) (*models.OrderSummary, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	summary, err := buyBundleTx(ctx, tx, userID, bundleID, quantity, approvalThreshold)
+	if err != nil {
+		txErr := tx.Rollback()
+		if txErr != nil {
+			log.Printf("tx rollback error: %s", txErr.Error())
+		}
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return summary, nil
+}
+
+// buyBundleTx expands the bundle into component lines and buys them like any other order,
+// so stock is reserved and purchase limits are checked for every component.
+func buyBundleTx(ctx context.Context, tx *sql.Tx, userID, bundleID, quantity,
+	approvalThreshold int) (*models.OrderSummary, error) {
+	column := func(table, column string) string {
+		return fmt.Sprintf("%s.%s", table, column)
+	}
+
+	selectBundleQuery, bundleArgs, err := sq.Select(
+		column(bundlesTable, bundlesNameColumn),
+		column(bundlesTable, bundlesPriceColumn),
+		column(bundleItemsTable, bundleItemsVariantIDColumn),
+		column(bundleItemsTable, bundleItemsQuantityColumn)).
+		From(bundlesTable).
+		Join(fmt.Sprintf("%s ON %s = %s", bundleItemsTable,
+			column(bundleItemsTable, bundleItemsBundleIDColumn), column(bundlesTable, bundlesIDColumn))).
+		Where(sq.Eq{column(bundlesTable, bundlesIDColumn): bundleID, column(bundlesTable, bundlesActiveColumn): true}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	bundle, lines, err := selectBundleLines(ctx, tx, selectBundleQuery, bundleArgs, quantity)
+	if err != nil {
+		return nil, err
+	}
+	bundle.id = bundleID
+
+	summary, err := buyItemsTx(ctx, tx, userID, nil, bundle, lines, "", approvalThreshold)
+	if err == ErrNoItem {
+		return nil, ErrBundleRetired
+	}
+
+	return summary, err
+}
+
+func selectBundleLines(ctx context.Context, tx *sql.Tx, query string, args []any,
+	quantity int) (*orderBundle, []models.CartLine, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	bundle := &orderBundle{}
+	lines := make([]models.CartLine, 0)
+	for rows.Next() {
+		var line models.CartLine
+		err := rows.Scan(&bundle.name, &bundle.price, &line.VariantID, &line.Quantity)
+		if err != nil {
+			return nil, nil, err
+		}
+		line.Quantity *= quantity
+		lines = append(lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	if len(lines) == 0 {
+		return nil, nil, ErrNoBundle
+	}
+	bundle.price *= quantity
+
+	return bundle, lines, nil
+}
+
+// bundleDiscounts splits the bundle saving between order lines in proportion to their totals.
+// Components may have got cheaper than the bundle since it was created, such a bundle is not sold
+// rather than charged at a price different from its own.
+func bundleDiscounts(bundle *orderBundle, lines []models.CartLine, items map[int]purchasedItem) ([]int, error) {
+	discounts := make([]int, len(lines))
+	regular := 0
+	for _, line := range lines {
+		regular += items[line.VariantID].price * line.Quantity
+	}
+
+	saving := regular - bundle.price
+	if saving < 0 {
+		return nil, ErrBundleDearer
+	}
+	if saving == 0 {
+		return discounts, nil
+	}
+
+	left := saving
+	for i, line := range lines {
+		discounts[i] = saving * items[line.VariantID].price * line.Quantity / regular
+		left -= discounts[i]
+	}
+	// Rounded down shares leave a few coins, they go to the first lines that are not free yet.
+	for i, line := range lines {
+		extra := min(left, items[line.VariantID].price*line.Quantity-discounts[i])
+		discounts[i] += extra
+		left -= extra
+	}
+
+	return discounts, nil
+}
@@ -0,0 +1,155 @@
+package db
+
+import (
+	"context"
+	"log"
+	"merch_shop/internal/models"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+const selectBundleLinesQueryRegexp = `
+	SELECT bundles.name, bundles.price, bundle_items.variant_id, bundle_items.quantity
+	FROM bundles JOIN bundle_items ON bundle_items.bundle_id = bundles.id WHERE bundles.active = \$1 AND bundles.id = \$2
+`
+
+func TestBuyBundle(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer mockDB.Close()
+
+	db := storage{db: mockDB}
+
+	userID, bundleID := 1, 3
+	bundleColumns := []string{bundlesNameColumn, bundlesPriceColumn, bundleItemsVariantIDColumn, bundleItemsQuantityColumn}
+	bundleRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows(bundleColumns).
+			AddRow("onboarding", 100, 2, 1).AddRow("onboarding", 100, 4, 2).AddRow("onboarding", 100, 6, 1)
+	}
+
+	testCases := []struct {
+		name       string
+		dbBehavior func()
+
+		expected    *models.OrderSummary
+		expectedErr error
+	}{
+		{
+			name: "bundle saving is split between components",
+			dbBehavior: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectBundleLinesQueryRegexp).WithArgs(true, bundleID).WillReturnRows(bundleRows())
+				mock.ExpectQuery(selectItemsForPurchaseQueryRegexp).
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 2, 4, 6, true).
+					WillReturnRows(sqlmock.NewRows(purchasedItemColumns).
+						AddRow(2, 1, "cup", "kitchen", "default", 20, 5, nil, nil).
+						AddRow(4, 3, "pen", "office", "blue", 10, nil, nil, nil).
+						AddRow(6, 5, "t-shirt", "clothes", "M", 80, nil, nil, nil))
+				mock.ExpectExec(reserveStockQueryRegexp).WithArgs(2, 2, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery(updateBalanceQueryRegexp).WithArgs(200, userID).
+					WillReturnRows(sqlmock.NewRows([]string{usersBalanceColumn}).AddRow(800))
+				mock.ExpectQuery(insertOrderQueryRegexp).WithArgs(userID, 200, models.OrderStatusPlaced,
+					sqlmock.AnyArg(), sqlmock.AnyArg(), nil, 40, nil, nil, bundleID, nil).
+					WillReturnRows(sqlmock.NewRows([]string{ordersIDColumn}).AddRow(7))
+				mock.ExpectExec(upsertUserItemsQueryRegexp).WithArgs(userID, 2, 2, userID, 4, 4, userID, 6, 2).
+					WillReturnResult(sqlmock.NewResult(0, 3))
+				mock.ExpectExec(insertPurchasesQueryRegexp).WillReturnResult(sqlmock.NewResult(3, 3))
+				mock.ExpectCommit()
+			},
+			expected: &models.OrderSummary{
+				Order: models.Order{
+					ID: 7, Status: models.OrderStatusPlaced, Bundle: "onboarding",
+					Items: []models.OrderLine{
+						{Item: "cup", Variant: "default", Quantity: 2, Price: 20, Discount: 8, Total: 32},
+						{Item: "pen", Variant: "blue", Quantity: 4, Price: 10, Discount: 6, Total: 34},
+						{Item: "t-shirt", Variant: "M", Quantity: 2, Price: 80, Discount: 26, Total: 134},
+					},
+					Discount: 40, Total: 200,
+				},
+				Balance: 800,
+			},
+		},
+		{
+			name: "unknown or inactive bundle",
+			dbBehavior: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectBundleLinesQueryRegexp).WithArgs(true, bundleID).
+					WillReturnRows(sqlmock.NewRows(bundleColumns))
+				mock.ExpectRollback()
+			},
+			expectedErr: ErrNoBundle,
+		},
+		{
+			name: "retired component",
+			dbBehavior: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectBundleLinesQueryRegexp).WithArgs(true, bundleID).WillReturnRows(bundleRows())
+				mock.ExpectQuery(selectItemsForPurchaseQueryRegexp).
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 2, 4, 6, true).
+					WillReturnRows(sqlmock.NewRows(purchasedItemColumns).
+						AddRow(2, 1, "cup", "kitchen", "default", 20, 5, nil, nil).
+						AddRow(4, 3, "pen", "office", "blue", 10, nil, nil, nil))
+				mock.ExpectRollback()
+			},
+			expectedErr: ErrBundleRetired,
+		},
+		{
+			name: "component out of stock",
+			dbBehavior: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectBundleLinesQueryRegexp).WithArgs(true, bundleID).WillReturnRows(bundleRows())
+				mock.ExpectQuery(selectItemsForPurchaseQueryRegexp).
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 2, 4, 6, true).
+					WillReturnRows(sqlmock.NewRows(purchasedItemColumns).
+						AddRow(2, 1, "cup", "kitchen", "default", 20, 1, nil, nil).
+						AddRow(4, 3, "pen", "office", "blue", 10, nil, nil, nil).
+						AddRow(6, 5, "t-shirt", "clothes", "M", 80, nil, nil, nil))
+				mock.ExpectExec(reserveStockQueryRegexp).WithArgs(2, 2, 2).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+			},
+			expectedErr: ErrOutOfStock,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.dbBehavior()
+
+			summary, err := db.BuyBundle(context.Background(), userID, bundleID, 2, 0)
+			assert.Equal(t, tc.expectedErr, err)
+			if summary != nil {
+				summary.CreatedAt, summary.StatusUpdatedAt = tc.expected.CreatedAt, tc.expected.StatusUpdatedAt
+			}
+			assert.Equal(t, tc.expected, summary)
+		})
+	}
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBundleDiscounts(t *testing.T) {
+	lines := []models.CartLine{{VariantID: 2, Quantity: 1}, {VariantID: 4, Quantity: 3}}
+	items := map[int]purchasedItem{2: {price: 20}, 4: {price: 10}}
+
+	testCases := []struct {
+		name        string
+		price       int
+		expected    []int
+		expectedErr error
+	}{
+		{name: "saving is split in proportion", price: 40, expected: []int{4, 6}},
+		{name: "rounding leftovers go to the first line", price: 43, expected: []int{3, 4}},
+		{name: "bundle at the regular price has no saving", price: 50, expected: []int{0, 0}},
+		{name: "bundle dearer than components is not sold", price: 60, expectedErr: ErrBundleDearer},
+	}
+
+	for _, tc := range testCases {
+		discounts, err := bundleDiscounts(&orderBundle{price: tc.price}, lines, items)
+		assert.Equal(t, tc.expectedErr, err, tc.name)
+		assert.Equal(t, tc.expected, discounts, tc.name)
+	}
+}
@@ -5,84 +5,393 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"merch_shop/internal/models"
+	"sort"
+	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/lib/pq"
 )
 
-func (s *storage) BuyItemByItemID(ctx context.Context, userID, itemID int) error {
-
-	selectItemQuery, itemSelectArgs, err := sq.Select(itemsTypeColumn, itemsPriceColumn).
-		From(itemsTable).
-		Where(sq.Eq{itemsIDColumn: itemID}).
-		PlaceholderFormat(sq.Dollar).ToSql()
+// BuyItems buys all lines in one transaction, so either every line is bought or none.
+// An empty promo buys the lines at full price. An order above a positive approvalThreshold
+// waits for approval, see buyItemsTx.
+func (s *storage) BuyItems(ctx context.Context, userID int, lines []models.CartLine, promo string,
+	approvalThreshold int) (*models.OrderSummary, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	var itemType string
-	var itemPrice int
-	row := s.db.QueryRowContext(ctx, selectItemQuery, itemSelectArgs...)
-	err = row.Scan(&itemType, &itemPrice)
+	summary, err := buyItemsTx(ctx, tx, userID, nil, nil, lines, promo, approvalThreshold)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return ErrNoItem
+		txErr := tx.Rollback()
+		if txErr != nil {
+			log.Printf("tx rollback error: %s", txErr.Error())
 		}
-		return err
+		return nil, err
 	}
 
-	updateUserBalanceQuery, balanceUpdArgs, err := sq.Update(usersTable).
-		Set(usersBalanceColumn, sq.Expr(
-			fmt.Sprintf("%s - %d", usersBalanceColumn, itemPrice))).
-		Where(sq.Eq{userIDColumn: userID}).
-		PlaceholderFormat(sq.Dollar).ToSql()
+	err = tx.Commit()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	updateUserInventoryQuery, inventoryUpdArgs, err := sq.Update(usersTable).
-		Set(usersInventoryColumn, sq.Expr(
-			fmt.Sprintf("jsonb_set(%s, '{%s}', (SELECT (COALESCE(%s -> '%s', '0'))::int + 1 FROM %s WHERE %s = %d)::text::jsonb)",
-				usersInventoryColumn, itemType, usersInventoryColumn, itemType, usersTable, userIDColumn, userID))).
-		Where(sq.Eq{userIDColumn: userID}).
-		PlaceholderFormat(sq.Dollar).ToSql()
+	return summary, nil
+}
+
+// BuyGift buys lines as BuyItems does, but puts them into the inventory of the recipient.
+// The payer is charged and the order is listed for both users.
+func (s *storage) BuyGift(ctx context.Context, payerID int, recipient string, lines []models.CartLine,
+	promo, note string, approvalThreshold int) (*models.OrderSummary, error) {
+	recipientID, _, err := s.GetUser(ctx, recipient)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if *recipientID == payerID {
+		return nil, ErrGiftToSelf
 	}
 
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	_, err = tx.ExecContext(ctx, updateUserBalanceQuery, balanceUpdArgs...)
+	gift := &giftRecipient{userID: *recipientID, username: recipient, note: note}
+	summary, err := buyItemsTx(ctx, tx, payerID, gift, nil, lines, promo, approvalThreshold)
 	if err != nil {
 		txErr := tx.Rollback()
 		if txErr != nil {
 			log.Printf("tx rollback error: %s", txErr.Error())
 		}
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return summary, nil
+}
+
+// giftRecipient receives the items of an order paid by another user.
+type giftRecipient struct {
+	userID   int
+	username string
+	note     string
+}
+
+// purchasedItem is a variant being bought with its item.
+type purchasedItem struct {
+	itemID   int
+	itemType string
+	category string
+	variant  string
+	price    int
+	stock    *int
+	limit    *models.PurchaseLimit
+}
+
+// buyItemsTx charges the user and puts the lines into their inventory, or into the inventory
+// of the gift recipient when it is set. Lines of a bundle are charged the bundle price.
+// An order with the total above a positive approvalThreshold is pending approval instead: the coins
+// are held and stock is reserved, but the lines reach the inventory only once the order is approved.
+func buyItemsTx(ctx context.Context, tx *sql.Tx, userID int, gift *giftRecipient, bundle *orderBundle,
+	lines []models.CartLine, promoCode string, approvalThreshold int) (*models.OrderSummary, error) {
+	lines = mergeCartLines(lines)
+
+	variantIDs := make([]int, 0, len(lines))
+	for _, line := range lines {
+		variantIDs = append(variantIDs, line.VariantID)
+	}
+
+	column := func(table, column string) string {
+		return fmt.Sprintf("%s.%s", table, column)
+	}
+
+	timing := time.Now()
+	query := sq.Select(
+		column(itemVariantsTable, itemVariantsIDColumn),
+		column(itemsTable, itemsIDColumn),
+		column(itemsTable, itemsTypeColumn),
+		column(itemsTable, itemsCategoryColumn),
+		column(itemVariantsTable, itemVariantsNameColumn),
+		fmt.Sprintf("COALESCE(%s, %s)",
+			column(itemVariantsTable, itemVariantsPriceColumn), column(currentPricesAlias, itemPricesPriceColumn)),
+		column(itemVariantsTable, itemVariantsStockColumn),
+		column(itemsTable, itemsLimitColumn),
+		column(itemsTable, itemsLimitDaysColumn)).
+		From(itemVariantsTable).
+		Join(fmt.Sprintf("%s ON %s = %s", itemsTable,
+			column(itemVariantsTable, itemVariantsItemIDColumn), column(itemsTable, itemsIDColumn)))
+
+	selectItemsQuery, itemsSelectArgs, err := joinCurrentPrice(query, timing).
+		// Retired items are not sold anymore.
+		Where(sq.Eq{column(itemVariantsTable, itemVariantsIDColumn): variantIDs, column(itemsTable, itemsActiveColumn): true}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := selectPurchasedItems(ctx, tx, selectItemsQuery, itemsSelectArgs)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) != len(lines) {
+		return nil, ErrNoItem
+	}
+
+	summary := &models.OrderSummary{Order: models.Order{
+		Status:          models.OrderStatusPlaced,
+		Items:           make([]models.OrderLine, 0, len(lines)),
+		CreatedAt:       timing,
+		StatusUpdatedAt: timing,
+	}}
+
+	var promo *models.PromoCode
+	discounts := make([]int, len(lines))
+	if promoCode != "" {
+		promo, err = selectValidPromo(ctx, tx, promoCode, timing)
+		if err != nil {
+			return nil, err
+		}
+
+		discounts, err = promoDiscounts(promo, lines, items)
+		if err != nil {
+			return nil, err
+		}
+		summary.Promo = promo.Code
+	}
+
+	var bundleID *int
+	if bundle != nil {
+		discounts, err = bundleDiscounts(bundle, lines, items)
+		if err != nil {
+			return nil, err
+		}
+		summary.Bundle = bundle.name
+		bundleID = &bundle.id
+	}
+
+	ownerID := userID
+	var recipientID *int
+	var giftNote *string
+	if gift != nil {
+		ownerID, recipientID = gift.userID, &gift.userID
+		if gift.note != "" {
+			giftNote = &gift.note
+		}
+		summary.Gift = &models.OrderGift{To: gift.username, Note: gift.note}
+	}
+
+	for i, line := range lines {
+		item := items[line.VariantID]
+		if item.stock != nil {
+			err := reserveStock(ctx, tx, line)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		lineTotal := item.price*line.Quantity - discounts[i]
+		summary.Items = append(summary.Items, models.OrderLine{
+			Item: item.itemType, Variant: item.variant, Quantity: line.Quantity, Price: item.price,
+			Discount: discounts[i], Total: lineTotal,
+		})
+		summary.Discount += discounts[i]
+		summary.Total += lineTotal
+	}
+
+	// A gift of a limited item is checked against the window of the recipient too,
+	// whose row has to be locked along with the payer's.
+	if recipientID != nil && hasPurchaseLimits(items) {
+		err := lockUsers(ctx, tx, userID, *recipientID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	updateUserBalanceQuery, balanceUpdArgs, err := sq.Update(usersTable).
+		Set(usersBalanceColumn, sq.Expr(fmt.Sprintf("%s - ?", usersBalanceColumn), summary.Total)).
+		Where(sq.Eq{userIDColumn: userID}).
+		Suffix("RETURNING " + usersBalanceColumn).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.QueryRowContext(ctx, updateUserBalanceQuery, balanceUpdArgs...).Scan(&summary.Balance)
+	if err != nil {
 		if pqErr, ok := err.(*pq.Error); ok {
 			// From http://www.postgresql.org/docs/9.3/static/errcodes-appendix.html
 			if pqErr.Code.Name() == "check_violation" {
-				return ErrNotEnoughCoins
+				return nil, ErrNotEnoughCoins
 			}
 		}
-		return err
+		return nil, err
 	}
 
-	_, err = tx.ExecContext(ctx, updateUserInventoryQuery, inventoryUpdArgs...)
+	// The user row is locked by now, so concurrent purchases of the user see each other's lines.
+	err = checkPurchaseLimits(ctx, tx, userID, recipientID, lines, items, timing)
 	if err != nil {
-		txErr := tx.Rollback()
-		if txErr != nil {
-			log.Printf("tx rollback error: %s", txErr.Error())
+		return nil, err
+	}
+
+	var promoID *int
+	if promo != nil {
+		err := redeemPromo(ctx, tx, promo, userID)
+		if err != nil {
+			return nil, err
+		}
+		promoID = &promo.ID
+	}
+
+	var approverID *int
+	if approvalThreshold > 0 && summary.Total > approvalThreshold {
+		summary.Status = models.OrderStatusPendingApproval
+		approverID, err = assignApprover(ctx, tx, userID, recipientID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	insertOrderQuery, orderInsArgs, err := sq.Insert(ordersTable).
+		Columns(ordersUserIDColumn, ordersTotalColumn, ordersStatusColumn, ordersCreatedAtColumn, ordersStatusUpdatedAtColumn,
+			ordersPromoIDColumn, ordersDiscountColumn, ordersRecipientIDColumn, ordersGiftNoteColumn, ordersBundleIDColumn,
+			ordersApproverIDColumn).
+		Values(userID, summary.Total, summary.Status, timing, timing, promoID, summary.Discount, recipientID, giftNote,
+			bundleID, approverID).
+		Suffix("RETURNING " + ordersIDColumn).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.QueryRowContext(ctx, insertOrderQuery, orderInsArgs...).Scan(&summary.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if summary.Status == models.OrderStatusPlaced {
+		err := addUserItems(ctx, tx, ownerID, lines)
+		if err != nil {
+			return nil, err
 		}
+	}
+
+	insertPurchases := sq.Insert(purchasesTable).
+		Columns(purchasesOrderIDColumn, purchasesUserIDColumn, purchasesItemIDColumn, purchasesVariantIDColumn,
+			purchasesPriceColumn, purchasesQuantityColumn, purchasesDiscountColumn, purchasesTimeColumn)
+	for i, line := range lines {
+		item := items[line.VariantID]
+		insertPurchases = insertPurchases.Values(summary.ID, userID, item.itemID, line.VariantID, item.price, line.Quantity,
+			discounts[i], timing)
+	}
+
+	insertPurchasesQuery, purchasesInsArgs, err := insertPurchases.PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, insertPurchasesQuery, purchasesInsArgs...)
+	if err != nil {
+		return nil, err
+	}
+
+	return summary, nil
+}
+
+func selectPurchasedItems(ctx context.Context, tx *sql.Tx, query string, args []any) (map[int]purchasedItem, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make(map[int]purchasedItem)
+	for rows.Next() {
+		var variantID int
+		var item purchasedItem
+		var limit, limitDays *int
+		err := rows.Scan(&variantID, &item.itemID, &item.itemType, &item.category, &item.variant, &item.price, &item.stock,
+			&limit, &limitDays)
+		if err != nil {
+			return nil, err
+		}
+		if limit != nil && limitDays != nil {
+			item.limit = &models.PurchaseLimit{Quantity: *limit, WindowDays: *limitDays}
+		}
+		items[variantID] = item
+	}
+
+	return items, rows.Err()
+}
+
+// reserveStock takes the row lock on the variant, so concurrent buyers of the last
+// units wait and the condition is rechecked once the lock is released.
+func reserveStock(ctx context.Context, tx *sql.Tx, line models.CartLine) error {
+	reserveStockQuery, stockUpdArgs, err := sq.Update(itemVariantsTable).
+		Set(itemVariantsStockColumn, sq.Expr(fmt.Sprintf("%s - ?", itemVariantsStockColumn), line.Quantity)).
+		Where(sq.Eq{itemVariantsIDColumn: line.VariantID}).
+		Where(sq.GtOrEq{itemVariantsStockColumn: line.Quantity}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
 		return err
 	}
 
-	err = tx.Commit()
+	result, err := tx.ExecContext(ctx, reserveStockQuery, stockUpdArgs...)
 	if err != nil {
 		return err
 	}
 
+	reserved, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if reserved == 0 {
+		return ErrOutOfStock
+	}
+
 	return nil
 }
+
+// addUserItems puts the lines into the user's inventory. The lines must be merged,
+// so every variant appears once and the upsert never hits the same row twice.
+func addUserItems(ctx context.Context, tx *sql.Tx, userID int, lines []models.CartLine) error {
+	upsertUserItems := sq.Insert(userItemsTable).
+		Columns(userItemsUserIDColumn, userItemsVariantIDColumn, userItemsQuantityColumn).
+		Suffix(fmt.Sprintf("ON CONFLICT (%s, %s) DO UPDATE SET %s = %s.%s + EXCLUDED.%s",
+			userItemsUserIDColumn, userItemsVariantIDColumn, userItemsQuantityColumn,
+			userItemsTable, userItemsQuantityColumn, userItemsQuantityColumn))
+	for _, line := range lines {
+		upsertUserItems = upsertUserItems.Values(userID, line.VariantID, line.Quantity)
+	}
+
+	upsertUserItemsQuery, userItemsUpsArgs, err := upsertUserItems.PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, upsertUserItemsQuery, userItemsUpsArgs...)
+	return err
+}
+
+// mergeCartLines sums quantities of repeated variants and sorts lines by variant id,
+// so concurrent orders lock variant rows in the same order and never deadlock.
+func mergeCartLines(lines []models.CartLine) []models.CartLine {
+	quantities := make(map[int]int, len(lines))
+	for _, line := range lines {
+		quantities[line.VariantID] += line.Quantity
+	}
+
+	merged := make([]models.CartLine, 0, len(quantities))
+	for variantID, quantity := range quantities {
+		merged = append(merged, models.CartLine{VariantID: variantID, Quantity: quantity})
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].VariantID < merged[j].VariantID
+	})
+
+	return merged
+}
@@ -0,0 +1,481 @@
+package db
+
+import (
+	"context"
+	"log"
+	"merch_shop/internal/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	selectItemsForPurchaseQueryRegexp = `
+		SELECT item_variants.id, items.id, items.type, items.category, item_variants.name,
+		COALESCE\(item_variants.price, current_prices.price\),
+		item_variants.stock, items.purchase_limit, items.purchase_limit_days
+		FROM item_variants JOIN items ON item_variants.item_id = items.id
+		JOIN LATERAL \(SELECT item_prices.price FROM item_prices WHERE item_prices.item_id = items.id (.*)\) AS current_prices ON true
+		WHERE item_variants.id IN \((.*)\) AND items.active = \$(.*)
+	`
+	reserveStockQueryRegexp    = `UPDATE item_variants SET stock = stock - \$1 WHERE id = \$2 AND stock >= \$3`
+	updateBalanceQueryRegexp   = `UPDATE users SET balance = balance - \$1 WHERE id = \$2 RETURNING balance`
+	insertOrderQueryRegexp     = `INSERT INTO orders (.*) VALUES (.*) RETURNING id`
+	insertPurchasesQueryRegexp = `INSERT INTO purchases (.*) VALUES (.*)`
+	deleteCartQueryRegexp      = `DELETE FROM cart_items WHERE user_id = \$1 RETURNING variant_id, quantity`
+	upsertUserItemsQueryRegexp = `
+		INSERT INTO user_items \(user_id,variant_id,quantity\) VALUES (.*)
+		ON CONFLICT \(user_id, variant_id\) DO UPDATE SET quantity = user_items.quantity \+ EXCLUDED.quantity
+	`
+	selectPromoQueryRegexp = `
+		SELECT (.*), \(starts_at IS NULL OR starts_at <= \$1\) AND \(ends_at IS NULL OR ends_at > \$2\)
+		FROM promo_codes WHERE code = \$3
+	`
+	countPromoUsesQueryRegexp        = `SELECT COUNT\(\*\) FROM orders WHERE promo_id = \$1 AND user_id = \$2 AND status <> \$3`
+	selectWindowPurchasesQueryRegexp = `
+		SELECT purchases.timing, purchases.quantity FROM purchases LEFT JOIN orders ON purchases.order_id = orders.id
+		WHERE purchases.item_id = \$1 AND \(purchases.user_id = \$2 OR orders.recipient_id = \$3\) AND purchases.timing > \$4
+		AND \(orders.status IS NULL OR orders.status <> \$5\) ORDER BY purchases.timing
+	`
+	lockUsersQueryRegexp   = `SELECT id FROM users WHERE id IN \(\$1,\$2\) ORDER BY id FOR UPDATE`
+	selectUserQueryRegexp  = `SELECT id, password FROM users WHERE username = \$1`
+	redeemPromoQueryRegexp = `UPDATE promo_codes SET uses = uses \+ 1 WHERE id = \$1 AND \(max_uses IS NULL OR uses < max_uses\)`
+)
+
+var (
+	purchasedItemColumns = []string{itemVariantsIDColumn, itemVariantsItemIDColumn, itemsTypeColumn, itemsCategoryColumn,
+		itemVariantsNameColumn, itemPricesPriceColumn, itemVariantsStockColumn, itemsLimitColumn, itemsLimitDaysColumn}
+	validPromoColumns = append(promoColumns, "current")
+)
+
+func TestBuyItems(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer mockDB.Close()
+
+	db := storage{db: mockDB}
+
+	userID, promoID := 1, 5
+	cup := models.CartLine{VariantID: 2, Quantity: 3}
+	firstPurchase := time.Date(2025, 2, 5, 12, 30, 0, 0, time.UTC)
+	secondPurchase := firstPurchase.AddDate(0, 1, 0)
+	nextPurchase := secondPurchase.AddDate(0, 0, 365)
+	windowColumns := []string{purchasesTimeColumn, purchasesQuantityColumn}
+	pen := models.CartLine{VariantID: 4, Quantity: 1}
+
+	testCases := []struct {
+		name       string
+		lines      []models.CartLine
+		promo      string
+		dbBehavior func()
+
+		expected    *models.OrderSummary
+		expectedErr error
+	}{
+		{
+			name:  "untracked stock is not reserved",
+			lines: []models.CartLine{cup},
+			dbBehavior: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectItemsForPurchaseQueryRegexp).
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), cup.VariantID, true).
+					WillReturnRows(sqlmock.NewRows(purchasedItemColumns).AddRow(2, 1, "cup", "kitchen", "default", 20, nil, nil, nil))
+				mock.ExpectQuery(updateBalanceQueryRegexp).WithArgs(60, userID).
+					WillReturnRows(sqlmock.NewRows([]string{usersBalanceColumn}).AddRow(940))
+				mock.ExpectQuery(insertOrderQueryRegexp).WithArgs(userID, sqlmock.AnyArg(), models.OrderStatusPlaced,
+					sqlmock.AnyArg(), sqlmock.AnyArg(), nil, 0, nil, nil, nil, nil).
+					WillReturnRows(sqlmock.NewRows([]string{ordersIDColumn}).AddRow(7))
+				mock.ExpectExec(upsertUserItemsQueryRegexp).WithArgs(userID, cup.VariantID, 3).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(insertPurchasesQueryRegexp).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+			},
+			expected: &models.OrderSummary{
+				Order: models.Order{
+					ID: 7, Status: models.OrderStatusPlaced,
+					Items: []models.OrderLine{{Item: "cup", Variant: "default", Quantity: 3, Price: 20, Total: 60}}, Total: 60,
+				},
+				Balance: 940,
+			},
+		},
+		{
+			name:  "repeated lines are merged and reserved in variant order",
+			lines: []models.CartLine{pen, cup, {VariantID: 2, Quantity: 1}},
+			dbBehavior: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectItemsForPurchaseQueryRegexp).
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), cup.VariantID, pen.VariantID, true).
+					WillReturnRows(sqlmock.NewRows(purchasedItemColumns).
+						AddRow(4, 3, "pen", "office", "blue", 10, 1, nil, nil).AddRow(2, 1, "cup", "kitchen", "default", 20, 4, nil, nil))
+				mock.ExpectExec(reserveStockQueryRegexp).WithArgs(4, cup.VariantID, 4).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(reserveStockQueryRegexp).WithArgs(1, pen.VariantID, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery(updateBalanceQueryRegexp).WithArgs(90, userID).
+					WillReturnRows(sqlmock.NewRows([]string{usersBalanceColumn}).AddRow(910))
+				mock.ExpectQuery(insertOrderQueryRegexp).WithArgs(userID, sqlmock.AnyArg(), models.OrderStatusPlaced,
+					sqlmock.AnyArg(), sqlmock.AnyArg(), nil, 0, nil, nil, nil, nil).
+					WillReturnRows(sqlmock.NewRows([]string{ordersIDColumn}).AddRow(7))
+				mock.ExpectExec(upsertUserItemsQueryRegexp).WithArgs(userID, cup.VariantID, 4, userID, pen.VariantID, 1).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec(insertPurchasesQueryRegexp).WillReturnResult(sqlmock.NewResult(2, 2))
+				mock.ExpectCommit()
+			},
+			expected: &models.OrderSummary{
+				Order: models.Order{
+					ID: 7, Status: models.OrderStatusPlaced,
+					Items: []models.OrderLine{
+						{Item: "cup", Variant: "default", Quantity: 4, Price: 20, Total: 80},
+						{Item: "pen", Variant: "blue", Quantity: 1, Price: 10, Total: 10},
+					},
+					Total: 90,
+				},
+				Balance: 910,
+			},
+		},
+		{
+			name:  "not enough stock for quantity",
+			lines: []models.CartLine{cup},
+			dbBehavior: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectItemsForPurchaseQueryRegexp).
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), cup.VariantID, true).
+					WillReturnRows(sqlmock.NewRows(purchasedItemColumns).AddRow(2, 1, "cup", "kitchen", "default", 20, 2, nil, nil))
+				mock.ExpectExec(reserveStockQueryRegexp).WithArgs(3, cup.VariantID, 3).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+			},
+			expectedErr: ErrOutOfStock,
+		},
+		{
+			name:  "not enough coins releases reservation",
+			lines: []models.CartLine{cup},
+			dbBehavior: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectItemsForPurchaseQueryRegexp).
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), cup.VariantID, true).
+					WillReturnRows(sqlmock.NewRows(purchasedItemColumns).AddRow(2, 1, "cup", "kitchen", "default", 20, 5, nil, nil))
+				mock.ExpectExec(reserveStockQueryRegexp).WithArgs(3, cup.VariantID, 3).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery(updateBalanceQueryRegexp).WithArgs(60, userID).WillReturnError(&pq.Error{Code: "23514"})
+				mock.ExpectRollback()
+			},
+			expectedErr: ErrNotEnoughCoins,
+		},
+		{
+			name:  "purchase limit reached names the date when enough purchases leave the window",
+			lines: []models.CartLine{cup},
+			dbBehavior: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectItemsForPurchaseQueryRegexp).
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), cup.VariantID, true).
+					WillReturnRows(sqlmock.NewRows(purchasedItemColumns).AddRow(2, 1, "hoody", "clothes", "pink", 20, nil, 4, 365))
+				mock.ExpectQuery(updateBalanceQueryRegexp).WithArgs(60, userID).
+					WillReturnRows(sqlmock.NewRows([]string{usersBalanceColumn}).AddRow(940))
+				mock.ExpectQuery(selectWindowPurchasesQueryRegexp).
+					WithArgs(1, userID, userID, sqlmock.AnyArg(), models.OrderStatusCancelled).
+					WillReturnRows(sqlmock.NewRows(windowColumns).AddRow(firstPurchase, 1).AddRow(secondPurchase, 2))
+				mock.ExpectRollback()
+			},
+			expectedErr: &PurchaseLimitError{
+				Item: "hoody", Limit: models.PurchaseLimit{Quantity: 4, WindowDays: 365}, NextPurchaseAt: &nextPurchase,
+			},
+		},
+		{
+			name:  "quantity over the purchase limit",
+			lines: []models.CartLine{cup},
+			dbBehavior: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectItemsForPurchaseQueryRegexp).
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), cup.VariantID, true).
+					WillReturnRows(sqlmock.NewRows(purchasedItemColumns).AddRow(2, 1, "hoody", "clothes", "pink", 20, nil, 1, 365))
+				mock.ExpectQuery(updateBalanceQueryRegexp).WithArgs(60, userID).
+					WillReturnRows(sqlmock.NewRows([]string{usersBalanceColumn}).AddRow(940))
+				mock.ExpectRollback()
+			},
+			expectedErr: &PurchaseLimitError{Item: "hoody", Limit: models.PurchaseLimit{Quantity: 1, WindowDays: 365}},
+		},
+		{
+			name:  "purchase limit with room left",
+			lines: []models.CartLine{cup},
+			dbBehavior: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectItemsForPurchaseQueryRegexp).
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), cup.VariantID, true).
+					WillReturnRows(sqlmock.NewRows(purchasedItemColumns).AddRow(2, 1, "hoody", "clothes", "pink", 20, nil, 4, 365))
+				mock.ExpectQuery(updateBalanceQueryRegexp).WithArgs(60, userID).
+					WillReturnRows(sqlmock.NewRows([]string{usersBalanceColumn}).AddRow(940))
+				mock.ExpectQuery(selectWindowPurchasesQueryRegexp).
+					WithArgs(1, userID, userID, sqlmock.AnyArg(), models.OrderStatusCancelled).
+					WillReturnRows(sqlmock.NewRows(windowColumns).AddRow(firstPurchase, 1))
+				mock.ExpectQuery(insertOrderQueryRegexp).WillReturnRows(sqlmock.NewRows([]string{ordersIDColumn}).AddRow(7))
+				mock.ExpectExec(upsertUserItemsQueryRegexp).WithArgs(userID, cup.VariantID, 3).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(insertPurchasesQueryRegexp).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+			},
+			expected: &models.OrderSummary{
+				Order: models.Order{
+					ID: 7, Status: models.OrderStatusPlaced,
+					Items: []models.OrderLine{{Item: "hoody", Variant: "pink", Quantity: 3, Price: 20, Total: 60}}, Total: 60,
+				},
+				Balance: 940,
+			},
+		},
+		{
+			name:  "percent promo is taken from lines of its category",
+			lines: []models.CartLine{cup, pen},
+			promo: "OFFICE20",
+			dbBehavior: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectItemsForPurchaseQueryRegexp).
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), cup.VariantID, pen.VariantID, true).
+					WillReturnRows(sqlmock.NewRows(purchasedItemColumns).
+						AddRow(2, 1, "cup", "kitchen", "default", 20, nil, nil, nil).AddRow(4, 3, "pen", "office", "blue", 10, nil, nil, nil))
+				mock.ExpectQuery(selectPromoQueryRegexp).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "OFFICE20").
+					WillReturnRows(sqlmock.NewRows(validPromoColumns).AddRow(promoID, "OFFICE20", models.PromoKindPercent, 20,
+						nil, "office", nil, nil, 100, 1, 10, true, true))
+				mock.ExpectQuery(updateBalanceQueryRegexp).WithArgs(68, userID).
+					WillReturnRows(sqlmock.NewRows([]string{usersBalanceColumn}).AddRow(932))
+				mock.ExpectQuery(countPromoUsesQueryRegexp).WithArgs(promoID, userID, models.OrderStatusCancelled).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+				mock.ExpectExec(redeemPromoQueryRegexp).WithArgs(promoID).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery(insertOrderQueryRegexp).WithArgs(userID, 68, models.OrderStatusPlaced,
+					sqlmock.AnyArg(), sqlmock.AnyArg(), promoID, 2, nil, nil, nil, nil).
+					WillReturnRows(sqlmock.NewRows([]string{ordersIDColumn}).AddRow(7))
+				mock.ExpectExec(upsertUserItemsQueryRegexp).WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec(insertPurchasesQueryRegexp).WillReturnResult(sqlmock.NewResult(2, 2))
+				mock.ExpectCommit()
+			},
+			expected: &models.OrderSummary{
+				Order: models.Order{
+					ID: 7, Status: models.OrderStatusPlaced, Promo: "OFFICE20", Discount: 2, Total: 68,
+					Items: []models.OrderLine{
+						{Item: "cup", Variant: "default", Quantity: 3, Price: 20, Total: 60},
+						{Item: "pen", Variant: "blue", Quantity: 1, Price: 10, Discount: 2, Total: 8},
+					},
+				},
+				Balance: 932,
+			},
+		},
+		{
+			name:  "promo used up by the user",
+			lines: []models.CartLine{pen},
+			promo: "OFFICE20",
+			dbBehavior: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectItemsForPurchaseQueryRegexp).
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), pen.VariantID, true).
+					WillReturnRows(sqlmock.NewRows(purchasedItemColumns).AddRow(4, 3, "pen", "office", "blue", 10, nil, nil, nil))
+				mock.ExpectQuery(selectPromoQueryRegexp).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "OFFICE20").
+					WillReturnRows(sqlmock.NewRows(validPromoColumns).AddRow(promoID, "OFFICE20", models.PromoKindPercent, 20,
+						nil, "office", nil, nil, nil, 1, 10, true, true))
+				mock.ExpectQuery(updateBalanceQueryRegexp).WithArgs(8, userID).
+					WillReturnRows(sqlmock.NewRows([]string{usersBalanceColumn}).AddRow(992))
+				mock.ExpectQuery(countPromoUsesQueryRegexp).WithArgs(promoID, userID, models.OrderStatusCancelled).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+				mock.ExpectRollback()
+			},
+			expectedErr: ErrPromoExhausted,
+		},
+		{
+			name:  "promo global limit reached by a concurrent purchase",
+			lines: []models.CartLine{pen},
+			promo: "FIXED5",
+			dbBehavior: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectItemsForPurchaseQueryRegexp).
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), pen.VariantID, true).
+					WillReturnRows(sqlmock.NewRows(purchasedItemColumns).AddRow(4, 3, "pen", "office", "blue", 10, nil, nil, nil))
+				mock.ExpectQuery(selectPromoQueryRegexp).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "FIXED5").
+					WillReturnRows(sqlmock.NewRows(validPromoColumns).AddRow(promoID, "FIXED5", models.PromoKindFixed, 5,
+						nil, "", nil, nil, 10, nil, 9, true, true))
+				mock.ExpectQuery(updateBalanceQueryRegexp).WithArgs(5, userID).
+					WillReturnRows(sqlmock.NewRows([]string{usersBalanceColumn}).AddRow(995))
+				mock.ExpectExec(redeemPromoQueryRegexp).WithArgs(promoID).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+			},
+			expectedErr: ErrPromoExhausted,
+		},
+		{
+			name:  "promo outside of its validity window",
+			lines: []models.CartLine{pen},
+			promo: "FIXED5",
+			dbBehavior: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectItemsForPurchaseQueryRegexp).
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), pen.VariantID, true).
+					WillReturnRows(sqlmock.NewRows(purchasedItemColumns).AddRow(4, 3, "pen", "office", "blue", 10, nil, nil, nil))
+				mock.ExpectQuery(selectPromoQueryRegexp).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "FIXED5").
+					WillReturnRows(sqlmock.NewRows(validPromoColumns).AddRow(promoID, "FIXED5", models.PromoKindFixed, 5,
+						nil, "", nil, nil, nil, nil, 0, true, false))
+				mock.ExpectRollback()
+			},
+			expectedErr: ErrPromoInactive,
+		},
+		{
+			name:  "unknown promo",
+			lines: []models.CartLine{pen},
+			promo: "NOPE",
+			dbBehavior: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectItemsForPurchaseQueryRegexp).
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), pen.VariantID, true).
+					WillReturnRows(sqlmock.NewRows(purchasedItemColumns).AddRow(4, 3, "pen", "office", "blue", 10, nil, nil, nil))
+				mock.ExpectQuery(selectPromoQueryRegexp).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "NOPE").
+					WillReturnRows(sqlmock.NewRows(validPromoColumns))
+				mock.ExpectRollback()
+			},
+			expectedErr: ErrNoPromo,
+		},
+		{
+			name:  "retired item or unknown variant",
+			lines: []models.CartLine{cup, pen},
+			dbBehavior: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectItemsForPurchaseQueryRegexp).
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), cup.VariantID, pen.VariantID, true).
+					WillReturnRows(sqlmock.NewRows(purchasedItemColumns).AddRow(2, 1, "cup", "kitchen", "default", 20, nil, nil, nil))
+				mock.ExpectRollback()
+			},
+			expectedErr: ErrNoItem,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.dbBehavior()
+
+			summary, err := db.BuyItems(context.Background(), userID, tc.lines, tc.promo, 0)
+			assert.Equal(t, tc.expectedErr, err)
+			if summary != nil {
+				// Timestamps are set by the storage.
+				summary.CreatedAt, summary.StatusUpdatedAt = time.Time{}, time.Time{}
+			}
+			assert.Equal(t, tc.expected, summary)
+		})
+	}
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBuyGift(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer mockDB.Close()
+
+	db := storage{db: mockDB}
+
+	payerID, recipientID := 1, 2
+	cup := models.CartLine{VariantID: 2, Quantity: 1}
+	userColumns := []string{userIDColumn, usersPasswordColumn}
+
+	t.Run("unknown recipient", func(t *testing.T) {
+		mock.ExpectQuery(selectUserQueryRegexp).WithArgs("nobody").WillReturnRows(sqlmock.NewRows(userColumns))
+
+		_, err := db.BuyGift(context.Background(), payerID, "nobody", []models.CartLine{cup}, "", "", 0)
+		assert.Equal(t, ErrNoUser, err)
+	})
+
+	t.Run("gift to yourself", func(t *testing.T) {
+		mock.ExpectQuery(selectUserQueryRegexp).WithArgs("alice").
+			WillReturnRows(sqlmock.NewRows(userColumns).AddRow(payerID, "hash"))
+
+		_, err := db.BuyGift(context.Background(), payerID, "alice", []models.CartLine{cup}, "", "", 0)
+		assert.Equal(t, ErrGiftToSelf, err)
+	})
+
+	t.Run("payer is charged, recipient gets the items", func(t *testing.T) {
+		mock.ExpectQuery(selectUserQueryRegexp).WithArgs("bob").
+			WillReturnRows(sqlmock.NewRows(userColumns).AddRow(recipientID, "hash"))
+		mock.ExpectBegin()
+		mock.ExpectQuery(selectItemsForPurchaseQueryRegexp).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), cup.VariantID, true).
+			WillReturnRows(sqlmock.NewRows(purchasedItemColumns).AddRow(2, 1, "cup", "kitchen", "default", 20, nil, nil, nil))
+		mock.ExpectQuery(updateBalanceQueryRegexp).WithArgs(20, payerID).
+			WillReturnRows(sqlmock.NewRows([]string{usersBalanceColumn}).AddRow(980))
+		mock.ExpectQuery(insertOrderQueryRegexp).WithArgs(payerID, 20, models.OrderStatusPlaced,
+			sqlmock.AnyArg(), sqlmock.AnyArg(), nil, 0, recipientID, "enjoy", nil, nil).
+			WillReturnRows(sqlmock.NewRows([]string{ordersIDColumn}).AddRow(8))
+		mock.ExpectExec(upsertUserItemsQueryRegexp).WithArgs(recipientID, cup.VariantID, 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(insertPurchasesQueryRegexp).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		summary, err := db.BuyGift(context.Background(), payerID, "bob", []models.CartLine{cup}, "", "enjoy", 0)
+		assert.NoError(t, err)
+		assert.Equal(t, 8, summary.ID)
+		assert.Equal(t, &models.OrderGift{To: "bob", Note: "enjoy"}, summary.Gift)
+		assert.Equal(t, 980, summary.Balance)
+	})
+
+	t.Run("gift over the purchase limit of the recipient", func(t *testing.T) {
+		received := time.Date(2025, 2, 5, 12, 30, 0, 0, time.UTC)
+		nextPurchase := received.AddDate(0, 0, 365)
+		windowColumns := []string{purchasesTimeColumn, purchasesQuantityColumn}
+
+		mock.ExpectQuery(selectUserQueryRegexp).WithArgs("bob").
+			WillReturnRows(sqlmock.NewRows(userColumns).AddRow(recipientID, "hash"))
+		mock.ExpectBegin()
+		mock.ExpectQuery(selectItemsForPurchaseQueryRegexp).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), cup.VariantID, true).
+			WillReturnRows(sqlmock.NewRows(purchasedItemColumns).AddRow(2, 1, "hoody", "clothes", "pink", 20, nil, 1, 365))
+		mock.ExpectExec(lockUsersQueryRegexp).WithArgs(payerID, recipientID).WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectQuery(updateBalanceQueryRegexp).WithArgs(20, payerID).
+			WillReturnRows(sqlmock.NewRows([]string{usersBalanceColumn}).AddRow(980))
+		mock.ExpectQuery(selectWindowPurchasesQueryRegexp).
+			WithArgs(1, payerID, payerID, sqlmock.AnyArg(), models.OrderStatusCancelled).
+			WillReturnRows(sqlmock.NewRows(windowColumns))
+		// Another colleague has already given the recipient one.
+		mock.ExpectQuery(selectWindowPurchasesQueryRegexp).
+			WithArgs(1, recipientID, recipientID, sqlmock.AnyArg(), models.OrderStatusCancelled).
+			WillReturnRows(sqlmock.NewRows(windowColumns).AddRow(received, 1))
+		mock.ExpectRollback()
+
+		_, err := db.BuyGift(context.Background(), payerID, "bob", []models.CartLine{cup}, "", "", 0)
+		assert.Equal(t, &PurchaseLimitError{
+			Item: "hoody", Limit: models.PurchaseLimit{Quantity: 1, WindowDays: 365}, NextPurchaseAt: &nextPurchase,
+		}, err)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCheckoutCart(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer mockDB.Close()
+
+	db := storage{db: mockDB}
+
+	userID := 1
+	cartColumns := []string{cartItemsVariantIDColumn, cartItemsQuantityColumn}
+
+	t.Run("empty cart", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(deleteCartQueryRegexp).WithArgs(userID).WillReturnRows(sqlmock.NewRows(cartColumns))
+		mock.ExpectRollback()
+
+		_, err := db.CheckoutCart(context.Background(), userID, "", 0)
+		assert.Equal(t, ErrCartEmpty, err)
+	})
+
+	t.Run("cart lines are bought in the same transaction", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(deleteCartQueryRegexp).WithArgs(userID).WillReturnRows(sqlmock.NewRows(cartColumns).AddRow(2, 3))
+		mock.ExpectQuery(selectItemsForPurchaseQueryRegexp).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 2, true).
+			WillReturnRows(sqlmock.NewRows(purchasedItemColumns).AddRow(2, 1, "cup", "kitchen", "default", 20, nil, nil, nil))
+		mock.ExpectQuery(updateBalanceQueryRegexp).WithArgs(60, userID).WillReturnError(&pq.Error{Code: "23514"})
+		mock.ExpectRollback()
+
+		_, err := db.CheckoutCart(context.Background(), userID, "", 0)
+		assert.Equal(t, ErrNotEnoughCoins, err)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,236 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"merch_shop/internal/models"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// CancelOrder cancels an order and refunds its total, which holds prices paid at purchase time.
+// Stock, inventory and the promo code use are returned in the same transaction.
+// A gift is refunded to the payer and taken out of the recipient's inventory.
+// Users cancel only orders whose items are all still in the owner's inventory and never won lots,
+// admins refund any.
+// Orders pending approval are cancelled only with that status set, their lines are not in any inventory yet.
+func (s *storage) CancelOrder(ctx context.Context, orderID int, cancellation *models.OrderCancellation) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	err = cancelOrderTx(ctx, tx, orderID, cancellation)
+	if err != nil {
+		txErr := tx.Rollback()
+		if txErr != nil {
+			log.Printf("tx rollback error: %s", txErr.Error())
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// cancelOrderTx locks the order first, then variant rows in ascending id order and the user rows last,
+// the same order purchases use, so a cancellation never deadlocks with a purchase.
+func cancelOrderTx(ctx context.Context, tx *sql.Tx, orderID int, cancellation *models.OrderCancellation) error {
+	timing := time.Now()
+	cancelOrder := sq.Update(ordersTable).
+		Set(ordersStatusColumn, models.OrderStatusCancelled).
+		Set(ordersStatusUpdatedAtColumn, timing).
+		Set(ordersRefundedAtColumn, timing).
+		Where(sq.Eq{ordersIDColumn: orderID}).
+		Where(sq.NotEq{ordersStatusColumn: models.OrderStatusCancelled})
+
+	// Orders of other users look missing rather than not cancellable.
+	exists := sq.Eq{ordersIDColumn: orderID}
+	if cancellation.UserID != nil {
+		cancelOrder = cancelOrder.Where(sq.Eq{ordersUserIDColumn: *cancellation.UserID})
+		exists[ordersUserIDColumn] = *cancellation.UserID
+		// A won lot is not put back on sale by its winner, other bidders have lost their chance at it.
+		cancelOrder = cancelOrder.Where(fmt.Sprintf("NOT EXISTS (SELECT 1 FROM %s WHERE %s.%s = %s.%s)",
+			auctionsTable, auctionsTable, auctionsOrderIDColumn, ordersTable, ordersIDColumn))
+	}
+	// Held coins are released by rejecting the order.
+	notPending := sq.NotEq{ordersStatusColumn: models.OrderStatusPendingApproval}
+	switch {
+	case cancellation.Status != "" && cancellation.PlacedAfter != nil:
+		// Either is enough: the order still has the status, or it was placed recently whatever its status.
+		cancelOrder = cancelOrder.Where(sq.Or{
+			sq.Eq{ordersStatusColumn: cancellation.Status},
+			sq.And{notPending, sq.GtOrEq{ordersCreatedAtColumn: *cancellation.PlacedAfter}},
+		})
+	case cancellation.Status != "":
+		cancelOrder = cancelOrder.Where(sq.Eq{ordersStatusColumn: cancellation.Status})
+	case cancellation.PlacedAfter != nil:
+		cancelOrder = cancelOrder.Where(notPending, sq.GtOrEq{ordersCreatedAtColumn: *cancellation.PlacedAfter})
+	default:
+		cancelOrder = cancelOrder.Where(notPending)
+	}
+	if cancellation.Status != models.OrderStatusPendingApproval {
+		// A pooled order is paid by contributions of a collection, its total refunds nobody.
+		cancelOrder = cancelOrder.Where(fmt.Sprintf("NOT EXISTS (SELECT 1 FROM %s WHERE %s.%s = %s.%s)",
+			collectionsTable, collectionsTable, collectionsOrderIDColumn, ordersTable, ordersIDColumn))
+	}
+	cancelOrderQuery, orderUpdArgs, err := cancelOrder.
+		Suffix(fmt.Sprintf("RETURNING %s, COALESCE(%s, %s), %s, %s", ordersUserIDColumn,
+			ordersRecipientIDColumn, ordersUserIDColumn, ordersTotalColumn, ordersPromoIDColumn)).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	var userID, ownerID, total int
+	var promoID *int
+	err = tx.QueryRowContext(ctx, cancelOrderQuery, orderUpdArgs...).Scan(&userID, &ownerID, &total, &promoID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return orderNotChangedError(ctx, tx, exists, ErrNotCancellable)
+		}
+		return err
+	}
+
+	lines, err := selectOrderLines(ctx, tx, orderID)
+	if err != nil {
+		return err
+	}
+
+	for _, line := range lines {
+		err := returnStock(ctx, tx, line)
+		if err != nil {
+			return err
+		}
+	}
+
+	updateUserBalanceQuery, balanceUpdArgs, err := sq.Update(usersTable).
+		Set(usersBalanceColumn, sq.Expr(fmt.Sprintf("%s + ?", usersBalanceColumn), total)).
+		Where(sq.Eq{userIDColumn: userID}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, updateUserBalanceQuery, balanceUpdArgs...)
+	if err != nil {
+		return err
+	}
+
+	// The code is locked after the user row, in the same order as on purchase.
+	if promoID != nil {
+		err := releasePromo(ctx, tx, *promoID)
+		if err != nil {
+			return err
+		}
+	}
+
+	if cancellation.Status == models.OrderStatusPendingApproval {
+		return nil
+	}
+
+	for _, line := range lines {
+		// Users are refunded only for items still in the inventory, items given away can't be cancelled.
+		if cancellation.UserID != nil {
+			err := takeUserItem(ctx, tx, ownerID, line)
+			if err == ErrNotEnoughItems {
+				return ErrNotCancellable
+			}
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		err := returnUserItem(ctx, tx, ownerID, line)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// returnStock puts the quantity of a line back on the shelf.
+func returnStock(ctx context.Context, tx *sql.Tx, line models.CartLine) error {
+	restockQuery, stockUpdArgs, err := sq.Update(itemVariantsTable).
+		Set(itemVariantsStockColumn, sq.Expr(fmt.Sprintf("%s + ?", itemVariantsStockColumn), line.Quantity)).
+		Where(sq.Eq{itemVariantsIDColumn: line.VariantID}).
+		// Untracked variants are not locked, like on purchase.
+		Where(sq.NotEq{itemVariantsStockColumn: nil}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, restockQuery, stockUpdArgs...)
+	return err
+}
+
+// selectOrderLines returns the lines of an order, one per variant.
+func selectOrderLines(ctx context.Context, tx *sql.Tx, orderID int) ([]models.CartLine, error) {
+	selectLinesQuery, linesSelectArgs, err := sq.Select(purchasesVariantIDColumn, purchasesQuantityColumn).
+		From(purchasesTable).
+		Where(sq.Eq{purchasesOrderIDColumn: orderID}).
+		OrderBy(purchasesVariantIDColumn).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, selectLinesQuery, linesSelectArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lines := make([]models.CartLine, 0)
+	for rows.Next() {
+		var line models.CartLine
+		err := rows.Scan(&line.VariantID, &line.Quantity)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+
+	return lines, rows.Err()
+}
+
+// returnUserItem takes the refunded quantity out of the inventory as far as it is still there,
+// a variant is removed from it once nothing is left. Only admin refunds are this lenient.
+func returnUserItem(ctx context.Context, tx *sql.Tx, userID int, line models.CartLine) error {
+	deleteUserItemQuery, userItemDelArgs, err := sq.Delete(userItemsTable).
+		Where(sq.Eq{userItemsUserIDColumn: userID, userItemsVariantIDColumn: line.VariantID}).
+		Where(sq.LtOrEq{userItemsQuantityColumn: line.Quantity}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, deleteUserItemQuery, userItemDelArgs...)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		return nil
+	}
+
+	updateUserItemQuery, userItemUpdArgs, err := sq.Update(userItemsTable).
+		Set(userItemsQuantityColumn, sq.Expr(fmt.Sprintf("%s - ?", userItemsQuantityColumn), line.Quantity)).
+		Where(sq.Eq{userItemsUserIDColumn: userID, userItemsVariantIDColumn: line.VariantID}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, updateUserItemQuery, userItemUpdArgs...)
+	return err
+}
@@ -0,0 +1,198 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"log"
+	"merch_shop/internal/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	cancelOrderQueryRegexp = `
+		UPDATE orders SET status = \$1, status_updated_at = \$2, refunded_at = \$3
+		WHERE id = \$4 AND status <> \$5 AND user_id = \$6
+		AND NOT EXISTS \(SELECT 1 FROM auctions WHERE auctions.order_id = orders.id\)
+		AND \(status = \$7 OR \(status <> \$8 AND created_at >= \$9\)\)
+		AND NOT EXISTS \(SELECT 1 FROM collections WHERE collections.order_id = orders.id\)
+		RETURNING user_id, COALESCE\(recipient_id, user_id\), total, promo_id
+	`
+	selectOrderLinesQueryRegexp = `SELECT variant_id, quantity FROM purchases WHERE order_id = \$1 ORDER BY variant_id`
+	returnStockQueryRegexp      = `UPDATE item_variants SET stock = stock \+ \$1 WHERE id = \$2 AND stock IS NOT NULL`
+	refundBalanceQueryRegexp    = `UPDATE users SET balance = balance \+ \$1 WHERE id = \$2`
+	deleteUserItemQueryRegexp   = `DELETE FROM user_items WHERE user_id = \$1 AND variant_id = \$2 AND quantity <= \$3`
+	releasePromoQueryRegexp     = `UPDATE promo_codes SET uses = uses - 1 WHERE id = \$1`
+	userOrderExistsQueryRegexp  = `SELECT EXISTS \( SELECT 1 FROM orders WHERE id = \$1 AND user_id = \$2 \)`
+)
+
+func TestCancelOrder(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer mockDB.Close()
+
+	db := storage{db: mockDB}
+
+	orderID, userID, recipientID, promoID := 3, 1, 2, 5
+	placedAfter := time.Date(2025, 2, 5, 12, 30, 0, 0, time.UTC)
+	cancellation := &models.OrderCancellation{UserID: &userID, Status: models.OrderStatusPlaced, PlacedAfter: &placedAfter}
+	cancelArgs := []driver.Value{sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), orderID, models.OrderStatusCancelled,
+		userID, models.OrderStatusPlaced, models.OrderStatusPendingApproval, placedAfter}
+	orderColumns := []string{ordersUserIDColumn, "owner_id", ordersTotalColumn, ordersPromoIDColumn}
+	lineColumns := []string{purchasesVariantIDColumn, purchasesQuantityColumn}
+
+	testCases := []struct {
+		name        string
+		dbBehavior  func()
+		expectedErr error
+	}{
+		{
+			name: "total is refunded, stock, inventory and promo use are returned",
+			dbBehavior: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(cancelOrderQueryRegexp).WithArgs(cancelArgs...).
+					WillReturnRows(sqlmock.NewRows(orderColumns).AddRow(userID, userID, 70, promoID))
+				mock.ExpectQuery(selectOrderLinesQueryRegexp).WithArgs(orderID).
+					WillReturnRows(sqlmock.NewRows(lineColumns).AddRow(2, 2).AddRow(4, 3))
+				mock.ExpectExec(returnStockQueryRegexp).WithArgs(2, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(returnStockQueryRegexp).WithArgs(3, 4).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(refundBalanceQueryRegexp).WithArgs(70, userID).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(releasePromoQueryRegexp).WithArgs(promoID).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(takeAllUserItemsQueryRegexp).WithArgs(2, userID, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(takeAllUserItemsQueryRegexp).WithArgs(3, userID, 4).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(takeUserItemsQueryRegexp).WithArgs(3, userID, 4, 3).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "gift is refunded to the payer and taken from the recipient",
+			dbBehavior: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(cancelOrderQueryRegexp).WithArgs(cancelArgs...).
+					WillReturnRows(sqlmock.NewRows(orderColumns).AddRow(userID, recipientID, 40, nil))
+				mock.ExpectQuery(selectOrderLinesQueryRegexp).WithArgs(orderID).
+					WillReturnRows(sqlmock.NewRows(lineColumns).AddRow(2, 2))
+				mock.ExpectExec(returnStockQueryRegexp).WithArgs(2, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(refundBalanceQueryRegexp).WithArgs(40, userID).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(takeAllUserItemsQueryRegexp).WithArgs(2, recipientID, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "owner holds fewer items than the order line",
+			dbBehavior: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(cancelOrderQueryRegexp).WithArgs(cancelArgs...).
+					WillReturnRows(sqlmock.NewRows(orderColumns).AddRow(userID, userID, 40, nil))
+				mock.ExpectQuery(selectOrderLinesQueryRegexp).WithArgs(orderID).
+					WillReturnRows(sqlmock.NewRows(lineColumns).AddRow(2, 2))
+				mock.ExpectExec(returnStockQueryRegexp).WithArgs(2, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(refundBalanceQueryRegexp).WithArgs(40, userID).WillReturnResult(sqlmock.NewResult(0, 1))
+				// One of the two pieces has been given away.
+				mock.ExpectExec(takeAllUserItemsQueryRegexp).WithArgs(2, userID, 2).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(takeUserItemsQueryRegexp).WithArgs(2, userID, 2, 2).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+			},
+			expectedErr: ErrNotCancellable,
+		},
+		{
+			name: "not cancellable",
+			dbBehavior: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(cancelOrderQueryRegexp).WithArgs(cancelArgs...).
+					WillReturnRows(sqlmock.NewRows(orderColumns))
+				mock.ExpectQuery(userOrderExistsQueryRegexp).WithArgs(orderID, userID).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+				mock.ExpectRollback()
+			},
+			expectedErr: ErrNotCancellable,
+		},
+		{
+			name: "won auction lot is not cancelled by the winner",
+			dbBehavior: func() {
+				mock.ExpectBegin()
+				// The auctions check leaves the order unmatched although it is placed and recent.
+				mock.ExpectQuery(cancelOrderQueryRegexp).WithArgs(cancelArgs...).
+					WillReturnRows(sqlmock.NewRows(orderColumns))
+				mock.ExpectQuery(userOrderExistsQueryRegexp).WithArgs(orderID, userID).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+				mock.ExpectRollback()
+			},
+			expectedErr: ErrNotCancellable,
+		},
+		{
+			name: "order of another user",
+			dbBehavior: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(cancelOrderQueryRegexp).WithArgs(cancelArgs...).
+					WillReturnRows(sqlmock.NewRows(orderColumns))
+				mock.ExpectQuery(userOrderExistsQueryRegexp).WithArgs(orderID, userID).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+				mock.ExpectRollback()
+			},
+			expectedErr: ErrNoOrder,
+		},
+		{
+			name: "refund error rolls back",
+			dbBehavior: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(cancelOrderQueryRegexp).WithArgs(cancelArgs...).
+					WillReturnRows(sqlmock.NewRows(orderColumns).AddRow(userID, userID, 40, nil))
+				mock.ExpectQuery(selectOrderLinesQueryRegexp).WithArgs(orderID).
+					WillReturnRows(sqlmock.NewRows(lineColumns).AddRow(2, 2))
+				mock.ExpectExec(returnStockQueryRegexp).WithArgs(2, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(refundBalanceQueryRegexp).WithArgs(40, userID).WillReturnError(errors.New("some error"))
+				mock.ExpectRollback()
+			},
+			expectedErr: errors.New("some error"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.dbBehavior()
+
+			err := db.CancelOrder(context.Background(), orderID, cancellation)
+			assert.Equal(t, tc.expectedErr, err)
+		})
+	}
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRefundOrder(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer mockDB.Close()
+
+	db := storage{db: mockDB}
+
+	orderID, userID := 3, 1
+	orderColumns := []string{ordersUserIDColumn, "owner_id", ordersTotalColumn, ordersPromoIDColumn}
+
+	// An admin refund goes through even if the owner has given part of the items away.
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE orders SET (.*) WHERE id = \$4 AND status <> \$5 AND status <> \$6 AND NOT EXISTS (.*)`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), orderID, models.OrderStatusCancelled,
+			models.OrderStatusPendingApproval).
+		WillReturnRows(sqlmock.NewRows(orderColumns).AddRow(userID, userID, 40, nil))
+	mock.ExpectQuery(selectOrderLinesQueryRegexp).WithArgs(orderID).
+		WillReturnRows(sqlmock.NewRows([]string{purchasesVariantIDColumn, purchasesQuantityColumn}).AddRow(2, 2))
+	mock.ExpectExec(returnStockQueryRegexp).WithArgs(2, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(refundBalanceQueryRegexp).WithArgs(40, userID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(deleteUserItemQueryRegexp).WithArgs(userID, 2, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err = db.CancelOrder(context.Background(), orderID, &models.OrderCancellation{})
+	assert.NoError(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
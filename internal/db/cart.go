@@ -0,0 +1,142 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"merch_shop/internal/models"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// AddCartItem puts an item variant into the cart, adding to the quantity already there.
+func (s *storage) AddCartItem(ctx context.Context, userID int, line models.CartLine) error {
+	upsertCartItemQuery, cartItemArgs, err := sq.Insert(cartItemsTable).
+		Columns(cartItemsUserIDColumn, cartItemsVariantIDColumn, cartItemsQuantityColumn).
+		Values(userID, line.VariantID, line.Quantity).
+		Suffix(fmt.Sprintf("ON CONFLICT (%s, %s) DO UPDATE SET %s = %s.%s + EXCLUDED.%s",
+			cartItemsUserIDColumn, cartItemsVariantIDColumn, cartItemsQuantityColumn,
+			cartItemsTable, cartItemsQuantityColumn, cartItemsQuantityColumn)).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx, upsertCartItemQuery, cartItemArgs...)
+	return err
+}
+
+// GetCart returns cart lines priced with current catalog prices.
+func (s *storage) GetCart(ctx context.Context, userID int) ([]models.CartItem, error) {
+	column := func(table, column string) string {
+		return fmt.Sprintf("%s.%s", table, column)
+	}
+	stock := column(itemVariantsTable, itemVariantsStockColumn)
+
+	query := sq.Select(
+		column(itemsTable, itemsTypeColumn),
+		column(itemVariantsTable, itemVariantsNameColumn),
+		column(cartItemsTable, cartItemsQuantityColumn),
+		fmt.Sprintf("COALESCE(%s, %s)",
+			column(itemVariantsTable, itemVariantsPriceColumn), column(currentPricesAlias, itemPricesPriceColumn)),
+		fmt.Sprintf("%s AND (%s IS NULL OR %s >= %s)", column(itemsTable, itemsActiveColumn),
+			stock, stock, column(cartItemsTable, cartItemsQuantityColumn))).
+		From(cartItemsTable).
+		Join(fmt.Sprintf("%s ON %s = %s", itemVariantsTable,
+			column(cartItemsTable, cartItemsVariantIDColumn), column(itemVariantsTable, itemVariantsIDColumn))).
+		Join(fmt.Sprintf("%s ON %s = %s", itemsTable,
+			column(itemVariantsTable, itemVariantsItemIDColumn), column(itemsTable, itemsIDColumn)))
+
+	selectCartQuery, cartArgs, err := joinCurrentPrice(query, time.Now()).
+		Where(sq.Eq{column(cartItemsTable, cartItemsUserIDColumn): userID}).
+		OrderBy(column(itemsTable, itemsIDColumn), column(itemVariantsTable, itemVariantsIDColumn)).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, selectCartQuery, cartArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]models.CartItem, 0)
+	for rows.Next() {
+		var item models.CartItem
+		err := rows.Scan(&item.Item, &item.Variant, &item.Quantity, &item.Price, &item.Available)
+		if err != nil {
+			return nil, err
+		}
+		item.Total = item.Price * item.Quantity
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// CheckoutCart buys everything in the cart in one transaction and empties it.
+// On any error the transaction is rolled back and the cart stays as it was.
+func (s *storage) CheckoutCart(ctx context.Context, userID int, promo string,
+	approvalThreshold int) (*models.OrderSummary, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	summary, err := checkoutCartTx(ctx, tx, userID, promo, approvalThreshold)
+	if err != nil {
+		txErr := tx.Rollback()
+		if txErr != nil {
+			log.Printf("tx rollback error: %s", txErr.Error())
+		}
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return summary, nil
+}
+
+func checkoutCartTx(ctx context.Context, tx *sql.Tx, userID int, promo string,
+	approvalThreshold int) (*models.OrderSummary, error) {
+	deleteCartQuery, cartDelArgs, err := sq.Delete(cartItemsTable).
+		Where(sq.Eq{cartItemsUserIDColumn: userID}).
+		Suffix(fmt.Sprintf("RETURNING %s, %s", cartItemsVariantIDColumn, cartItemsQuantityColumn)).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, deleteCartQuery, cartDelArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lines := make([]models.CartLine, 0)
+	for rows.Next() {
+		var line models.CartLine
+		err := rows.Scan(&line.VariantID, &line.Quantity)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, ErrCartEmpty
+	}
+
+	return buyItemsTx(ctx, tx, userID, nil, nil, lines, promo, approvalThreshold)
+}
@@ -0,0 +1,565 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"merch_shop/internal/models"
+	"sort"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+const (
+	collectionCreatorsAlias   = "creators"
+	collectionRecipientsAlias = "recipients"
+	collectionRaisedAlias     = "raised"
+)
+
+// CreateCollection starts a collection for the recipient, the target is the current price of the variant.
+// Items priced above a positive approvalThreshold can not be collected for, see createCollectionTx.
+func (s *storage) CreateCollection(ctx context.Context, creatorID int, recipient string, variantID int,
+	deadline time.Time, approvalThreshold int) (*models.Collection, error) {
+	recipientID, _, err := s.GetUser(ctx, recipient)
+	if err != nil {
+		return nil, err
+	}
+	if *recipientID == creatorID {
+		return nil, ErrGiftToSelf
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := createCollectionTx(ctx, tx, creatorID, *recipientID, variantID, deadline, approvalThreshold)
+	if err != nil {
+		txErr := tx.Rollback()
+		if txErr != nil {
+			log.Printf("tx rollback error: %s", txErr.Error())
+		}
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+// createCollectionTx refuses items above a positive approvalThreshold: a rejected order refunds its payer,
+// while the coins of a group gift come from the contributors.
+func createCollectionTx(ctx context.Context, tx *sql.Tx, creatorID, recipientID, variantID int,
+	deadline time.Time, approvalThreshold int) (*models.Collection, error) {
+	column := func(table, column string) string {
+		return fmt.Sprintf("%s.%s", table, column)
+	}
+
+	timing := time.Now()
+	query := sq.Select(
+		column(itemsTable, itemsIDColumn),
+		column(itemsTable, itemsTypeColumn),
+		fmt.Sprintf("COALESCE(%s, %s)",
+			column(itemVariantsTable, itemVariantsPriceColumn), column(currentPricesAlias, itemPricesPriceColumn)),
+		column(itemsTable, itemsLimitColumn),
+		column(itemsTable, itemsLimitDaysColumn)).
+		From(itemVariantsTable).
+		Join(fmt.Sprintf("%s ON %s = %s", itemsTable,
+			column(itemVariantsTable, itemVariantsItemIDColumn), column(itemsTable, itemsIDColumn)))
+
+	selectPriceQuery, priceArgs, err := joinCurrentPrice(query, timing).
+		// Retired items are not sold anymore.
+		Where(sq.Eq{column(itemVariantsTable, itemVariantsIDColumn): variantID, column(itemsTable, itemsActiveColumn): true}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var item purchasedItem
+	var target int
+	var limit, limitDays *int
+	err = tx.QueryRowContext(ctx, selectPriceQuery, priceArgs...).Scan(&item.itemID, &item.itemType, &target,
+		&limit, &limitDays)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNoItem
+		}
+		return nil, err
+	}
+	if limit != nil && limitDays != nil {
+		item.limit = &models.PurchaseLimit{Quantity: *limit, WindowDays: *limitDays}
+	}
+	if approvalThreshold > 0 && target > approvalThreshold {
+		return nil, ErrOverThreshold
+	}
+
+	// The unit is kept for the recipient, so reaching the target always buys it.
+	err = reserveUnit(ctx, tx, variantID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkCollectionLimit(ctx, tx, creatorID, recipientID, variantID, item, timing)
+	if err != nil {
+		return nil, err
+	}
+
+	insertCollectionQuery, collectionInsArgs, err := sq.Insert(collectionsTable).
+		Columns(collectionsCreatorIDColumn, collectionsRecipientIDColumn, collectionsVariantIDColumn, collectionsTargetColumn,
+			collectionsDeadlineColumn, collectionsCreatedAtColumn).
+		Values(creatorID, recipientID, variantID, target, deadline, timing).
+		Suffix("RETURNING " + collectionsIDColumn).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var collectionID int
+	err = tx.QueryRowContext(ctx, insertCollectionQuery, collectionInsArgs...).Scan(&collectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return getCollection(ctx, tx, collectionID)
+}
+
+// GetCollections returns collections with the nearest deadline first, openOnly skips completed and refunded ones.
+func (s *storage) GetCollections(ctx context.Context, openOnly bool) ([]models.Collection, error) {
+	selectCollections := selectCollections()
+	if openOnly {
+		selectCollections = selectCollections.Where(sq.Eq{
+			fmt.Sprintf("%s.%s", collectionsTable, collectionsCompletedAtColumn): nil,
+			fmt.Sprintf("%s.%s", collectionsTable, collectionsRefundedAtColumn):  nil,
+		})
+	}
+
+	selectCollectionsQuery, collectionsArgs, err := selectCollections.PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, selectCollectionsQuery, collectionsArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	collections := make([]models.Collection, 0)
+	for rows.Next() {
+		collection, err := scanCollection(rows)
+		if err != nil {
+			return nil, err
+		}
+		collections = append(collections, *collection)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return collections, nil
+}
+
+func (s *storage) GetCollection(ctx context.Context, collectionID int) (*models.Collection, error) {
+	return getCollection(ctx, s.db, collectionID)
+}
+
+func getCollection(ctx context.Context, q rowQuerier, collectionID int) (*models.Collection, error) {
+	selectCollectionQuery, collectionArgs, err := selectCollections().
+		Where(sq.Eq{fmt.Sprintf("%s.%s", collectionsTable, collectionsIDColumn): collectionID}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	collection, err := scanCollection(q.QueryRowContext(ctx, selectCollectionQuery, collectionArgs...))
+	if err == sql.ErrNoRows {
+		return nil, ErrNoCollection
+	}
+
+	return collection, err
+}
+
+// selectCollections selects collections with the sum and the number of distinct contributors,
+// refunded contributions are still counted, so a refunded collection shows how much was returned.
+func selectCollections() sq.SelectBuilder {
+	column := func(table, column string) string {
+		return fmt.Sprintf("%s.%s", table, column)
+	}
+
+	return sq.Select(
+		column(collectionsTable, collectionsIDColumn),
+		column(collectionCreatorsAlias, usersNameColumn),
+		column(collectionRecipientsAlias, usersNameColumn),
+		column(itemsTable, itemsTypeColumn),
+		column(itemVariantsTable, itemVariantsNameColumn),
+		column(collectionsTable, collectionsTargetColumn),
+		column(collectionRaisedAlias, contributionsAmountColumn),
+		column(collectionRaisedAlias, contributionsUserIDColumn),
+		column(collectionsTable, collectionsDeadlineColumn),
+		column(collectionsTable, collectionsCreatedAtColumn),
+		column(collectionsTable, collectionsCompletedAtColumn),
+		column(collectionsTable, collectionsOrderIDColumn),
+		column(collectionsTable, collectionsRefundedAtColumn)).
+		From(collectionsTable).
+		Join(fmt.Sprintf("%s AS %s ON %s = %s", usersTable, collectionCreatorsAlias,
+			column(collectionsTable, collectionsCreatorIDColumn), column(collectionCreatorsAlias, userIDColumn))).
+		Join(fmt.Sprintf("%s AS %s ON %s = %s", usersTable, collectionRecipientsAlias,
+			column(collectionsTable, collectionsRecipientIDColumn), column(collectionRecipientsAlias, userIDColumn))).
+		Join(fmt.Sprintf("%s ON %s = %s", itemVariantsTable,
+			column(collectionsTable, collectionsVariantIDColumn), column(itemVariantsTable, itemVariantsIDColumn))).
+		Join(fmt.Sprintf("%s ON %s = %s", itemsTable,
+			column(itemVariantsTable, itemVariantsItemIDColumn), column(itemsTable, itemsIDColumn))).
+		Join(fmt.Sprintf("LATERAL (SELECT COALESCE(SUM(%s), 0) AS %s, COUNT(DISTINCT %s) AS %s FROM %s WHERE %s = %s) AS %s ON true",
+			contributionsAmountColumn, contributionsAmountColumn, contributionsUserIDColumn, contributionsUserIDColumn,
+			contributionsTable, column(contributionsTable, contributionsCollectionIDColumn),
+			column(collectionsTable, collectionsIDColumn), collectionRaisedAlias)).
+		OrderBy(column(collectionsTable, collectionsDeadlineColumn), column(collectionsTable, collectionsIDColumn))
+}
+
+// scanCollection reads a row selected with selectCollections.
+func scanCollection(row interface{ Scan(dest ...any) error }) (*models.Collection, error) {
+	collection := &models.Collection{}
+	err := row.Scan(&collection.ID, &collection.Creator, &collection.Recipient, &collection.Item, &collection.Variant,
+		&collection.Target, &collection.Raised, &collection.Contributors, &collection.Deadline, &collection.CreatedAt,
+		&collection.CompletedAt, &collection.OrderID, &collection.RefundedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return collection, nil
+}
+
+// Contribute takes coins of the user for the collection. The contribution reaching the target
+// places the order for the recipient in the same transaction.
+func (s *storage) Contribute(ctx context.Context, userID, collectionID, amount int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	err = contributeTx(ctx, tx, userID, collectionID, amount)
+	if err != nil {
+		txErr := tx.Rollback()
+		if txErr != nil {
+			log.Printf("tx rollback error: %s", txErr.Error())
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// checkCollectionLimit checks a group gift against the purchase limits of its creator and recipient
+// as any gift is checked, locking both user rows first.
+func checkCollectionLimit(ctx context.Context, tx *sql.Tx, creatorID, recipientID, variantID int, item purchasedItem,
+	now time.Time) error {
+	if item.limit == nil {
+		return nil
+	}
+
+	err := lockUsers(ctx, tx, creatorID, recipientID)
+	if err != nil {
+		return err
+	}
+
+	return checkPurchaseLimits(ctx, tx, creatorID, &recipientID, []models.CartLine{{VariantID: variantID, Quantity: 1}},
+		map[int]purchasedItem{variantID: item}, now)
+}
+
+// openCollection is a collection locked for contributions.
+type openCollection struct {
+	id          int
+	creatorID   int
+	recipientID int
+	variantID   int
+	item        purchasedItem
+	target      int
+}
+
+// contributeTx locks the collection row first, so concurrent contributions see each other
+// and only one of them completes the collection.
+func contributeTx(ctx context.Context, tx *sql.Tx, userID, collectionID, amount int) error {
+	column := func(table, column string) string {
+		return fmt.Sprintf("%s.%s", table, column)
+	}
+
+	timing := time.Now()
+	selectCollectionQuery, collectionArgs, err := sq.Select(
+		column(collectionsTable, collectionsCreatorIDColumn),
+		column(collectionsTable, collectionsRecipientIDColumn),
+		column(collectionsTable, collectionsVariantIDColumn),
+		column(itemsTable, itemsIDColumn),
+		column(itemsTable, itemsTypeColumn),
+		column(itemsTable, itemsLimitColumn),
+		column(itemsTable, itemsLimitDaysColumn),
+		column(collectionsTable, collectionsTargetColumn)).
+		Column(fmt.Sprintf("%s IS NULL AND %s IS NULL AND %s > ?", column(collectionsTable, collectionsCompletedAtColumn),
+			column(collectionsTable, collectionsRefundedAtColumn), column(collectionsTable, collectionsDeadlineColumn)), timing).
+		From(collectionsTable).
+		Join(fmt.Sprintf("%s ON %s = %s", itemVariantsTable,
+			column(collectionsTable, collectionsVariantIDColumn), column(itemVariantsTable, itemVariantsIDColumn))).
+		Join(fmt.Sprintf("%s ON %s = %s", itemsTable,
+			column(itemVariantsTable, itemVariantsItemIDColumn), column(itemsTable, itemsIDColumn))).
+		Where(sq.Eq{column(collectionsTable, collectionsIDColumn): collectionID}).
+		Suffix("FOR UPDATE OF " + collectionsTable).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	collection := &openCollection{id: collectionID}
+	var limit, limitDays *int
+	var open bool
+	err = tx.QueryRowContext(ctx, selectCollectionQuery, collectionArgs...).Scan(&collection.creatorID,
+		&collection.recipientID, &collection.variantID, &collection.item.itemID, &collection.item.itemType,
+		&limit, &limitDays, &collection.target, &open)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNoCollection
+		}
+		return err
+	}
+	if !open {
+		return ErrCollectionOver
+	}
+	if limit != nil && limitDays != nil {
+		collection.item.limit = &models.PurchaseLimit{Quantity: *limit, WindowDays: *limitDays}
+	}
+
+	selectRaisedQuery, raisedArgs, err := sq.Select(fmt.Sprintf("COALESCE(SUM(%s), 0)", contributionsAmountColumn)).
+		From(contributionsTable).
+		Where(sq.Eq{contributionsCollectionIDColumn: collectionID}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	var raised int
+	err = tx.QueryRowContext(ctx, selectRaisedQuery, raisedArgs...).Scan(&raised)
+	if err != nil {
+		return err
+	}
+	if raised+amount > collection.target {
+		return ErrOverTarget
+	}
+
+	err = adjustBalance(ctx, tx, userID, -amount)
+	if err != nil {
+		return err
+	}
+
+	insertContributionQuery, contributionInsArgs, err := sq.Insert(contributionsTable).
+		Columns(contributionsCollectionIDColumn, contributionsUserIDColumn, contributionsAmountColumn,
+			contributionsCreatedAtColumn).
+		Values(collectionID, userID, amount, timing).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, insertContributionQuery, contributionInsArgs...)
+	if err != nil {
+		return err
+	}
+
+	if raised+amount < collection.target {
+		return nil
+	}
+
+	return completeCollection(ctx, tx, collection, timing)
+}
+
+// completeCollection places the order of the creator for the recipient. The contributions have paid for it,
+// so the whole price is the discount of the order and nobody is charged again.
+// The unit has been reserved when the collection was created, but the purchase limits are checked again:
+// other purchases may have used up the window since.
+func completeCollection(ctx context.Context, tx *sql.Tx, collection *openCollection, at time.Time) error {
+	err := checkCollectionLimit(ctx, tx, collection.creatorID, collection.recipientID, collection.variantID,
+		collection.item, at)
+	if err != nil {
+		return err
+	}
+
+	insertOrderQuery, orderInsArgs, err := sq.Insert(ordersTable).
+		Columns(ordersUserIDColumn, ordersTotalColumn, ordersStatusColumn, ordersCreatedAtColumn, ordersStatusUpdatedAtColumn,
+			ordersDiscountColumn, ordersRecipientIDColumn).
+		Values(collection.creatorID, 0, models.OrderStatusPlaced, at, at, collection.target, collection.recipientID).
+		Suffix("RETURNING " + ordersIDColumn).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	var orderID int
+	err = tx.QueryRowContext(ctx, insertOrderQuery, orderInsArgs...).Scan(&orderID)
+	if err != nil {
+		return err
+	}
+
+	insertPurchaseQuery, purchaseInsArgs, err := sq.Insert(purchasesTable).
+		Columns(purchasesOrderIDColumn, purchasesUserIDColumn, purchasesItemIDColumn, purchasesVariantIDColumn,
+			purchasesPriceColumn, purchasesQuantityColumn, purchasesDiscountColumn, purchasesTimeColumn).
+		Values(orderID, collection.creatorID, collection.item.itemID, collection.variantID, collection.target, 1,
+			collection.target, at).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, insertPurchaseQuery, purchaseInsArgs...)
+	if err != nil {
+		return err
+	}
+
+	err = addUserItems(ctx, tx, collection.recipientID, []models.CartLine{{VariantID: collection.variantID, Quantity: 1}})
+	if err != nil {
+		return err
+	}
+
+	completeCollectionQuery, collectionUpdArgs, err := sq.Update(collectionsTable).
+		Set(collectionsCompletedAtColumn, at).
+		Set(collectionsOrderIDColumn, orderID).
+		Where(sq.Eq{collectionsIDColumn: collection.id}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, completeCollectionQuery, collectionUpdArgs...)
+	return err
+}
+
+// ExpireCollections refunds contributions of collections that have not reached the target by the deadline.
+// Every collection is refunded in its own transaction.
+func (s *storage) ExpireCollections(ctx context.Context, at time.Time) error {
+	selectExpiredQuery, expiredArgs, err := sq.Select(collectionsIDColumn).
+		From(collectionsTable).
+		Where(sq.Eq{collectionsCompletedAtColumn: nil, collectionsRefundedAtColumn: nil}).
+		Where(sq.LtOrEq{collectionsDeadlineColumn: at}).
+		OrderBy(collectionsIDColumn).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	rows, err := s.db.QueryContext(ctx, selectExpiredQuery, expiredArgs...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	collectionIDs := make([]int, 0)
+	for rows.Next() {
+		var collectionID int
+		err := rows.Scan(&collectionID)
+		if err != nil {
+			return err
+		}
+		collectionIDs = append(collectionIDs, collectionID)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, collectionID := range collectionIDs {
+		err := s.refundCollection(ctx, collectionID, at)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *storage) refundCollection(ctx context.Context, collectionID int, at time.Time) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	err = refundCollectionTx(ctx, tx, collectionID, at)
+	if err != nil {
+		txErr := tx.Rollback()
+		if txErr != nil {
+			log.Printf("tx rollback error: %s", txErr.Error())
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// refundCollectionTx skips a collection completed meanwhile. The reserved unit goes back on the shelf,
+// contributors are refunded in ascending id order, the order user rows are locked in everywhere else.
+func refundCollectionTx(ctx context.Context, tx *sql.Tx, collectionID int, at time.Time) error {
+	refundCollectionQuery, collectionUpdArgs, err := sq.Update(collectionsTable).
+		Set(collectionsRefundedAtColumn, at).
+		Where(sq.Eq{collectionsIDColumn: collectionID, collectionsCompletedAtColumn: nil, collectionsRefundedAtColumn: nil}).
+		Suffix("RETURNING " + collectionsVariantIDColumn).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	var variantID int
+	err = tx.QueryRowContext(ctx, refundCollectionQuery, collectionUpdArgs...).Scan(&variantID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+
+	err = returnStock(ctx, tx, models.CartLine{VariantID: variantID, Quantity: 1})
+	if err != nil {
+		return err
+	}
+
+	refundContributionsQuery, contributionsUpdArgs, err := sq.Update(contributionsTable).
+		Set(contributionsRefundedAtColumn, at).
+		Where(sq.Eq{contributionsCollectionIDColumn: collectionID}).
+		Suffix(fmt.Sprintf("RETURNING %s, %s", contributionsUserIDColumn, contributionsAmountColumn)).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	rows, err := tx.QueryContext(ctx, refundContributionsQuery, contributionsUpdArgs...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	refunds := make(map[int]int)
+	userIDs := make([]int, 0)
+	for rows.Next() {
+		var userID, amount int
+		err := rows.Scan(&userID, &amount)
+		if err != nil {
+			return err
+		}
+		if _, ok := refunds[userID]; !ok {
+			userIDs = append(userIDs, userID)
+		}
+		refunds[userID] += amount
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	sort.Ints(userIDs)
+	for _, userID := range userIDs {
+		err := adjustBalance(ctx, tx, userID, refunds[userID])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
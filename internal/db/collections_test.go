@@ -0,0 +1,287 @@
+package db
+
+import (
+	"context"
+	"log"
+	"merch_shop/internal/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	selectCollectionPriceQueryRegexp = `
+		SELECT items.id, items.type, COALESCE\(item_variants.price, current_prices.price\),
+		items.purchase_limit, items.purchase_limit_days FROM item_variants JOIN items ON (.*)
+		WHERE item_variants.id = \$3 AND items.active = \$4
+	`
+	lockCollectionQueryRegexp = `
+		SELECT collections.creator_id, collections.recipient_id, collections.variant_id, items.id, items.type,
+		items.purchase_limit, items.purchase_limit_days, collections.target, (.*)
+		FROM collections JOIN item_variants ON (.*) JOIN items ON (.*)
+		WHERE collections.id = \$2 FOR UPDATE OF collections
+	`
+	selectRaisedQueryRegexp       = `SELECT COALESCE\(SUM\(amount\), 0\) FROM contributions WHERE collection_id = \$1`
+	insertContributionQueryRegexp = `
+		INSERT INTO contributions \(collection_id,user_id,amount,created_at\) VALUES \(\$1,\$2,\$3,\$4\)
+	`
+	reserveUnitQueryRegexp = `
+		UPDATE item_variants SET stock = stock - 1 WHERE id = \$1 AND \(stock IS NULL OR stock >= \$2\)
+	`
+	completeCollectionQueryRegexp  = `UPDATE collections SET completed_at = \$1, order_id = \$2 WHERE id = \$3`
+	selectExpiredCollectionsRegexp = `
+		SELECT id FROM collections WHERE completed_at IS NULL AND refunded_at IS NULL AND deadline <= \$1 ORDER BY id
+	`
+	refundCollectionQueryRegexp = `
+		UPDATE collections SET refunded_at = \$1 WHERE completed_at IS NULL AND id = \$2 AND refunded_at IS NULL
+		RETURNING variant_id
+	`
+	refundContributionsQueryRegexp = `
+		UPDATE contributions SET refunded_at = \$1 WHERE collection_id = \$2 RETURNING user_id, amount
+	`
+)
+
+func TestCreateCollection(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer mockDB.Close()
+
+	db := storage{db: mockDB}
+
+	creatorID, recipientID := 1, 2
+	userColumns := []string{userIDColumn, usersPasswordColumn}
+	priceColumns := []string{itemsIDColumn, itemsTypeColumn, itemPricesPriceColumn, itemsLimitColumn, itemsLimitDaysColumn}
+	deadline := time.Date(2025, 2, 5, 12, 30, 0, 0, time.UTC)
+
+	t.Run("item is out of stock", func(t *testing.T) {
+		mock.ExpectQuery(selectUserQueryRegexp).WithArgs("bob").
+			WillReturnRows(sqlmock.NewRows(userColumns).AddRow(recipientID, "hash"))
+		mock.ExpectBegin()
+		mock.ExpectQuery(selectCollectionPriceQueryRegexp).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 7, true).
+			WillReturnRows(sqlmock.NewRows(priceColumns).AddRow(4, "mug", 500, nil, nil))
+		// The unit is reserved up front, a collection is never opened for an item it could not buy.
+		mock.ExpectExec(reserveUnitQueryRegexp).WithArgs(7, 1).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		collection, err := db.CreateCollection(context.Background(), creatorID, "bob", 7, deadline, 1000)
+		assert.Equal(t, ErrOutOfStock, err)
+		assert.Nil(t, collection)
+	})
+
+	t.Run("item above the approval threshold", func(t *testing.T) {
+		mock.ExpectQuery(selectUserQueryRegexp).WithArgs("bob").
+			WillReturnRows(sqlmock.NewRows(userColumns).AddRow(recipientID, "hash"))
+		mock.ExpectBegin()
+		mock.ExpectQuery(selectCollectionPriceQueryRegexp).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 7, true).
+			WillReturnRows(sqlmock.NewRows(priceColumns).AddRow(4, "mug", 1500, nil, nil))
+		// Nothing is reserved, a pooled order can not wait for approval.
+		mock.ExpectRollback()
+
+		collection, err := db.CreateCollection(context.Background(), creatorID, "bob", 7, deadline, 1000)
+		assert.Equal(t, ErrOverThreshold, err)
+		assert.Nil(t, collection)
+	})
+
+	t.Run("second collection for a capped item", func(t *testing.T) {
+		completed := time.Date(2025, 1, 20, 9, 0, 0, 0, time.UTC)
+		nextPurchase := completed.AddDate(0, 0, 365)
+
+		mock.ExpectQuery(selectUserQueryRegexp).WithArgs("bob").
+			WillReturnRows(sqlmock.NewRows(userColumns).AddRow(recipientID, "hash"))
+		mock.ExpectBegin()
+		mock.ExpectQuery(selectCollectionPriceQueryRegexp).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 7, true).
+			WillReturnRows(sqlmock.NewRows(priceColumns).AddRow(4, "hoody", 500, 1, 365))
+		mock.ExpectExec(reserveUnitQueryRegexp).WithArgs(7, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(lockUsersQueryRegexp).WithArgs(creatorID, recipientID).WillReturnResult(sqlmock.NewResult(0, 2))
+		// The first collection of the creator has bought the hoody already.
+		mock.ExpectQuery(selectWindowPurchasesQueryRegexp).
+			WithArgs(4, creatorID, creatorID, sqlmock.AnyArg(), models.OrderStatusCancelled).
+			WillReturnRows(sqlmock.NewRows([]string{purchasesTimeColumn, purchasesQuantityColumn}).AddRow(completed, 1))
+		mock.ExpectRollback()
+
+		collection, err := db.CreateCollection(context.Background(), creatorID, "bob", 7, deadline, 1000)
+		assert.Equal(t, &PurchaseLimitError{
+			Item: "hoody", Limit: models.PurchaseLimit{Quantity: 1, WindowDays: 365}, NextPurchaseAt: &nextPurchase,
+		}, err)
+		assert.Nil(t, collection)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestContribute(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer mockDB.Close()
+
+	db := storage{db: mockDB}
+
+	collectionID, userID, creatorID, recipientID := 3, 5, 1, 2
+	collectionColumns := []string{collectionsCreatorIDColumn, collectionsRecipientIDColumn, collectionsVariantIDColumn,
+		itemsIDColumn, itemsTypeColumn, itemsLimitColumn, itemsLimitDaysColumn, collectionsTargetColumn, "open"}
+	lockCollection := func(open bool) {
+		mock.ExpectQuery(lockCollectionQueryRegexp).WithArgs(sqlmock.AnyArg(), collectionID).
+			WillReturnRows(sqlmock.NewRows(collectionColumns).AddRow(creatorID, recipientID, 7, 4, "mug", nil, nil, 500, open))
+	}
+	received := time.Date(2025, 1, 20, 9, 0, 0, 0, time.UTC)
+	nextPurchase := received.AddDate(0, 0, 365)
+	raised := func(amount int) {
+		mock.ExpectQuery(selectRaisedQueryRegexp).WithArgs(collectionID).
+			WillReturnRows(sqlmock.NewRows([]string{contributionsAmountColumn}).AddRow(amount))
+	}
+
+	testCases := []struct {
+		name       string
+		amount     int
+		dbBehavior func()
+
+		expectedErr error
+	}{
+		{
+			name:   "target is not reached yet",
+			amount: 100,
+			dbBehavior: func() {
+				mock.ExpectBegin()
+				lockCollection(true)
+				raised(200)
+				mock.ExpectExec(refundBalanceQueryRegexp).WithArgs(-100, userID).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(insertContributionQueryRegexp).WithArgs(collectionID, userID, 100, sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:   "contribution reaching the target buys the item",
+			amount: 300,
+			dbBehavior: func() {
+				mock.ExpectBegin()
+				lockCollection(true)
+				raised(200)
+				mock.ExpectExec(refundBalanceQueryRegexp).WithArgs(-300, userID).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(insertContributionQueryRegexp).WithArgs(collectionID, userID, 300, sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				// The unit has been reserved on creation, the contributions have paid for the order, the creator is not charged.
+				mock.ExpectQuery(insertOrderQueryRegexp).WithArgs(creatorID, 0, models.OrderStatusPlaced,
+					sqlmock.AnyArg(), sqlmock.AnyArg(), 500, recipientID).
+					WillReturnRows(sqlmock.NewRows([]string{ordersIDColumn}).AddRow(11))
+				mock.ExpectExec(insertPurchasesQueryRegexp).WithArgs(11, creatorID, 4, 7, 500, 1, 500, sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(upsertUserItemsQueryRegexp).WithArgs(recipientID, 7, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(completeCollectionQueryRegexp).WithArgs(sqlmock.AnyArg(), 11, collectionID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:   "purchase limit used up since the collection was created",
+			amount: 300,
+			dbBehavior: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockCollectionQueryRegexp).WithArgs(sqlmock.AnyArg(), collectionID).
+					WillReturnRows(sqlmock.NewRows(collectionColumns).AddRow(creatorID, recipientID, 7, 4, "hoody", 1, 365, 500, true))
+				raised(200)
+				mock.ExpectExec(refundBalanceQueryRegexp).WithArgs(-300, userID).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(insertContributionQueryRegexp).WithArgs(collectionID, userID, 300, sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(lockUsersQueryRegexp).WithArgs(creatorID, recipientID).WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectQuery(selectWindowPurchasesQueryRegexp).
+					WithArgs(4, creatorID, creatorID, sqlmock.AnyArg(), models.OrderStatusCancelled).
+					WillReturnRows(sqlmock.NewRows([]string{purchasesTimeColumn, purchasesQuantityColumn}))
+				// The recipient has been given a hoody by a colleague meanwhile.
+				mock.ExpectQuery(selectWindowPurchasesQueryRegexp).
+					WithArgs(4, recipientID, recipientID, sqlmock.AnyArg(), models.OrderStatusCancelled).
+					WillReturnRows(sqlmock.NewRows([]string{purchasesTimeColumn, purchasesQuantityColumn}).AddRow(received, 1))
+				mock.ExpectRollback()
+			},
+			expectedErr: &PurchaseLimitError{
+				Item: "hoody", Limit: models.PurchaseLimit{Quantity: 1, WindowDays: 365}, NextPurchaseAt: &nextPurchase,
+			},
+		},
+		{
+			name:   "contribution over the target",
+			amount: 301,
+			dbBehavior: func() {
+				mock.ExpectBegin()
+				lockCollection(true)
+				raised(200)
+				mock.ExpectRollback()
+			},
+			expectedErr: ErrOverTarget,
+		},
+		{
+			name:   "collection is over",
+			amount: 100,
+			dbBehavior: func() {
+				mock.ExpectBegin()
+				lockCollection(false)
+				mock.ExpectRollback()
+			},
+			expectedErr: ErrCollectionOver,
+		},
+		{
+			name:   "no collection",
+			amount: 100,
+			dbBehavior: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockCollectionQueryRegexp).WithArgs(sqlmock.AnyArg(), collectionID).
+					WillReturnRows(sqlmock.NewRows(collectionColumns))
+				mock.ExpectRollback()
+			},
+			expectedErr: ErrNoCollection,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.dbBehavior()
+
+			err := db.Contribute(context.Background(), userID, collectionID, tc.amount)
+			assert.Equal(t, tc.expectedErr, err)
+		})
+	}
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExpireCollections(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer mockDB.Close()
+
+	db := storage{db: mockDB}
+
+	at := time.Date(2025, 2, 5, 12, 30, 0, 0, time.UTC)
+	contributionColumns := []string{contributionsUserIDColumn, contributionsAmountColumn}
+
+	mock.ExpectQuery(selectExpiredCollectionsRegexp).WithArgs(at).
+		WillReturnRows(sqlmock.NewRows([]string{collectionsIDColumn}).AddRow(3).AddRow(4))
+	// Every contributor gets back the sum of their contributions, users are updated in ascending id order.
+	mock.ExpectBegin()
+	mock.ExpectQuery(refundCollectionQueryRegexp).WithArgs(at, 3).
+		WillReturnRows(sqlmock.NewRows([]string{collectionsVariantIDColumn}).AddRow(7))
+	// The reserved unit goes back on sale.
+	mock.ExpectExec(returnStockQueryRegexp).WithArgs(1, 7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(refundContributionsQueryRegexp).WithArgs(at, 3).
+		WillReturnRows(sqlmock.NewRows(contributionColumns).AddRow(5, 100).AddRow(2, 50).AddRow(5, 30))
+	mock.ExpectExec(refundBalanceQueryRegexp).WithArgs(50, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(refundBalanceQueryRegexp).WithArgs(130, 5).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	// Collection 4 has been completed after it was selected.
+	mock.ExpectBegin()
+	mock.ExpectQuery(refundCollectionQueryRegexp).WithArgs(at, 4).
+		WillReturnRows(sqlmock.NewRows([]string{collectionsVariantIDColumn}))
+	mock.ExpectCommit()
+
+	err = db.ExpireCollections(context.Background(), at)
+	assert.NoError(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
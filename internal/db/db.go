@@ -6,6 +6,7 @@ import (
 	"errors"
 	"merch_shop/internal/config"
 	"merch_shop/internal/models"
+	"time"
 
 	_ "github.com/lib/pq"
 )
@@ -16,24 +17,249 @@ const (
 	usersNameColumn      = "username"
 	usersPasswordColumn  = "password"
 	usersBalanceColumn   = "balance"
-	usersInventoryColumn = "inventory"
+	usersIsAdminColumn   = "is_admin"
+	usersCreatedAtColumn = "created_at"
+	// Every user is granted this balance on creation, see users table default.
+	usersInitialBalance = 1000
 
 	coinTransfersTable        = "coin_transfers"
+	coinTransfersIDColumn     = "id"
 	coinTransfersSourceColumn = "from_user_id"
 	coinTransfersDestColumn   = "to_user_id"
 	coinTransfersAmountColumn = "amount"
 	coinTransfersTimeColumn   = "timing"
 
-	itemsTable       = "items"
-	itemsIDColumn    = "id"
-	itemsTypeColumn  = "type"
-	itemsPriceColumn = "price"
+	coinTransferTotalsTable       = "coin_transfer_totals"
+	coinTransferTotalsCountColumn = "transfers_count"
+
+	itemsTable             = "items"
+	itemsIDColumn          = "id"
+	itemsTypeColumn        = "type"
+	itemsDescriptionColumn = "description"
+	itemsActiveColumn      = "active"
+	itemsCategoryColumn    = "category"
+	itemsLimitColumn       = "purchase_limit"
+	itemsLimitDaysColumn   = "purchase_limit_days"
+
+	itemPricesTable          = "item_prices"
+	itemPricesIDColumn       = "id"
+	itemPricesItemIDColumn   = "item_id"
+	itemPricesPriceColumn    = "price"
+	itemPricesStartsAtColumn = "starts_at"
+	itemPricesEndsAtColumn   = "ends_at"
+	// Aliases of the lateral subqueries resolving prices of selected items, see joinCurrentPrice.
+	currentPricesAlias     = "current_prices"
+	priceChangesAlias      = "price_changes"
+	priceChangesTimeColumn = "changes_at"
+
+	itemVariantsTable        = "item_variants"
+	itemVariantsIDColumn     = "id"
+	itemVariantsItemIDColumn = "item_id"
+	itemVariantsSKUColumn    = "sku"
+	itemVariantsNameColumn   = "name"
+	itemVariantsPriceColumn  = "price"
+	itemVariantsStockColumn  = "stock"
+	// Every item gets this variant on creation.
+	defaultVariantName = "default"
+
+	purchasesTable           = "purchases"
+	purchasesIDColumn        = "id"
+	purchasesUserIDColumn    = "user_id"
+	purchasesItemIDColumn    = "item_id"
+	purchasesPriceColumn     = "price"
+	purchasesQuantityColumn  = "quantity"
+	purchasesTimeColumn      = "timing"
+	purchasesOrderIDColumn   = "order_id"
+	purchasesVariantIDColumn = "variant_id"
+	purchasesDiscountColumn  = "discount"
+
+	ordersTable                 = "orders"
+	ordersIDColumn              = "id"
+	ordersUserIDColumn          = "user_id"
+	ordersTotalColumn           = "total"
+	ordersStatusColumn          = "status"
+	ordersCreatedAtColumn       = "created_at"
+	ordersStatusUpdatedAtColumn = "status_updated_at"
+	ordersRefundedAtColumn      = "refunded_at"
+	ordersPromoIDColumn         = "promo_id"
+	ordersDiscountColumn        = "discount"
+	ordersRecipientIDColumn     = "recipient_id"
+	ordersGiftNoteColumn        = "gift_note"
+	ordersBundleIDColumn        = "bundle_id"
+	ordersApproverIDColumn      = "approver_id"
+
+	approversTable           = "approvers"
+	approversUserIDColumn    = "user_id"
+	approversCreatedAtColumn = "created_at"
+
+	auctionsTable              = "auctions"
+	auctionsIDColumn           = "id"
+	auctionsVariantIDColumn    = "variant_id"
+	auctionsStartingBidColumn  = "starting_bid"
+	auctionsMinIncrementColumn = "min_increment"
+	auctionsCreatedAtColumn    = "created_at"
+	auctionsEndsAtColumn       = "ends_at"
+	auctionsClosedAtColumn     = "closed_at"
+	auctionsOrderIDColumn      = "order_id"
+
+	bidsTable            = "bids"
+	bidsIDColumn         = "id"
+	bidsAuctionIDColumn  = "auction_id"
+	bidsUserIDColumn     = "user_id"
+	bidsAmountColumn     = "amount"
+	bidsCreatedAtColumn  = "created_at"
+	bidsReleasedAtColumn = "released_at"
+
+	collectionsTable             = "collections"
+	collectionsIDColumn          = "id"
+	collectionsCreatorIDColumn   = "creator_id"
+	collectionsRecipientIDColumn = "recipient_id"
+	collectionsVariantIDColumn   = "variant_id"
+	collectionsTargetColumn      = "target"
+	collectionsDeadlineColumn    = "deadline"
+	collectionsCreatedAtColumn   = "created_at"
+	collectionsCompletedAtColumn = "completed_at"
+	collectionsRefundedAtColumn  = "refunded_at"
+	collectionsOrderIDColumn     = "order_id"
+
+	contributionsTable              = "contributions"
+	contributionsIDColumn           = "id"
+	contributionsCollectionIDColumn = "collection_id"
+	contributionsUserIDColumn       = "user_id"
+	contributionsAmountColumn       = "amount"
+	contributionsCreatedAtColumn    = "created_at"
+	contributionsRefundedAtColumn   = "refunded_at"
+
+	reviewsTable           = "reviews"
+	reviewsIDColumn        = "id"
+	reviewsItemIDColumn    = "item_id"
+	reviewsUserIDColumn    = "user_id"
+	reviewsRatingColumn    = "rating"
+	reviewsTextColumn      = "text"
+	reviewsCreatedAtColumn = "created_at"
+	reviewsUpdatedAtColumn = "updated_at"
+	reviewsHiddenAtColumn  = "hidden_at"
+	// Alias of the lateral subquery aggregating visible reviews of selected items, see joinRatings.
+	ratingsAlias         = "ratings"
+	ratingsReviewsColumn = "reviews"
+
+	bundlesTable             = "bundles"
+	bundlesIDColumn          = "id"
+	bundlesNameColumn        = "name"
+	bundlesDescriptionColumn = "description"
+	bundlesPriceColumn       = "price"
+	bundlesActiveColumn      = "active"
+
+	bundleItemsTable           = "bundle_items"
+	bundleItemsBundleIDColumn  = "bundle_id"
+	bundleItemsVariantIDColumn = "variant_id"
+	bundleItemsQuantityColumn  = "quantity"
+
+	promoCodesTable                = "promo_codes"
+	promoCodesIDColumn             = "id"
+	promoCodesCodeColumn           = "code"
+	promoCodesKindColumn           = "kind"
+	promoCodesValueColumn          = "value"
+	promoCodesItemIDColumn         = "item_id"
+	promoCodesCategoryColumn       = "category"
+	promoCodesStartsAtColumn       = "starts_at"
+	promoCodesEndsAtColumn         = "ends_at"
+	promoCodesMaxUsesColumn        = "max_uses"
+	promoCodesMaxUsesPerUserColumn = "max_uses_per_user"
+	promoCodesUsesColumn           = "uses"
+	promoCodesActiveColumn         = "active"
+
+	itemTransfersTable           = "item_transfers"
+	itemTransfersIDColumn        = "id"
+	itemTransfersSourceColumn    = "from_user_id"
+	itemTransfersDestColumn      = "to_user_id"
+	itemTransfersVariantIDColumn = "variant_id"
+	itemTransfersQuantityColumn  = "quantity"
+	itemTransfersTimeColumn      = "timing"
+
+	userItemsTable           = "user_items"
+	userItemsUserIDColumn    = "user_id"
+	userItemsVariantIDColumn = "variant_id"
+	userItemsQuantityColumn  = "quantity"
+
+	cartItemsTable           = "cart_items"
+	cartItemsUserIDColumn    = "user_id"
+	cartItemsVariantIDColumn = "variant_id"
+	cartItemsQuantityColumn  = "quantity"
+
+	wishlistItemsTable               = "wishlist_items"
+	wishlistItemsUserIDColumn        = "user_id"
+	wishlistItemsItemIDColumn        = "item_id"
+	wishlistItemsCreatedAtColumn     = "created_at"
+	wishlistItemsSeenPriceColumn     = "seen_price"
+	wishlistItemsSeenAvailableColumn = "seen_available"
+
+	notificationsTable           = "notifications"
+	notificationsIDColumn        = "id"
+	notificationsUserIDColumn    = "user_id"
+	notificationsKindColumn      = "kind"
+	notificationsItemIDColumn    = "item_id"
+	notificationsMessageColumn   = "message"
+	notificationsCreatedAtColumn = "created_at"
+	notificationsReadAtColumn    = "read_at"
+	// Users get this many latest notifications.
+	notificationsLimit = 100
+
+	monthlyStatementsTable      = "monthly_statements"
+	statementsUserIDColumn      = "user_id"
+	statementsMonthColumn       = "month"
+	statementsOpeningColumn     = "opening_balance"
+	statementsGrantedColumn     = "granted"
+	statementsSentColumn        = "sent"
+	statementsReceivedColumn    = "received"
+	statementsPurchasesColumn   = "purchases"
+	statementsRefundsColumn     = "refunds"
+	statementsClosingColumn     = "closing_balance"
+	statementsGeneratedAtColumn = "generated_at"
 )
 
 var (
 	ErrNoUser         = errors.New("no such user")
 	ErrNoItem         = errors.New("no such item")
+	ErrItemExists     = errors.New("item with this type already exists")
+	ErrNoVariant      = errors.New("no such item variant")
+	ErrVariantExists  = errors.New("variant with this sku or name already exists")
 	ErrNotEnoughCoins = errors.New("not enough coins")
+	ErrOutOfStock     = errors.New("item is out of stock")
+	ErrNoStatement    = errors.New("no such statement")
+	ErrCartEmpty      = errors.New("cart is empty")
+	ErrNoOrder        = errors.New("no such order")
+	ErrOrderFinal     = errors.New("order status is final")
+	ErrNotCancellable = errors.New("order can not be cancelled anymore")
+	ErrNoPromo        = errors.New("no such promo code")
+	ErrPromoExists    = errors.New("promo code already exists")
+	ErrPromoInactive  = errors.New("promo code is not valid now")
+	ErrPromoNotApply  = errors.New("promo code does not apply to any item in the order")
+	ErrPromoExhausted = errors.New("promo code usage limit is reached")
+	ErrNoPrice        = errors.New("no such price")
+	ErrPriceStarted   = errors.New("price has already taken effect")
+	ErrGiftToSelf     = errors.New("can not gift to yourself, buy the item instead")
+	ErrGiveToSelf     = errors.New("can not give an item to yourself")
+	ErrNotEnoughItems = errors.New("not enough items in the inventory")
+	ErrNotWished      = errors.New("item is not in the wishlist")
+	ErrNoNotification = errors.New("no such notification")
+	ErrNoBundle       = errors.New("no such bundle")
+	ErrBundleExists   = errors.New("bundle with this name already exists")
+	ErrBundleRetired  = errors.New("bundle has items that are not sold anymore")
+	ErrBundleDearer   = errors.New("bundle price must be below the regular price of its items")
+	ErrNoApprover     = errors.New("no such approver")
+	ErrApproverExists = errors.New("user is already an approver")
+	ErrNotPending     = errors.New("order is not waiting for approval")
+	ErrNoAuction      = errors.New("no such auction")
+	ErrAuctionClosed  = errors.New("auction is closed")
+	ErrBidTooLow      = errors.New("bid is lower than the minimal bid")
+	ErrAlreadyLeading = errors.New("your bid is the highest already")
+	ErrNoCollection   = errors.New("no such collection")
+	ErrCollectionOver = errors.New("collection is completed or past its deadline")
+	ErrOverTarget     = errors.New("contribution exceeds the amount left to collect")
+	ErrOverThreshold  = errors.New("item price is above the approval threshold, buy it as a gift instead")
+	ErrNoReview       = errors.New("no such review")
+	ErrNotOwner       = errors.New("only owners of the item can review it")
 )
 
 //go:generate go run github.com/vektra/mockery/v2@v2.52.2 --name=DB
@@ -41,8 +267,70 @@ type DB interface {
 	CreateUser(ctx context.Context, username, password string) (*int, error)
 	GetUser(ctx context.Context, username string) (*int, string, error)
 	SendCoinByUsername(ctx context.Context, userID int, destUsername string, amount int) error
-	BuyItemByItemID(ctx context.Context, userID, itemID int) error
-	GetUserInfoByUserID(ctx context.Context, userID int) (*int, []byte, *models.CoinTransferHistory, error)
+	GiveItem(ctx context.Context, userID int, destUsername string, line models.CartLine) error
+	BuyItems(ctx context.Context, userID int, lines []models.CartLine, promo string,
+		approvalThreshold int) (*models.OrderSummary, error)
+	BuyGift(ctx context.Context, payerID int, recipient string, lines []models.CartLine,
+		promo, note string, approvalThreshold int) (*models.OrderSummary, error)
+	AddCartItem(ctx context.Context, userID int, line models.CartLine) error
+	GetCart(ctx context.Context, userID int) ([]models.CartItem, error)
+	CheckoutCart(ctx context.Context, userID int, promo string, approvalThreshold int) (*models.OrderSummary, error)
+	GetOrders(ctx context.Context, filter *models.OrderFilter) ([]models.Order, error)
+	AdvanceOrder(ctx context.Context, orderID int) error
+	CancelOrder(ctx context.Context, orderID int, cancellation *models.OrderCancellation) error
+	ApproveOrder(ctx context.Context, orderID int, approval *models.OrderApproval) error
+	RejectOrder(ctx context.Context, orderID int, approval *models.OrderApproval) error
+	ExpireApprovals(ctx context.Context, placedBefore time.Time) error
+	GetApprovers(ctx context.Context) ([]models.Approver, error)
+	AddApprover(ctx context.Context, username string) error
+	RemoveApprover(ctx context.Context, username string) error
+	GetUserInfoByUserID(ctx context.Context, userID int, grouped bool) (*int, []models.Item, *models.CoinTransferHistory,
+		*models.ItemTransferHistory, error)
+	GetTransferHistoryByUserID(ctx context.Context, userID int, filter *models.TransferFilter) ([]models.Transfer, error)
+	IsAdmin(ctx context.Context, userID int) (bool, error)
+	ExportLedger(ctx context.Context, filter *models.LedgerFilter, write func(*models.LedgerEntry) error) error
+	GetMonthlyStatement(ctx context.Context, userID int, month time.Time) (*models.Statement, error)
+	ComputeMonthlyStatement(ctx context.Context, userID int, month time.Time) (*models.Statement, error)
+	SaveMonthlyStatements(ctx context.Context, month time.Time) error
+	GetItems(ctx context.Context) ([]models.CatalogItem, error)
+	GetVariantStock(ctx context.Context) (map[int]int, error)
+	CreateItem(ctx context.Context, item *models.ItemRequest) (*models.CatalogItem, error)
+	UpdateItem(ctx context.Context, itemID int, item *models.ItemRequest) (*models.CatalogItem, error)
+	SetItemActive(ctx context.Context, itemID int, active bool) (*models.CatalogItem, error)
+	CreateVariant(ctx context.Context, itemID int, variant *models.VariantRequest) (*models.CatalogItem, error)
+	UpdateVariant(ctx context.Context, variantID int, variant *models.VariantRequest) (*models.CatalogItem, error)
+	RestockVariant(ctx context.Context, variantID, quantity int) (*models.CatalogItem, error)
+	GetLowStockVariants(ctx context.Context, threshold int) ([]models.ItemVariant, error)
+	CreatePromo(ctx context.Context, promo *models.PromoRequest) (*models.PromoCode, error)
+	GetPromos(ctx context.Context) ([]models.PromoCode, error)
+	SetPromoActive(ctx context.Context, promoID int, active bool) (*models.PromoCode, error)
+	SchedulePrice(ctx context.Context, itemID int, price *models.PriceRequest) (*models.ItemPrice, error)
+	GetItemPrices(ctx context.Context, itemID int) ([]models.ItemPrice, error)
+	CancelScheduledPrice(ctx context.Context, priceID int, now time.Time) error
+	CreateBundle(ctx context.Context, bundle *models.BundleRequest, lines []models.CartLine) (*models.Bundle, error)
+	GetBundles(ctx context.Context) ([]models.Bundle, error)
+	SetBundleActive(ctx context.Context, bundleID int, active bool) (*models.Bundle, error)
+	BuyBundle(ctx context.Context, userID, bundleID, quantity, approvalThreshold int) (*models.OrderSummary, error)
+	CreateAuction(ctx context.Context, variantID int, auction *models.AuctionRequest) (*models.Auction, error)
+	GetAuctions(ctx context.Context, openOnly bool) ([]models.Auction, error)
+	GetAuction(ctx context.Context, auctionID int) (*models.Auction, error)
+	PlaceBid(ctx context.Context, userID, auctionID, amount int) error
+	CloseAuctions(ctx context.Context, at time.Time) error
+	CreateCollection(ctx context.Context, creatorID int, recipient string, variantID int,
+		deadline time.Time, approvalThreshold int) (*models.Collection, error)
+	GetCollections(ctx context.Context, openOnly bool) ([]models.Collection, error)
+	GetCollection(ctx context.Context, collectionID int) (*models.Collection, error)
+	Contribute(ctx context.Context, userID, collectionID, amount int) error
+	ExpireCollections(ctx context.Context, at time.Time) error
+	UpsertReview(ctx context.Context, userID, itemID int, review *models.ReviewRequest) (*models.Review, error)
+	GetReviews(ctx context.Context, filter *models.ReviewFilter) ([]models.Review, error)
+	SetReviewHidden(ctx context.Context, reviewID int, hidden bool) (*models.Review, error)
+	AddWishlistItem(ctx context.Context, userID, itemID int) error
+	RemoveWishlistItem(ctx context.Context, userID, itemID int) error
+	GetWishlist(ctx context.Context, userID int) (*models.Wishlist, error)
+	NotifyWishlists(ctx context.Context, at time.Time) error
+	GetNotifications(ctx context.Context, userID int, unreadOnly bool) ([]models.Notification, error)
+	MarkNotificationRead(ctx context.Context, userID, notificationID int) error
 }
 
 type storage struct {
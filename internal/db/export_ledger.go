@@ -0,0 +1,229 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"merch_shop/internal/models"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+const (
+	ledgerOwnersAlias         = "owners"
+	ledgerCounterpartiesAlias = "counterparties"
+)
+
+// ExportLedger streams balance changes ordered by time, calling write for every row
+// as it arrives, so an export never holds the whole period in memory.
+func (s *storage) ExportLedger(ctx context.Context, filter *models.LedgerFilter, write func(*models.LedgerEntry) error) error {
+	branches := []sq.SelectBuilder{
+		transferLedgerBranch(models.LedgerKindSent, filter),
+		transferLedgerBranch(models.LedgerKindReceived, filter),
+		purchaseLedgerBranch(filter),
+		refundLedgerBranch(filter),
+		bidLedgerBranch(models.LedgerKindBid, filter),
+		bidLedgerBranch(models.LedgerKindBidRelease, filter),
+		contributionLedgerBranch(models.LedgerKindContribution, filter),
+		contributionLedgerBranch(models.LedgerKindContributionRefund, filter),
+	}
+
+	queries := make([]string, 0, len(branches))
+	ledgerArgs := make([]any, 0)
+	for _, branch := range branches {
+		query, args, err := branch.ToSql()
+		if err != nil {
+			return err
+		}
+		queries = append(queries, fmt.Sprintf("(%s)", query))
+		ledgerArgs = append(ledgerArgs, args...)
+	}
+
+	selectLedgerQuery, err := sq.Dollar.ReplacePlaceholders(fmt.Sprintf("%s ORDER BY %s, %s",
+		strings.Join(queries, " UNION ALL "), coinTransfersTimeColumn, coinTransfersIDColumn))
+	if err != nil {
+		return err
+	}
+
+	rows, err := s.db.QueryContext(ctx, selectLedgerQuery, ledgerArgs...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	entry := &models.LedgerEntry{}
+	for rows.Next() {
+		var sourceID int
+		err := rows.Scan(&sourceID, &entry.Kind, &entry.Username, &entry.Counterparty, &entry.Item, &entry.Amount, &entry.Timing)
+		if err != nil {
+			return err
+		}
+		// Branches select ids of different tables, and both sides of a transfer share one, the kind tells them apart.
+		entry.ID = fmt.Sprintf("%s-%d", entry.Kind, sourceID)
+		if err := write(entry); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+func transferLedgerBranch(kind string, filter *models.LedgerFilter) sq.SelectBuilder {
+	ownerColumn, counterpartyColumn, amountSign := coinTransfersSourceColumn, coinTransfersDestColumn, "-"
+	if kind == models.LedgerKindReceived {
+		ownerColumn, counterpartyColumn, amountSign = coinTransfersDestColumn, coinTransfersSourceColumn, ""
+	}
+
+	timeColumn := fmt.Sprintf("%s.%s", coinTransfersTable, coinTransfersTimeColumn)
+
+	query := sq.Select(
+		fmt.Sprintf("%s.%s", coinTransfersTable, coinTransfersIDColumn),
+		fmt.Sprintf("'%s'", kind),
+		fmt.Sprintf("%s.%s", ledgerOwnersAlias, usersNameColumn),
+		fmt.Sprintf("%s.%s", ledgerCounterpartiesAlias, usersNameColumn),
+		"''",
+		fmt.Sprintf("%s%s.%s", amountSign, coinTransfersTable, coinTransfersAmountColumn),
+		timeColumn).
+		From(coinTransfersTable).
+		Join(fmt.Sprintf("%s AS %s ON %s.%s = %s.%s",
+			usersTable, ledgerOwnersAlias, coinTransfersTable, ownerColumn, ledgerOwnersAlias, userIDColumn)).
+		Join(fmt.Sprintf("%s AS %s ON %s.%s = %s.%s",
+			usersTable, ledgerCounterpartiesAlias, coinTransfersTable, counterpartyColumn, ledgerCounterpartiesAlias, userIDColumn))
+
+	if filter.UserID != nil {
+		query = query.Where(sq.Eq{fmt.Sprintf("%s.%s", coinTransfersTable, ownerColumn): *filter.UserID})
+	}
+
+	return applyLedgerPeriod(query, timeColumn, filter)
+}
+
+// purchaseLedgerBranch lists purchased lines, lines bought as a gift name the recipient as counterparty.
+func purchaseLedgerBranch(filter *models.LedgerFilter) sq.SelectBuilder {
+	timeColumn := fmt.Sprintf("%s.%s", purchasesTable, purchasesTimeColumn)
+	recipientColumn := fmt.Sprintf("%s.%s", ledgerCounterpartiesAlias, usersNameColumn)
+
+	query := sq.Select(
+		fmt.Sprintf("%s.%s", purchasesTable, purchasesIDColumn),
+		fmt.Sprintf("CASE WHEN %s IS NULL THEN '%s' ELSE '%s' END",
+			recipientColumn, models.LedgerKindPurchase, models.LedgerKindGift),
+		fmt.Sprintf("%s.%s", ledgerOwnersAlias, usersNameColumn),
+		fmt.Sprintf("COALESCE(%s, '')", recipientColumn),
+		fmt.Sprintf("%s.%s", itemsTable, itemsTypeColumn),
+		fmt.Sprintf("%[1]s.%[2]s - %[1]s.%[3]s * %[1]s.%[4]s",
+			purchasesTable, purchasesDiscountColumn, purchasesPriceColumn, purchasesQuantityColumn),
+		timeColumn).
+		From(purchasesTable).
+		Join(fmt.Sprintf("%s AS %s ON %s.%s = %s.%s",
+			usersTable, ledgerOwnersAlias, purchasesTable, purchasesUserIDColumn, ledgerOwnersAlias, userIDColumn)).
+		Join(fmt.Sprintf("%s ON %s.%s = %s.%s", itemsTable, purchasesTable, purchasesItemIDColumn, itemsTable, itemsIDColumn)).
+		LeftJoin(fmt.Sprintf("%s ON %s.%s = %s.%s", ordersTable, purchasesTable, purchasesOrderIDColumn, ordersTable, ordersIDColumn)).
+		LeftJoin(fmt.Sprintf("%s AS %s ON %s.%s = %s.%s",
+			usersTable, ledgerCounterpartiesAlias, ordersTable, ordersRecipientIDColumn, ledgerCounterpartiesAlias, userIDColumn))
+
+	if filter.UserID != nil {
+		query = query.Where(sq.Eq{fmt.Sprintf("%s.%s", purchasesTable, purchasesUserIDColumn): *filter.UserID})
+	}
+
+	return applyLedgerPeriod(query, timeColumn, filter)
+}
+
+// refundLedgerBranch lists cancelled orders, the whole order total is returned at once.
+func refundLedgerBranch(filter *models.LedgerFilter) sq.SelectBuilder {
+	timeColumn := fmt.Sprintf("%s.%s", ordersTable, ordersRefundedAtColumn)
+
+	query := sq.Select(
+		fmt.Sprintf("%s.%s", ordersTable, ordersIDColumn),
+		fmt.Sprintf("'%s'", models.LedgerKindRefund),
+		fmt.Sprintf("%s.%s", ledgerOwnersAlias, usersNameColumn),
+		"''",
+		"''",
+		fmt.Sprintf("%s.%s", ordersTable, ordersTotalColumn),
+		timeColumn).
+		From(ordersTable).
+		Join(fmt.Sprintf("%s AS %s ON %s.%s = %s.%s",
+			usersTable, ledgerOwnersAlias, ordersTable, ordersUserIDColumn, ledgerOwnersAlias, userIDColumn)).
+		Where(sq.NotEq{timeColumn: nil})
+
+	if filter.UserID != nil {
+		query = query.Where(sq.Eq{fmt.Sprintf("%s.%s", ordersTable, ordersUserIDColumn): *filter.UserID})
+	}
+
+	return applyLedgerPeriod(query, timeColumn, filter)
+}
+
+// bidLedgerBranch lists coins held by auction bids and their release, the item is the lot of the auction.
+func bidLedgerBranch(kind string, filter *models.LedgerFilter) sq.SelectBuilder {
+	timeColumn, amountSign := fmt.Sprintf("%s.%s", bidsTable, bidsCreatedAtColumn), "-"
+	if kind == models.LedgerKindBidRelease {
+		timeColumn, amountSign = fmt.Sprintf("%s.%s", bidsTable, bidsReleasedAtColumn), ""
+	}
+
+	query := sq.Select(
+		fmt.Sprintf("%s.%s", bidsTable, bidsIDColumn),
+		fmt.Sprintf("'%s'", kind),
+		fmt.Sprintf("%s.%s", ledgerOwnersAlias, usersNameColumn),
+		"''",
+		fmt.Sprintf("%s.%s", itemsTable, itemsTypeColumn),
+		fmt.Sprintf("%s%s.%s", amountSign, bidsTable, bidsAmountColumn),
+		timeColumn).
+		From(bidsTable).
+		Join(fmt.Sprintf("%s AS %s ON %s.%s = %s.%s",
+			usersTable, ledgerOwnersAlias, bidsTable, bidsUserIDColumn, ledgerOwnersAlias, userIDColumn)).
+		Join(fmt.Sprintf("%s ON %s.%s = %s.%s", auctionsTable, bidsTable, bidsAuctionIDColumn, auctionsTable, auctionsIDColumn)).
+		Join(fmt.Sprintf("%s ON %s.%s = %s.%s",
+			itemVariantsTable, auctionsTable, auctionsVariantIDColumn, itemVariantsTable, itemVariantsIDColumn)).
+		Join(fmt.Sprintf("%s ON %s.%s = %s.%s",
+			itemsTable, itemVariantsTable, itemVariantsItemIDColumn, itemsTable, itemsIDColumn)).
+		Where(sq.NotEq{timeColumn: nil})
+
+	if filter.UserID != nil {
+		query = query.Where(sq.Eq{fmt.Sprintf("%s.%s", bidsTable, bidsUserIDColumn): *filter.UserID})
+	}
+
+	return applyLedgerPeriod(query, timeColumn, filter)
+}
+
+// contributionLedgerBranch lists contributions to collections and their refunds, the counterparty is the recipient.
+func contributionLedgerBranch(kind string, filter *models.LedgerFilter) sq.SelectBuilder {
+	timeColumn, amountSign := fmt.Sprintf("%s.%s", contributionsTable, contributionsCreatedAtColumn), "-"
+	if kind == models.LedgerKindContributionRefund {
+		timeColumn, amountSign = fmt.Sprintf("%s.%s", contributionsTable, contributionsRefundedAtColumn), ""
+	}
+
+	query := sq.Select(
+		fmt.Sprintf("%s.%s", contributionsTable, contributionsIDColumn),
+		fmt.Sprintf("'%s'", kind),
+		fmt.Sprintf("%s.%s", ledgerOwnersAlias, usersNameColumn),
+		fmt.Sprintf("%s.%s", ledgerCounterpartiesAlias, usersNameColumn),
+		fmt.Sprintf("%s.%s", itemsTable, itemsTypeColumn),
+		fmt.Sprintf("%s%s.%s", amountSign, contributionsTable, contributionsAmountColumn),
+		timeColumn).
+		From(contributionsTable).
+		Join(fmt.Sprintf("%s AS %s ON %s.%s = %s.%s",
+			usersTable, ledgerOwnersAlias, contributionsTable, contributionsUserIDColumn, ledgerOwnersAlias, userIDColumn)).
+		Join(fmt.Sprintf("%s ON %s.%s = %s.%s",
+			collectionsTable, contributionsTable, contributionsCollectionIDColumn, collectionsTable, collectionsIDColumn)).
+		Join(fmt.Sprintf("%s AS %s ON %s.%s = %s.%s", usersTable, ledgerCounterpartiesAlias,
+			collectionsTable, collectionsRecipientIDColumn, ledgerCounterpartiesAlias, userIDColumn)).
+		Join(fmt.Sprintf("%s ON %s.%s = %s.%s",
+			itemVariantsTable, collectionsTable, collectionsVariantIDColumn, itemVariantsTable, itemVariantsIDColumn)).
+		Join(fmt.Sprintf("%s ON %s.%s = %s.%s",
+			itemsTable, itemVariantsTable, itemVariantsItemIDColumn, itemsTable, itemsIDColumn)).
+		Where(sq.NotEq{timeColumn: nil})
+
+	if filter.UserID != nil {
+		query = query.Where(sq.Eq{fmt.Sprintf("%s.%s", contributionsTable, contributionsUserIDColumn): *filter.UserID})
+	}
+
+	return applyLedgerPeriod(query, timeColumn, filter)
+}
+
+func applyLedgerPeriod(query sq.SelectBuilder, timeColumn string, filter *models.LedgerFilter) sq.SelectBuilder {
+	if filter.Since != nil {
+		query = query.Where(sq.GtOrEq{timeColumn: *filter.Since})
+	}
+	if filter.Until != nil {
+		query = query.Where(sq.Lt{timeColumn: *filter.Until})
+	}
+	return query
+}
@@ -0,0 +1,45 @@
+package db
+
+import (
+	"context"
+	"log"
+	"merch_shop/internal/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+const selectLedgerQueryRegexp = `\(SELECT (.*)\) UNION ALL (.*) ORDER BY timing, id`
+
+func TestExportLedger(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer mockDB.Close()
+
+	db := storage{db: mockDB}
+
+	timing := time.Date(2025, 2, 5, 12, 30, 0, 0, time.UTC)
+	ledgerColumns := []string{coinTransfersIDColumn, "kind", usersNameColumn, "counterparty", itemsTypeColumn,
+		coinTransfersAmountColumn, coinTransfersTimeColumn}
+
+	// A transfer shows up on both sides and a purchase can share its id with the transfer.
+	mock.ExpectQuery(selectLedgerQueryRegexp).
+		WillReturnRows(sqlmock.NewRows(ledgerColumns).
+			AddRow(7, models.LedgerKindSent, "alice", "bob", "", -10, timing).
+			AddRow(7, models.LedgerKindReceived, "bob", "alice", "", 10, timing).
+			AddRow(7, models.LedgerKindPurchase, "bob", "", "cup", -20, timing))
+
+	ids := make([]string, 0)
+	err = db.ExportLedger(context.Background(), &models.LedgerFilter{}, func(entry *models.LedgerEntry) error {
+		ids = append(ids, entry.ID)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"sent-7", "received-7", "purchase-7"}, ids)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
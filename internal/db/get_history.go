@@ -0,0 +1,105 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"merch_shop/internal/models"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+func (s *storage) GetTransferHistoryByUserID(ctx context.Context, userID int,
+	filter *models.TransferFilter) ([]models.Transfer, error) {
+	// One more row than requested tells the caller whether the next page exists.
+	limit := uint64(filter.Limit + 1)
+
+	var selectHistoryQuery string
+	var historyArgs []any
+	var err error
+
+	switch filter.Direction {
+	case models.TransferDirectionSent, models.TransferDirectionReceived:
+		selectHistoryQuery, historyArgs, err = transferHistoryBranch(userID, filter.Direction, filter, limit).
+			PlaceholderFormat(sq.Dollar).ToSql()
+		if err != nil {
+			return nil, err
+		}
+	default:
+		// Each branch is served by its own (user, timing, id) index, so both sides
+		// are cut to the page size before merging instead of scanning with OR.
+		sentQuery, sentArgs, err := transferHistoryBranch(userID, models.TransferDirectionSent, filter, limit).ToSql()
+		if err != nil {
+			return nil, err
+		}
+		receivedQuery, receivedArgs, err := transferHistoryBranch(userID, models.TransferDirectionReceived, filter, limit).ToSql()
+		if err != nil {
+			return nil, err
+		}
+
+		selectHistoryQuery, err = sq.Dollar.ReplacePlaceholders(fmt.Sprintf(
+			"(%s) UNION ALL (%s) ORDER BY %s DESC, %s DESC LIMIT %d",
+			sentQuery, receivedQuery, coinTransfersTimeColumn, coinTransfersIDColumn, limit))
+		if err != nil {
+			return nil, err
+		}
+		historyArgs = append(sentArgs, receivedArgs...)
+	}
+
+	rows, err := s.db.QueryContext(ctx, selectHistoryQuery, historyArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := make([]models.Transfer, 0, limit)
+	entry := models.Transfer{}
+	for rows.Next() {
+		err := rows.Scan(&entry.ID, &entry.Direction, &entry.Username, &entry.Amount, &entry.Timing)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+func transferHistoryBranch(userID int, direction string, filter *models.TransferFilter, limit uint64) sq.SelectBuilder {
+	userColumn, counterpartyColumn := coinTransfersSourceColumn, coinTransfersDestColumn
+	if direction == models.TransferDirectionReceived {
+		userColumn, counterpartyColumn = coinTransfersDestColumn, coinTransfersSourceColumn
+	}
+
+	idColumn := fmt.Sprintf("%s.%s", coinTransfersTable, coinTransfersIDColumn)
+	timeColumn := fmt.Sprintf("%s.%s", coinTransfersTable, coinTransfersTimeColumn)
+	amountColumn := fmt.Sprintf("%s.%s", coinTransfersTable, coinTransfersAmountColumn)
+
+	query := sq.Select(idColumn, fmt.Sprintf("'%s'", direction), usersNameColumn, amountColumn, timeColumn).
+		From(coinTransfersTable).
+		Join(fmt.Sprintf("%s ON %s.%s = %s.%s", usersTable, coinTransfersTable, counterpartyColumn, usersTable, userIDColumn)).
+		Where(sq.Eq{fmt.Sprintf("%s.%s", coinTransfersTable, userColumn): userID})
+
+	if filter.Counterparty != "" {
+		query = query.Where(sq.Eq{usersNameColumn: filter.Counterparty})
+	}
+	if filter.Since != nil {
+		query = query.Where(sq.GtOrEq{timeColumn: *filter.Since})
+	}
+	if filter.Until != nil {
+		query = query.Where(sq.Lt{timeColumn: *filter.Until})
+	}
+	if filter.MinAmount != nil {
+		query = query.Where(sq.GtOrEq{amountColumn: *filter.MinAmount})
+	}
+	if filter.MaxAmount != nil {
+		query = query.Where(sq.LtOrEq{amountColumn: *filter.MaxAmount})
+	}
+	if filter.After != nil {
+		query = query.Where(fmt.Sprintf("(%s, %s) < (?, ?)", timeColumn, idColumn), filter.After.Timing, filter.After.ID)
+	}
+
+	return query.OrderBy(timeColumn+" DESC", idColumn+" DESC").Limit(limit)
+}
@@ -0,0 +1,106 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"log"
+	"merch_shop/internal/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	selectOneDirectionHistoryQueryRegexp = `
+		SELECT (.*) FROM coin_transfers JOIN users ON (.*) WHERE (.*) ORDER BY (.*) LIMIT 3
+	`
+	selectBothDirectionsHistoryQueryRegexp = `
+		\(SELECT (.*) FROM coin_transfers JOIN users ON (.*) WHERE coin_transfers.from_user_id = \$1 (.*)\)
+		UNION ALL
+		\(SELECT (.*) FROM coin_transfers JOIN users ON (.*) WHERE coin_transfers.to_user_id = \$4 (.*)\)
+		ORDER BY timing DESC, id DESC LIMIT 3
+	`
+)
+
+func TestGetTransferHistoryByUserID(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer mockDB.Close()
+
+	db := storage{db: mockDB}
+
+	type mockBehavior func(userID int, filter *models.TransferFilter)
+
+	timing := time.Date(2025, 2, 5, 12, 30, 0, 0, time.UTC)
+	historyColumns := []string{
+		coinTransfersIDColumn, "direction", usersNameColumn, coinTransfersAmountColumn, coinTransfersTimeColumn,
+	}
+
+	testCases := []struct {
+		name       string
+		filter     *models.TransferFilter
+		expected   []models.Transfer
+		dbBehavior mockBehavior
+
+		expectErr bool
+	}{
+		{
+			name:   "one direction",
+			filter: &models.TransferFilter{Direction: models.TransferDirectionSent, Counterparty: "bob", Limit: 2},
+			expected: []models.Transfer{{
+				ID: 1, Direction: models.TransferDirectionSent, Username: "bob", Amount: 100, Timing: timing,
+			}},
+			dbBehavior: func(userID int, filter *models.TransferFilter) {
+				rows := sqlmock.NewRows(historyColumns).AddRow(1, models.TransferDirectionSent, "bob", 100, timing)
+				mock.ExpectQuery(selectOneDirectionHistoryQueryRegexp).WithArgs(userID, filter.Counterparty).WillReturnRows(rows)
+			},
+			expectErr: false,
+		},
+		{
+			name:   "both directions after cursor",
+			filter: &models.TransferFilter{After: &models.TransferCursor{Timing: timing, ID: 5}, Limit: 2},
+			expected: []models.Transfer{
+				{ID: 4, Direction: models.TransferDirectionReceived, Username: "eve", Amount: 20, Timing: timing},
+				{ID: 3, Direction: models.TransferDirectionSent, Username: "bob", Amount: 10, Timing: timing},
+			},
+			dbBehavior: func(userID int, filter *models.TransferFilter) {
+				rows := sqlmock.NewRows(historyColumns).
+					AddRow(4, models.TransferDirectionReceived, "eve", 20, timing).
+					AddRow(3, models.TransferDirectionSent, "bob", 10, timing)
+				mock.ExpectQuery(selectBothDirectionsHistoryQueryRegexp).
+					WithArgs(userID, timing, 5, userID, timing, 5).WillReturnRows(rows)
+			},
+			expectErr: false,
+		},
+		{
+			name:   "query error",
+			filter: &models.TransferFilter{Direction: models.TransferDirectionReceived, Limit: 2},
+			dbBehavior: func(userID int, filter *models.TransferFilter) {
+				mock.ExpectQuery(selectOneDirectionHistoryQueryRegexp).WithArgs(userID).WillReturnError(errors.New("some error"))
+			},
+			expectErr: true,
+		},
+	}
+
+	userID := 0
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.dbBehavior(userID, tc.filter)
+
+			history, err := db.GetTransferHistoryByUserID(context.Background(), userID, tc.filter)
+			if tc.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expected, history)
+			}
+		})
+	}
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
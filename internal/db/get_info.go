@@ -2,89 +2,144 @@ package db
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"merch_shop/internal/models"
 
 	sq "github.com/Masterminds/squirrel"
 )
 
-func (s *storage) GetUserInfoByUserID(ctx context.Context, userID int) (*int, []byte, *models.CoinTransferHistory, error) {
-	selectUserDataQuery, userSelectArgs, err := sq.Select(usersBalanceColumn, usersInventoryColumn).
-		From(usersTable).
-		Where(sq.Eq{userIDColumn: userID}).
-		PlaceholderFormat(sq.Dollar).ToSql()
-	if err != nil {
-		return nil, nil, nil, err
-	}
+const (
+	sentTransfersCTE     = "sent"
+	receivedTransfersCTE = "received"
+	sentItemsCTE         = "sent_items"
+	receivedItemsCTE     = "received_items"
+	inventoryCTE         = "inventory"
+	inventoryJSONColumn  = "items"
+	transfersJSONColumn  = "transfers"
+)
 
-	selectOutgoingTransfersQuery, outgoingTransfersArgs, err := sq.Select(usersNameColumn, coinTransfersAmountColumn).
-		From(coinTransfersTable).
-		LeftJoin(fmt.Sprintf("%s ON %s.%s = %s.%s", usersTable, coinTransfersTable, coinTransfersDestColumn, usersTable, userIDColumn)).
-		Where(sq.Eq{coinTransfersSourceColumn: userID}).
-		PlaceholderFormat(sq.Dollar).ToSql()
-	if err != nil {
-		return nil, nil, nil, err
+// GetUserInfoByUserID collects balance, inventory, coin and item history in one statement,
+// so all parts are read from the same snapshot within a single round trip.
+func (s *storage) GetUserInfoByUserID(ctx context.Context, userID int,
+	grouped bool) (*int, []models.Item, *models.CoinTransferHistory, *models.ItemTransferHistory, error) {
+	// Totals share column names with transfers, holding one row per sender and receiver pair.
+	transfersTable := coinTransfersTable
+	if grouped {
+		transfersTable = coinTransferTotalsTable
 	}
 
-	selectIngoingTransfersQuery, ingoingTransfersArgs, err := sq.Select(usersNameColumn, coinTransfersAmountColumn).
-		From(coinTransfersTable).
+	selectSentTransfers := sq.Select(fmt.Sprintf(
+		"COALESCE(json_agg(json_build_object('toUser', %s, 'amount', %s.%s)), '[]') AS %s",
+		usersNameColumn, transfersTable, coinTransfersAmountColumn, transfersJSONColumn)).
+		From(transfersTable).
+		LeftJoin(fmt.Sprintf("%s ON %s.%s = %s.%s", usersTable, transfersTable, coinTransfersDestColumn, usersTable, userIDColumn)).
+		Where(sq.Eq{coinTransfersSourceColumn: userID})
+
+	selectReceivedTransfers := sq.Select(fmt.Sprintf(
+		"COALESCE(json_agg(json_build_object('fromUser', %s, 'amount', %s.%s)), '[]') AS %s",
+		usersNameColumn, transfersTable, coinTransfersAmountColumn, transfersJSONColumn)).
+		From(transfersTable).
 		LeftJoin(fmt.Sprintf("%s ON %s.%s = %s.%s",
-			usersTable, coinTransfersTable, coinTransfersSourceColumn, usersTable, userIDColumn)).
-		Where(sq.Eq{coinTransfersDestColumn: userID}).
+			usersTable, transfersTable, coinTransfersSourceColumn, usersTable, userIDColumn)).
+		Where(sq.Eq{coinTransfersDestColumn: userID})
+
+	selectInventory := sq.Select(fmt.Sprintf(
+		"COALESCE(json_agg(json_build_object('type', %s.%s, 'variant', %s.%s, 'quantity', %s.%s) ORDER BY %s.%s, %s.%s), '[]') AS %s",
+		itemsTable, itemsTypeColumn, itemVariantsTable, itemVariantsNameColumn, userItemsTable, userItemsQuantityColumn,
+		itemsTable, itemsTypeColumn, itemVariantsTable, itemVariantsNameColumn, inventoryJSONColumn)).
+		From(userItemsTable).
+		Join(fmt.Sprintf("%s ON %s.%s = %s.%s",
+			itemVariantsTable, userItemsTable, userItemsVariantIDColumn, itemVariantsTable, itemVariantsIDColumn)).
+		Join(fmt.Sprintf("%s ON %s.%s = %s.%s", itemsTable, itemVariantsTable, itemVariantsItemIDColumn, itemsTable, itemsIDColumn)).
+		Where(sq.Eq{userItemsUserIDColumn: userID})
+
+	selectSentItems := selectItemTransfers("toUser", itemTransfersDestColumn).
+		Where(sq.Eq{fmt.Sprintf("%s.%s", itemTransfersTable, itemTransfersSourceColumn): userID})
+	selectReceivedItems := selectItemTransfers("fromUser", itemTransfersSourceColumn).
+		Where(sq.Eq{fmt.Sprintf("%s.%s", itemTransfersTable, itemTransfersDestColumn): userID})
+
+	selectUserInfoQuery, userInfoArgs, err := sq.Select(
+		usersBalanceColumn,
+		fmt.Sprintf("%s.%s", inventoryCTE, inventoryJSONColumn),
+		fmt.Sprintf("%s.%s", sentTransfersCTE, transfersJSONColumn),
+		fmt.Sprintf("%s.%s", receivedTransfersCTE, transfersJSONColumn),
+		fmt.Sprintf("%s.%s", sentItemsCTE, transfersJSONColumn),
+		fmt.Sprintf("%s.%s", receivedItemsCTE, transfersJSONColumn)).
+		PrefixExpr(sq.Expr(fmt.Sprintf("WITH %s AS (?), %s AS (?), %s AS (?), %s AS (?), %s AS (?)",
+			inventoryCTE, sentTransfersCTE, receivedTransfersCTE, sentItemsCTE, receivedItemsCTE),
+			selectInventory, selectSentTransfers, selectReceivedTransfers, selectSentItems, selectReceivedItems)).
+		From(fmt.Sprintf("%s, %s, %s, %s, %s, %s",
+			usersTable, inventoryCTE, sentTransfersCTE, receivedTransfersCTE, sentItemsCTE, receivedItemsCTE)).
+		Where(sq.Eq{fmt.Sprintf("%s.%s", usersTable, userIDColumn): userID}).
 		PlaceholderFormat(sq.Dollar).ToSql()
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, nil, nil, nil, err
 	}
 
 	var balance *int
-	var inventory []byte
+	var inventory, sent, received, sentItems, receivedItems []byte
 
-	row := s.db.QueryRowContext(ctx, selectUserDataQuery, userSelectArgs...)
-	err = row.Scan(&balance, &inventory)
+	row := s.db.QueryRowContext(ctx, selectUserInfoQuery, userInfoArgs...)
+	err = row.Scan(&balance, &inventory, &sent, &received, &sentItems, &receivedItems)
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, nil, nil, nil, err
+	}
+
+	items := make([]models.Item, 0)
+	err = json.Unmarshal(inventory, &items)
+	if err != nil {
+		return nil, nil, nil, nil, err
 	}
 
 	history := &models.CoinTransferHistory{
 		Recieved: make([]models.IngoingCoinTransfer, 0),
 		Sent:     make([]models.OutgoingCoinTransfer, 0),
 	}
-	inTransfer := models.IngoingCoinTransfer{}
-	outTransfer := models.OutgoingCoinTransfer{}
 
-	rowsOut, err := s.db.QueryContext(ctx, selectOutgoingTransfersQuery, outgoingTransfersArgs...)
+	err = json.Unmarshal(sent, &history.Sent)
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, nil, nil, nil, err
 	}
-	defer rowsOut.Close()
-
-	for rowsOut.Next() {
-		err := rowsOut.Scan(&outTransfer.Username, &outTransfer.Amount)
-		if err != nil {
-			return nil, nil, nil, err
-		}
-		history.Sent = append(history.Sent, outTransfer)
+
+	err = json.Unmarshal(received, &history.Recieved)
+	if err != nil {
+		return nil, nil, nil, nil, err
 	}
-	if rowsOut.Err() != nil {
-		return nil, nil, nil, err
+
+	itemHistory := &models.ItemTransferHistory{
+		Received: make([]models.IngoingItemTransfer, 0),
+		Sent:     make([]models.OutgoingItemTransfer, 0),
 	}
 
-	rowsIn, err := s.db.QueryContext(ctx, selectIngoingTransfersQuery, ingoingTransfersArgs...)
+	err = json.Unmarshal(sentItems, &itemHistory.Sent)
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, nil, nil, nil, err
 	}
-	defer rowsIn.Close()
-
-	for rowsIn.Next() {
-		err := rowsIn.Scan(&inTransfer.Username, &inTransfer.Amount)
-		if err != nil {
-			return nil, nil, nil, err
-		}
-		history.Recieved = append(history.Recieved, inTransfer)
+
+	err = json.Unmarshal(receivedItems, &itemHistory.Received)
+	if err != nil {
+		return nil, nil, nil, nil, err
 	}
-	if rowsIn.Err() != nil {
-		return nil, nil, nil, err
+
+	return balance, items, history, itemHistory, nil
+}
+
+// selectItemTransfers aggregates item transfers with the other user read from userColumn, oldest first.
+func selectItemTransfers(userKey, userColumn string) sq.SelectBuilder {
+	column := func(table, column string) string {
+		return fmt.Sprintf("%s.%s", table, column)
 	}
 
-	return balance, inventory, history, nil
+	return sq.Select(fmt.Sprintf(
+		"COALESCE(json_agg(json_build_object('%s', %s, 'type', %s, 'variant', %s, 'quantity', %s) ORDER BY %s), '[]') AS %s",
+		userKey, column(usersTable, usersNameColumn), column(itemsTable, itemsTypeColumn),
+		column(itemVariantsTable, itemVariantsNameColumn), column(itemTransfersTable, itemTransfersQuantityColumn),
+		column(itemTransfersTable, itemTransfersIDColumn), transfersJSONColumn)).
+		From(itemTransfersTable).
+		Join(fmt.Sprintf("%s ON %s = %s", usersTable, column(itemTransfersTable, userColumn), column(usersTable, userIDColumn))).
+		Join(fmt.Sprintf("%s ON %s = %s", itemVariantsTable,
+			column(itemTransfersTable, itemTransfersVariantIDColumn), column(itemVariantsTable, itemVariantsIDColumn))).
+		Join(fmt.Sprintf("%s ON %s = %s", itemsTable,
+			column(itemVariantsTable, itemVariantsItemIDColumn), column(itemsTable, itemsIDColumn)))
 }
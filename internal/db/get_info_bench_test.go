@@ -0,0 +1,173 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+)
+
+// Benchmarks need a disposable PostgreSQL database with migrations applied:
+//
+//	BENCH_DB_URL=postgres://... go test -run=^$ -bench=GetUserInfo ./internal/db/
+//
+// On the first run the database is seeded with benchUsers users and benchTransfers transfers.
+const (
+	benchDBURLEnv = "BENCH_DB_URL"
+
+	benchUsers     = 100_000
+	benchTransfers = 3_000_000
+	// Every benchActiveShare-th transfer involves the first bench user.
+	benchActiveShare = 100
+
+	benchUserPrefix = "bench"
+)
+
+func BenchmarkGetUserInfoByUserID(b *testing.B) {
+	s, activeUserID, typicalUserID := benchStorage(b)
+	ctx := context.Background()
+
+	users := []struct {
+		name   string
+		userID int
+	}{
+		{name: "typical user", userID: typicalUserID},
+		{name: "active user", userID: activeUserID},
+	}
+
+	for _, user := range users {
+		for _, grouped := range []bool{false, true} {
+			suffix := fmt.Sprintf("%s/grouped=%t", user.name, grouped)
+
+			b.Run("single query/"+suffix, func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					if _, _, _, _, err := s.GetUserInfoByUserID(ctx, user.userID, grouped); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+
+			b.Run("sequential queries/"+suffix, func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					if err := getUserInfoSequential(ctx, s.db, user.userID, grouped); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+// getUserInfoSequential reads every part of the info in a separate round trip, kept as a baseline.
+func getUserInfoSequential(ctx context.Context, db *sql.DB, userID int, grouped bool) error {
+	transfersTable := coinTransfersTable
+	if grouped {
+		transfersTable = coinTransferTotalsTable
+	}
+
+	var balance int
+	err := db.QueryRowContext(ctx, "SELECT balance FROM users WHERE id = $1", userID).Scan(&balance)
+	if err != nil {
+		return err
+	}
+
+	queries := []string{
+		"SELECT items.type, user_items.quantity FROM user_items JOIN item_variants ON user_items.variant_id = item_variants.id " +
+			"JOIN items ON item_variants.item_id = items.id WHERE user_id = $1",
+		fmt.Sprintf("SELECT username, %[1]s.amount FROM %[1]s LEFT JOIN users ON %[1]s.to_user_id = users.id WHERE from_user_id = $1",
+			transfersTable),
+		fmt.Sprintf("SELECT username, %[1]s.amount FROM %[1]s LEFT JOIN users ON %[1]s.from_user_id = users.id WHERE to_user_id = $1",
+			transfersTable),
+	}
+	for _, query := range queries {
+		rows, err := db.QueryContext(ctx, query, userID)
+		if err != nil {
+			return err
+		}
+
+		var name string
+		var amount int
+		for rows.Next() {
+			if err := rows.Scan(&name, &amount); err != nil {
+				rows.Close()
+				return err
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func benchStorage(b *testing.B) (*storage, int, int) {
+	url := os.Getenv(benchDBURLEnv)
+	if url == "" {
+		b.Skipf("%s is not set", benchDBURLEnv)
+	}
+
+	database, err := sql.Open("postgres", url)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { database.Close() })
+
+	if err := seedBenchData(database); err != nil {
+		b.Fatal(err)
+	}
+
+	var activeUserID, typicalUserID int
+	err = database.QueryRow(`SELECT MIN(id), MAX(id) FROM users WHERE username LIKE $1`, benchUserPrefix+"%").
+		Scan(&activeUserID, &typicalUserID)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	return &storage{db: database}, activeUserID, typicalUserID
+}
+
+func seedBenchData(database *sql.DB) error {
+	var transfers int
+	if err := database.QueryRow(`SELECT COUNT(*) FROM coin_transfers`).Scan(&transfers); err != nil {
+		return err
+	}
+	if transfers >= benchTransfers {
+		return nil
+	}
+
+	_, err := database.Exec(`
+		INSERT INTO users (username, password)
+		SELECT $1 || g, '' FROM generate_series(1, $2::int) g
+		ON CONFLICT DO NOTHING`, benchUserPrefix, benchUsers)
+	if err != nil {
+		return err
+	}
+
+	_, err = database.Exec(`
+		WITH ids AS (SELECT array_agg(id ORDER BY id) AS a, COUNT(*)::int AS n FROM users WHERE username LIKE $1)
+		INSERT INTO coin_transfers (from_user_id, to_user_id, amount, timing)
+		SELECT
+			CASE WHEN g % $3 = 0 THEN a[1] ELSE a[1 + g % n] END,
+			a[1 + ((g::bigint * 7919) % n)::int],
+			1 + g % 100,
+			now() - make_interval(secs => g)
+		FROM ids, generate_series(1, $2::int) g`, benchUserPrefix+"%", benchTransfers, benchActiveShare)
+	if err != nil {
+		return err
+	}
+
+	_, err = database.Exec(`
+		INSERT INTO coin_transfer_totals (from_user_id, to_user_id, amount, transfers_count)
+		SELECT from_user_id, to_user_id, SUM(amount), COUNT(*) FROM coin_transfers GROUP BY from_user_id, to_user_id
+		ON CONFLICT (from_user_id, to_user_id) DO UPDATE
+		SET amount = EXCLUDED.amount, transfers_count = EXCLUDED.transfers_count`)
+	if err != nil {
+		return err
+	}
+
+	_, err = database.Exec(`ANALYZE`)
+	return err
+}
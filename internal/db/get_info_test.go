@@ -12,11 +12,25 @@ import (
 )
 
 const (
-	selectUserDataQueryRegexp = `
-		SELECT (.*) FROM users WHERE (.*)
+	selectUserInfoQueryRegexp = `
+		WITH inventory AS \(SELECT (.*) FROM user_items JOIN item_variants ON (.*) JOIN items ON (.*) WHERE (.*)\),
+		sent AS \(SELECT (.*) FROM coin_transfers LEFT JOIN users ON (.*) WHERE (.*)\),
+		received AS \(SELECT (.*) FROM coin_transfers LEFT JOIN users ON (.*) WHERE (.*)\),
+		sent_items AS \(SELECT (.*) FROM item_transfers JOIN users ON (.*) JOIN item_variants ON (.*)
+		JOIN items ON (.*) WHERE (.*)\),
+		received_items AS \(SELECT (.*) FROM item_transfers JOIN users ON (.*) JOIN item_variants ON (.*)
+		JOIN items ON (.*) WHERE (.*)\)
+		SELECT (.*) FROM users, inventory, sent, received, sent_items, received_items WHERE (.*)
 	`
-	selectTransfersQueryRegexp = `
-		SELECT (.*) FROM coin_transfers LEFT JOIN users ON (.*) WHERE (.*)
+	selectGroupedUserInfoQueryRegexp = `
+		WITH inventory AS \(SELECT (.*) FROM user_items JOIN item_variants ON (.*) JOIN items ON (.*) WHERE (.*)\),
+		sent AS \(SELECT (.*) FROM coin_transfer_totals LEFT JOIN users ON (.*) WHERE (.*)\),
+		received AS \(SELECT (.*) FROM coin_transfer_totals LEFT JOIN users ON (.*) WHERE (.*)\),
+		sent_items AS \(SELECT (.*) FROM item_transfers JOIN users ON (.*) JOIN item_variants ON (.*)
+		JOIN items ON (.*) WHERE (.*)\),
+		received_items AS \(SELECT (.*) FROM item_transfers JOIN users ON (.*) JOIN item_variants ON (.*)
+		JOIN items ON (.*) WHERE (.*)\)
+		SELECT (.*) FROM users, inventory, sent, received, sent_items, received_items WHERE (.*)
 	`
 )
 
@@ -33,14 +47,18 @@ func TestGetUserInfoByUserID(t *testing.T) {
 
 	type expectedRes struct {
 		balance   *int
-		inventory []byte
+		inventory []models.Item
 		history   *models.CoinTransferHistory
+		items     *models.ItemTransferHistory
 	}
 
 	expBalance := 1000
+	userInfoColumns := []string{usersBalanceColumn, inventoryCTE, sentTransfersCTE, receivedTransfersCTE,
+		sentItemsCTE, receivedItemsCTE}
 
 	testCases := []struct {
 		name       string
+		grouped    bool
 		expected   expectedRes
 		dbBehavior mockBehavior
 
@@ -50,7 +68,7 @@ func TestGetUserInfoByUserID(t *testing.T) {
 			name: "positive result",
 			expected: expectedRes{
 				balance:   &expBalance,
-				inventory: []byte("test"),
+				inventory: []models.Item{{Type: "cup", Variant: "default", Quantity: 2}, {Type: "t-shirt", Variant: "red", Quantity: 1}},
 				history: &models.CoinTransferHistory{
 					Recieved: []models.IngoingCoinTransfer{{
 						Username: "testIn",
@@ -59,72 +77,72 @@ func TestGetUserInfoByUserID(t *testing.T) {
 					Sent: []models.OutgoingCoinTransfer{{
 						Username: "testOut",
 						Amount:   100,
+					}, {
+						Username: "testOut",
+						Amount:   50,
 					}},
 				},
+				items: &models.ItemTransferHistory{
+					Received: []models.IngoingItemTransfer{},
+					Sent:     []models.OutgoingItemTransfer{{Username: "testOut", Type: "cup", Variant: "default", Quantity: 1}},
+				},
 			},
 			dbBehavior: func(arg int) {
-				selectUserDataRows := sqlmock.NewRows([]string{usersBalanceColumn, usersInventoryColumn}).
-					AddRow(expBalance, []byte("test"))
-				mock.ExpectQuery(selectUserDataQueryRegexp).WithArgs(arg).WillReturnRows(selectUserDataRows)
-
-				selectOutgoingTransfersRows := sqlmock.NewRows([]string{usersBalanceColumn, coinTransfersAmountColumn}).AddRow(
-					"testOut", 100,
+				selectUserInfoRows := sqlmock.NewRows(userInfoColumns).AddRow(
+					expBalance,
+					[]byte(`[{"type": "cup", "variant": "default", "quantity": 2}, {"type": "t-shirt", "variant": "red", "quantity": 1}]`),
+					[]byte(`[{"toUser": "testOut", "amount": 100}, {"toUser": "testOut", "amount": 50}]`),
+					[]byte(`[{"fromUser": "testIn", "amount": 200}]`),
+					[]byte(`[{"toUser": "testOut", "type": "cup", "variant": "default", "quantity": 1}]`),
+					[]byte(`[]`),
 				)
-				mock.ExpectQuery(selectTransfersQueryRegexp).WithArgs(arg).WillReturnRows(selectOutgoingTransfersRows)
-
-				selectIngoingTransfersRows := sqlmock.NewRows([]string{usersBalanceColumn, coinTransfersAmountColumn}).AddRow(
-					"testIn", 200,
-				)
-				mock.ExpectQuery(selectTransfersQueryRegexp).WithArgs(arg).WillReturnRows(selectIngoingTransfersRows)
+				mock.ExpectQuery(selectUserInfoQueryRegexp).WithArgs(arg, arg, arg, arg, arg, arg).WillReturnRows(selectUserInfoRows)
 			},
 			expectErr: false,
 		},
 		{
-			name: "select user data scan error",
+			name:    "positive grouped result",
+			grouped: true,
 			expected: expectedRes{
-				balance:   nil,
-				inventory: nil,
-				history:   nil,
+				balance:   &expBalance,
+				inventory: []models.Item{},
+				history: &models.CoinTransferHistory{
+					Recieved: []models.IngoingCoinTransfer{},
+					Sent: []models.OutgoingCoinTransfer{{
+						Username: "testOut",
+						Amount:   150,
+					}},
+				},
+				items: &models.ItemTransferHistory{
+					Received: []models.IngoingItemTransfer{{Username: "testIn", Type: "pen", Variant: "blue", Quantity: 2}},
+					Sent:     []models.OutgoingItemTransfer{},
+				},
 			},
 			dbBehavior: func(arg int) {
-				mock.ExpectQuery(selectUserDataQueryRegexp).WithArgs(arg).WillReturnError(errors.New("some error"))
+				selectUserInfoRows := sqlmock.NewRows(userInfoColumns).AddRow(
+					expBalance, []byte(`[]`), []byte(`[{"toUser": "testOut", "amount": 150}]`), []byte(`[]`), []byte(`[]`),
+					[]byte(`[{"fromUser": "testIn", "type": "pen", "variant": "blue", "quantity": 2}]`),
+				)
+				mock.ExpectQuery(selectGroupedUserInfoQueryRegexp).WithArgs(arg, arg, arg, arg, arg, arg).
+					WillReturnRows(selectUserInfoRows)
 			},
-			expectErr: true,
+			expectErr: false,
 		},
 		{
-			name: "select outgoing transfers query error",
-			expected: expectedRes{
-				balance:   nil,
-				inventory: nil,
-				history:   nil,
-			},
+			name: "select user info query error",
 			dbBehavior: func(arg int) {
-				selectUserDataRows := sqlmock.NewRows([]string{usersBalanceColumn, usersInventoryColumn}).
-					AddRow(expBalance, []byte("test"))
-				mock.ExpectQuery(selectUserDataQueryRegexp).WithArgs(arg).WillReturnRows(selectUserDataRows)
-
-				mock.ExpectQuery(selectTransfersQueryRegexp).WithArgs(arg).WillReturnError(errors.New("some error"))
+				mock.ExpectQuery(selectUserInfoQueryRegexp).WithArgs(arg, arg, arg, arg, arg, arg).
+					WillReturnError(errors.New("some error"))
 			},
 			expectErr: true,
 		},
 		{
-			name: "select ingoing transfers query error",
-			expected: expectedRes{
-				balance:   nil,
-				inventory: nil,
-				history:   nil,
-			},
+			name: "malformed transfers json",
 			dbBehavior: func(arg int) {
-				selectUserDataRows := sqlmock.NewRows([]string{usersBalanceColumn, usersInventoryColumn}).
-					AddRow(expBalance, []byte("test"))
-				mock.ExpectQuery(selectUserDataQueryRegexp).WithArgs(arg).WillReturnRows(selectUserDataRows)
-
-				selectOutgoingTransfersRows := sqlmock.NewRows([]string{usersBalanceColumn, coinTransfersAmountColumn}).AddRow(
-					"testOut", 100,
+				selectUserInfoRows := sqlmock.NewRows(userInfoColumns).AddRow(
+					expBalance, []byte(`[]`), []byte(`[`), []byte(`[]`), []byte(`[]`), []byte(`[]`),
 				)
-				mock.ExpectQuery(selectTransfersQueryRegexp).WithArgs(arg).WillReturnRows(selectOutgoingTransfersRows)
-
-				mock.ExpectQuery(selectTransfersQueryRegexp).WithArgs(arg).WillReturnError(errors.New("some error"))
+				mock.ExpectQuery(selectUserInfoQueryRegexp).WithArgs(arg, arg, arg, arg, arg, arg).WillReturnRows(selectUserInfoRows)
 			},
 			expectErr: true,
 		},
@@ -136,7 +154,7 @@ func TestGetUserInfoByUserID(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			tc.dbBehavior(userID)
 
-			balance, inventory, history, err := db.GetUserInfoByUserID(context.Background(), userID)
+			balance, inventory, history, items, err := db.GetUserInfoByUserID(context.Background(), userID, tc.grouped)
 			if tc.expectErr {
 				assert.Error(t, err)
 			} else {
@@ -145,8 +163,10 @@ func TestGetUserInfoByUserID(t *testing.T) {
 				assert.Equal(t, tc.expected.balance, balance)
 				assert.Equal(t, tc.expected.inventory, inventory)
 				assert.Equal(t, tc.expected.history, history)
+				assert.Equal(t, tc.expected.items, items)
 			}
 		})
 	}
 
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
@@ -0,0 +1,146 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"merch_shop/internal/models"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// GetItems returns the whole catalog including retired items.
+func (s *storage) GetItems(ctx context.Context) ([]models.CatalogItem, error) {
+	selectItemsQuery, itemsArgs, err := selectItems(time.Now()).PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, selectItemsQuery, itemsArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]models.CatalogItem, 0)
+	for rows.Next() {
+		item, err := scanItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// GetVariantStock returns the stock of every tracked variant by variant id.
+func (s *storage) GetVariantStock(ctx context.Context) (map[int]int, error) {
+	selectStockQuery, stockArgs, err := sq.Select(itemVariantsIDColumn, itemVariantsStockColumn).
+		From(itemVariantsTable).
+		Where(sq.NotEq{itemVariantsStockColumn: nil}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, selectStockQuery, stockArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stock := make(map[int]int)
+	for rows.Next() {
+		var variantID, quantity int
+		err := rows.Scan(&variantID, &quantity)
+		if err != nil {
+			return nil, err
+		}
+		stock[variantID] = quantity
+	}
+
+	return stock, rows.Err()
+}
+
+// getItem reads one item as GetItems does, q is either the storage or a transaction.
+func getItem(ctx context.Context, q rowQuerier, itemID int) (*models.CatalogItem, error) {
+	selectItemQuery, itemArgs, err := selectItems(time.Now()).
+		Where(sq.Eq{fmt.Sprintf("%s.%s", itemsTable, itemsIDColumn): itemID}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	return scanItem(q.QueryRowContext(ctx, selectItemQuery, itemArgs...))
+}
+
+// selectItems selects items priced at the given time with their variants aggregated into a JSON array.
+func selectItems(at time.Time) sq.SelectBuilder {
+	column := func(table, column string) string {
+		return fmt.Sprintf("%s.%s", table, column)
+	}
+	price := column(currentPricesAlias, itemPricesPriceColumn)
+	changesAt := column(priceChangesAlias, priceChangesTimeColumn)
+	rating, reviews := column(ratingsAlias, reviewsRatingColumn), column(ratingsAlias, ratingsReviewsColumn)
+
+	query := sq.Select(
+		column(itemsTable, itemsIDColumn),
+		column(itemsTable, itemsTypeColumn),
+		price,
+		changesAt,
+		column(itemsTable, itemsDescriptionColumn),
+		column(itemsTable, itemsCategoryColumn),
+		column(itemsTable, itemsActiveColumn),
+		column(itemsTable, itemsLimitColumn),
+		column(itemsTable, itemsLimitDaysColumn),
+		rating,
+		reviews,
+		fmt.Sprintf("COALESCE(json_agg(json_build_object('id', %s, 'itemId', %s, 'sku', %s, 'name', %s, "+
+			"'price', COALESCE(%s, %s), 'priceOverride', %s, 'stock', %s) ORDER BY %s) FILTER (WHERE %s IS NOT NULL), '[]')",
+			column(itemVariantsTable, itemVariantsIDColumn), column(itemVariantsTable, itemVariantsItemIDColumn),
+			column(itemVariantsTable, itemVariantsSKUColumn), column(itemVariantsTable, itemVariantsNameColumn),
+			column(itemVariantsTable, itemVariantsPriceColumn), price,
+			column(itemVariantsTable, itemVariantsPriceColumn), column(itemVariantsTable, itemVariantsStockColumn),
+			column(itemVariantsTable, itemVariantsIDColumn), column(itemVariantsTable, itemVariantsIDColumn))).
+		From(itemsTable).
+		LeftJoin(fmt.Sprintf("%s ON %s = %s", itemVariantsTable,
+			column(itemVariantsTable, itemVariantsItemIDColumn), column(itemsTable, itemsIDColumn)))
+
+	return joinRatings(joinPriceChanges(joinCurrentPrice(query, at), at)).
+		GroupBy(column(itemsTable, itemsIDColumn), price, changesAt, rating, reviews).
+		OrderBy(column(itemsTable, itemsIDColumn))
+}
+
+// scanItem reads a row selected with selectItems.
+func scanItem(row interface{ Scan(dest ...any) error }) (*models.CatalogItem, error) {
+	item := &models.CatalogItem{}
+	var variants []byte
+	var limit, limitDays *int
+	err := row.Scan(&item.ID, &item.Type, &item.Price, &item.PriceValidUntil, &item.Description, &item.Category,
+		&item.Active, &limit, &limitDays, &item.Rating, &item.Reviews, &variants)
+	if err != nil {
+		return nil, err
+	}
+
+	if limit != nil && limitDays != nil {
+		item.PurchaseLimit = &models.PurchaseLimit{Quantity: *limit, WindowDays: *limitDays}
+	}
+
+	err = json.Unmarshal(variants, &item.Variants)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range item.Variants {
+		variant := &item.Variants[i]
+		variant.Available = item.Active && (variant.Stock == nil || *variant.Stock > 0)
+		item.Available = item.Available || variant.Available
+	}
+
+	return item, nil
+}
@@ -0,0 +1,166 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"merch_shop/internal/models"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// GiveItem moves items from the inventory of the user into the inventory of another user
+// and records the transfer, both in one transaction.
+func (s *storage) GiveItem(ctx context.Context, userID int, destUsername string, line models.CartLine) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	err = giveItemTx(ctx, tx, userID, destUsername, line)
+	if err != nil {
+		txErr := tx.Rollback()
+		if txErr != nil {
+			log.Printf("tx rollback error: %s", txErr.Error())
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// giveItemTx locks both user rows in ascending id order before touching inventories,
+// so opposite transfers between the same users wait for each other instead of deadlocking.
+func giveItemTx(ctx context.Context, tx *sql.Tx, userID int, destUsername string, line models.CartLine) error {
+	lockUsersQuery, usersLockArgs, err := sq.Select(userIDColumn, usersNameColumn).
+		From(usersTable).
+		Where(sq.Or{sq.Eq{userIDColumn: userID}, sq.Eq{usersNameColumn: destUsername}}).
+		OrderBy(userIDColumn).
+		Suffix("FOR UPDATE").
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	destID, err := lockTransferUsers(ctx, tx, lockUsersQuery, usersLockArgs, destUsername)
+	if err != nil {
+		return err
+	}
+	if destID == userID {
+		return ErrGiveToSelf
+	}
+
+	err = takeUserItem(ctx, tx, userID, line)
+	if err != nil {
+		return err
+	}
+
+	upsertUserItemQuery, userItemUpsArgs, err := sq.Insert(userItemsTable).
+		Columns(userItemsUserIDColumn, userItemsVariantIDColumn, userItemsQuantityColumn).
+		Values(destID, line.VariantID, line.Quantity).
+		Suffix(fmt.Sprintf("ON CONFLICT (%s, %s) DO UPDATE SET %s = %s.%s + EXCLUDED.%s",
+			userItemsUserIDColumn, userItemsVariantIDColumn, userItemsQuantityColumn,
+			userItemsTable, userItemsQuantityColumn, userItemsQuantityColumn)).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, upsertUserItemQuery, userItemUpsArgs...)
+	if err != nil {
+		return err
+	}
+
+	insertTransferQuery, transferInsArgs, err := sq.Insert(itemTransfersTable).
+		Columns(itemTransfersSourceColumn, itemTransfersDestColumn, itemTransfersVariantIDColumn,
+			itemTransfersQuantityColumn, itemTransfersTimeColumn).
+		Values(userID, destID, line.VariantID, line.Quantity, time.Now()).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, insertTransferQuery, transferInsArgs...)
+	return err
+}
+
+// lockTransferUsers returns the id of the receiver among the locked users.
+func lockTransferUsers(ctx context.Context, tx *sql.Tx, query string, args []any, destUsername string) (int, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	destID := 0
+	for rows.Next() {
+		var id int
+		var username string
+		err := rows.Scan(&id, &username)
+		if err != nil {
+			return 0, err
+		}
+		if username == destUsername {
+			destID = id
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if destID == 0 {
+		return 0, ErrNoUser
+	}
+
+	return destID, nil
+}
+
+// takeUserItem removes the given quantity from the inventory. Both statements only match rows
+// holding enough items, so concurrent transfers can never give away more than owned.
+func takeUserItem(ctx context.Context, tx *sql.Tx, userID int, line models.CartLine) error {
+	deleteUserItemQuery, userItemDelArgs, err := sq.Delete(userItemsTable).
+		Where(sq.Eq{userItemsUserIDColumn: userID, userItemsVariantIDColumn: line.VariantID,
+			userItemsQuantityColumn: line.Quantity}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, deleteUserItemQuery, userItemDelArgs...)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		return nil
+	}
+
+	updateUserItemQuery, userItemUpdArgs, err := sq.Update(userItemsTable).
+		Set(userItemsQuantityColumn, sq.Expr(fmt.Sprintf("%s - ?", userItemsQuantityColumn), line.Quantity)).
+		Where(sq.Eq{userItemsUserIDColumn: userID, userItemsVariantIDColumn: line.VariantID}).
+		Where(sq.Gt{userItemsQuantityColumn: line.Quantity}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	result, err = tx.ExecContext(ctx, updateUserItemQuery, userItemUpdArgs...)
+	if err != nil {
+		return err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return ErrNotEnoughItems
+	}
+
+	return nil
+}
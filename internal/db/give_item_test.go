@@ -0,0 +1,134 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"log"
+	"merch_shop/internal/models"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	lockTransferUsersQueryRegexp = `SELECT id, username FROM users WHERE \(id = \$1 OR username = \$2\) ORDER BY id FOR UPDATE`
+	takeAllUserItemsQueryRegexp  = `DELETE FROM user_items WHERE quantity = \$1 AND user_id = \$2 AND variant_id = \$3`
+	takeUserItemsQueryRegexp     = `
+		UPDATE user_items SET quantity = quantity - \$1 WHERE user_id = \$2 AND variant_id = \$3 AND quantity > \$4
+	`
+	insertItemTransferQueryRegexp = `
+		INSERT INTO item_transfers \(from_user_id,to_user_id,variant_id,quantity,timing\) VALUES \(\$1,\$2,\$3,\$4,\$5\)
+	`
+)
+
+func TestGiveItem(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer mockDB.Close()
+
+	db := storage{db: mockDB}
+
+	userID, destID := 2, 1
+	line := models.CartLine{VariantID: 4, Quantity: 2}
+	userColumns := []string{userIDColumn, usersNameColumn}
+
+	testCases := []struct {
+		name        string
+		dest        string
+		dbBehavior  func()
+		expectedErr error
+	}{
+		{
+			name: "whole quantity is given away",
+			dest: "bob",
+			dbBehavior: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockTransferUsersQueryRegexp).WithArgs(userID, "bob").
+					WillReturnRows(sqlmock.NewRows(userColumns).AddRow(destID, "bob").AddRow(userID, "alice"))
+				mock.ExpectExec(takeAllUserItemsQueryRegexp).WithArgs(2, userID, 4).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(upsertUserItemsQueryRegexp).WithArgs(destID, 4, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(insertItemTransferQueryRegexp).WithArgs(userID, destID, 4, 2, sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "part of the quantity is given away",
+			dest: "bob",
+			dbBehavior: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockTransferUsersQueryRegexp).WithArgs(userID, "bob").
+					WillReturnRows(sqlmock.NewRows(userColumns).AddRow(destID, "bob").AddRow(userID, "alice"))
+				mock.ExpectExec(takeAllUserItemsQueryRegexp).WithArgs(2, userID, 4).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(takeUserItemsQueryRegexp).WithArgs(2, userID, 4, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(upsertUserItemsQueryRegexp).WithArgs(destID, 4, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(insertItemTransferQueryRegexp).WithArgs(userID, destID, 4, 2, sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "not enough items",
+			dest: "bob",
+			dbBehavior: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockTransferUsersQueryRegexp).WithArgs(userID, "bob").
+					WillReturnRows(sqlmock.NewRows(userColumns).AddRow(destID, "bob").AddRow(userID, "alice"))
+				mock.ExpectExec(takeAllUserItemsQueryRegexp).WithArgs(2, userID, 4).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(takeUserItemsQueryRegexp).WithArgs(2, userID, 4, 2).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+			},
+			expectedErr: ErrNotEnoughItems,
+		},
+		{
+			name: "unknown receiver",
+			dest: "nobody",
+			dbBehavior: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockTransferUsersQueryRegexp).WithArgs(userID, "nobody").
+					WillReturnRows(sqlmock.NewRows(userColumns).AddRow(userID, "alice"))
+				mock.ExpectRollback()
+			},
+			expectedErr: ErrNoUser,
+		},
+		{
+			name: "give to yourself",
+			dest: "alice",
+			dbBehavior: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockTransferUsersQueryRegexp).WithArgs(userID, "alice").
+					WillReturnRows(sqlmock.NewRows(userColumns).AddRow(userID, "alice"))
+				mock.ExpectRollback()
+			},
+			expectedErr: ErrGiveToSelf,
+		},
+		{
+			name: "history error rolls back",
+			dest: "bob",
+			dbBehavior: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockTransferUsersQueryRegexp).WithArgs(userID, "bob").
+					WillReturnRows(sqlmock.NewRows(userColumns).AddRow(destID, "bob").AddRow(userID, "alice"))
+				mock.ExpectExec(takeAllUserItemsQueryRegexp).WithArgs(2, userID, 4).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(upsertUserItemsQueryRegexp).WithArgs(destID, 4, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(insertItemTransferQueryRegexp).WillReturnError(errors.New("some error"))
+				mock.ExpectRollback()
+			},
+			expectedErr: errors.New("some error"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.dbBehavior()
+
+			err := db.GiveItem(context.Background(), userID, tc.dest, line)
+			assert.Equal(t, tc.expectedErr, err)
+		})
+	}
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
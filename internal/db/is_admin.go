@@ -0,0 +1,30 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+func (s *storage) IsAdmin(ctx context.Context, userID int) (bool, error) {
+	selectQuery, selArgs, err := sq.Select(usersIsAdminColumn).
+		From(usersTable).
+		Where(sq.Eq{userIDColumn: userID}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return false, err
+	}
+
+	var isAdmin bool
+	row := s.db.QueryRowContext(ctx, selectQuery, selArgs...)
+	err = row.Scan(&isAdmin)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, ErrNoUser
+		}
+		return false, err
+	}
+
+	return isAdmin, nil
+}
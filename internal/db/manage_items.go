@@ -0,0 +1,304 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"merch_shop/internal/models"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+)
+
+// CreateItem adds an item together with its default variant, which holds the requested stock,
+// and its regular price effective from now.
+func (s *storage) CreateItem(ctx context.Context, item *models.ItemRequest) (*models.CatalogItem, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := createItemTx(ctx, tx, item)
+	if err != nil {
+		txErr := tx.Rollback()
+		if txErr != nil {
+			log.Printf("tx rollback error: %s", txErr.Error())
+		}
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+func createItemTx(ctx context.Context, tx *sql.Tx, item *models.ItemRequest) (*models.CatalogItem, error) {
+	limit, limitDays := purchaseLimitValues(item.PurchaseLimit)
+	insertItemQuery, itemInsArgs, err := sq.Insert(itemsTable).
+		Columns(itemsTypeColumn, itemsDescriptionColumn, itemsCategoryColumn, itemsLimitColumn, itemsLimitDaysColumn).
+		Values(item.Type, item.Description, item.Category, limit, limitDays).
+		Suffix("RETURNING " + itemsIDColumn).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var itemID int
+	err = tx.QueryRowContext(ctx, insertItemQuery, itemInsArgs...).Scan(&itemID)
+	if err != nil {
+		return nil, itemWriteError(err)
+	}
+
+	err = insertPrice(ctx, tx, itemID, item.Price, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	// The default variant takes the item type as SKU, like variants created by the migration.
+	err = insertVariant(ctx, tx, itemID, &models.VariantRequest{SKU: item.Type, Name: defaultVariantName, Stock: item.Stock})
+	if err != nil {
+		return nil, err
+	}
+
+	return getItem(ctx, tx, itemID)
+}
+
+// UpdateItem changes type, description and category of an item, a changed price becomes the regular
+// price from now on. Inventories and purchases reference items by id, so a renamed item keeps being
+// owned and bought under the new type.
+func (s *storage) UpdateItem(ctx context.Context, itemID int, item *models.ItemRequest) (*models.CatalogItem, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := updateItemTx(ctx, tx, itemID, item)
+	if err != nil {
+		txErr := tx.Rollback()
+		if txErr != nil {
+			log.Printf("tx rollback error: %s", txErr.Error())
+		}
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+func updateItemTx(ctx context.Context, tx *sql.Tx, itemID int, item *models.ItemRequest) (*models.CatalogItem, error) {
+	limit, limitDays := purchaseLimitValues(item.PurchaseLimit)
+	updateItemQuery, itemUpdArgs, err := sq.Update(itemsTable).
+		Set(itemsTypeColumn, item.Type).
+		Set(itemsDescriptionColumn, item.Description).
+		Set(itemsCategoryColumn, item.Category).
+		Set(itemsLimitColumn, limit).
+		Set(itemsLimitDaysColumn, limitDays).
+		Where(sq.Eq{itemsIDColumn: itemID}).
+		Suffix("RETURNING " + itemsIDColumn).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.QueryRowContext(ctx, updateItemQuery, itemUpdArgs...).Scan(&itemID)
+	if err != nil {
+		return nil, itemWriteError(err)
+	}
+
+	err = setRegularPrice(ctx, tx, itemID, item.Price, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return getItem(ctx, tx, itemID)
+}
+
+// SetItemActive retires or restores an item. Items are never deleted,
+// so purchases and inventories keep resolving retired ones.
+func (s *storage) SetItemActive(ctx context.Context, itemID int, active bool) (*models.CatalogItem, error) {
+	updateItemQuery, itemUpdArgs, err := sq.Update(itemsTable).
+		Set(itemsActiveColumn, active).
+		Where(sq.Eq{itemsIDColumn: itemID}).
+		Suffix("RETURNING " + itemsIDColumn).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.QueryRowContext(ctx, updateItemQuery, itemUpdArgs...).Scan(&itemID)
+	if err != nil {
+		return nil, itemWriteError(err)
+	}
+
+	return getItem(ctx, s.db, itemID)
+}
+
+// purchaseLimitValues returns the purchase limit columns, both are NULL for items without a limit.
+func purchaseLimitValues(limit *models.PurchaseLimit) (*int, *int) {
+	if limit == nil {
+		return nil, nil
+	}
+	return &limit.Quantity, &limit.WindowDays
+}
+
+func itemWriteError(err error) error {
+	if err == sql.ErrNoRows {
+		return ErrNoItem
+	}
+	if pqErr, ok := err.(*pq.Error); ok {
+		if pqErr.Code.Name() == "unique_violation" {
+			return ErrItemExists
+		}
+	}
+	return err
+}
+
+// CreateVariant adds a variant to an item and returns the item with all its variants.
+func (s *storage) CreateVariant(ctx context.Context, itemID int, variant *models.VariantRequest) (*models.CatalogItem, error) {
+	err := insertVariant(ctx, s.db, itemID, variant)
+	if err != nil {
+		return nil, err
+	}
+
+	return getItem(ctx, s.db, itemID)
+}
+
+func insertVariant(ctx context.Context, q rowQuerier, itemID int, variant *models.VariantRequest) error {
+	insertVariantQuery, variantInsArgs, err := sq.Insert(itemVariantsTable).
+		Columns(itemVariantsItemIDColumn, itemVariantsSKUColumn, itemVariantsNameColumn,
+			itemVariantsPriceColumn, itemVariantsStockColumn).
+		Values(itemID, variant.SKU, variant.Name, variant.Price, variant.Stock).
+		Suffix("RETURNING " + itemVariantsIDColumn).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	var variantID int
+	err = q.QueryRowContext(ctx, insertVariantQuery, variantInsArgs...).Scan(&variantID)
+	if err != nil {
+		return variantWriteError(err)
+	}
+
+	return nil
+}
+
+// UpdateVariant changes SKU, name and price override of a variant, stock is changed by restock only.
+func (s *storage) UpdateVariant(ctx context.Context, variantID int, variant *models.VariantRequest) (*models.CatalogItem, error) {
+	updateVariantQuery, variantUpdArgs, err := sq.Update(itemVariantsTable).
+		Set(itemVariantsSKUColumn, variant.SKU).
+		Set(itemVariantsNameColumn, variant.Name).
+		Set(itemVariantsPriceColumn, variant.Price).
+		Where(sq.Eq{itemVariantsIDColumn: variantID}).
+		Suffix("RETURNING " + itemVariantsItemIDColumn).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var itemID int
+	err = s.db.QueryRowContext(ctx, updateVariantQuery, variantUpdArgs...).Scan(&itemID)
+	if err != nil {
+		return nil, variantWriteError(err)
+	}
+
+	return getItem(ctx, s.db, itemID)
+}
+
+// RestockVariant adds quantity to the variant stock, an untracked variant starts being tracked.
+func (s *storage) RestockVariant(ctx context.Context, variantID, quantity int) (*models.CatalogItem, error) {
+	updateVariantQuery, variantUpdArgs, err := sq.Update(itemVariantsTable).
+		Set(itemVariantsStockColumn, sq.Expr(fmt.Sprintf("COALESCE(%s, 0) + ?", itemVariantsStockColumn), quantity)).
+		Where(sq.Eq{itemVariantsIDColumn: variantID}).
+		Suffix("RETURNING " + itemVariantsItemIDColumn).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var itemID int
+	err = s.db.QueryRowContext(ctx, updateVariantQuery, variantUpdArgs...).Scan(&itemID)
+	if err != nil {
+		return nil, variantWriteError(err)
+	}
+
+	return getItem(ctx, s.db, itemID)
+}
+
+func variantWriteError(err error) error {
+	if err == sql.ErrNoRows {
+		return ErrNoVariant
+	}
+	if pqErr, ok := err.(*pq.Error); ok {
+		switch pqErr.Code.Name() {
+		case "unique_violation":
+			return ErrVariantExists
+		case "foreign_key_violation":
+			return ErrNoItem
+		}
+	}
+	return err
+}
+
+// GetLowStockVariants returns tracked variants of active items with stock at or below threshold, scarcest first.
+func (s *storage) GetLowStockVariants(ctx context.Context, threshold int) ([]models.ItemVariant, error) {
+	column := func(table, column string) string {
+		return fmt.Sprintf("%s.%s", table, column)
+	}
+
+	query := sq.Select(
+		column(itemVariantsTable, itemVariantsIDColumn),
+		column(itemVariantsTable, itemVariantsItemIDColumn),
+		column(itemsTable, itemsTypeColumn),
+		column(itemVariantsTable, itemVariantsSKUColumn),
+		column(itemVariantsTable, itemVariantsNameColumn),
+		fmt.Sprintf("COALESCE(%s, %s)",
+			column(itemVariantsTable, itemVariantsPriceColumn), column(currentPricesAlias, itemPricesPriceColumn)),
+		column(itemVariantsTable, itemVariantsPriceColumn),
+		column(itemVariantsTable, itemVariantsStockColumn)).
+		From(itemVariantsTable).
+		Join(fmt.Sprintf("%s ON %s = %s", itemsTable,
+			column(itemVariantsTable, itemVariantsItemIDColumn), column(itemsTable, itemsIDColumn)))
+
+	selectVariantsQuery, variantsArgs, err := joinCurrentPrice(query, time.Now()).
+		Where(sq.Eq{column(itemsTable, itemsActiveColumn): true}).
+		Where(sq.LtOrEq{column(itemVariantsTable, itemVariantsStockColumn): threshold}).
+		OrderBy(column(itemVariantsTable, itemVariantsStockColumn), column(itemVariantsTable, itemVariantsIDColumn)).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, selectVariantsQuery, variantsArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	variants := make([]models.ItemVariant, 0)
+	for rows.Next() {
+		var variant models.ItemVariant
+		err := rows.Scan(&variant.ID, &variant.ItemID, &variant.Item, &variant.SKU, &variant.Name,
+			&variant.Price, &variant.PriceOverride, &variant.Stock)
+		if err != nil {
+			return nil, err
+		}
+		// Only tracked variants of active items are selected.
+		variant.Available = *variant.Stock > 0
+		variants = append(variants, variant)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return variants, nil
+}
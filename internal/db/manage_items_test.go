@@ -0,0 +1,199 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"log"
+	"merch_shop/internal/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	updateItemQueryRegexp = `
+		UPDATE items SET type = \$1, description = \$2, category = \$3, purchase_limit = \$4, purchase_limit_days = \$5
+		WHERE id = \$6 RETURNING id
+	`
+	selectRegularPriceQueryRegexp = `
+		SELECT price FROM item_prices WHERE ends_at IS NULL AND item_id = \$1 AND \(starts_at IS NULL OR starts_at <= \$2\)
+		ORDER BY starts_at DESC NULLS LAST, id DESC LIMIT 1
+	`
+	insertPriceQueryRegexp = `INSERT INTO item_prices \(item_id,price,starts_at\) VALUES \(\$1,\$2,\$3\)`
+	selectItemQueryRegexp  = `
+		SELECT (.*) FROM items LEFT JOIN item_variants ON item_variants.item_id = items.id
+		JOIN LATERAL (.*) AS current_prices ON true JOIN LATERAL (.*) AS price_changes ON true
+		JOIN LATERAL (.*) AS ratings ON true WHERE items.id = \$5
+		GROUP BY items.id, current_prices.price, price_changes.changes_at, ratings.rating, ratings.reviews ORDER BY items.id
+	`
+	restockVariantQueryRegexp = `UPDATE item_variants SET stock = COALESCE\(stock, 0\) \+ \$1 WHERE id = \$2 RETURNING item_id`
+)
+
+var itemColumns = []string{
+	itemsIDColumn, itemsTypeColumn, itemPricesPriceColumn, priceChangesTimeColumn, itemsDescriptionColumn, itemsCategoryColumn,
+	itemsActiveColumn, itemsLimitColumn, itemsLimitDaysColumn, reviewsRatingColumn, ratingsReviewsColumn, "variants",
+}
+
+func TestUpdateItem(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer mockDB.Close()
+
+	db := storage{db: mockDB}
+
+	itemID := 1
+	xlPrice, xlStock := 25, 0
+	saleEnd := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name       string
+		request    *models.ItemRequest
+		expected   *models.CatalogItem
+		dbBehavior func(request *models.ItemRequest)
+
+		expectedErr error
+	}{
+		{
+			name:    "rename keeps the price",
+			request: &models.ItemRequest{Type: "mug", Price: 20, Category: "kitchen"},
+			expected: &models.CatalogItem{
+				ID: itemID, Type: "mug", Price: 20, Category: "kitchen", Active: true, Available: true,
+				Variants: []models.ItemVariant{
+					{ID: 1, ItemID: itemID, SKU: "mug", Name: "default", Price: 20, Available: true},
+					{ID: 5, ItemID: itemID, SKU: "mug-xl", Name: "XL", Price: 25, PriceOverride: &xlPrice, Stock: &xlStock},
+				},
+			},
+			dbBehavior: func(request *models.ItemRequest) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(updateItemQueryRegexp).
+					WithArgs(request.Type, request.Description, request.Category, nil, nil, itemID).
+					WillReturnRows(sqlmock.NewRows([]string{itemsIDColumn}).AddRow(itemID))
+				mock.ExpectQuery(selectRegularPriceQueryRegexp).WithArgs(itemID, sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{itemPricesPriceColumn}).AddRow(20))
+				mock.ExpectQuery(selectItemQueryRegexp).
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), itemID).
+					WillReturnRows(sqlmock.NewRows(itemColumns).AddRow(itemID, "mug", 20, nil, "", "kitchen", true, nil, nil, 0, 0,
+						`[{"id":1,"itemId":1,"sku":"mug","name":"default","price":20,"priceOverride":null,"stock":null},`+
+							`{"id":5,"itemId":1,"sku":"mug-xl","name":"XL","price":25,"priceOverride":25,"stock":0}]`))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:    "changed price takes effect now",
+			request: &models.ItemRequest{Type: "mug", Price: 20},
+			expected: &models.CatalogItem{
+				ID: itemID, Type: "mug", Price: 20, PriceValidUntil: &saleEnd, Active: true, Available: true,
+				Variants: []models.ItemVariant{{ID: 1, ItemID: itemID, SKU: "mug", Name: "default", Price: 20, Available: true}},
+			},
+			dbBehavior: func(request *models.ItemRequest) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(updateItemQueryRegexp).
+					WithArgs(request.Type, request.Description, request.Category, nil, nil, itemID).
+					WillReturnRows(sqlmock.NewRows([]string{itemsIDColumn}).AddRow(itemID))
+				mock.ExpectQuery(selectRegularPriceQueryRegexp).WithArgs(itemID, sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{itemPricesPriceColumn}).AddRow(15))
+				mock.ExpectExec(insertPriceQueryRegexp).WithArgs(itemID, 20, sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectQuery(selectItemQueryRegexp).
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), itemID).
+					WillReturnRows(sqlmock.NewRows(itemColumns).AddRow(itemID, "mug", 20, saleEnd, "", "", true, nil, nil, 0, 0,
+						`[{"id":1,"itemId":1,"sku":"mug","name":"default","price":20,"priceOverride":null,"stock":null}]`))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:    "no item",
+			request: &models.ItemRequest{Type: "mug", Price: 20},
+			dbBehavior: func(request *models.ItemRequest) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(updateItemQueryRegexp).
+					WithArgs(request.Type, request.Description, request.Category, nil, nil, itemID).
+					WillReturnRows(sqlmock.NewRows([]string{itemsIDColumn}))
+				mock.ExpectRollback()
+			},
+			expectedErr: ErrNoItem,
+		},
+		{
+			name:    "type already taken",
+			request: &models.ItemRequest{Type: "pen", Price: 20},
+			dbBehavior: func(request *models.ItemRequest) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(updateItemQueryRegexp).
+					WithArgs(request.Type, request.Description, request.Category, nil, nil, itemID).
+					WillReturnError(&pq.Error{Code: "23505"})
+				mock.ExpectRollback()
+			},
+			expectedErr: ErrItemExists,
+		},
+		{
+			name:    "query error",
+			request: &models.ItemRequest{Type: "mug", Price: 20},
+			dbBehavior: func(request *models.ItemRequest) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(updateItemQueryRegexp).WillReturnError(errors.New("some error"))
+				mock.ExpectRollback()
+			},
+			expectedErr: errors.New("some error"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.dbBehavior(tc.request)
+
+			item, err := db.UpdateItem(context.Background(), itemID, tc.request)
+			if tc.expectedErr != nil {
+				assert.Equal(t, tc.expectedErr, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expected, item)
+			}
+		})
+	}
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRestockVariant(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer mockDB.Close()
+
+	db := storage{db: mockDB}
+
+	variantID, itemID, stock := 5, 1, 3
+
+	t.Run("untracked variant starts being tracked", func(t *testing.T) {
+		mock.ExpectQuery(restockVariantQueryRegexp).WithArgs(3, variantID).
+			WillReturnRows(sqlmock.NewRows([]string{itemVariantsItemIDColumn}).AddRow(itemID))
+		mock.ExpectQuery(selectItemQueryRegexp).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), itemID).
+			WillReturnRows(sqlmock.NewRows(itemColumns).AddRow(itemID, "mug", 20, nil, "", "", false, nil, nil, 0, 0,
+				`[{"id":5,"itemId":1,"sku":"mug","name":"default","price":20,"priceOverride":null,"stock":3}]`))
+
+		item, err := db.RestockVariant(context.Background(), variantID, 3)
+		assert.NoError(t, err)
+		// Variants of retired items are not available whatever the stock.
+		assert.Equal(t, &models.CatalogItem{
+			ID: itemID, Type: "mug", Price: 20,
+			Variants: []models.ItemVariant{{ID: variantID, ItemID: itemID, SKU: "mug", Name: "default", Price: 20, Stock: &stock}},
+		}, item)
+	})
+
+	t.Run("no variant", func(t *testing.T) {
+		mock.ExpectQuery(restockVariantQueryRegexp).WithArgs(3, variantID).
+			WillReturnRows(sqlmock.NewRows([]string{itemVariantsItemIDColumn}))
+
+		_, err := db.RestockVariant(context.Background(), variantID, 3)
+		assert.Equal(t, ErrNoVariant, err)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
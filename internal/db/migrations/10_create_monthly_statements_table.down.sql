@@ -0,0 +1 @@
+DROP TABLE IF EXISTS "monthly_statements";
\ No newline at end of file
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS "monthly_statements"
+(
+    "user_id" INTEGER NOT NULL REFERENCES users(id),
+    "month" DATE NOT NULL,
+    "opening_balance" INTEGER NOT NULL,
+    "granted" INTEGER NOT NULL,
+    "sent" INTEGER NOT NULL,
+    "received" INTEGER NOT NULL,
+    "purchases" INTEGER NOT NULL,
+    "closing_balance" INTEGER NOT NULL,
+    "generated_at" TIMESTAMP NOT NULL,
+    PRIMARY KEY ("user_id", "month")
+);
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE "items"
+    DROP COLUMN IF EXISTS "description",
+    DROP COLUMN IF EXISTS "active";
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE "items"
+    ADD COLUMN IF NOT EXISTS "description" TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS "active" BOOLEAN NOT NULL DEFAULT true;
\ No newline at end of file
@@ -0,0 +1,3 @@
+-- NULL stock means the item is not tracked and never runs out.
+ALTER TABLE "items"
+    ADD COLUMN IF NOT EXISTS "stock" INTEGER CONSTRAINT "items_stock_check" CHECK ("stock" >= 0);
\ No newline at end of file
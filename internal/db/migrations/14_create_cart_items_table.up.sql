@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS "cart_items"
+(
+    "user_id" INTEGER NOT NULL REFERENCES users(id),
+    "item_id" INTEGER NOT NULL REFERENCES items(id),
+    "quantity" INTEGER NOT NULL CHECK ("quantity" > 0),
+    PRIMARY KEY ("user_id", "item_id")
+);
\ No newline at end of file
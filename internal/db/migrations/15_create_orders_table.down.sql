@@ -0,0 +1,3 @@
+ALTER TABLE "purchases" DROP COLUMN IF EXISTS "order_id";
+
+DROP TABLE IF EXISTS "orders";
\ No newline at end of file
@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS "orders"
+(
+    "id" SERIAL PRIMARY KEY,
+    "user_id" INTEGER NOT NULL REFERENCES users(id),
+    "total" INTEGER NOT NULL,
+    "status" VARCHAR(20) NOT NULL DEFAULT 'placed'
+        CHECK ("status" IN ('placed', 'packed', 'ready_for_pickup', 'delivered', 'cancelled')),
+    "created_at" TIMESTAMP NOT NULL,
+    "status_updated_at" TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS orders_user_id_index ON orders(user_id);
+CREATE INDEX IF NOT EXISTS orders_status_index ON orders(status);
+
+-- Purchases become order lines. Earlier purchases were handed over on the spot,
+-- so each of them gets its own delivered order with the same id.
+ALTER TABLE "purchases" ADD COLUMN IF NOT EXISTS "order_id" INTEGER REFERENCES orders(id);
+
+INSERT INTO "orders" (id, user_id, total, status, created_at, status_updated_at)
+SELECT id, user_id, price * quantity, 'delivered', timing, timing FROM "purchases";
+
+UPDATE "purchases" SET order_id = id;
+
+SELECT setval(pg_get_serial_sequence('orders', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM "orders";
+
+ALTER TABLE "purchases" ALTER COLUMN "order_id" SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS purchases_order_id_index ON purchases(order_id);
\ No newline at end of file
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS orders_user_id_refunded_at_index;
+
+ALTER TABLE "orders" DROP COLUMN IF EXISTS "refunded_at";
\ No newline at end of file
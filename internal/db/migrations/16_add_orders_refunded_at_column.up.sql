@@ -0,0 +1,3 @@
+ALTER TABLE "orders" ADD COLUMN IF NOT EXISTS "refunded_at" TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS orders_user_id_refunded_at_index ON orders(user_id, refunded_at) WHERE refunded_at IS NOT NULL;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE "monthly_statements" DROP COLUMN IF EXISTS "refunds";
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE "monthly_statements" ADD COLUMN IF NOT EXISTS "refunds" INTEGER NOT NULL DEFAULT 0;
\ No newline at end of file
@@ -0,0 +1,12 @@
+ALTER TABLE "users" ADD COLUMN IF NOT EXISTS "inventory" JSONB NOT NULL DEFAULT '{}';
+
+UPDATE users SET inventory = owned.inventory
+FROM (
+    SELECT user_items.user_id, jsonb_object_agg(items.type, user_items.quantity) AS inventory
+    FROM user_items
+    JOIN items ON items.id = user_items.item_id
+    GROUP BY user_items.user_id
+) AS owned
+WHERE users.id = owned.user_id;
+
+DROP TABLE IF EXISTS "user_items";
\ No newline at end of file
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS "user_items"
+(
+    "user_id" INTEGER NOT NULL REFERENCES users(id),
+    "item_id" INTEGER NOT NULL REFERENCES items(id),
+    "quantity" INTEGER NOT NULL CHECK ("quantity" > 0),
+    PRIMARY KEY ("user_id", "item_id")
+);
+
+-- Inventory keys are item types, values were stored both as numbers and as strings.
+INSERT INTO user_items (user_id, item_id, quantity)
+SELECT users.id, items.id, inventory.value::int
+FROM users
+CROSS JOIN LATERAL jsonb_each_text(users.inventory) AS inventory
+JOIN items ON items.type = inventory.key
+WHERE inventory.value::int > 0
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE "users" DROP COLUMN IF EXISTS "inventory";
\ No newline at end of file
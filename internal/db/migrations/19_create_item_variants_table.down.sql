@@ -0,0 +1,9 @@
+-- Stock of all variants is summed up, an item with any untracked variant becomes untracked.
+ALTER TABLE "items"
+    ADD COLUMN IF NOT EXISTS "stock" INTEGER CONSTRAINT "items_stock_check" CHECK ("stock" >= 0);
+
+UPDATE "items" SET stock = variants.stock
+FROM (SELECT item_id, SUM(stock) AS stock FROM "item_variants" GROUP BY item_id HAVING COUNT(stock) = COUNT(*)) AS variants
+WHERE items.id = variants.item_id;
+
+DROP TABLE IF EXISTS "item_variants";
\ No newline at end of file
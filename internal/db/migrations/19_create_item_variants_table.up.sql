@@ -0,0 +1,18 @@
+-- Variants are sold versions of an item, e.g. sizes. A NULL price means the item price,
+-- a NULL stock means the variant is not tracked and never runs out.
+CREATE TABLE IF NOT EXISTS "item_variants"
+(
+    "id" SERIAL PRIMARY KEY,
+    "item_id" INTEGER NOT NULL REFERENCES items(id),
+    "sku" VARCHAR(30) NOT NULL UNIQUE,
+    "name" VARCHAR(15) NOT NULL,
+    "price" INTEGER CHECK ("price" > 0),
+    "stock" INTEGER CONSTRAINT "item_variants_stock_check" CHECK ("stock" >= 0),
+    UNIQUE ("item_id", "name")
+);
+
+-- Every existing item gets a default variant that takes over its stock.
+INSERT INTO "item_variants" (item_id, sku, name, stock)
+SELECT id, type, 'default', stock FROM "items";
+
+ALTER TABLE "items" DROP COLUMN IF EXISTS "stock";
\ No newline at end of file
@@ -0,0 +1,30 @@
+ALTER TABLE "cart_items" ADD COLUMN IF NOT EXISTS "item_id" INTEGER REFERENCES items(id);
+ALTER TABLE "user_items" ADD COLUMN IF NOT EXISTS "item_id" INTEGER REFERENCES items(id);
+
+UPDATE "cart_items" SET item_id = item_variants.item_id FROM "item_variants" WHERE item_variants.id = cart_items.variant_id;
+UPDATE "user_items" SET item_id = item_variants.item_id FROM "item_variants" WHERE item_variants.id = user_items.variant_id;
+
+-- Variants of the same item are merged into the line with the lowest variant id.
+UPDATE "cart_items" SET quantity = merged.quantity
+FROM (SELECT user_id, item_id, MIN(variant_id) AS variant_id, SUM(quantity) AS quantity FROM "cart_items" GROUP BY user_id, item_id) AS merged
+WHERE cart_items.user_id = merged.user_id AND cart_items.variant_id = merged.variant_id;
+DELETE FROM "cart_items" USING "cart_items" AS kept
+WHERE kept.user_id = cart_items.user_id AND kept.item_id = cart_items.item_id AND kept.variant_id < cart_items.variant_id;
+
+UPDATE "user_items" SET quantity = merged.quantity
+FROM (SELECT user_id, item_id, MIN(variant_id) AS variant_id, SUM(quantity) AS quantity FROM "user_items" GROUP BY user_id, item_id) AS merged
+WHERE user_items.user_id = merged.user_id AND user_items.variant_id = merged.variant_id;
+DELETE FROM "user_items" USING "user_items" AS kept
+WHERE kept.user_id = user_items.user_id AND kept.item_id = user_items.item_id AND kept.variant_id < user_items.variant_id;
+
+ALTER TABLE "cart_items" DROP CONSTRAINT IF EXISTS "cart_items_pkey";
+ALTER TABLE "cart_items" DROP COLUMN IF EXISTS "variant_id";
+ALTER TABLE "cart_items" ALTER COLUMN "item_id" SET NOT NULL;
+ALTER TABLE "cart_items" ADD PRIMARY KEY ("user_id", "item_id");
+
+ALTER TABLE "user_items" DROP CONSTRAINT IF EXISTS "user_items_pkey";
+ALTER TABLE "user_items" DROP COLUMN IF EXISTS "variant_id";
+ALTER TABLE "user_items" ALTER COLUMN "item_id" SET NOT NULL;
+ALTER TABLE "user_items" ADD PRIMARY KEY ("user_id", "item_id");
+
+ALTER TABLE "purchases" DROP COLUMN IF EXISTS "variant_id";
\ No newline at end of file
@@ -0,0 +1,21 @@
+ALTER TABLE "purchases" ADD COLUMN IF NOT EXISTS "variant_id" INTEGER REFERENCES item_variants(id);
+ALTER TABLE "cart_items" ADD COLUMN IF NOT EXISTS "variant_id" INTEGER REFERENCES item_variants(id);
+ALTER TABLE "user_items" ADD COLUMN IF NOT EXISTS "variant_id" INTEGER REFERENCES item_variants(id);
+
+-- Each item has only its default variant at this point.
+UPDATE "purchases" SET variant_id = item_variants.id FROM "item_variants" WHERE item_variants.item_id = purchases.item_id;
+UPDATE "cart_items" SET variant_id = item_variants.id FROM "item_variants" WHERE item_variants.item_id = cart_items.item_id;
+UPDATE "user_items" SET variant_id = item_variants.id FROM "item_variants" WHERE item_variants.item_id = user_items.item_id;
+
+ALTER TABLE "purchases" ALTER COLUMN "variant_id" SET NOT NULL;
+
+-- Carts and inventories hold variants, the item is reached through the variant.
+ALTER TABLE "cart_items" DROP CONSTRAINT IF EXISTS "cart_items_pkey";
+ALTER TABLE "cart_items" DROP COLUMN IF EXISTS "item_id";
+ALTER TABLE "cart_items" ALTER COLUMN "variant_id" SET NOT NULL;
+ALTER TABLE "cart_items" ADD PRIMARY KEY ("user_id", "variant_id");
+
+ALTER TABLE "user_items" DROP CONSTRAINT IF EXISTS "user_items_pkey";
+ALTER TABLE "user_items" DROP COLUMN IF EXISTS "item_id";
+ALTER TABLE "user_items" ALTER COLUMN "variant_id" SET NOT NULL;
+ALTER TABLE "user_items" ADD PRIMARY KEY ("user_id", "variant_id");
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE "items"
+    ADD COLUMN IF NOT EXISTS "category" VARCHAR(30) NOT NULL DEFAULT '';
\ No newline at end of file
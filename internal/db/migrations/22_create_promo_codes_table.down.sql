@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS orders_promo_id_index;
+
+ALTER TABLE "purchases" DROP COLUMN IF EXISTS "discount";
+
+ALTER TABLE "orders"
+    DROP COLUMN IF EXISTS "discount",
+    DROP COLUMN IF EXISTS "promo_id";
+
+DROP TABLE IF EXISTS "promo_codes";
\ No newline at end of file
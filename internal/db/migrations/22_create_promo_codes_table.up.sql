@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS "promo_codes"
+(
+    "id" SERIAL PRIMARY KEY,
+    "code" VARCHAR(30) UNIQUE NOT NULL,
+    "kind" VARCHAR(10) NOT NULL CHECK ("kind" IN ('percent', 'fixed')),
+    "value" INTEGER NOT NULL CHECK ("value" > 0),
+    -- A code applies to one item, to one category or, with both empty, to every item.
+    "item_id" INTEGER REFERENCES items(id),
+    "category" VARCHAR(30) NOT NULL DEFAULT '',
+    "starts_at" TIMESTAMP,
+    "ends_at" TIMESTAMP,
+    -- NULL limits are not applied.
+    "max_uses" INTEGER CHECK ("max_uses" > 0),
+    "max_uses_per_user" INTEGER CHECK ("max_uses_per_user" > 0),
+    "uses" INTEGER NOT NULL DEFAULT 0 CHECK ("uses" >= 0),
+    "active" BOOLEAN NOT NULL DEFAULT true,
+    CHECK ("kind" <> 'percent' OR "value" <= 100),
+    CHECK ("item_id" IS NULL OR "category" = ''),
+    CHECK ("starts_at" IS NULL OR "ends_at" IS NULL OR "starts_at" < "ends_at")
+);
+
+-- Order discount is the sum of line discounts, totals are already discounted.
+ALTER TABLE "orders"
+    ADD COLUMN IF NOT EXISTS "promo_id" INTEGER REFERENCES promo_codes(id),
+    ADD COLUMN IF NOT EXISTS "discount" INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE "purchases"
+    ADD COLUMN IF NOT EXISTS "discount" INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS orders_promo_id_index ON orders(promo_id) WHERE promo_id IS NOT NULL;
\ No newline at end of file
@@ -0,0 +1,15 @@
+-- Items get back the regular price in effect, scheduled prices and sales are lost.
+ALTER TABLE "items"
+    ADD COLUMN IF NOT EXISTS "price" INTEGER;
+
+UPDATE "items" SET price = prices.price
+FROM (
+    SELECT DISTINCT ON (item_id) item_id, price FROM "item_prices"
+    WHERE ends_at IS NULL
+    ORDER BY item_id, (starts_at IS NULL OR starts_at <= now()) DESC, starts_at DESC NULLS LAST, id DESC
+) AS prices
+WHERE items.id = prices.item_id;
+
+ALTER TABLE "items" ALTER COLUMN "price" SET NOT NULL;
+
+DROP TABLE IF EXISTS "item_prices";
\ No newline at end of file
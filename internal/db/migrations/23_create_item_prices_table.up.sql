@@ -0,0 +1,19 @@
+-- Prices are effective-dated. A row without end is a regular price, the latest started one applies;
+-- a row with end is a sale that wins over the regular price while it lasts.
+-- A NULL start is a price set before price history was recorded.
+CREATE TABLE IF NOT EXISTS "item_prices"
+(
+    "id" SERIAL PRIMARY KEY,
+    "item_id" INTEGER NOT NULL REFERENCES items(id),
+    "price" INTEGER NOT NULL CHECK ("price" > 0),
+    "starts_at" TIMESTAMP,
+    "ends_at" TIMESTAMP,
+    CHECK ("ends_at" IS NULL OR "starts_at" < "ends_at")
+);
+
+CREATE INDEX IF NOT EXISTS item_prices_item_id_index ON item_prices(item_id);
+
+INSERT INTO "item_prices" (item_id, price)
+SELECT id, price FROM "items";
+
+ALTER TABLE "items" DROP COLUMN IF EXISTS "price";
\ No newline at end of file
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS orders_recipient_id_index;
+
+ALTER TABLE "orders"
+    DROP CONSTRAINT IF EXISTS "orders_recipient_check",
+    DROP COLUMN IF EXISTS "gift_note",
+    DROP COLUMN IF EXISTS "recipient_id";
\ No newline at end of file
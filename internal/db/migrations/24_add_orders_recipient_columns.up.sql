@@ -0,0 +1,8 @@
+-- A gift order is paid by user_id and delivered to the inventory of recipient_id,
+-- orders bought for oneself have no recipient.
+ALTER TABLE "orders"
+    ADD COLUMN IF NOT EXISTS "recipient_id" INTEGER REFERENCES users(id),
+    ADD COLUMN IF NOT EXISTS "gift_note" VARCHAR(200),
+    ADD CONSTRAINT "orders_recipient_check" CHECK ("recipient_id" <> "user_id");
+
+CREATE INDEX IF NOT EXISTS orders_recipient_id_index ON orders(recipient_id) WHERE recipient_id IS NOT NULL;
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS "item_transfers";
\ No newline at end of file
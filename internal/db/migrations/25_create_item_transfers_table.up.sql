@@ -0,0 +1,14 @@
+-- Items given away by one user to another, the quantity is moved between user_items rows.
+CREATE TABLE IF NOT EXISTS "item_transfers"
+(
+    "id" SERIAL PRIMARY KEY,
+    "from_user_id" INTEGER NOT NULL REFERENCES users(id),
+    "to_user_id" INTEGER NOT NULL REFERENCES users(id),
+    "variant_id" INTEGER NOT NULL REFERENCES item_variants(id),
+    "quantity" INTEGER NOT NULL CHECK ("quantity" > 0),
+    "timing" TIMESTAMP NOT NULL,
+    CHECK ("from_user_id" <> "to_user_id")
+);
+
+CREATE INDEX IF NOT EXISTS item_transfers_from_user_id_index ON item_transfers(from_user_id);
+CREATE INDEX IF NOT EXISTS item_transfers_to_user_id_index ON item_transfers(to_user_id);
\ No newline at end of file
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS purchases_user_id_item_id_timing_index;
+
+ALTER TABLE "items"
+    DROP CONSTRAINT IF EXISTS "items_purchase_limit_check",
+    DROP COLUMN IF EXISTS "purchase_limit_days",
+    DROP COLUMN IF EXISTS "purchase_limit";
\ No newline at end of file
@@ -0,0 +1,8 @@
+-- At most purchase_limit pieces of an item can be bought by a user within the last purchase_limit_days days,
+-- items without a limit have both columns NULL.
+ALTER TABLE "items"
+    ADD COLUMN IF NOT EXISTS "purchase_limit" INTEGER CHECK ("purchase_limit" > 0),
+    ADD COLUMN IF NOT EXISTS "purchase_limit_days" INTEGER CHECK ("purchase_limit_days" > 0),
+    ADD CONSTRAINT "items_purchase_limit_check" CHECK (("purchase_limit" IS NULL) = ("purchase_limit_days" IS NULL));
+
+CREATE INDEX IF NOT EXISTS purchases_user_id_item_id_timing_index ON purchases(user_id, item_id, timing);
\ No newline at end of file
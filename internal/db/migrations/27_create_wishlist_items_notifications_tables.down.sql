@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "notifications";
+DROP TABLE IF EXISTS "wishlist_items";
\ No newline at end of file
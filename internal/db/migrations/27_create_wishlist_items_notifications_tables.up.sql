@@ -0,0 +1,27 @@
+-- Items users wish to buy. seen_price and seen_available hold the item state the user
+-- was last notified about, the wishlists job notifies on price drops and restocks against them.
+CREATE TABLE IF NOT EXISTS "wishlist_items"
+(
+    "user_id" INTEGER NOT NULL REFERENCES users(id),
+    "item_id" INTEGER NOT NULL REFERENCES items(id),
+    "created_at" TIMESTAMP NOT NULL,
+    "seen_price" INTEGER NOT NULL,
+    "seen_available" BOOLEAN NOT NULL,
+    PRIMARY KEY ("user_id", "item_id")
+);
+
+CREATE INDEX IF NOT EXISTS wishlist_items_item_id_index ON wishlist_items(item_id);
+
+-- In-app notifications, read_at is NULL until the user marks one as read.
+CREATE TABLE IF NOT EXISTS "notifications"
+(
+    "id" SERIAL PRIMARY KEY,
+    "user_id" INTEGER NOT NULL REFERENCES users(id),
+    "kind" VARCHAR(20) NOT NULL,
+    "item_id" INTEGER REFERENCES items(id),
+    "message" TEXT NOT NULL,
+    "created_at" TIMESTAMP NOT NULL,
+    "read_at" TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS notifications_user_id_index ON notifications(user_id, id);
\ No newline at end of file
@@ -0,0 +1,4 @@
+ALTER TABLE "orders" DROP COLUMN IF EXISTS "bundle_id";
+
+DROP TABLE IF EXISTS "bundle_items";
+DROP TABLE IF EXISTS "bundles";
\ No newline at end of file
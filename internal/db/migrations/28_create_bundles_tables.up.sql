@@ -0,0 +1,20 @@
+-- Bundles sell several item variants together at their own price.
+CREATE TABLE IF NOT EXISTS "bundles"
+(
+    "id" SERIAL PRIMARY KEY,
+    "name" VARCHAR(50) NOT NULL UNIQUE,
+    "description" TEXT NOT NULL DEFAULT '',
+    "price" INTEGER NOT NULL CHECK ("price" > 0),
+    "active" BOOLEAN NOT NULL DEFAULT true
+);
+
+CREATE TABLE IF NOT EXISTS "bundle_items"
+(
+    "bundle_id" INTEGER NOT NULL REFERENCES bundles(id),
+    "variant_id" INTEGER NOT NULL REFERENCES item_variants(id),
+    "quantity" INTEGER NOT NULL CHECK ("quantity" > 0),
+    PRIMARY KEY ("bundle_id", "variant_id")
+);
+
+-- A bundle order has a purchase per component, the bundle saving is split between their discounts.
+ALTER TABLE "orders" ADD COLUMN IF NOT EXISTS "bundle_id" INTEGER REFERENCES bundles(id);
\ No newline at end of file
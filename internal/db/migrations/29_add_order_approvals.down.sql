@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS orders_approver_id_index;
+
+ALTER TABLE "orders"
+    DROP CONSTRAINT IF EXISTS "orders_status_check",
+    ADD CONSTRAINT "orders_status_check"
+        CHECK ("status" IN ('placed', 'packed', 'ready_for_pickup', 'delivered', 'cancelled')),
+    DROP COLUMN IF EXISTS "approver_id";
+
+DROP TABLE IF EXISTS "approvers";
\ No newline at end of file
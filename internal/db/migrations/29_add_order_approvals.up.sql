@@ -0,0 +1,16 @@
+-- Approvers decide on orders above the approval threshold, the list is kept by admins.
+CREATE TABLE IF NOT EXISTS "approvers"
+(
+    "user_id" INTEGER PRIMARY KEY REFERENCES users(id),
+    "created_at" TIMESTAMP NOT NULL
+);
+
+-- An order pending approval has its coins held and stock reserved, its items reach the inventory on approval.
+-- approver_id is the assigned approver and, once decided, the user who decided.
+ALTER TABLE "orders"
+    ADD COLUMN IF NOT EXISTS "approver_id" INTEGER REFERENCES users(id),
+    DROP CONSTRAINT IF EXISTS "orders_status_check",
+    ADD CONSTRAINT "orders_status_check"
+        CHECK ("status" IN ('placed', 'packed', 'ready_for_pickup', 'delivered', 'cancelled', 'pending_approval'));
+
+CREATE INDEX IF NOT EXISTS orders_approver_id_index ON orders(approver_id) WHERE status = 'pending_approval';
\ No newline at end of file
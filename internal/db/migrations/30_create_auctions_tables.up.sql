@@ -0,0 +1,30 @@
+-- An auction sells one unit of a variant to the highest bidder, the unit is reserved while it runs.
+-- order_id is the order of the winner, set when the auction is closed with bids.
+CREATE TABLE IF NOT EXISTS "auctions"
+(
+    "id" SERIAL PRIMARY KEY,
+    "variant_id" INTEGER NOT NULL REFERENCES item_variants(id),
+    "starting_bid" INTEGER NOT NULL CHECK ("starting_bid" > 0),
+    "min_increment" INTEGER NOT NULL DEFAULT 1 CHECK ("min_increment" > 0),
+    "created_at" TIMESTAMP NOT NULL,
+    "ends_at" TIMESTAMP NOT NULL,
+    "closed_at" TIMESTAMP,
+    "order_id" INTEGER REFERENCES orders(id)
+);
+
+CREATE INDEX IF NOT EXISTS auctions_ends_at_index ON auctions(ends_at) WHERE closed_at IS NULL;
+
+-- The coins of a bid are held until it is outbid or the auction is closed, then released_at is set.
+-- The winning bid is released when the winner is charged for the order.
+CREATE TABLE IF NOT EXISTS "bids"
+(
+    "id" SERIAL PRIMARY KEY,
+    "auction_id" INTEGER NOT NULL REFERENCES auctions(id),
+    "user_id" INTEGER NOT NULL REFERENCES users(id),
+    "amount" INTEGER NOT NULL CHECK ("amount" > 0),
+    "created_at" TIMESTAMP NOT NULL,
+    "released_at" TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS bids_auction_id_amount_index ON bids(auction_id, amount DESC);
+CREATE INDEX IF NOT EXISTS bids_user_id_index ON bids(user_id);
\ No newline at end of file
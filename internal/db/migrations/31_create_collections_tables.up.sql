@@ -0,0 +1,34 @@
+-- A collection pools coins of colleagues to buy an item variant for the recipient. The target is the price
+-- of the variant when the collection is started. Once contributions reach it, the order is placed and order_id is set;
+-- contributions of a collection still open at its deadline are returned and refunded_at is set.
+CREATE TABLE IF NOT EXISTS "collections"
+(
+    "id" SERIAL PRIMARY KEY,
+    "creator_id" INTEGER NOT NULL REFERENCES users(id),
+    "recipient_id" INTEGER NOT NULL REFERENCES users(id),
+    "variant_id" INTEGER NOT NULL REFERENCES item_variants(id),
+    "target" INTEGER NOT NULL CHECK ("target" > 0),
+    "deadline" TIMESTAMP NOT NULL,
+    "created_at" TIMESTAMP NOT NULL,
+    "completed_at" TIMESTAMP,
+    "refunded_at" TIMESTAMP,
+    "order_id" INTEGER REFERENCES orders(id)
+);
+
+CREATE INDEX IF NOT EXISTS collections_deadline_index ON collections(deadline)
+    WHERE completed_at IS NULL AND refunded_at IS NULL;
+CREATE INDEX IF NOT EXISTS collections_order_id_index ON collections(order_id) WHERE order_id IS NOT NULL;
+
+-- The coins of a contribution are spent on the order once the target is reached, or returned at the deadline.
+CREATE TABLE IF NOT EXISTS "contributions"
+(
+    "id" SERIAL PRIMARY KEY,
+    "collection_id" INTEGER NOT NULL REFERENCES collections(id),
+    "user_id" INTEGER NOT NULL REFERENCES users(id),
+    "amount" INTEGER NOT NULL CHECK ("amount" > 0),
+    "created_at" TIMESTAMP NOT NULL,
+    "refunded_at" TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS contributions_collection_id_index ON contributions(collection_id);
+CREATE INDEX IF NOT EXISTS contributions_user_id_index ON contributions(user_id);
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS "reviews";
\ No newline at end of file
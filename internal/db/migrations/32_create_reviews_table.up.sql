@@ -0,0 +1,16 @@
+-- A review of an item by a user owning it, one per user and item. Editing keeps created_at and sets updated_at.
+-- Hidden reviews are left out of the catalog rating and listings until an admin shows them again.
+CREATE TABLE IF NOT EXISTS "reviews"
+(
+    "id" SERIAL PRIMARY KEY,
+    "item_id" INTEGER NOT NULL REFERENCES items(id),
+    "user_id" INTEGER NOT NULL REFERENCES users(id),
+    "rating" SMALLINT NOT NULL CHECK ("rating" BETWEEN 1 AND 5),
+    "text" VARCHAR(1000) NOT NULL DEFAULT '',
+    "created_at" TIMESTAMP NOT NULL,
+    "updated_at" TIMESTAMP NOT NULL,
+    "hidden_at" TIMESTAMP,
+    UNIQUE ("item_id", "user_id")
+);
+
+CREATE INDEX IF NOT EXISTS reviews_visible_item_id_index ON reviews(item_id) WHERE hidden_at IS NULL;
\ No newline at end of file
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS from_user_id_timing_id_index;
+DROP INDEX IF EXISTS to_user_id_timing_id_index;
+DROP INDEX IF EXISTS from_to_user_id_timing_index;
\ No newline at end of file
@@ -0,0 +1,3 @@
+CREATE INDEX IF NOT EXISTS from_user_id_timing_id_index ON coin_transfers(from_user_id, timing DESC, id DESC);
+CREATE INDEX IF NOT EXISTS to_user_id_timing_id_index ON coin_transfers(to_user_id, timing DESC, id DESC);
+CREATE INDEX IF NOT EXISTS from_to_user_id_timing_index ON coin_transfers(from_user_id, to_user_id, timing DESC);
\ No newline at end of file
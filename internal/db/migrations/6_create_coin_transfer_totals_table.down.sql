@@ -0,0 +1 @@
+DROP TABLE IF EXISTS "coin_transfer_totals";
\ No newline at end of file
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS "coin_transfer_totals"
+(
+    "from_user_id" INTEGER NOT NULL REFERENCES users(id),
+    "to_user_id" INTEGER NOT NULL REFERENCES users(id),
+    "amount" INTEGER NOT NULL,
+    "transfers_count" INTEGER NOT NULL,
+    PRIMARY KEY ("from_user_id", "to_user_id")
+);
+
+CREATE INDEX IF NOT EXISTS totals_to_user_id_index ON coin_transfer_totals(to_user_id);
+
+INSERT INTO "coin_transfer_totals" (from_user_id, to_user_id, amount, transfers_count)
+    SELECT from_user_id, to_user_id, SUM(amount), COUNT(*)
+    FROM coin_transfers
+    GROUP BY from_user_id, to_user_id
+ON CONFLICT DO NOTHING;
\ No newline at end of file
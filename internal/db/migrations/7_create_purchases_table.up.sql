@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS "purchases"
+(
+    "id" SERIAL PRIMARY KEY,
+    "user_id" INTEGER NOT NULL REFERENCES users(id),
+    "item_id" INTEGER NOT NULL REFERENCES items(id),
+    "price" INTEGER NOT NULL,
+    "timing" TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS purchases_user_id_timing_index ON purchases(user_id, timing);
+CREATE INDEX IF NOT EXISTS purchases_timing_index ON purchases(timing);
\ No newline at end of file
@@ -0,0 +1,14 @@
+ALTER TABLE "users" ADD COLUMN IF NOT EXISTS "created_at" TIMESTAMP;
+
+-- Existing users are dated by their first balance movement.
+UPDATE "users" SET "created_at" = COALESCE((
+    SELECT MIN(timing) FROM (
+        SELECT timing FROM coin_transfers WHERE from_user_id = users.id OR to_user_id = users.id
+        UNION ALL
+        SELECT timing FROM purchases WHERE user_id = users.id
+    ) AS movements
+), now())
+WHERE "created_at" IS NULL;
+
+ALTER TABLE "users" ALTER COLUMN "created_at" SET DEFAULT now();
+ALTER TABLE "users" ALTER COLUMN "created_at" SET NOT NULL;
\ No newline at end of file
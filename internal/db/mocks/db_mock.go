@@ -4,6 +4,7 @@ package mocks
 
 import (
 	context "context"
+	time "time"
 
 	mock "github.com/stretchr/testify/mock"
 
@@ -15,12 +16,48 @@ type DB struct {
 	mock.Mock
 }
 
-// BuyItemByItemID provides a mock function with given fields: ctx, userID, itemID
-func (_m *DB) BuyItemByItemID(ctx context.Context, userID int, itemID int) error {
+// AddApprover provides a mock function with given fields: ctx, username
+func (_m *DB) AddApprover(ctx context.Context, username string) error {
+	ret := _m.Called(ctx, username)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddApprover")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, username)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// AddCartItem provides a mock function with given fields: ctx, userID, line
+func (_m *DB) AddCartItem(ctx context.Context, userID int, line models.CartLine) error {
+	ret := _m.Called(ctx, userID, line)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddCartItem")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, models.CartLine) error); ok {
+		r0 = rf(ctx, userID, line)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// AddWishlistItem provides a mock function with given fields: ctx, userID, itemID
+func (_m *DB) AddWishlistItem(ctx context.Context, userID int, itemID int) error {
 	ret := _m.Called(ctx, userID, itemID)
 
 	if len(ret) == 0 {
-		panic("no return value specified for BuyItemByItemID")
+		panic("no return value specified for AddWishlistItem")
 	}
 
 	var r0 error
@@ -33,29 +70,65 @@ func (_m *DB) BuyItemByItemID(ctx context.Context, userID int, itemID int) error
 	return r0
 }
 
-// CreateUser provides a mock function with given fields: ctx, username, password
-func (_m *DB) CreateUser(ctx context.Context, username string, password string) (*int, error) {
-	ret := _m.Called(ctx, username, password)
+// AdvanceOrder provides a mock function with given fields: ctx, orderID
+func (_m *DB) AdvanceOrder(ctx context.Context, orderID int) error {
+	ret := _m.Called(ctx, orderID)
 
 	if len(ret) == 0 {
-		panic("no return value specified for CreateUser")
+		panic("no return value specified for AdvanceOrder")
 	}
 
-	var r0 *int
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) error); ok {
+		r0 = rf(ctx, orderID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ApproveOrder provides a mock function with given fields: ctx, orderID, approval
+func (_m *DB) ApproveOrder(ctx context.Context, orderID int, approval *models.OrderApproval) error {
+	ret := _m.Called(ctx, orderID, approval)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ApproveOrder")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, *models.OrderApproval) error); ok {
+		r0 = rf(ctx, orderID, approval)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// BuyBundle provides a mock function with given fields: ctx, userID, bundleID, quantity, approvalThreshold
+func (_m *DB) BuyBundle(ctx context.Context, userID int, bundleID int, quantity int, approvalThreshold int) (*models.OrderSummary, error) {
+	ret := _m.Called(ctx, userID, bundleID, quantity, approvalThreshold)
+
+	if len(ret) == 0 {
+		panic("no return value specified for BuyBundle")
+	}
+
+	var r0 *models.OrderSummary
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*int, error)); ok {
-		return rf(ctx, username, password)
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, int, int) (*models.OrderSummary, error)); ok {
+		return rf(ctx, userID, bundleID, quantity, approvalThreshold)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string, string) *int); ok {
-		r0 = rf(ctx, username, password)
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, int, int) *models.OrderSummary); ok {
+		r0 = rf(ctx, userID, bundleID, quantity, approvalThreshold)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*int)
+			r0 = ret.Get(0).(*models.OrderSummary)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
-		r1 = rf(ctx, username, password)
+	if rf, ok := ret.Get(1).(func(context.Context, int, int, int, int) error); ok {
+		r1 = rf(ctx, userID, bundleID, quantity, approvalThreshold)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -63,102 +136,191 @@ func (_m *DB) CreateUser(ctx context.Context, username string, password string)
 	return r0, r1
 }
 
-// GetUser provides a mock function with given fields: ctx, username
-func (_m *DB) GetUser(ctx context.Context, username string) (*int, string, error) {
-	ret := _m.Called(ctx, username)
+// BuyGift provides a mock function with given fields: ctx, payerID, recipient, lines, promo, note, approvalThreshold
+func (_m *DB) BuyGift(ctx context.Context, payerID int, recipient string, lines []models.CartLine, promo string, note string, approvalThreshold int) (*models.OrderSummary, error) {
+	ret := _m.Called(ctx, payerID, recipient, lines, promo, note, approvalThreshold)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetUser")
+		panic("no return value specified for BuyGift")
 	}
 
-	var r0 *int
-	var r1 string
-	var r2 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) (*int, string, error)); ok {
-		return rf(ctx, username)
+	var r0 *models.OrderSummary
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, string, []models.CartLine, string, string, int) (*models.OrderSummary, error)); ok {
+		return rf(ctx, payerID, recipient, lines, promo, note, approvalThreshold)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string) *int); ok {
-		r0 = rf(ctx, username)
+	if rf, ok := ret.Get(0).(func(context.Context, int, string, []models.CartLine, string, string, int) *models.OrderSummary); ok {
+		r0 = rf(ctx, payerID, recipient, lines, promo, note, approvalThreshold)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*int)
+			r0 = ret.Get(0).(*models.OrderSummary)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string) string); ok {
-		r1 = rf(ctx, username)
+	if rf, ok := ret.Get(1).(func(context.Context, int, string, []models.CartLine, string, string, int) error); ok {
+		r1 = rf(ctx, payerID, recipient, lines, promo, note, approvalThreshold)
 	} else {
-		r1 = ret.Get(1).(string)
+		r1 = ret.Error(1)
 	}
 
-	if rf, ok := ret.Get(2).(func(context.Context, string) error); ok {
-		r2 = rf(ctx, username)
+	return r0, r1
+}
+
+// BuyItems provides a mock function with given fields: ctx, userID, lines, promo, approvalThreshold
+func (_m *DB) BuyItems(ctx context.Context, userID int, lines []models.CartLine, promo string, approvalThreshold int) (*models.OrderSummary, error) {
+	ret := _m.Called(ctx, userID, lines, promo, approvalThreshold)
+
+	if len(ret) == 0 {
+		panic("no return value specified for BuyItems")
+	}
+
+	var r0 *models.OrderSummary
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, []models.CartLine, string, int) (*models.OrderSummary, error)); ok {
+		return rf(ctx, userID, lines, promo, approvalThreshold)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, []models.CartLine, string, int) *models.OrderSummary); ok {
+		r0 = rf(ctx, userID, lines, promo, approvalThreshold)
 	} else {
-		r2 = ret.Error(2)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OrderSummary)
+		}
 	}
 
-	return r0, r1, r2
+	if rf, ok := ret.Get(1).(func(context.Context, int, []models.CartLine, string, int) error); ok {
+		r1 = rf(ctx, userID, lines, promo, approvalThreshold)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
-// GetUserInfoByUserID provides a mock function with given fields: ctx, userID
-func (_m *DB) GetUserInfoByUserID(ctx context.Context, userID int) (*int, []byte, *models.CoinTransferHistory, error) {
-	ret := _m.Called(ctx, userID)
+// CancelOrder provides a mock function with given fields: ctx, orderID, cancellation
+func (_m *DB) CancelOrder(ctx context.Context, orderID int, cancellation *models.OrderCancellation) error {
+	ret := _m.Called(ctx, orderID, cancellation)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetUserInfoByUserID")
+		panic("no return value specified for CancelOrder")
 	}
 
-	var r0 *int
-	var r1 []byte
-	var r2 *models.CoinTransferHistory
-	var r3 error
-	if rf, ok := ret.Get(0).(func(context.Context, int) (*int, []byte, *models.CoinTransferHistory, error)); ok {
-		return rf(ctx, userID)
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, *models.OrderCancellation) error); ok {
+		r0 = rf(ctx, orderID, cancellation)
+	} else {
+		r0 = ret.Error(0)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, int) *int); ok {
-		r0 = rf(ctx, userID)
+
+	return r0
+}
+
+// CancelScheduledPrice provides a mock function with given fields: ctx, priceID, now
+func (_m *DB) CancelScheduledPrice(ctx context.Context, priceID int, now time.Time) error {
+	ret := _m.Called(ctx, priceID, now)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CancelScheduledPrice")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, time.Time) error); ok {
+		r0 = rf(ctx, priceID, now)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CheckoutCart provides a mock function with given fields: ctx, userID, promo, approvalThreshold
+func (_m *DB) CheckoutCart(ctx context.Context, userID int, promo string, approvalThreshold int) (*models.OrderSummary, error) {
+	ret := _m.Called(ctx, userID, promo, approvalThreshold)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CheckoutCart")
+	}
+
+	var r0 *models.OrderSummary
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, string, int) (*models.OrderSummary, error)); ok {
+		return rf(ctx, userID, promo, approvalThreshold)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, string, int) *models.OrderSummary); ok {
+		r0 = rf(ctx, userID, promo, approvalThreshold)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*int)
+			r0 = ret.Get(0).(*models.OrderSummary)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, int) []byte); ok {
-		r1 = rf(ctx, userID)
+	if rf, ok := ret.Get(1).(func(context.Context, int, string, int) error); ok {
+		r1 = rf(ctx, userID, promo, approvalThreshold)
 	} else {
-		if ret.Get(1) != nil {
-			r1 = ret.Get(1).([]byte)
-		}
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CloseAuctions provides a mock function with given fields: ctx, at
+func (_m *DB) CloseAuctions(ctx context.Context, at time.Time) error {
+	ret := _m.Called(ctx, at)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CloseAuctions")
 	}
 
-	if rf, ok := ret.Get(2).(func(context.Context, int) *models.CoinTransferHistory); ok {
-		r2 = rf(ctx, userID)
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) error); ok {
+		r0 = rf(ctx, at)
 	} else {
-		if ret.Get(2) != nil {
-			r2 = ret.Get(2).(*models.CoinTransferHistory)
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ComputeMonthlyStatement provides a mock function with given fields: ctx, userID, month
+func (_m *DB) ComputeMonthlyStatement(ctx context.Context, userID int, month time.Time) (*models.Statement, error) {
+	ret := _m.Called(ctx, userID, month)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ComputeMonthlyStatement")
+	}
+
+	var r0 *models.Statement
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, time.Time) (*models.Statement, error)); ok {
+		return rf(ctx, userID, month)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, time.Time) *models.Statement); ok {
+		r0 = rf(ctx, userID, month)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Statement)
 		}
 	}
 
-	if rf, ok := ret.Get(3).(func(context.Context, int) error); ok {
-		r3 = rf(ctx, userID)
+	if rf, ok := ret.Get(1).(func(context.Context, int, time.Time) error); ok {
+		r1 = rf(ctx, userID, month)
 	} else {
-		r3 = ret.Error(3)
+		r1 = ret.Error(1)
 	}
 
-	return r0, r1, r2, r3
+	return r0, r1
 }
 
-// SendCoinByUsername provides a mock function with given fields: ctx, userID, destUsername, amount
-func (_m *DB) SendCoinByUsername(ctx context.Context, userID int, destUsername string, amount int) error {
-	ret := _m.Called(ctx, userID, destUsername, amount)
+// Contribute provides a mock function with given fields: ctx, userID, collectionID, amount
+func (_m *DB) Contribute(ctx context.Context, userID int, collectionID int, amount int) error {
+	ret := _m.Called(ctx, userID, collectionID, amount)
 
 	if len(ret) == 0 {
-		panic("no return value specified for SendCoinByUsername")
+		panic("no return value specified for Contribute")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, int, string, int) error); ok {
-		r0 = rf(ctx, userID, destUsername, amount)
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, int) error); ok {
+		r0 = rf(ctx, userID, collectionID, amount)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -166,6 +328,1364 @@ func (_m *DB) SendCoinByUsername(ctx context.Context, userID int, destUsername s
 	return r0
 }
 
+// CreateAuction provides a mock function with given fields: ctx, variantID, auction
+func (_m *DB) CreateAuction(ctx context.Context, variantID int, auction *models.AuctionRequest) (*models.Auction, error) {
+	ret := _m.Called(ctx, variantID, auction)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateAuction")
+	}
+
+	var r0 *models.Auction
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, *models.AuctionRequest) (*models.Auction, error)); ok {
+		return rf(ctx, variantID, auction)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, *models.AuctionRequest) *models.Auction); ok {
+		r0 = rf(ctx, variantID, auction)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Auction)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, *models.AuctionRequest) error); ok {
+		r1 = rf(ctx, variantID, auction)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateBundle provides a mock function with given fields: ctx, bundle, lines
+func (_m *DB) CreateBundle(ctx context.Context, bundle *models.BundleRequest, lines []models.CartLine) (*models.Bundle, error) {
+	ret := _m.Called(ctx, bundle, lines)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateBundle")
+	}
+
+	var r0 *models.Bundle
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.BundleRequest, []models.CartLine) (*models.Bundle, error)); ok {
+		return rf(ctx, bundle, lines)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.BundleRequest, []models.CartLine) *models.Bundle); ok {
+		r0 = rf(ctx, bundle, lines)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Bundle)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.BundleRequest, []models.CartLine) error); ok {
+		r1 = rf(ctx, bundle, lines)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateCollection provides a mock function with given fields: ctx, creatorID, recipient, variantID, deadline, approvalThreshold
+func (_m *DB) CreateCollection(ctx context.Context, creatorID int, recipient string, variantID int, deadline time.Time, approvalThreshold int) (*models.Collection, error) {
+	ret := _m.Called(ctx, creatorID, recipient, variantID, deadline, approvalThreshold)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateCollection")
+	}
+
+	var r0 *models.Collection
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, string, int, time.Time, int) (*models.Collection, error)); ok {
+		return rf(ctx, creatorID, recipient, variantID, deadline, approvalThreshold)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, string, int, time.Time, int) *models.Collection); ok {
+		r0 = rf(ctx, creatorID, recipient, variantID, deadline, approvalThreshold)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Collection)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, string, int, time.Time, int) error); ok {
+		r1 = rf(ctx, creatorID, recipient, variantID, deadline, approvalThreshold)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateItem provides a mock function with given fields: ctx, item
+func (_m *DB) CreateItem(ctx context.Context, item *models.ItemRequest) (*models.CatalogItem, error) {
+	ret := _m.Called(ctx, item)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateItem")
+	}
+
+	var r0 *models.CatalogItem
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.ItemRequest) (*models.CatalogItem, error)); ok {
+		return rf(ctx, item)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.ItemRequest) *models.CatalogItem); ok {
+		r0 = rf(ctx, item)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.CatalogItem)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.ItemRequest) error); ok {
+		r1 = rf(ctx, item)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreatePromo provides a mock function with given fields: ctx, promo
+func (_m *DB) CreatePromo(ctx context.Context, promo *models.PromoRequest) (*models.PromoCode, error) {
+	ret := _m.Called(ctx, promo)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreatePromo")
+	}
+
+	var r0 *models.PromoCode
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.PromoRequest) (*models.PromoCode, error)); ok {
+		return rf(ctx, promo)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.PromoRequest) *models.PromoCode); ok {
+		r0 = rf(ctx, promo)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.PromoCode)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.PromoRequest) error); ok {
+		r1 = rf(ctx, promo)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateUser provides a mock function with given fields: ctx, username, password
+func (_m *DB) CreateUser(ctx context.Context, username string, password string) (*int, error) {
+	ret := _m.Called(ctx, username, password)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateUser")
+	}
+
+	var r0 *int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*int, error)); ok {
+		return rf(ctx, username, password)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *int); ok {
+		r0 = rf(ctx, username, password)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*int)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, username, password)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateVariant provides a mock function with given fields: ctx, itemID, variant
+func (_m *DB) CreateVariant(ctx context.Context, itemID int, variant *models.VariantRequest) (*models.CatalogItem, error) {
+	ret := _m.Called(ctx, itemID, variant)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateVariant")
+	}
+
+	var r0 *models.CatalogItem
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, *models.VariantRequest) (*models.CatalogItem, error)); ok {
+		return rf(ctx, itemID, variant)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, *models.VariantRequest) *models.CatalogItem); ok {
+		r0 = rf(ctx, itemID, variant)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.CatalogItem)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, *models.VariantRequest) error); ok {
+		r1 = rf(ctx, itemID, variant)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ExpireApprovals provides a mock function with given fields: ctx, placedBefore
+func (_m *DB) ExpireApprovals(ctx context.Context, placedBefore time.Time) error {
+	ret := _m.Called(ctx, placedBefore)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ExpireApprovals")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) error); ok {
+		r0 = rf(ctx, placedBefore)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ExpireCollections provides a mock function with given fields: ctx, at
+func (_m *DB) ExpireCollections(ctx context.Context, at time.Time) error {
+	ret := _m.Called(ctx, at)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ExpireCollections")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) error); ok {
+		r0 = rf(ctx, at)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ExportLedger provides a mock function with given fields: ctx, filter, write
+func (_m *DB) ExportLedger(ctx context.Context, filter *models.LedgerFilter, write func(*models.LedgerEntry) error) error {
+	ret := _m.Called(ctx, filter, write)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ExportLedger")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.LedgerFilter, func(*models.LedgerEntry) error) error); ok {
+		r0 = rf(ctx, filter, write)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetApprovers provides a mock function with given fields: ctx
+func (_m *DB) GetApprovers(ctx context.Context) ([]models.Approver, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetApprovers")
+	}
+
+	var r0 []models.Approver
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]models.Approver, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []models.Approver); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Approver)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetAuction provides a mock function with given fields: ctx, auctionID
+func (_m *DB) GetAuction(ctx context.Context, auctionID int) (*models.Auction, error) {
+	ret := _m.Called(ctx, auctionID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAuction")
+	}
+
+	var r0 *models.Auction
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) (*models.Auction, error)); ok {
+		return rf(ctx, auctionID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) *models.Auction); ok {
+		r0 = rf(ctx, auctionID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Auction)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, auctionID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetAuctions provides a mock function with given fields: ctx, openOnly
+func (_m *DB) GetAuctions(ctx context.Context, openOnly bool) ([]models.Auction, error) {
+	ret := _m.Called(ctx, openOnly)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAuctions")
+	}
+
+	var r0 []models.Auction
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, bool) ([]models.Auction, error)); ok {
+		return rf(ctx, openOnly)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, bool) []models.Auction); ok {
+		r0 = rf(ctx, openOnly)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Auction)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, bool) error); ok {
+		r1 = rf(ctx, openOnly)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetBundles provides a mock function with given fields: ctx
+func (_m *DB) GetBundles(ctx context.Context) ([]models.Bundle, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetBundles")
+	}
+
+	var r0 []models.Bundle
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]models.Bundle, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []models.Bundle); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Bundle)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetCart provides a mock function with given fields: ctx, userID
+func (_m *DB) GetCart(ctx context.Context, userID int) ([]models.CartItem, error) {
+	ret := _m.Called(ctx, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetCart")
+	}
+
+	var r0 []models.CartItem
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) ([]models.CartItem, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) []models.CartItem); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.CartItem)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetCollection provides a mock function with given fields: ctx, collectionID
+func (_m *DB) GetCollection(ctx context.Context, collectionID int) (*models.Collection, error) {
+	ret := _m.Called(ctx, collectionID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetCollection")
+	}
+
+	var r0 *models.Collection
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) (*models.Collection, error)); ok {
+		return rf(ctx, collectionID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) *models.Collection); ok {
+		r0 = rf(ctx, collectionID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Collection)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, collectionID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetCollections provides a mock function with given fields: ctx, openOnly
+func (_m *DB) GetCollections(ctx context.Context, openOnly bool) ([]models.Collection, error) {
+	ret := _m.Called(ctx, openOnly)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetCollections")
+	}
+
+	var r0 []models.Collection
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, bool) ([]models.Collection, error)); ok {
+		return rf(ctx, openOnly)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, bool) []models.Collection); ok {
+		r0 = rf(ctx, openOnly)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Collection)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, bool) error); ok {
+		r1 = rf(ctx, openOnly)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetItemPrices provides a mock function with given fields: ctx, itemID
+func (_m *DB) GetItemPrices(ctx context.Context, itemID int) ([]models.ItemPrice, error) {
+	ret := _m.Called(ctx, itemID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetItemPrices")
+	}
+
+	var r0 []models.ItemPrice
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) ([]models.ItemPrice, error)); ok {
+		return rf(ctx, itemID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) []models.ItemPrice); ok {
+		r0 = rf(ctx, itemID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.ItemPrice)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, itemID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetItems provides a mock function with given fields: ctx
+func (_m *DB) GetItems(ctx context.Context) ([]models.CatalogItem, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetItems")
+	}
+
+	var r0 []models.CatalogItem
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]models.CatalogItem, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []models.CatalogItem); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.CatalogItem)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetLowStockVariants provides a mock function with given fields: ctx, threshold
+func (_m *DB) GetLowStockVariants(ctx context.Context, threshold int) ([]models.ItemVariant, error) {
+	ret := _m.Called(ctx, threshold)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetLowStockVariants")
+	}
+
+	var r0 []models.ItemVariant
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) ([]models.ItemVariant, error)); ok {
+		return rf(ctx, threshold)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) []models.ItemVariant); ok {
+		r0 = rf(ctx, threshold)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.ItemVariant)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, threshold)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetMonthlyStatement provides a mock function with given fields: ctx, userID, month
+func (_m *DB) GetMonthlyStatement(ctx context.Context, userID int, month time.Time) (*models.Statement, error) {
+	ret := _m.Called(ctx, userID, month)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetMonthlyStatement")
+	}
+
+	var r0 *models.Statement
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, time.Time) (*models.Statement, error)); ok {
+		return rf(ctx, userID, month)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, time.Time) *models.Statement); ok {
+		r0 = rf(ctx, userID, month)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Statement)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, time.Time) error); ok {
+		r1 = rf(ctx, userID, month)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetNotifications provides a mock function with given fields: ctx, userID, unreadOnly
+func (_m *DB) GetNotifications(ctx context.Context, userID int, unreadOnly bool) ([]models.Notification, error) {
+	ret := _m.Called(ctx, userID, unreadOnly)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetNotifications")
+	}
+
+	var r0 []models.Notification
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, bool) ([]models.Notification, error)); ok {
+		return rf(ctx, userID, unreadOnly)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, bool) []models.Notification); ok {
+		r0 = rf(ctx, userID, unreadOnly)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Notification)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, bool) error); ok {
+		r1 = rf(ctx, userID, unreadOnly)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetOrders provides a mock function with given fields: ctx, filter
+func (_m *DB) GetOrders(ctx context.Context, filter *models.OrderFilter) ([]models.Order, error) {
+	ret := _m.Called(ctx, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetOrders")
+	}
+
+	var r0 []models.Order
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.OrderFilter) ([]models.Order, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.OrderFilter) []models.Order); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Order)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.OrderFilter) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPromos provides a mock function with given fields: ctx
+func (_m *DB) GetPromos(ctx context.Context) ([]models.PromoCode, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPromos")
+	}
+
+	var r0 []models.PromoCode
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]models.PromoCode, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []models.PromoCode); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.PromoCode)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetReviews provides a mock function with given fields: ctx, filter
+func (_m *DB) GetReviews(ctx context.Context, filter *models.ReviewFilter) ([]models.Review, error) {
+	ret := _m.Called(ctx, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetReviews")
+	}
+
+	var r0 []models.Review
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.ReviewFilter) ([]models.Review, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.ReviewFilter) []models.Review); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Review)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.ReviewFilter) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetTransferHistoryByUserID provides a mock function with given fields: ctx, userID, filter
+func (_m *DB) GetTransferHistoryByUserID(ctx context.Context, userID int, filter *models.TransferFilter) ([]models.Transfer, error) {
+	ret := _m.Called(ctx, userID, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetTransferHistoryByUserID")
+	}
+
+	var r0 []models.Transfer
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, *models.TransferFilter) ([]models.Transfer, error)); ok {
+		return rf(ctx, userID, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, *models.TransferFilter) []models.Transfer); ok {
+		r0 = rf(ctx, userID, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Transfer)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, *models.TransferFilter) error); ok {
+		r1 = rf(ctx, userID, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUser provides a mock function with given fields: ctx, username
+func (_m *DB) GetUser(ctx context.Context, username string) (*int, string, error) {
+	ret := _m.Called(ctx, username)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUser")
+	}
+
+	var r0 *int
+	var r1 string
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*int, string, error)); ok {
+		return rf(ctx, username)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *int); ok {
+		r0 = rf(ctx, username)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*int)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) string); ok {
+		r1 = rf(ctx, username)
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, string) error); ok {
+		r2 = rf(ctx, username)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// GetUserInfoByUserID provides a mock function with given fields: ctx, userID, grouped
+func (_m *DB) GetUserInfoByUserID(ctx context.Context, userID int, grouped bool) (*int, []models.Item, *models.CoinTransferHistory, *models.ItemTransferHistory, error) {
+	ret := _m.Called(ctx, userID, grouped)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUserInfoByUserID")
+	}
+
+	var r0 *int
+	var r1 []models.Item
+	var r2 *models.CoinTransferHistory
+	var r3 *models.ItemTransferHistory
+	var r4 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, bool) (*int, []models.Item, *models.CoinTransferHistory, *models.ItemTransferHistory, error)); ok {
+		return rf(ctx, userID, grouped)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, bool) *int); ok {
+		r0 = rf(ctx, userID, grouped)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*int)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, bool) []models.Item); ok {
+		r1 = rf(ctx, userID, grouped)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).([]models.Item)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, int, bool) *models.CoinTransferHistory); ok {
+		r2 = rf(ctx, userID, grouped)
+	} else {
+		if ret.Get(2) != nil {
+			r2 = ret.Get(2).(*models.CoinTransferHistory)
+		}
+	}
+
+	if rf, ok := ret.Get(3).(func(context.Context, int, bool) *models.ItemTransferHistory); ok {
+		r3 = rf(ctx, userID, grouped)
+	} else {
+		if ret.Get(3) != nil {
+			r3 = ret.Get(3).(*models.ItemTransferHistory)
+		}
+	}
+
+	if rf, ok := ret.Get(4).(func(context.Context, int, bool) error); ok {
+		r4 = rf(ctx, userID, grouped)
+	} else {
+		r4 = ret.Error(4)
+	}
+
+	return r0, r1, r2, r3, r4
+}
+
+// GetVariantStock provides a mock function with given fields: ctx
+func (_m *DB) GetVariantStock(ctx context.Context) (map[int]int, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetVariantStock")
+	}
+
+	var r0 map[int]int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (map[int]int, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) map[int]int); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[int]int)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetWishlist provides a mock function with given fields: ctx, userID
+func (_m *DB) GetWishlist(ctx context.Context, userID int) (*models.Wishlist, error) {
+	ret := _m.Called(ctx, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetWishlist")
+	}
+
+	var r0 *models.Wishlist
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) (*models.Wishlist, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) *models.Wishlist); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Wishlist)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GiveItem provides a mock function with given fields: ctx, userID, destUsername, line
+func (_m *DB) GiveItem(ctx context.Context, userID int, destUsername string, line models.CartLine) error {
+	ret := _m.Called(ctx, userID, destUsername, line)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GiveItem")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, string, models.CartLine) error); ok {
+		r0 = rf(ctx, userID, destUsername, line)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// IsAdmin provides a mock function with given fields: ctx, userID
+func (_m *DB) IsAdmin(ctx context.Context, userID int) (bool, error) {
+	ret := _m.Called(ctx, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for IsAdmin")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) (bool, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) bool); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MarkNotificationRead provides a mock function with given fields: ctx, userID, notificationID
+func (_m *DB) MarkNotificationRead(ctx context.Context, userID int, notificationID int) error {
+	ret := _m.Called(ctx, userID, notificationID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MarkNotificationRead")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) error); ok {
+		r0 = rf(ctx, userID, notificationID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NotifyWishlists provides a mock function with given fields: ctx, at
+func (_m *DB) NotifyWishlists(ctx context.Context, at time.Time) error {
+	ret := _m.Called(ctx, at)
+
+	if len(ret) == 0 {
+		panic("no return value specified for NotifyWishlists")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) error); ok {
+		r0 = rf(ctx, at)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// PlaceBid provides a mock function with given fields: ctx, userID, auctionID, amount
+func (_m *DB) PlaceBid(ctx context.Context, userID int, auctionID int, amount int) error {
+	ret := _m.Called(ctx, userID, auctionID, amount)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PlaceBid")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, int) error); ok {
+		r0 = rf(ctx, userID, auctionID, amount)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RejectOrder provides a mock function with given fields: ctx, orderID, approval
+func (_m *DB) RejectOrder(ctx context.Context, orderID int, approval *models.OrderApproval) error {
+	ret := _m.Called(ctx, orderID, approval)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RejectOrder")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, *models.OrderApproval) error); ok {
+		r0 = rf(ctx, orderID, approval)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RemoveApprover provides a mock function with given fields: ctx, username
+func (_m *DB) RemoveApprover(ctx context.Context, username string) error {
+	ret := _m.Called(ctx, username)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RemoveApprover")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, username)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RemoveWishlistItem provides a mock function with given fields: ctx, userID, itemID
+func (_m *DB) RemoveWishlistItem(ctx context.Context, userID int, itemID int) error {
+	ret := _m.Called(ctx, userID, itemID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RemoveWishlistItem")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) error); ok {
+		r0 = rf(ctx, userID, itemID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RestockVariant provides a mock function with given fields: ctx, variantID, quantity
+func (_m *DB) RestockVariant(ctx context.Context, variantID int, quantity int) (*models.CatalogItem, error) {
+	ret := _m.Called(ctx, variantID, quantity)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RestockVariant")
+	}
+
+	var r0 *models.CatalogItem
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) (*models.CatalogItem, error)); ok {
+		return rf(ctx, variantID, quantity)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) *models.CatalogItem); ok {
+		r0 = rf(ctx, variantID, quantity)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.CatalogItem)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, int) error); ok {
+		r1 = rf(ctx, variantID, quantity)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SaveMonthlyStatements provides a mock function with given fields: ctx, month
+func (_m *DB) SaveMonthlyStatements(ctx context.Context, month time.Time) error {
+	ret := _m.Called(ctx, month)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveMonthlyStatements")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) error); ok {
+		r0 = rf(ctx, month)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SchedulePrice provides a mock function with given fields: ctx, itemID, price
+func (_m *DB) SchedulePrice(ctx context.Context, itemID int, price *models.PriceRequest) (*models.ItemPrice, error) {
+	ret := _m.Called(ctx, itemID, price)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SchedulePrice")
+	}
+
+	var r0 *models.ItemPrice
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, *models.PriceRequest) (*models.ItemPrice, error)); ok {
+		return rf(ctx, itemID, price)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, *models.PriceRequest) *models.ItemPrice); ok {
+		r0 = rf(ctx, itemID, price)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.ItemPrice)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, *models.PriceRequest) error); ok {
+		r1 = rf(ctx, itemID, price)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SendCoinByUsername provides a mock function with given fields: ctx, userID, destUsername, amount
+func (_m *DB) SendCoinByUsername(ctx context.Context, userID int, destUsername string, amount int) error {
+	ret := _m.Called(ctx, userID, destUsername, amount)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SendCoinByUsername")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, string, int) error); ok {
+		r0 = rf(ctx, userID, destUsername, amount)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetBundleActive provides a mock function with given fields: ctx, bundleID, active
+func (_m *DB) SetBundleActive(ctx context.Context, bundleID int, active bool) (*models.Bundle, error) {
+	ret := _m.Called(ctx, bundleID, active)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetBundleActive")
+	}
+
+	var r0 *models.Bundle
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, bool) (*models.Bundle, error)); ok {
+		return rf(ctx, bundleID, active)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, bool) *models.Bundle); ok {
+		r0 = rf(ctx, bundleID, active)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Bundle)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, bool) error); ok {
+		r1 = rf(ctx, bundleID, active)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetItemActive provides a mock function with given fields: ctx, itemID, active
+func (_m *DB) SetItemActive(ctx context.Context, itemID int, active bool) (*models.CatalogItem, error) {
+	ret := _m.Called(ctx, itemID, active)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetItemActive")
+	}
+
+	var r0 *models.CatalogItem
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, bool) (*models.CatalogItem, error)); ok {
+		return rf(ctx, itemID, active)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, bool) *models.CatalogItem); ok {
+		r0 = rf(ctx, itemID, active)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.CatalogItem)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, bool) error); ok {
+		r1 = rf(ctx, itemID, active)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetPromoActive provides a mock function with given fields: ctx, promoID, active
+func (_m *DB) SetPromoActive(ctx context.Context, promoID int, active bool) (*models.PromoCode, error) {
+	ret := _m.Called(ctx, promoID, active)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetPromoActive")
+	}
+
+	var r0 *models.PromoCode
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, bool) (*models.PromoCode, error)); ok {
+		return rf(ctx, promoID, active)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, bool) *models.PromoCode); ok {
+		r0 = rf(ctx, promoID, active)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.PromoCode)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, bool) error); ok {
+		r1 = rf(ctx, promoID, active)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetReviewHidden provides a mock function with given fields: ctx, reviewID, hidden
+func (_m *DB) SetReviewHidden(ctx context.Context, reviewID int, hidden bool) (*models.Review, error) {
+	ret := _m.Called(ctx, reviewID, hidden)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetReviewHidden")
+	}
+
+	var r0 *models.Review
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, bool) (*models.Review, error)); ok {
+		return rf(ctx, reviewID, hidden)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, bool) *models.Review); ok {
+		r0 = rf(ctx, reviewID, hidden)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Review)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, bool) error); ok {
+		r1 = rf(ctx, reviewID, hidden)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateItem provides a mock function with given fields: ctx, itemID, item
+func (_m *DB) UpdateItem(ctx context.Context, itemID int, item *models.ItemRequest) (*models.CatalogItem, error) {
+	ret := _m.Called(ctx, itemID, item)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateItem")
+	}
+
+	var r0 *models.CatalogItem
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, *models.ItemRequest) (*models.CatalogItem, error)); ok {
+		return rf(ctx, itemID, item)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, *models.ItemRequest) *models.CatalogItem); ok {
+		r0 = rf(ctx, itemID, item)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.CatalogItem)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, *models.ItemRequest) error); ok {
+		r1 = rf(ctx, itemID, item)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateVariant provides a mock function with given fields: ctx, variantID, variant
+func (_m *DB) UpdateVariant(ctx context.Context, variantID int, variant *models.VariantRequest) (*models.CatalogItem, error) {
+	ret := _m.Called(ctx, variantID, variant)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateVariant")
+	}
+
+	var r0 *models.CatalogItem
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, *models.VariantRequest) (*models.CatalogItem, error)); ok {
+		return rf(ctx, variantID, variant)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, *models.VariantRequest) *models.CatalogItem); ok {
+		r0 = rf(ctx, variantID, variant)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.CatalogItem)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, *models.VariantRequest) error); ok {
+		r1 = rf(ctx, variantID, variant)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpsertReview provides a mock function with given fields: ctx, userID, itemID, review
+func (_m *DB) UpsertReview(ctx context.Context, userID int, itemID int, review *models.ReviewRequest) (*models.Review, error) {
+	ret := _m.Called(ctx, userID, itemID, review)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpsertReview")
+	}
+
+	var r0 *models.Review
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, *models.ReviewRequest) (*models.Review, error)); ok {
+		return rf(ctx, userID, itemID, review)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, *models.ReviewRequest) *models.Review); ok {
+		r0 = rf(ctx, userID, itemID, review)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Review)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, int, *models.ReviewRequest) error); ok {
+		r1 = rf(ctx, userID, itemID, review)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewDB creates a new instance of DB. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewDB(t interface {
@@ -0,0 +1,88 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"merch_shop/internal/models"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// GetNotifications returns the latest notifications of the user, newest first.
+func (s *storage) GetNotifications(ctx context.Context, userID int, unreadOnly bool) ([]models.Notification, error) {
+	column := func(table, column string) string {
+		return fmt.Sprintf("%s.%s", table, column)
+	}
+
+	query := sq.Select(
+		column(notificationsTable, notificationsIDColumn),
+		column(notificationsTable, notificationsKindColumn),
+		fmt.Sprintf("COALESCE(%s, '')", column(itemsTable, itemsTypeColumn)),
+		column(notificationsTable, notificationsMessageColumn),
+		column(notificationsTable, notificationsCreatedAtColumn),
+		column(notificationsTable, notificationsReadAtColumn)).
+		From(notificationsTable).
+		LeftJoin(fmt.Sprintf("%s ON %s = %s", itemsTable,
+			column(notificationsTable, notificationsItemIDColumn), column(itemsTable, itemsIDColumn))).
+		Where(sq.Eq{column(notificationsTable, notificationsUserIDColumn): userID})
+	if unreadOnly {
+		query = query.Where(sq.Eq{column(notificationsTable, notificationsReadAtColumn): nil})
+	}
+
+	selectNotificationsQuery, notificationsArgs, err := query.
+		OrderBy(column(notificationsTable, notificationsIDColumn) + " DESC").
+		Limit(notificationsLimit).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, selectNotificationsQuery, notificationsArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := make([]models.Notification, 0)
+	for rows.Next() {
+		var notification models.Notification
+		err := rows.Scan(&notification.ID, &notification.Kind, &notification.Item, &notification.Message,
+			&notification.CreatedAt, &notification.ReadAt)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, notification)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return notifications, nil
+}
+
+// MarkNotificationRead marks a notification of the user as read, reading it again keeps the first read time.
+func (s *storage) MarkNotificationRead(ctx context.Context, userID, notificationID int) error {
+	updateNotificationQuery, notificationUpdArgs, err := sq.Update(notificationsTable).
+		Set(notificationsReadAtColumn, sq.Expr(fmt.Sprintf("COALESCE(%s, ?)", notificationsReadAtColumn), time.Now())).
+		Where(sq.Eq{notificationsIDColumn: notificationID, notificationsUserIDColumn: userID}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	result, err := s.db.ExecContext(ctx, updateNotificationQuery, notificationUpdArgs...)
+	if err != nil {
+		return err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return ErrNoNotification
+	}
+
+	return nil
+}
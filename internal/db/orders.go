@@ -0,0 +1,181 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"merch_shop/internal/models"
+	"sort"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+const recipientsAlias = "recipients"
+
+// GetOrders returns orders with their lines, newest first.
+func (s *storage) GetOrders(ctx context.Context, filter *models.OrderFilter) ([]models.Order, error) {
+	column := func(table, column string) string {
+		return fmt.Sprintf("%s.%s", table, column)
+	}
+
+	selectOrders := sq.Select(
+		column(ordersTable, ordersIDColumn),
+		column(usersTable, usersNameColumn),
+		column(ordersTable, ordersStatusColumn),
+		fmt.Sprintf("COALESCE(%s, '')", column(promoCodesTable, promoCodesCodeColumn)),
+		column(ordersTable, ordersDiscountColumn),
+		column(ordersTable, ordersTotalColumn),
+		column(ordersTable, ordersCreatedAtColumn),
+		column(ordersTable, ordersStatusUpdatedAtColumn),
+		fmt.Sprintf("COALESCE(%s, '')", column(recipientsAlias, usersNameColumn)),
+		fmt.Sprintf("COALESCE(%s, '')", column(ordersTable, ordersGiftNoteColumn)),
+		fmt.Sprintf("COALESCE(%s, '')", column(bundlesTable, bundlesNameColumn)),
+		fmt.Sprintf("json_agg(json_build_object('item', %s, 'variant', %s, 'quantity', %s, 'price', %s, 'discount', %s, "+
+			"'total', %s * %s - %s) ORDER BY %s)",
+			column(itemsTable, itemsTypeColumn), column(itemVariantsTable, itemVariantsNameColumn),
+			column(purchasesTable, purchasesQuantityColumn), column(purchasesTable, purchasesPriceColumn),
+			column(purchasesTable, purchasesDiscountColumn), column(purchasesTable, purchasesPriceColumn),
+			column(purchasesTable, purchasesQuantityColumn), column(purchasesTable, purchasesDiscountColumn),
+			column(purchasesTable, purchasesIDColumn))).
+		From(ordersTable).
+		Join(fmt.Sprintf("%s ON %s = %s", usersTable, column(ordersTable, ordersUserIDColumn), column(usersTable, userIDColumn))).
+		LeftJoin(fmt.Sprintf("%s AS %s ON %s = %s", usersTable, recipientsAlias,
+			column(ordersTable, ordersRecipientIDColumn), column(recipientsAlias, userIDColumn))).
+		LeftJoin(fmt.Sprintf("%s ON %s = %s", promoCodesTable,
+			column(ordersTable, ordersPromoIDColumn), column(promoCodesTable, promoCodesIDColumn))).
+		LeftJoin(fmt.Sprintf("%s ON %s = %s", bundlesTable,
+			column(ordersTable, ordersBundleIDColumn), column(bundlesTable, bundlesIDColumn))).
+		Join(fmt.Sprintf("%s ON %s = %s", purchasesTable,
+			column(purchasesTable, purchasesOrderIDColumn), column(ordersTable, ordersIDColumn))).
+		Join(fmt.Sprintf("%s ON %s = %s", itemsTable,
+			column(purchasesTable, purchasesItemIDColumn), column(itemsTable, itemsIDColumn))).
+		Join(fmt.Sprintf("%s ON %s = %s", itemVariantsTable,
+			column(purchasesTable, purchasesVariantIDColumn), column(itemVariantsTable, itemVariantsIDColumn))).
+		GroupBy(column(ordersTable, ordersIDColumn), column(usersTable, usersNameColumn),
+			column(promoCodesTable, promoCodesCodeColumn), column(recipientsAlias, usersNameColumn),
+			column(bundlesTable, bundlesNameColumn)).
+		OrderBy(column(ordersTable, ordersIDColumn) + " DESC")
+
+	if filter.OrderID != nil {
+		selectOrders = selectOrders.Where(sq.Eq{column(ordersTable, ordersIDColumn): *filter.OrderID})
+	}
+	if filter.UserID != nil && filter.ReceivedGifts {
+		selectOrders = selectOrders.Where(sq.Or{
+			sq.Eq{column(ordersTable, ordersUserIDColumn): *filter.UserID},
+			sq.Eq{column(ordersTable, ordersRecipientIDColumn): *filter.UserID},
+		})
+	} else if filter.UserID != nil {
+		selectOrders = selectOrders.Where(sq.Eq{column(ordersTable, ordersUserIDColumn): *filter.UserID})
+	}
+	if filter.ApproverID != nil {
+		selectOrders = selectOrders.Where(sq.Eq{column(ordersTable, ordersApproverIDColumn): *filter.ApproverID})
+	}
+	if filter.Status != "" {
+		selectOrders = selectOrders.Where(sq.Eq{column(ordersTable, ordersStatusColumn): filter.Status})
+	}
+
+	selectOrdersQuery, ordersArgs, err := selectOrders.PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, selectOrdersQuery, ordersArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := make([]models.Order, 0)
+	for rows.Next() {
+		var order models.Order
+		var gift models.OrderGift
+		var lines []byte
+		err := rows.Scan(&order.ID, &order.User, &order.Status, &order.Promo, &order.Discount, &order.Total,
+			&order.CreatedAt, &order.StatusUpdatedAt, &gift.To, &gift.Note, &order.Bundle, &lines)
+		if err != nil {
+			return nil, err
+		}
+		if gift.To != "" {
+			order.Gift = &gift
+		}
+
+		err = json.Unmarshal(lines, &order.Items)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+}
+
+// AdvanceOrder moves an order to the next status of models.NextOrderStatus.
+// The status is checked and changed in one statement, so concurrent calls never skip a step.
+func (s *storage) AdvanceOrder(ctx context.Context, orderID int) error {
+	statuses := make([]string, 0, len(models.NextOrderStatus))
+	for status := range models.NextOrderStatus {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+
+	nextStatus := sq.Case(ordersStatusColumn)
+	for _, status := range statuses {
+		nextStatus = nextStatus.When(sq.Expr("?", status), sq.Expr("?", models.NextOrderStatus[status]))
+	}
+
+	updateOrderQuery, orderUpdArgs, err := sq.Update(ordersTable).
+		Set(ordersStatusColumn, nextStatus).
+		Set(ordersStatusUpdatedAtColumn, time.Now()).
+		Where(sq.Eq{ordersIDColumn: orderID, ordersStatusColumn: statuses}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	result, err := s.db.ExecContext(ctx, updateOrderQuery, orderUpdArgs...)
+	if err != nil {
+		return err
+	}
+
+	advanced, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if advanced > 0 {
+		return nil
+	}
+
+	return orderNotChangedError(ctx, s.db, sq.Eq{ordersIDColumn: orderID}, ErrOrderFinal)
+}
+
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// orderNotChangedError tells a missing order from one whose status does not allow the change.
+func orderNotChangedError(ctx context.Context, q rowQuerier, order sq.Eq, notChanged error) error {
+	selectOrderQuery, orderSelectArgs, err := sq.Select("1").
+		From(ordersTable).
+		Where(order).
+		Prefix("SELECT EXISTS (").Suffix(")").
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	var exists bool
+	err = q.QueryRowContext(ctx, selectOrderQuery, orderSelectArgs...).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrNoOrder
+	}
+
+	return notChanged
+}
@@ -0,0 +1,149 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"log"
+	"merch_shop/internal/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	selectOrdersQueryRegexp = `
+		SELECT (.*) FROM orders JOIN users ON (.*) LEFT JOIN users AS recipients ON (.*) LEFT JOIN promo_codes ON (.*)
+		LEFT JOIN bundles ON (.*) JOIN purchases ON (.*) JOIN items ON (.*) JOIN item_variants ON (.*)
+		WHERE orders.user_id = \$1 GROUP BY orders.id, users.username, promo_codes.code, recipients.username, bundles.name
+		ORDER BY orders.id DESC
+	`
+	advanceOrderQueryRegexp = `
+		UPDATE orders SET status = CASE status WHEN \$1 THEN \$2 WHEN \$3 THEN \$4 WHEN \$5 THEN \$6 END,
+		status_updated_at = \$7 WHERE id = \$8 AND status IN \(\$9,\$10,\$11\)
+	`
+	selectOrdersWithGiftsQueryRegexp = `
+		SELECT (.*) FROM orders (.*) WHERE \(orders.user_id = \$1 OR orders.recipient_id = \$2\) GROUP BY (.*)
+	`
+	orderExistsQueryRegexp = `SELECT EXISTS \( SELECT 1 FROM orders WHERE id = \$1 \)`
+)
+
+func TestGetOrders(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer mockDB.Close()
+
+	db := storage{db: mockDB}
+
+	userID := 1
+	timing := time.Date(2025, 2, 5, 12, 30, 0, 0, time.UTC)
+	orderColumns := []string{ordersIDColumn, usersNameColumn, ordersStatusColumn, promoCodesCodeColumn, ordersDiscountColumn,
+		ordersTotalColumn, ordersCreatedAtColumn, ordersStatusUpdatedAtColumn, "recipient", ordersGiftNoteColumn, "bundle", "items"}
+
+	t.Run("positive result", func(t *testing.T) {
+		rows := sqlmock.NewRows(orderColumns).AddRow(3, "alice", models.OrderStatusPacked, "CUPS10", 4, 66, timing, timing, "", "", "",
+			`[{"item": "cup", "variant": "default", "quantity": 2, "price": 20, "discount": 4, "total": 36}, `+
+				`{"item": "pen", "variant": "blue", "quantity": 3, "price": 10, "discount": 0, "total": 30}]`)
+		mock.ExpectQuery(selectOrdersQueryRegexp).WithArgs(userID).WillReturnRows(rows)
+
+		orders, err := db.GetOrders(context.Background(), &models.OrderFilter{UserID: &userID})
+		assert.NoError(t, err)
+		assert.Equal(t, []models.Order{{
+			ID: 3, User: "alice", Status: models.OrderStatusPacked, Promo: "CUPS10", Discount: 4, Total: 66,
+			CreatedAt: timing, StatusUpdatedAt: timing,
+			Items: []models.OrderLine{
+				{Item: "cup", Variant: "default", Quantity: 2, Price: 20, Discount: 4, Total: 36},
+				{Item: "pen", Variant: "blue", Quantity: 3, Price: 10, Total: 30},
+			},
+		}}, orders)
+	})
+
+	t.Run("received gifts", func(t *testing.T) {
+		rows := sqlmock.NewRows(orderColumns).AddRow(4, "bob", models.OrderStatusPlaced, "", 0, 20, timing, timing,
+			"alice", "happy birthday", "",
+			`[{"item": "cup", "variant": "default", "quantity": 1, "price": 20, "discount": 0, "total": 20}]`)
+		mock.ExpectQuery(selectOrdersWithGiftsQueryRegexp).WithArgs(userID, userID).WillReturnRows(rows)
+
+		orders, err := db.GetOrders(context.Background(), &models.OrderFilter{UserID: &userID, ReceivedGifts: true})
+		assert.NoError(t, err)
+		assert.Equal(t, []models.Order{{
+			ID: 4, User: "bob", Status: models.OrderStatusPlaced, Total: 20, CreatedAt: timing, StatusUpdatedAt: timing,
+			Items: []models.OrderLine{{Item: "cup", Variant: "default", Quantity: 1, Price: 20, Total: 20}},
+			Gift:  &models.OrderGift{To: "alice", Note: "happy birthday"},
+		}}, orders)
+	})
+
+	t.Run("query error", func(t *testing.T) {
+		mock.ExpectQuery(selectOrdersQueryRegexp).WithArgs(userID).WillReturnError(errors.New("some error"))
+
+		_, err := db.GetOrders(context.Background(), &models.OrderFilter{UserID: &userID})
+		assert.Error(t, err)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAdvanceOrder(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer mockDB.Close()
+
+	db := storage{db: mockDB}
+
+	orderID := 3
+	advanceArgs := []driver.Value{
+		models.OrderStatusPacked, models.OrderStatusReadyForPickup,
+		models.OrderStatusPlaced, models.OrderStatusPacked,
+		models.OrderStatusReadyForPickup, models.OrderStatusDelivered,
+		sqlmock.AnyArg(), orderID,
+		models.OrderStatusPacked, models.OrderStatusPlaced, models.OrderStatusReadyForPickup,
+	}
+
+	testCases := []struct {
+		name        string
+		dbBehavior  func()
+		expectedErr error
+	}{
+		{
+			name: "advanced",
+			dbBehavior: func() {
+				mock.ExpectExec(advanceOrderQueryRegexp).WithArgs(advanceArgs...).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "final status",
+			dbBehavior: func() {
+				mock.ExpectExec(advanceOrderQueryRegexp).WithArgs(advanceArgs...).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(orderExistsQueryRegexp).WithArgs(orderID).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+			},
+			expectedErr: ErrOrderFinal,
+		},
+		{
+			name: "no order",
+			dbBehavior: func() {
+				mock.ExpectExec(advanceOrderQueryRegexp).WithArgs(advanceArgs...).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(orderExistsQueryRegexp).WithArgs(orderID).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+			},
+			expectedErr: ErrNoOrder,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.dbBehavior()
+
+			err := db.AdvanceOrder(context.Background(), orderID)
+			assert.Equal(t, tc.expectedErr, err)
+		})
+	}
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,194 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"merch_shop/internal/models"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+)
+
+var itemPriceColumns = []string{
+	itemPricesIDColumn, itemPricesItemIDColumn, itemPricesPriceColumn, itemPricesStartsAtColumn, itemPricesEndsAtColumn,
+}
+
+// joinCurrentPrice joins the price of every selected item in effect at the given time as current_prices.price.
+// An ongoing sale wins over the regular price, within each kind the latest started price wins.
+func joinCurrentPrice(query sq.SelectBuilder, at time.Time) sq.SelectBuilder {
+	column := func(column string) string {
+		return fmt.Sprintf("%s.%s", itemPricesTable, column)
+	}
+	startsAt, endsAt := column(itemPricesStartsAtColumn), column(itemPricesEndsAtColumn)
+
+	return query.Join(fmt.Sprintf("LATERAL (SELECT %s FROM %s WHERE %s = %s.%s "+
+		"AND (%s IS NULL OR %s <= ?) AND (%s IS NULL OR %s > ?) "+
+		"ORDER BY %s IS NULL, %s DESC NULLS LAST, %s DESC LIMIT 1) AS %s ON true",
+		column(itemPricesPriceColumn), itemPricesTable, column(itemPricesItemIDColumn), itemsTable, itemsIDColumn,
+		startsAt, startsAt, endsAt, endsAt,
+		endsAt, startsAt, column(itemPricesIDColumn), currentPricesAlias), at, at)
+}
+
+// joinPriceChanges joins the next time after the given one a price of every selected item
+// starts or ends as price_changes.changes_at, NULL if nothing is scheduled.
+func joinPriceChanges(query sq.SelectBuilder, at time.Time) sq.SelectBuilder {
+	column := func(column string) string {
+		return fmt.Sprintf("%s.%s", itemPricesTable, column)
+	}
+	startsAt, endsAt := column(itemPricesStartsAtColumn), column(itemPricesEndsAtColumn)
+
+	return query.Join(fmt.Sprintf("LATERAL (SELECT LEAST(MIN(%s) FILTER (WHERE %s > ?), MIN(%s) FILTER (WHERE %s > ?)) AS %s "+
+		"FROM %s WHERE %s = %s.%s) AS %s ON true",
+		startsAt, startsAt, endsAt, endsAt, priceChangesTimeColumn,
+		itemPricesTable, column(itemPricesItemIDColumn), itemsTable, itemsIDColumn, priceChangesAlias), at, at)
+}
+
+// SchedulePrice adds a price of an item. Prices that have taken effect are never changed,
+// so the history keeps what was charged at any moment.
+func (s *storage) SchedulePrice(ctx context.Context, itemID int, price *models.PriceRequest) (*models.ItemPrice, error) {
+	insertPriceQuery, priceInsArgs, err := sq.Insert(itemPricesTable).
+		Columns(itemPricesItemIDColumn, itemPricesPriceColumn, itemPricesStartsAtColumn, itemPricesEndsAtColumn).
+		Values(itemID, price.Price, price.StartsAt, price.EndsAt).
+		Suffix("RETURNING " + strings.Join(itemPriceColumns, ", ")).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	scheduled, err := scanItemPrice(s.db.QueryRowContext(ctx, insertPriceQuery, priceInsArgs...))
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "foreign_key_violation" {
+			return nil, ErrNoItem
+		}
+		return nil, err
+	}
+
+	return scheduled, nil
+}
+
+// GetItemPrices returns all prices of an item including scheduled ones, in order of their start.
+func (s *storage) GetItemPrices(ctx context.Context, itemID int) ([]models.ItemPrice, error) {
+	selectPricesQuery, pricesArgs, err := sq.Select(itemPriceColumns...).
+		From(itemPricesTable).
+		Where(sq.Eq{itemPricesItemIDColumn: itemID}).
+		OrderBy(itemPricesStartsAtColumn+" NULLS FIRST", itemPricesIDColumn).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, selectPricesQuery, pricesArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prices := make([]models.ItemPrice, 0)
+	for rows.Next() {
+		price, err := scanItemPrice(rows)
+		if err != nil {
+			return nil, err
+		}
+		prices = append(prices, *price)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return prices, nil
+}
+
+// CancelScheduledPrice deletes a price that has not taken effect by now.
+func (s *storage) CancelScheduledPrice(ctx context.Context, priceID int, now time.Time) error {
+	deletePriceQuery, priceDelArgs, err := sq.Delete(itemPricesTable).
+		Where(sq.Eq{itemPricesIDColumn: priceID}).
+		Where(sq.Gt{itemPricesStartsAtColumn: now}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	result, err := s.db.ExecContext(ctx, deletePriceQuery, priceDelArgs...)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		return nil
+	}
+
+	selectPriceQuery, priceArgs, err := sq.Select("1").
+		From(itemPricesTable).
+		Where(sq.Eq{itemPricesIDColumn: priceID}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	var exists int
+	err = s.db.QueryRowContext(ctx, selectPriceQuery, priceArgs...).Scan(&exists)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNoPrice
+		}
+		return err
+	}
+
+	return ErrPriceStarted
+}
+
+// setRegularPrice makes the price regular from now on unless it is the regular price already.
+func setRegularPrice(ctx context.Context, tx *sql.Tx, itemID, price int, now time.Time) error {
+	selectPriceQuery, priceArgs, err := sq.Select(itemPricesPriceColumn).
+		From(itemPricesTable).
+		Where(sq.Eq{itemPricesItemIDColumn: itemID, itemPricesEndsAtColumn: nil}).
+		Where(sq.Or{sq.Eq{itemPricesStartsAtColumn: nil}, sq.LtOrEq{itemPricesStartsAtColumn: now}}).
+		OrderBy(itemPricesStartsAtColumn+" DESC NULLS LAST", itemPricesIDColumn+" DESC").
+		Limit(1).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	var regular int
+	err = tx.QueryRowContext(ctx, selectPriceQuery, priceArgs...).Scan(&regular)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if regular == price {
+		return nil
+	}
+
+	return insertPrice(ctx, tx, itemID, price, now)
+}
+
+// insertPrice adds a regular price starting at the given time.
+func insertPrice(ctx context.Context, tx *sql.Tx, itemID, price int, startsAt time.Time) error {
+	insertPriceQuery, priceInsArgs, err := sq.Insert(itemPricesTable).
+		Columns(itemPricesItemIDColumn, itemPricesPriceColumn, itemPricesStartsAtColumn).
+		Values(itemID, price, startsAt).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, insertPriceQuery, priceInsArgs...)
+	return err
+}
+
+func scanItemPrice(row interface{ Scan(dest ...any) error }) (*models.ItemPrice, error) {
+	price := &models.ItemPrice{}
+	err := row.Scan(&price.ID, &price.ItemID, &price.Price, &price.StartsAt, &price.EndsAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return price, nil
+}
@@ -0,0 +1,109 @@
+package db
+
+import (
+	"context"
+	"log"
+	"merch_shop/internal/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	insertScheduledPriceQueryRegexp = `
+		INSERT INTO item_prices \(item_id,price,starts_at,ends_at\) VALUES \(\$1,\$2,\$3,\$4\)
+		RETURNING id, item_id, price, starts_at, ends_at
+	`
+	deleteScheduledPriceQueryRegexp = `DELETE FROM item_prices WHERE id = \$1 AND starts_at > \$2`
+	selectPriceExistsQueryRegexp    = `SELECT 1 FROM item_prices WHERE id = \$1`
+)
+
+func TestSchedulePrice(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer mockDB.Close()
+
+	db := storage{db: mockDB}
+
+	startsAt := time.Date(2025, 3, 3, 10, 0, 0, 0, time.UTC)
+	endsAt := startsAt.Add(2 * time.Hour)
+	sale := &models.PriceRequest{Price: 15, StartsAt: &startsAt, EndsAt: &endsAt}
+
+	t.Run("flash sale", func(t *testing.T) {
+		mock.ExpectQuery(insertScheduledPriceQueryRegexp).WithArgs(1, 15, startsAt, endsAt).
+			WillReturnRows(sqlmock.NewRows(itemPriceColumns).AddRow(7, 1, 15, startsAt, endsAt))
+
+		price, err := db.SchedulePrice(context.Background(), 1, sale)
+		assert.NoError(t, err)
+		assert.Equal(t, &models.ItemPrice{ID: 7, ItemID: 1, Price: 15, StartsAt: &startsAt, EndsAt: &endsAt}, price)
+	})
+
+	t.Run("no item", func(t *testing.T) {
+		mock.ExpectQuery(insertScheduledPriceQueryRegexp).WithArgs(9, 15, startsAt, endsAt).
+			WillReturnError(&pq.Error{Code: "23503"})
+
+		_, err := db.SchedulePrice(context.Background(), 9, sale)
+		assert.Equal(t, ErrNoItem, err)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCancelScheduledPrice(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer mockDB.Close()
+
+	db := storage{db: mockDB}
+
+	now := time.Date(2025, 3, 3, 10, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name       string
+		dbBehavior func()
+
+		expectedErr error
+	}{
+		{
+			name: "scheduled price",
+			dbBehavior: func() {
+				mock.ExpectExec(deleteScheduledPriceQueryRegexp).WithArgs(7, now).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "price already started",
+			dbBehavior: func() {
+				mock.ExpectExec(deleteScheduledPriceQueryRegexp).WithArgs(7, now).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(selectPriceExistsQueryRegexp).WithArgs(7).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(1))
+			},
+			expectedErr: ErrPriceStarted,
+		},
+		{
+			name: "no price",
+			dbBehavior: func() {
+				mock.ExpectExec(deleteScheduledPriceQueryRegexp).WithArgs(7, now).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(selectPriceExistsQueryRegexp).WithArgs(7).WillReturnRows(sqlmock.NewRows([]string{"exists"}))
+			},
+			expectedErr: ErrNoPrice,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.dbBehavior()
+
+			err := db.CancelScheduledPrice(context.Background(), 7, now)
+			assert.Equal(t, tc.expectedErr, err)
+		})
+	}
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,243 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"merch_shop/internal/models"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+)
+
+var promoColumns = []string{
+	promoCodesIDColumn, promoCodesCodeColumn, promoCodesKindColumn, promoCodesValueColumn,
+	promoCodesItemIDColumn, promoCodesCategoryColumn, promoCodesStartsAtColumn, promoCodesEndsAtColumn,
+	promoCodesMaxUsesColumn, promoCodesMaxUsesPerUserColumn, promoCodesUsesColumn, promoCodesActiveColumn,
+}
+
+func (s *storage) CreatePromo(ctx context.Context, promo *models.PromoRequest) (*models.PromoCode, error) {
+	insertPromoQuery, promoInsArgs, err := sq.Insert(promoCodesTable).
+		Columns(promoCodesCodeColumn, promoCodesKindColumn, promoCodesValueColumn, promoCodesItemIDColumn,
+			promoCodesCategoryColumn, promoCodesStartsAtColumn, promoCodesEndsAtColumn,
+			promoCodesMaxUsesColumn, promoCodesMaxUsesPerUserColumn).
+		Values(promo.Code, promo.Kind, promo.Value, promo.ItemID, promo.Category, promo.StartsAt, promo.EndsAt,
+			promo.MaxUses, promo.MaxUsesPerUser).
+		Suffix("RETURNING " + strings.Join(promoColumns, ", ")).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := scanPromo(s.db.QueryRowContext(ctx, insertPromoQuery, promoInsArgs...))
+	if err != nil {
+		return nil, promoWriteError(err)
+	}
+
+	return created, nil
+}
+
+// GetPromos returns all promo codes including inactive ones, newest first.
+func (s *storage) GetPromos(ctx context.Context) ([]models.PromoCode, error) {
+	selectPromosQuery, promosArgs, err := sq.Select(promoColumns...).
+		From(promoCodesTable).
+		OrderBy(promoCodesIDColumn + " DESC").
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, selectPromosQuery, promosArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	promos := make([]models.PromoCode, 0)
+	for rows.Next() {
+		promo, err := scanPromo(rows)
+		if err != nil {
+			return nil, err
+		}
+		promos = append(promos, *promo)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return promos, nil
+}
+
+// SetPromoActive stops or resumes a promo code. Codes are never deleted, orders keep referencing them.
+func (s *storage) SetPromoActive(ctx context.Context, promoID int, active bool) (*models.PromoCode, error) {
+	updatePromoQuery, promoUpdArgs, err := sq.Update(promoCodesTable).
+		Set(promoCodesActiveColumn, active).
+		Where(sq.Eq{promoCodesIDColumn: promoID}).
+		Suffix("RETURNING " + strings.Join(promoColumns, ", ")).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := scanPromo(s.db.QueryRowContext(ctx, updatePromoQuery, promoUpdArgs...))
+	if err != nil {
+		return nil, promoWriteError(err)
+	}
+
+	return updated, nil
+}
+
+func scanPromo(row interface{ Scan(dest ...any) error }) (*models.PromoCode, error) {
+	promo := &models.PromoCode{}
+	err := row.Scan(&promo.ID, &promo.Code, &promo.Kind, &promo.Value, &promo.ItemID, &promo.Category,
+		&promo.StartsAt, &promo.EndsAt, &promo.MaxUses, &promo.MaxUsesPerUser, &promo.Uses, &promo.Active)
+	if err != nil {
+		return nil, err
+	}
+
+	return promo, nil
+}
+
+func promoWriteError(err error) error {
+	if err == sql.ErrNoRows {
+		return ErrNoPromo
+	}
+	if pqErr, ok := err.(*pq.Error); ok {
+		switch pqErr.Code.Name() {
+		case "unique_violation":
+			return ErrPromoExists
+		case "foreign_key_violation":
+			return ErrNoItem
+		}
+	}
+	return err
+}
+
+// selectValidPromo reads a promo code for a purchase. The code is not locked here:
+// usage limits are checked when it is redeemed after the user row is locked.
+func selectValidPromo(ctx context.Context, tx *sql.Tx, code string, timing time.Time) (*models.PromoCode, error) {
+	selectPromoQuery, promoArgs, err := sq.Select(promoColumns...).
+		Column(sq.Expr(fmt.Sprintf("(%s IS NULL OR %s <= ?) AND (%s IS NULL OR %s > ?)",
+			promoCodesStartsAtColumn, promoCodesStartsAtColumn, promoCodesEndsAtColumn, promoCodesEndsAtColumn),
+			timing, timing)).
+		From(promoCodesTable).
+		Where(sq.Eq{promoCodesCodeColumn: code}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	promo := &models.PromoCode{}
+	var current bool
+	err = tx.QueryRowContext(ctx, selectPromoQuery, promoArgs...).Scan(&promo.ID, &promo.Code, &promo.Kind,
+		&promo.Value, &promo.ItemID, &promo.Category, &promo.StartsAt, &promo.EndsAt, &promo.MaxUses,
+		&promo.MaxUsesPerUser, &promo.Uses, &promo.Active, &current)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNoPromo
+		}
+		return nil, err
+	}
+	if !promo.Active || !current {
+		return nil, ErrPromoInactive
+	}
+
+	return promo, nil
+}
+
+// promoDiscounts splits the discount between order lines. A percentage is taken from every
+// eligible line, a fixed amount from eligible lines in order until it is used up,
+// so no line ever costs less than zero.
+func promoDiscounts(promo *models.PromoCode, lines []models.CartLine, items map[int]purchasedItem) ([]int, error) {
+	discounts := make([]int, len(lines))
+	left, applied := promo.Value, false
+	for i, line := range lines {
+		item := items[line.VariantID]
+		if promo.ItemID != nil && *promo.ItemID != item.itemID || promo.Category != "" && promo.Category != item.category {
+			continue
+		}
+
+		applied = true
+		lineTotal := item.price * line.Quantity
+		switch promo.Kind {
+		case models.PromoKindPercent:
+			discounts[i] = lineTotal * promo.Value / 100
+		case models.PromoKindFixed:
+			discounts[i] = min(left, lineTotal)
+			left -= discounts[i]
+		}
+	}
+	if !applied {
+		return nil, ErrPromoNotApply
+	}
+
+	return discounts, nil
+}
+
+// redeemPromo counts a use of the promo code. It runs after the balance update, so the user row
+// is locked and purchases of the same user are counted one after another, while the global limit
+// is rechecked by the update itself once a concurrent purchase releases the code row.
+func redeemPromo(ctx context.Context, tx *sql.Tx, promo *models.PromoCode, userID int) error {
+	if promo.MaxUsesPerUser != nil {
+		countUsesQuery, usesArgs, err := sq.Select("COUNT(*)").
+			From(ordersTable).
+			Where(sq.Eq{ordersUserIDColumn: userID, ordersPromoIDColumn: promo.ID}).
+			Where(sq.NotEq{ordersStatusColumn: models.OrderStatusCancelled}).
+			PlaceholderFormat(sq.Dollar).ToSql()
+		if err != nil {
+			return err
+		}
+
+		var uses int
+		err = tx.QueryRowContext(ctx, countUsesQuery, usesArgs...).Scan(&uses)
+		if err != nil {
+			return err
+		}
+		if uses >= *promo.MaxUsesPerUser {
+			return ErrPromoExhausted
+		}
+	}
+
+	redeemPromoQuery, promoUpdArgs, err := sq.Update(promoCodesTable).
+		Set(promoCodesUsesColumn, sq.Expr(promoCodesUsesColumn+" + 1")).
+		Where(sq.Eq{promoCodesIDColumn: promo.ID}).
+		Where(sq.Or{
+			sq.Eq{promoCodesMaxUsesColumn: nil},
+			sq.Expr(fmt.Sprintf("%s < %s", promoCodesUsesColumn, promoCodesMaxUsesColumn)),
+		}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, redeemPromoQuery, promoUpdArgs...)
+	if err != nil {
+		return err
+	}
+
+	redeemed, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if redeemed == 0 {
+		return ErrPromoExhausted
+	}
+
+	return nil
+}
+
+// releasePromo gives the use of a cancelled order back to the promo code.
+func releasePromo(ctx context.Context, tx *sql.Tx, promoID int) error {
+	releasePromoQuery, promoUpdArgs, err := sq.Update(promoCodesTable).
+		Set(promoCodesUsesColumn, sq.Expr(promoCodesUsesColumn+" - 1")).
+		Where(sq.Eq{promoCodesIDColumn: promoID}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, releasePromoQuery, promoUpdArgs...)
+	return err
+}
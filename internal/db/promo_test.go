@@ -0,0 +1,59 @@
+package db
+
+import (
+	"merch_shop/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPromoDiscounts(t *testing.T) {
+	hoodieID := 3
+	lines := []models.CartLine{{VariantID: 1, Quantity: 2}, {VariantID: 2, Quantity: 1}, {VariantID: 4, Quantity: 3}}
+	items := map[int]purchasedItem{
+		1: {itemID: 1, category: "kitchen", price: 15},
+		2: {itemID: 3, category: "clothes", price: 500},
+		4: {itemID: 4, category: "clothes", price: 80},
+	}
+
+	testCases := []struct {
+		name        string
+		promo       models.PromoCode
+		expected    []int
+		expectedErr error
+	}{
+		{
+			name:     "percent of every line rounded down",
+			promo:    models.PromoCode{Kind: models.PromoKindPercent, Value: 15},
+			expected: []int{4, 75, 36},
+		},
+		{
+			name:     "percent of one item",
+			promo:    models.PromoCode{Kind: models.PromoKindPercent, Value: 20, ItemID: &hoodieID},
+			expected: []int{0, 100, 0},
+		},
+		{
+			name:     "fixed amount is spread over category lines in order",
+			promo:    models.PromoCode{Kind: models.PromoKindFixed, Value: 600, Category: "clothes"},
+			expected: []int{0, 500, 100},
+		},
+		{
+			name:     "fixed amount never exceeds the lines",
+			promo:    models.PromoCode{Kind: models.PromoKindFixed, Value: 100, Category: "kitchen"},
+			expected: []int{30, 0, 0},
+		},
+		{
+			name:        "no line of the category",
+			promo:       models.PromoCode{Kind: models.PromoKindFixed, Value: 100, Category: "stationery"},
+			expectedErr: ErrPromoNotApply,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			discounts, err := promoDiscounts(&tc.promo, lines, items)
+			assert.Equal(t, tc.expectedErr, err)
+			assert.Equal(t, tc.expected, discounts)
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"merch_shop/internal/models"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// PurchaseLimitError is returned when a purchase would exceed the purchase limit of an item.
+// NextPurchaseAt is when enough earlier purchases leave the window for the requested quantity,
+// it is nil if the quantity exceeds the limit itself.
+type PurchaseLimitError struct {
+	Item           string
+	Limit          models.PurchaseLimit
+	NextPurchaseAt *time.Time
+}
+
+func (e *PurchaseLimitError) Error() string {
+	message := fmt.Sprintf("purchase limit of %s is reached: max %d per %d days",
+		e.Item, e.Limit.Quantity, e.Limit.WindowDays)
+	if e.NextPurchaseAt != nil {
+		message += ", can be bought again from " + e.NextPurchaseAt.Format(time.RFC3339)
+	}
+	return message
+}
+
+// hasPurchaseLimits reports whether any of the bought items is limited.
+func hasPurchaseLimits(items map[int]purchasedItem) bool {
+	for _, item := range items {
+		if item.limit != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// lockUsers locks the user rows in ascending id order, so opposite gifts between the same users
+// wait for each other instead of deadlocking.
+func lockUsers(ctx context.Context, tx *sql.Tx, userIDs ...int) error {
+	lockUsersQuery, usersLockArgs, err := sq.Select(userIDColumn).
+		From(usersTable).
+		Where(sq.Eq{userIDColumn: userIDs}).
+		OrderBy(userIDColumn).
+		Suffix("FOR UPDATE").
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, lockUsersQuery, usersLockArgs...)
+	return err
+}
+
+// checkPurchaseLimits sums the bought quantity of every limited item over its variants
+// and checks it against the purchases of the user within the item window. A gift is checked
+// against the window of its recipient as well, so the limit holds per person however many colleagues
+// give the item. Both user rows must be locked, so concurrent orders see each other's lines.
+func checkPurchaseLimits(ctx context.Context, tx *sql.Tx, userID int, recipientID *int, lines []models.CartLine,
+	items map[int]purchasedItem, now time.Time) error {
+	quantities := make(map[int]int)
+	limited := make([]purchasedItem, 0)
+	for _, line := range lines {
+		item := items[line.VariantID]
+		if item.limit == nil {
+			continue
+		}
+		if _, ok := quantities[item.itemID]; !ok {
+			limited = append(limited, item)
+		}
+		quantities[item.itemID] += line.Quantity
+	}
+
+	for _, item := range limited {
+		err := checkPurchaseLimit(ctx, tx, userID, item, quantities[item.itemID], now)
+		if err != nil {
+			return err
+		}
+		if recipientID != nil {
+			err := checkPurchaseLimit(ctx, tx, *recipientID, item, quantities[item.itemID], now)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkPurchaseLimit counts purchases of the user within the window, cancelled orders are not counted.
+// Gifts count towards the limits of both the payer and the recipient.
+func checkPurchaseLimit(ctx context.Context, tx *sql.Tx, userID int, item purchasedItem, quantity int, now time.Time) error {
+	limitErr := &PurchaseLimitError{Item: item.itemType, Limit: *item.limit}
+	if quantity > item.limit.Quantity {
+		return limitErr
+	}
+
+	column := func(table, column string) string {
+		return fmt.Sprintf("%s.%s", table, column)
+	}
+
+	window := time.Duration(item.limit.WindowDays) * 24 * time.Hour
+	selectPurchasesQuery, purchasesSelectArgs, err := sq.Select(
+		column(purchasesTable, purchasesTimeColumn),
+		column(purchasesTable, purchasesQuantityColumn)).
+		From(purchasesTable).
+		// Purchases made before orders were introduced have no order.
+		LeftJoin(fmt.Sprintf("%s ON %s = %s", ordersTable,
+			column(purchasesTable, purchasesOrderIDColumn), column(ordersTable, ordersIDColumn))).
+		Where(sq.Eq{column(purchasesTable, purchasesItemIDColumn): item.itemID}).
+		Where(sq.Or{
+			sq.Eq{column(purchasesTable, purchasesUserIDColumn): userID},
+			sq.Eq{column(ordersTable, ordersRecipientIDColumn): userID},
+		}).
+		Where(sq.Gt{column(purchasesTable, purchasesTimeColumn): now.Add(-window)}).
+		Where(sq.Or{
+			sq.Eq{column(ordersTable, ordersStatusColumn): nil},
+			sq.NotEq{column(ordersTable, ordersStatusColumn): models.OrderStatusCancelled},
+		}).
+		OrderBy(column(purchasesTable, purchasesTimeColumn)).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	rows, err := tx.QueryContext(ctx, selectPurchasesQuery, purchasesSelectArgs...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	type windowPurchase struct {
+		timing   time.Time
+		quantity int
+	}
+
+	bought := 0
+	purchases := make([]windowPurchase, 0)
+	for rows.Next() {
+		var purchase windowPurchase
+		err := rows.Scan(&purchase.timing, &purchase.quantity)
+		if err != nil {
+			return err
+		}
+		bought += purchase.quantity
+		purchases = append(purchases, purchase)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if bought+quantity <= item.limit.Quantity {
+		return nil
+	}
+
+	// Purchases leave the window oldest first, the quantity fits once enough of them have left.
+	for _, purchase := range purchases {
+		bought -= purchase.quantity
+		if bought+quantity <= item.limit.Quantity {
+			next := purchase.timing.Add(window)
+			limitErr.NextPurchaseAt = &next
+			break
+		}
+	}
+
+	return limitErr
+}
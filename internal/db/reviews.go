@@ -0,0 +1,192 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"merch_shop/internal/models"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// joinRatings joins the average rating rounded to tenths and the number of visible reviews of every selected item
+// as ratings.rating and ratings.reviews.
+func joinRatings(query sq.SelectBuilder) sq.SelectBuilder {
+	column := func(column string) string {
+		return fmt.Sprintf("%s.%s", reviewsTable, column)
+	}
+
+	return query.Join(fmt.Sprintf("LATERAL (SELECT COALESCE(ROUND(AVG(%s), 1), 0) AS %s, COUNT(*) AS %s "+
+		"FROM %s WHERE %s = %s.%s AND %s IS NULL) AS %s ON true",
+		column(reviewsRatingColumn), reviewsRatingColumn, ratingsReviewsColumn,
+		reviewsTable, column(reviewsItemIDColumn), itemsTable, itemsIDColumn, column(reviewsHiddenAtColumn), ratingsAlias))
+}
+
+// UpsertReview posts a review of the user or replaces the text and rating of the one posted before.
+// Only users having any variant of the item in their inventory may review it, editing included.
+func (s *storage) UpsertReview(ctx context.Context, userID, itemID int, review *models.ReviewRequest) (*models.Review, error) {
+	column := func(table, column string) string {
+		return fmt.Sprintf("%s.%s", table, column)
+	}
+
+	selectOwnedQuery, ownedArgs, err := sq.Select("1").
+		From(userItemsTable).
+		Join(fmt.Sprintf("%s ON %s = %s", itemVariantsTable,
+			column(userItemsTable, userItemsVariantIDColumn), column(itemVariantsTable, itemVariantsIDColumn))).
+		Where(sq.Eq{
+			column(userItemsTable, userItemsUserIDColumn):       userID,
+			column(itemVariantsTable, itemVariantsItemIDColumn): itemID,
+		}).
+		Prefix("SELECT EXISTS (").Suffix(")").
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var owned bool
+	err = s.db.QueryRowContext(ctx, selectOwnedQuery, ownedArgs...).Scan(&owned)
+	if err != nil {
+		return nil, err
+	}
+	if !owned {
+		return nil, ErrNotOwner
+	}
+
+	// A hidden review stays hidden when edited.
+	timing := time.Now()
+	upsertReviewQuery, reviewArgs, err := sq.Insert(reviewsTable).
+		Columns(reviewsItemIDColumn, reviewsUserIDColumn, reviewsRatingColumn, reviewsTextColumn,
+			reviewsCreatedAtColumn, reviewsUpdatedAtColumn).
+		Values(itemID, userID, review.Rating, review.Text, timing, timing).
+		Suffix(fmt.Sprintf("ON CONFLICT (%s, %s) DO UPDATE SET %s RETURNING %s", reviewsItemIDColumn, reviewsUserIDColumn,
+			excludedAssignments(reviewsRatingColumn, reviewsTextColumn, reviewsUpdatedAtColumn), reviewsIDColumn)).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var reviewID int
+	err = s.db.QueryRowContext(ctx, upsertReviewQuery, reviewArgs...).Scan(&reviewID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.getReview(ctx, reviewID)
+}
+
+// GetReviews returns reviews, the latest edited first.
+func (s *storage) GetReviews(ctx context.Context, filter *models.ReviewFilter) ([]models.Review, error) {
+	selectReviews := selectReviews()
+	if filter.ItemID != nil {
+		selectReviews = selectReviews.Where(sq.Eq{fmt.Sprintf("%s.%s", reviewsTable, reviewsItemIDColumn): *filter.ItemID})
+	}
+	if !filter.IncludeHidden {
+		selectReviews = selectReviews.Where(sq.Eq{fmt.Sprintf("%s.%s", reviewsTable, reviewsHiddenAtColumn): nil})
+	}
+
+	selectReviewsQuery, reviewsArgs, err := selectReviews.PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, selectReviewsQuery, reviewsArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reviews := make([]models.Review, 0)
+	for rows.Next() {
+		review, err := scanReview(rows)
+		if err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, *review)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return reviews, nil
+}
+
+// SetReviewHidden hides a review from the catalog or shows it again.
+func (s *storage) SetReviewHidden(ctx context.Context, reviewID int, hidden bool) (*models.Review, error) {
+	var hiddenAt any
+	if hidden {
+		hiddenAt = sq.Expr(fmt.Sprintf("COALESCE(%s, ?)", reviewsHiddenAtColumn), time.Now())
+	}
+
+	updateReviewQuery, reviewUpdArgs, err := sq.Update(reviewsTable).
+		Set(reviewsHiddenAtColumn, hiddenAt).
+		Where(sq.Eq{reviewsIDColumn: reviewID}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.db.ExecContext(ctx, updateReviewQuery, reviewUpdArgs...)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if updated == 0 {
+		return nil, ErrNoReview
+	}
+
+	return s.getReview(ctx, reviewID)
+}
+
+func (s *storage) getReview(ctx context.Context, reviewID int) (*models.Review, error) {
+	selectReviewQuery, reviewArgs, err := selectReviews().
+		Where(sq.Eq{fmt.Sprintf("%s.%s", reviewsTable, reviewsIDColumn): reviewID}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	review, err := scanReview(s.db.QueryRowContext(ctx, selectReviewQuery, reviewArgs...))
+	if err == sql.ErrNoRows {
+		return nil, ErrNoReview
+	}
+
+	return review, err
+}
+
+func selectReviews() sq.SelectBuilder {
+	column := func(table, column string) string {
+		return fmt.Sprintf("%s.%s", table, column)
+	}
+
+	return sq.Select(
+		column(reviewsTable, reviewsIDColumn),
+		column(reviewsTable, reviewsItemIDColumn),
+		column(itemsTable, itemsTypeColumn),
+		column(usersTable, usersNameColumn),
+		column(reviewsTable, reviewsRatingColumn),
+		column(reviewsTable, reviewsTextColumn),
+		column(reviewsTable, reviewsCreatedAtColumn),
+		column(reviewsTable, reviewsUpdatedAtColumn),
+		fmt.Sprintf("%s IS NOT NULL", column(reviewsTable, reviewsHiddenAtColumn))).
+		From(reviewsTable).
+		Join(fmt.Sprintf("%s ON %s = %s", itemsTable, column(reviewsTable, reviewsItemIDColumn), column(itemsTable, itemsIDColumn))).
+		Join(fmt.Sprintf("%s ON %s = %s", usersTable, column(reviewsTable, reviewsUserIDColumn), column(usersTable, userIDColumn))).
+		OrderBy(column(reviewsTable, reviewsUpdatedAtColumn)+" DESC", column(reviewsTable, reviewsIDColumn)+" DESC")
+}
+
+// scanReview reads a row selected with selectReviews.
+func scanReview(row interface{ Scan(dest ...any) error }) (*models.Review, error) {
+	review := &models.Review{}
+	err := row.Scan(&review.ID, &review.ItemID, &review.Item, &review.User, &review.Rating, &review.Text,
+		&review.CreatedAt, &review.UpdatedAt, &review.Hidden)
+	if err != nil {
+		return nil, err
+	}
+
+	return review, nil
+}
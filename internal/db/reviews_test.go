@@ -0,0 +1,107 @@
+package db
+
+import (
+	"context"
+	"log"
+	"merch_shop/internal/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	selectOwnedQueryRegexp = `
+		SELECT EXISTS \( SELECT 1 FROM user_items JOIN item_variants ON user_items.variant_id = item_variants.id
+		WHERE item_variants.item_id = \$1 AND user_items.user_id = \$2 \)
+	`
+	upsertReviewQueryRegexp = `
+		INSERT INTO reviews \(item_id,user_id,rating,text,created_at,updated_at\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6\)
+		ON CONFLICT \(item_id, user_id\) DO UPDATE SET (.*) RETURNING id
+	`
+	selectReviewQueryRegexp = `
+		SELECT (.*) FROM reviews JOIN items ON (.*) JOIN users ON (.*) WHERE reviews.id = \$1
+	`
+	hideReviewQueryRegexp = `UPDATE reviews SET hidden_at = COALESCE\(hidden_at, \$1\) WHERE id = \$2`
+)
+
+var reviewColumns = []string{
+	reviewsIDColumn, reviewsItemIDColumn, itemsTypeColumn, usersNameColumn, reviewsRatingColumn, reviewsTextColumn,
+	reviewsCreatedAtColumn, reviewsUpdatedAtColumn, "hidden",
+}
+
+func TestUpsertReview(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer mockDB.Close()
+
+	db := storage{db: mockDB}
+
+	userID, itemID := 5, 4
+	request := &models.ReviewRequest{Item: "mug", Rating: 4, Text: "holds coffee"}
+	at := time.Date(2025, 2, 5, 12, 30, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name       string
+		dbBehavior func()
+
+		expected    *models.Review
+		expectedErr error
+	}{
+		{
+			name: "owner reviews the item",
+			dbBehavior: func() {
+				mock.ExpectQuery(selectOwnedQueryRegexp).WithArgs(itemID, userID).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+				mock.ExpectQuery(upsertReviewQueryRegexp).
+					WithArgs(itemID, userID, 4, "holds coffee", sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{reviewsIDColumn}).AddRow(9))
+				mock.ExpectQuery(selectReviewQueryRegexp).WithArgs(9).
+					WillReturnRows(sqlmock.NewRows(reviewColumns).AddRow(9, itemID, "mug", "alice", 4, "holds coffee", at, at, false))
+			},
+			expected: &models.Review{ID: 9, ItemID: itemID, Item: "mug", User: "alice", Rating: 4, Text: "holds coffee",
+				CreatedAt: at, UpdatedAt: at},
+		},
+		{
+			name: "user does not own the item",
+			dbBehavior: func() {
+				mock.ExpectQuery(selectOwnedQueryRegexp).WithArgs(itemID, userID).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+			},
+			expectedErr: ErrNotOwner,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.dbBehavior()
+
+			review, err := db.UpsertReview(context.Background(), userID, itemID, request)
+			assert.Equal(t, tc.expectedErr, err)
+			assert.Equal(t, tc.expected, review)
+		})
+	}
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSetReviewHidden(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer mockDB.Close()
+
+	db := storage{db: mockDB}
+
+	mock.ExpectExec(hideReviewQueryRegexp).WithArgs(sqlmock.AnyArg(), 9).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	review, err := db.SetReviewHidden(context.Background(), 9, true)
+	assert.Equal(t, ErrNoReview, err)
+	assert.Nil(t, review)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
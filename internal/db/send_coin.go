@@ -85,6 +85,31 @@ func (s *storage) SendCoinByUsername(ctx context.Context, userID int, destUserna
 		return err
 	}
 
+	upsertTotalsQuery, upsertArgs, err := sq.Insert(coinTransferTotalsTable).
+		Columns(coinTransfersSourceColumn, coinTransfersDestColumn, coinTransfersAmountColumn, coinTransferTotalsCountColumn).
+		Values(userID, destID, amount, 1).
+		Suffix(fmt.Sprintf("ON CONFLICT (%s, %s) DO UPDATE SET %s = %s.%s + EXCLUDED.%s, %s = %s.%s + 1",
+			coinTransfersSourceColumn, coinTransfersDestColumn,
+			coinTransfersAmountColumn, coinTransferTotalsTable, coinTransfersAmountColumn, coinTransfersAmountColumn,
+			coinTransferTotalsCountColumn, coinTransferTotalsTable, coinTransferTotalsCountColumn)).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		txErr := tx.Rollback()
+		if txErr != nil {
+			log.Printf("tx rollback error: %s", txErr.Error())
+		}
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, upsertTotalsQuery, upsertArgs...)
+	if err != nil {
+		txErr := tx.Rollback()
+		if txErr != nil {
+			log.Printf("tx rollback error: %s", txErr.Error())
+		}
+		return err
+	}
+
 	err = tx.Commit()
 	if err != nil {
 		return err
@@ -0,0 +1,179 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"merch_shop/internal/models"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+const (
+	movementsCTE          = "movements"
+	movementsUserIDColumn = "user_id"
+	movementsTimeColumn   = "timing"
+	movementsDeltaColumn  = "delta"
+	movementsKindColumn   = "kind"
+	movementKindGrant     = "grant"
+
+	statementMonthLayout = "2006-01"
+)
+
+// GetMonthlyStatement returns a statement precomputed by SaveMonthlyStatements.
+func (s *storage) GetMonthlyStatement(ctx context.Context, userID int, month time.Time) (*models.Statement, error) {
+	selectQuery, selArgs, err := sq.Select(statementsOpeningColumn, statementsGrantedColumn, statementsSentColumn,
+		statementsReceivedColumn, statementsPurchasesColumn, statementsRefundsColumn, statementsClosingColumn).
+		From(monthlyStatementsTable).
+		Where(sq.Eq{statementsUserIDColumn: userID, statementsMonthColumn: month}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	statement := &models.Statement{Month: month.Format(statementMonthLayout), Final: true}
+	row := s.db.QueryRowContext(ctx, selectQuery, selArgs...)
+	err = row.Scan(&statement.OpeningBalance, &statement.Granted, &statement.Sent,
+		&statement.Received, &statement.Purchases, &statement.Refunds, &statement.ClosingBalance)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNoStatement
+		}
+		return nil, err
+	}
+
+	return statement, nil
+}
+
+// ComputeMonthlyStatement builds a statement from coin transfers, purchases and refunds without storing it.
+func (s *storage) ComputeMonthlyStatement(ctx context.Context, userID int, month time.Time) (*models.Statement, error) {
+	selectStatement, err := statementsQuery(&userID, month)
+	if err != nil {
+		return nil, err
+	}
+
+	selectQuery, selArgs, err := selectStatement.PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var ownerID int
+	statement := &models.Statement{Month: month.Format(statementMonthLayout)}
+	row := s.db.QueryRowContext(ctx, selectQuery, selArgs...)
+	err = row.Scan(&ownerID, &statement.OpeningBalance, &statement.Granted, &statement.Sent,
+		&statement.Received, &statement.Purchases, &statement.Refunds, &statement.ClosingBalance)
+	// No movements at all: the user did not exist by the end of the month.
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	return statement, nil
+}
+
+// SaveMonthlyStatements computes statements of every user for the month and stores them.
+func (s *storage) SaveMonthlyStatements(ctx context.Context, month time.Time) error {
+	selectStatements, err := statementsQuery(nil, month)
+	if err != nil {
+		return err
+	}
+
+	upsertQuery, upsertArgs, err := sq.Insert(monthlyStatementsTable).
+		Columns(statementsUserIDColumn, statementsOpeningColumn, statementsGrantedColumn, statementsSentColumn,
+			statementsReceivedColumn, statementsPurchasesColumn, statementsRefundsColumn, statementsClosingColumn,
+			statementsMonthColumn, statementsGeneratedAtColumn).
+		Select(selectStatements.Column("?::date", month).Column("?", time.Now())).
+		Suffix(fmt.Sprintf("ON CONFLICT (%s, %s) DO UPDATE SET %s",
+			statementsUserIDColumn, statementsMonthColumn, excludedAssignments(statementsOpeningColumn, statementsGrantedColumn,
+				statementsSentColumn, statementsReceivedColumn, statementsPurchasesColumn, statementsRefundsColumn,
+				statementsClosingColumn, statementsGeneratedAtColumn))).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx, upsertQuery, upsertArgs...)
+	return err
+}
+
+// statementsQuery sums every balance movement up to the end of the month per user,
+// so the closing balance always reconciles with coin transfers, purchases and refunds.
+func statementsQuery(userID *int, month time.Time) (sq.SelectBuilder, error) {
+	start, end := month, month.AddDate(0, 1, 0)
+
+	branches := []sq.SelectBuilder{
+		movementsBranch(coinTransfersTable, coinTransfersSourceColumn, coinTransfersTimeColumn,
+			"-"+coinTransfersAmountColumn, models.LedgerKindSent, userID, end),
+		movementsBranch(coinTransfersTable, coinTransfersDestColumn, coinTransfersTimeColumn,
+			coinTransfersAmountColumn, models.LedgerKindReceived, userID, end),
+		movementsBranch(purchasesTable, purchasesUserIDColumn, purchasesTimeColumn,
+			fmt.Sprintf("%s - %s * %s", purchasesDiscountColumn, purchasesPriceColumn, purchasesQuantityColumn),
+			models.LedgerKindPurchase, userID, end),
+		movementsBranch(ordersTable, ordersUserIDColumn, ordersRefundedAtColumn,
+			ordersTotalColumn, models.LedgerKindRefund, userID, end),
+		// Held bids count as purchases and released ones as refunds, the winning bid is charged by its order.
+		movementsBranch(bidsTable, bidsUserIDColumn, bidsCreatedAtColumn,
+			"-"+bidsAmountColumn, models.LedgerKindPurchase, userID, end),
+		movementsBranch(bidsTable, bidsUserIDColumn, bidsReleasedAtColumn,
+			bidsAmountColumn, models.LedgerKindRefund, userID, end),
+		// Contributions pay for pooled orders, which are placed with the whole price as discount.
+		movementsBranch(contributionsTable, contributionsUserIDColumn, contributionsCreatedAtColumn,
+			"-"+contributionsAmountColumn, models.LedgerKindPurchase, userID, end),
+		movementsBranch(contributionsTable, contributionsUserIDColumn, contributionsRefundedAtColumn,
+			contributionsAmountColumn, models.LedgerKindRefund, userID, end),
+		movementsBranch(usersTable, userIDColumn, usersCreatedAtColumn,
+			fmt.Sprint(usersInitialBalance), movementKindGrant, userID, end),
+	}
+
+	queries := make([]string, 0, len(branches))
+	movementsArgs := make([]any, 0)
+	for _, branch := range branches {
+		query, args, err := branch.ToSql()
+		if err != nil {
+			return sq.SelectBuilder{}, err
+		}
+		queries = append(queries, query)
+		movementsArgs = append(movementsArgs, args...)
+	}
+
+	inMonth := func(kind string) string {
+		return fmt.Sprintf("%s >= ? AND %s = '%s'", movementsTimeColumn, movementsKindColumn, kind)
+	}
+
+	return sq.Select(movementsUserIDColumn).
+		Column(fmt.Sprintf("COALESCE(SUM(%s) FILTER (WHERE %s < ?), 0)", movementsDeltaColumn, movementsTimeColumn), start).
+		Column(fmt.Sprintf("COALESCE(SUM(%s) FILTER (WHERE %s), 0)", movementsDeltaColumn, inMonth(movementKindGrant)), start).
+		Column(fmt.Sprintf("COALESCE(-SUM(%s) FILTER (WHERE %s), 0)", movementsDeltaColumn, inMonth(models.LedgerKindSent)), start).
+		Column(fmt.Sprintf("COALESCE(SUM(%s) FILTER (WHERE %s), 0)", movementsDeltaColumn, inMonth(models.LedgerKindReceived)), start).
+		Column(fmt.Sprintf("COALESCE(-SUM(%s) FILTER (WHERE %s), 0)", movementsDeltaColumn, inMonth(models.LedgerKindPurchase)), start).
+		Column(fmt.Sprintf("COALESCE(SUM(%s) FILTER (WHERE %s), 0)", movementsDeltaColumn, inMonth(models.LedgerKindRefund)), start).
+		Column(fmt.Sprintf("COALESCE(SUM(%s), 0)", movementsDeltaColumn)).
+		PrefixExpr(sq.Expr(fmt.Sprintf("WITH %s AS (%s)", movementsCTE, strings.Join(queries, " UNION ALL ")), movementsArgs...)).
+		From(movementsCTE).
+		GroupBy(movementsUserIDColumn), nil
+}
+
+func movementsBranch(table, userColumn, timeColumn, delta, kind string, userID *int, end time.Time) sq.SelectBuilder {
+	query := sq.Select(
+		fmt.Sprintf("%s AS %s", userColumn, movementsUserIDColumn),
+		fmt.Sprintf("%s AS %s", timeColumn, movementsTimeColumn),
+		fmt.Sprintf("%s AS %s", delta, movementsDeltaColumn),
+		fmt.Sprintf("'%s' AS %s", kind, movementsKindColumn)).
+		From(table).
+		Where(sq.Lt{timeColumn: end})
+
+	if userID != nil {
+		query = query.Where(sq.Eq{userColumn: *userID})
+	}
+
+	return query
+}
+
+func excludedAssignments(columns ...string) string {
+	assignments := make([]string, 0, len(columns))
+	for _, column := range columns {
+		assignments = append(assignments, fmt.Sprintf("%s = EXCLUDED.%s", column, column))
+	}
+	return strings.Join(assignments, ", ")
+}
@@ -0,0 +1,110 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"merch_shop/internal/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	selectStatementQueryRegexp = `
+		WITH movements AS \((.*)\) SELECT (.*) FROM movements GROUP BY user_id
+	`
+	upsertStatementsQueryRegexp = `
+		INSERT INTO monthly_statements (.*) WITH movements AS \((.*)\) SELECT (.*) FROM movements GROUP BY user_id
+		ON CONFLICT \(user_id, month\) DO UPDATE SET (.*)
+	`
+)
+
+func TestComputeMonthlyStatement(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer mockDB.Close()
+
+	db := storage{db: mockDB}
+
+	month := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	statementColumns := []string{statementsUserIDColumn, statementsOpeningColumn, statementsGrantedColumn,
+		statementsSentColumn, statementsReceivedColumn, statementsPurchasesColumn, statementsRefundsColumn, statementsClosingColumn}
+
+	testCases := []struct {
+		name       string
+		expected   *models.Statement
+		dbBehavior func()
+
+		expectErr bool
+	}{
+		{
+			name: "positive result",
+			expected: &models.Statement{
+				Month: "2025-01", OpeningBalance: 500, Granted: 0, Sent: 100, Received: 30, Purchases: 80, Refunds: 20, ClosingBalance: 370,
+			},
+			dbBehavior: func() {
+				rows := sqlmock.NewRows(statementColumns).AddRow(1, 500, 0, 100, 30, 80, 20, 370)
+				mock.ExpectQuery(selectStatementQueryRegexp).WillReturnRows(rows)
+			},
+			expectErr: false,
+		},
+		{
+			name:     "user did not exist yet",
+			expected: &models.Statement{Month: "2025-01"},
+			dbBehavior: func() {
+				mock.ExpectQuery(selectStatementQueryRegexp).WillReturnError(sql.ErrNoRows)
+			},
+			expectErr: false,
+		},
+		{
+			name: "query error",
+			dbBehavior: func() {
+				mock.ExpectQuery(selectStatementQueryRegexp).WillReturnError(errors.New("some error"))
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.dbBehavior()
+
+			statement, err := db.ComputeMonthlyStatement(context.Background(), 1, month)
+			if tc.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expected, statement)
+			}
+		})
+	}
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSaveMonthlyStatements(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer mockDB.Close()
+
+	db := storage{db: mockDB}
+
+	month := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := month.AddDate(0, 1, 0)
+
+	mock.ExpectExec(upsertStatementsQueryRegexp).
+		WithArgs(end, end, end, end, end, end, end, end, end,
+			month, month, month, month, month, month, month, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	assert.NoError(t, db.SaveMonthlyStatements(context.Background(), month))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
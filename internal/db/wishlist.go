@@ -0,0 +1,290 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"merch_shop/internal/models"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// wishedItemChange is a wished item whose price or availability differs from what the user was last notified about.
+type wishedItemChange struct {
+	userID        int
+	itemID        int
+	item          string
+	seenPrice     int
+	seenAvailable bool
+	price         int
+	available     bool
+}
+
+// itemAvailable is true while any variant of an active item is in stock, as Available of scanned items.
+func itemAvailable() string {
+	column := func(table, column string) string {
+		return fmt.Sprintf("%s.%s", table, column)
+	}
+	stock := column(itemVariantsTable, itemVariantsStockColumn)
+
+	return fmt.Sprintf("(%s AND EXISTS (SELECT 1 FROM %s WHERE %s = %s AND (%s IS NULL OR %s > 0)))",
+		column(itemsTable, itemsActiveColumn), itemVariantsTable,
+		column(itemVariantsTable, itemVariantsItemIDColumn), column(itemsTable, itemsIDColumn), stock, stock)
+}
+
+// AddWishlistItem puts an item into the wishlist remembering its current price and availability,
+// so only later changes are notified. Adding an item already wished keeps it as it is.
+func (s *storage) AddWishlistItem(ctx context.Context, userID, itemID int) error {
+	column := func(table, column string) string {
+		return fmt.Sprintf("%s.%s", table, column)
+	}
+	now := time.Now()
+
+	selectItem := sq.Select().
+		Column("?::integer", userID).
+		Column(column(itemsTable, itemsIDColumn)).
+		Column("?::timestamp", now).
+		Column(column(currentPricesAlias, itemPricesPriceColumn)).
+		Column(itemAvailable()).
+		From(itemsTable)
+
+	insertWishQuery, wishInsArgs, err := sq.Insert(wishlistItemsTable).
+		Columns(wishlistItemsUserIDColumn, wishlistItemsItemIDColumn, wishlistItemsCreatedAtColumn,
+			wishlistItemsSeenPriceColumn, wishlistItemsSeenAvailableColumn).
+		Select(joinCurrentPrice(selectItem, now).Where(sq.Eq{column(itemsTable, itemsIDColumn): itemID})).
+		Suffix(fmt.Sprintf("ON CONFLICT (%s, %s) DO NOTHING", wishlistItemsUserIDColumn, wishlistItemsItemIDColumn)).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx, insertWishQuery, wishInsArgs...)
+	return err
+}
+
+func (s *storage) RemoveWishlistItem(ctx context.Context, userID, itemID int) error {
+	deleteWishQuery, wishDelArgs, err := sq.Delete(wishlistItemsTable).
+		Where(sq.Eq{wishlistItemsUserIDColumn: userID, wishlistItemsItemIDColumn: itemID}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	result, err := s.db.ExecContext(ctx, deleteWishQuery, wishDelArgs...)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrNotWished
+	}
+
+	return nil
+}
+
+// GetWishlist returns wished items priced with current catalog prices along with the balance of the user.
+func (s *storage) GetWishlist(ctx context.Context, userID int) (*models.Wishlist, error) {
+	selectBalanceQuery, balanceArgs, err := sq.Select(usersBalanceColumn).
+		From(usersTable).
+		Where(sq.Eq{userIDColumn: userID}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	wishlist := &models.Wishlist{Items: make([]models.WishlistItem, 0)}
+	err = s.db.QueryRowContext(ctx, selectBalanceQuery, balanceArgs...).Scan(&wishlist.Balance)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNoUser
+		}
+		return nil, err
+	}
+
+	column := func(table, column string) string {
+		return fmt.Sprintf("%s.%s", table, column)
+	}
+
+	query := sq.Select(
+		column(itemsTable, itemsIDColumn),
+		column(itemsTable, itemsTypeColumn),
+		column(currentPricesAlias, itemPricesPriceColumn),
+		itemAvailable(),
+		column(wishlistItemsTable, wishlistItemsCreatedAtColumn)).
+		From(wishlistItemsTable).
+		Join(fmt.Sprintf("%s ON %s = %s", itemsTable,
+			column(wishlistItemsTable, wishlistItemsItemIDColumn), column(itemsTable, itemsIDColumn)))
+
+	selectWishlistQuery, wishlistArgs, err := joinCurrentPrice(query, time.Now()).
+		Where(sq.Eq{column(wishlistItemsTable, wishlistItemsUserIDColumn): userID}).
+		OrderBy(column(wishlistItemsTable, wishlistItemsCreatedAtColumn), column(itemsTable, itemsIDColumn)).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, selectWishlistQuery, wishlistArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item models.WishlistItem
+		err := rows.Scan(&item.ItemID, &item.Item, &item.Price, &item.Available, &item.AddedAt)
+		if err != nil {
+			return nil, err
+		}
+		wishlist.Items = append(wishlist.Items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return wishlist, nil
+}
+
+// NotifyWishlists notifies users about wished items that got cheaper or came back in stock
+// since they were last notified, and remembers the state they are notified about.
+func (s *storage) NotifyWishlists(ctx context.Context, at time.Time) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	err = notifyWishlistsTx(ctx, tx, at)
+	if err != nil {
+		txErr := tx.Rollback()
+		if txErr != nil {
+			log.Printf("tx rollback error: %s", txErr.Error())
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// notifyWishlistsTx locks changed wishlist rows, so concurrent runs never notify about the same change twice:
+// a run waiting for the lock rechecks the rows and skips the ones already updated.
+func notifyWishlistsTx(ctx context.Context, tx *sql.Tx, at time.Time) error {
+	changes, err := selectWishedItemChanges(ctx, tx, at)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	insertNotifications := sq.Insert(notificationsTable).
+		Columns(notificationsUserIDColumn, notificationsKindColumn, notificationsItemIDColumn,
+			notificationsMessageColumn, notificationsCreatedAtColumn)
+	notified := false
+
+	for _, change := range changes {
+		updateWishQuery, wishUpdArgs, err := sq.Update(wishlistItemsTable).
+			Set(wishlistItemsSeenPriceColumn, change.price).
+			Set(wishlistItemsSeenAvailableColumn, change.available).
+			Where(sq.Eq{wishlistItemsUserIDColumn: change.userID, wishlistItemsItemIDColumn: change.itemID}).
+			PlaceholderFormat(sq.Dollar).ToSql()
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, updateWishQuery, wishUpdArgs...)
+		if err != nil {
+			return err
+		}
+
+		kind, message, ok := wishlistNotification(&change)
+		if ok {
+			insertNotifications = insertNotifications.Values(change.userID, kind, change.itemID, message, at)
+			notified = true
+		}
+	}
+
+	if !notified {
+		return nil
+	}
+
+	insertNotificationsQuery, notificationsInsArgs, err := insertNotifications.PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, insertNotificationsQuery, notificationsInsArgs...)
+	return err
+}
+
+func selectWishedItemChanges(ctx context.Context, tx *sql.Tx, at time.Time) ([]wishedItemChange, error) {
+	column := func(table, column string) string {
+		return fmt.Sprintf("%s.%s", table, column)
+	}
+	price := column(currentPricesAlias, itemPricesPriceColumn)
+	seenPrice := column(wishlistItemsTable, wishlistItemsSeenPriceColumn)
+	seenAvailable := column(wishlistItemsTable, wishlistItemsSeenAvailableColumn)
+
+	query := sq.Select(
+		column(wishlistItemsTable, wishlistItemsUserIDColumn),
+		column(itemsTable, itemsIDColumn),
+		column(itemsTable, itemsTypeColumn),
+		seenPrice,
+		seenAvailable,
+		price,
+		itemAvailable()).
+		From(wishlistItemsTable).
+		Join(fmt.Sprintf("%s ON %s = %s", itemsTable,
+			column(wishlistItemsTable, wishlistItemsItemIDColumn), column(itemsTable, itemsIDColumn)))
+
+	selectChangesQuery, changesArgs, err := joinCurrentPrice(query, at).
+		Where(fmt.Sprintf("(%s <> %s OR %s <> %s)", seenPrice, price, seenAvailable, itemAvailable())).
+		OrderBy(column(wishlistItemsTable, wishlistItemsUserIDColumn), column(itemsTable, itemsIDColumn)).
+		Suffix("FOR UPDATE OF " + wishlistItemsTable).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, selectChangesQuery, changesArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := make([]wishedItemChange, 0)
+	for rows.Next() {
+		var change wishedItemChange
+		err := rows.Scan(&change.userID, &change.itemID, &change.item, &change.seenPrice, &change.seenAvailable,
+			&change.price, &change.available)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// wishlistNotification describes a change worth notifying about. Items that can not be bought
+// are not notified about until they are back in stock, price rises are not notified at all.
+func wishlistNotification(change *wishedItemChange) (string, string, bool) {
+	switch {
+	case !change.available:
+		return "", "", false
+	case !change.seenAvailable:
+		return models.NotificationKindRestock,
+			fmt.Sprintf("%s is back in stock for %d coins", change.item, change.price), true
+	case change.price < change.seenPrice:
+		return models.NotificationKindPriceDrop,
+			fmt.Sprintf("%s price dropped from %d to %d coins", change.item, change.seenPrice, change.price), true
+	}
+	return "", "", false
+}
@@ -0,0 +1,103 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	selectWishedItemChangesQueryRegexp = `SELECT .+ FROM wishlist_items JOIN items .+ FOR UPDATE OF wishlist_items`
+	updateWishlistItemQueryRegexp      = `
+		UPDATE wishlist_items SET seen_price = \$1, seen_available = \$2 WHERE item_id = \$3 AND user_id = \$4
+	`
+	insertNotificationsQueryRegexp = `INSERT INTO notifications \(user_id,kind,item_id,message,created_at\) VALUES `
+)
+
+func TestNotifyWishlists(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer mockDB.Close()
+
+	db := storage{db: mockDB}
+
+	at := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	changeColumns := []string{"user_id", "id", "type", "seen_price", "seen_available", "price", "available"}
+
+	testCases := []struct {
+		name        string
+		dbBehavior  func()
+		expectedErr error
+	}{
+		{
+			name: "price drops and restocks are notified",
+			dbBehavior: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectWishedItemChangesQueryRegexp).WithArgs(at, at).
+					WillReturnRows(sqlmock.NewRows(changeColumns).
+						AddRow(1, 4, "pen", 10, true, 8, true).
+						AddRow(2, 4, "pen", 10, false, 8, true).
+						AddRow(2, 5, "cup", 20, true, 25, true).
+						AddRow(3, 6, "hat", 5, true, 4, false))
+				mock.ExpectExec(updateWishlistItemQueryRegexp).WithArgs(8, true, 4, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(updateWishlistItemQueryRegexp).WithArgs(8, true, 4, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(updateWishlistItemQueryRegexp).WithArgs(25, true, 5, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(updateWishlistItemQueryRegexp).WithArgs(4, false, 6, 3).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(insertNotificationsQueryRegexp).
+					WithArgs(1, "price_drop", 4, "pen price dropped from 10 to 8 coins", at,
+						2, "restock", 4, "pen is back in stock for 8 coins", at).
+					WillReturnResult(sqlmock.NewResult(2, 2))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "nothing changed",
+			dbBehavior: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectWishedItemChangesQueryRegexp).WithArgs(at, at).
+					WillReturnRows(sqlmock.NewRows(changeColumns))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "changes without notifications are remembered",
+			dbBehavior: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectWishedItemChangesQueryRegexp).WithArgs(at, at).
+					WillReturnRows(sqlmock.NewRows(changeColumns).AddRow(2, 5, "cup", 20, true, 25, true))
+				mock.ExpectExec(updateWishlistItemQueryRegexp).WithArgs(25, true, 5, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "notification error rolls back",
+			dbBehavior: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectWishedItemChangesQueryRegexp).WithArgs(at, at).
+					WillReturnRows(sqlmock.NewRows(changeColumns).AddRow(1, 4, "pen", 10, true, 8, true))
+				mock.ExpectExec(updateWishlistItemQueryRegexp).WithArgs(8, true, 4, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(insertNotificationsQueryRegexp).WillReturnError(errors.New("some error"))
+				mock.ExpectRollback()
+			},
+			expectedErr: errors.New("some error"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.dbBehavior()
+
+			err := db.NotifyWishlists(context.Background(), at)
+			assert.Equal(t, tc.expectedErr, err)
+		})
+	}
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"encoding/json"
+	"merch_shop/internal/models"
+	"merch_shop/pkg/response"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+func (c *Controller) GetApprovals() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orders, servErr := c.service.GetApprovals(r.Context())
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, orders)
+	}
+}
+
+func (c *Controller) ApproveOrder() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		order, servErr := c.service.ApproveOrder(r.Context(), mux.Vars(r)["id"])
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, order)
+	}
+}
+
+func (c *Controller) RejectOrder() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		order, servErr := c.service.RejectOrder(r.Context(), mux.Vars(r)["id"])
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, order)
+	}
+}
+
+func (c *Controller) GetApprovers() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		approvers, servErr := c.service.GetApprovers(r.Context())
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, approvers)
+	}
+}
+
+func (c *Controller) AddApprover() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		request := &models.ApproverRequest{}
+		err := json.NewDecoder(r.Body).Decode(request)
+		if err != nil {
+			response.MakeErrorResponseJSON(w, http.StatusBadRequest, err)
+			return
+		}
+
+		servErr := c.service.AddApprover(r.Context(), request)
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, nil)
+	}
+}
+
+func (c *Controller) RemoveApprover() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		servErr := c.service.RemoveApprover(r.Context(), mux.Vars(r)["username"])
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, nil)
+	}
+}
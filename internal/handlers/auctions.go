@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"encoding/json"
+	"merch_shop/internal/models"
+	"merch_shop/pkg/response"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+func (c *Controller) GetAuctions() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		auctions, servErr := c.service.GetAuctions(r.Context())
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, auctions)
+	}
+}
+
+func (c *Controller) GetAllAuctions() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		auctions, servErr := c.service.GetAllAuctions(r.Context())
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, auctions)
+	}
+}
+
+func (c *Controller) GetAuction() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		auction, servErr := c.service.GetAuction(r.Context(), mux.Vars(r)["id"])
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, auction)
+	}
+}
+
+func (c *Controller) CreateAuction() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		request := &models.AuctionRequest{}
+		err := json.NewDecoder(r.Body).Decode(request)
+		if err != nil {
+			response.MakeErrorResponseJSON(w, http.StatusBadRequest, err)
+			return
+		}
+
+		auction, servErr := c.service.CreateAuction(r.Context(), request)
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusCreated, auction)
+	}
+}
+
+func (c *Controller) PlaceBid() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		request := &models.BidRequest{}
+		err := json.NewDecoder(r.Body).Decode(request)
+		if err != nil {
+			response.MakeErrorResponseJSON(w, http.StatusBadRequest, err)
+			return
+		}
+
+		auction, servErr := c.service.PlaceBid(r.Context(), mux.Vars(r)["id"], request)
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, auction)
+	}
+}
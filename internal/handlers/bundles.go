@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"encoding/json"
+	"merch_shop/internal/models"
+	"merch_shop/pkg/response"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+func (c *Controller) GetBundles() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bundles, servErr := c.service.GetBundles(r.Context())
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, bundles)
+	}
+}
+
+func (c *Controller) GetAllBundles() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bundles, servErr := c.service.GetAllBundles(r.Context())
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, bundles)
+	}
+}
+
+func (c *Controller) CreateBundle() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		request := &models.BundleRequest{}
+		err := json.NewDecoder(r.Body).Decode(request)
+		if err != nil {
+			response.MakeErrorResponseJSON(w, http.StatusBadRequest, err)
+			return
+		}
+
+		bundle, servErr := c.service.CreateBundle(r.Context(), request)
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusCreated, bundle)
+	}
+}
+
+func (c *Controller) DeactivateBundle() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bundle, servErr := c.service.DeactivateBundle(r.Context(), mux.Vars(r)["id"])
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, bundle)
+	}
+}
+
+func (c *Controller) BuyBundle() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		summary, servErr := c.service.BuyBundle(r.Context(), mux.Vars(r)["id"], r.URL.Query().Get("quantity"))
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, summary)
+	}
+}
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"encoding/json"
+	"merch_shop/internal/models"
 	"merch_shop/pkg/response"
 	"net/http"
 
@@ -9,12 +11,34 @@ import (
 
 func (c *Controller) BuyItem() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		itemID := mux.Vars(r)["item"]
+		item := mux.Vars(r)["item"]
 
-		servErr := c.service.BuyItem(r.Context(), itemID)
+		query := r.URL.Query()
+		summary, servErr := c.service.BuyItem(r.Context(), item, query.Get("variant"), query.Get("quantity"), query.Get("promo"))
 		if servErr != nil {
 			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
 			return
 		}
+
+		response.MakeResponseJSON(w, http.StatusOK, summary)
+	}
+}
+
+func (c *Controller) BuyGift() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		request := &models.GiftRequest{}
+		err := json.NewDecoder(r.Body).Decode(request)
+		if err != nil {
+			response.MakeErrorResponseJSON(w, http.StatusBadRequest, err)
+			return
+		}
+
+		summary, servErr := c.service.BuyGift(r.Context(), request)
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, summary)
 	}
 }
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"encoding/json"
+	"merch_shop/internal/models"
+	"merch_shop/pkg/response"
+	"net/http"
+)
+
+func (c *Controller) AddCartItem() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		request := &models.CartItemRequest{}
+		err := json.NewDecoder(r.Body).Decode(request)
+		if err != nil {
+			response.MakeErrorResponseJSON(w, http.StatusBadRequest, err)
+			return
+		}
+
+		servErr := c.service.AddCartItem(r.Context(), request)
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, nil)
+	}
+}
+
+func (c *Controller) GetCart() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cart, servErr := c.service.GetCart(r.Context())
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, cart)
+	}
+}
+
+func (c *Controller) CheckoutCart() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		summary, servErr := c.service.CheckoutCart(r.Context(), r.URL.Query().Get("promo"))
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, summary)
+	}
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"encoding/json"
+	"merch_shop/internal/models"
+	"merch_shop/pkg/response"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+func (c *Controller) GetCollections() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		collections, servErr := c.service.GetCollections(r.Context())
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, collections)
+	}
+}
+
+func (c *Controller) GetAllCollections() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		collections, servErr := c.service.GetAllCollections(r.Context())
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, collections)
+	}
+}
+
+func (c *Controller) GetCollection() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		collection, servErr := c.service.GetCollection(r.Context(), mux.Vars(r)["id"])
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, collection)
+	}
+}
+
+func (c *Controller) CreateCollection() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		request := &models.CollectionRequest{}
+		err := json.NewDecoder(r.Body).Decode(request)
+		if err != nil {
+			response.MakeErrorResponseJSON(w, http.StatusBadRequest, err)
+			return
+		}
+
+		collection, servErr := c.service.CreateCollection(r.Context(), request)
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusCreated, collection)
+	}
+}
+
+func (c *Controller) Contribute() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		request := &models.ContributionRequest{}
+		err := json.NewDecoder(r.Body).Decode(request)
+		if err != nil {
+			response.MakeErrorResponseJSON(w, http.StatusBadRequest, err)
+			return
+		}
+
+		collection, servErr := c.service.Contribute(r.Context(), mux.Vars(r)["id"], request)
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, collection)
+	}
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"merch_shop/internal/models"
+	"merch_shop/pkg/response"
+	"merch_shop/pkg/xerrors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type exportFunc func(ctx context.Context, request *models.ExportRequest, write func(*models.LedgerEntry) error) xerrors.Xerror
+
+var ledgerCSVHeader = []string{"id", "kind", "user", "counterparty", "item", "amount", "timestamp"}
+
+func (c *Controller) ExportHistory() http.HandlerFunc {
+	return exportLedger("history", c.service.ExportHistory)
+}
+
+func (c *Controller) ExportAllHistory() http.HandlerFunc {
+	return exportLedger("all_history", c.service.ExportAllHistory)
+}
+
+func exportLedger(filenamePrefix string, export exportFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		request := &models.ExportRequest{
+			Format: query.Get("format"),
+			From:   query.Get("from"),
+			To:     query.Get("to"),
+		}
+
+		// The stream is opened on the first entry, so validation errors
+		// are still answered with a regular JSON error.
+		var stream response.Stream
+		openStream := func() error {
+			var err error
+			filename := fmt.Sprintf("%s_%s.%s", filenamePrefix, time.Now().Format("20060102150405"), request.Format)
+			if request.Format == models.ExportFormatJSON {
+				stream, err = response.NewJSONStream(w, filename)
+			} else {
+				stream, err = response.NewCSVStream(w, filename, ledgerCSVHeader, ledgerEntryToCSV)
+			}
+			return err
+		}
+
+		servErr := export(r.Context(), request, func(entry *models.LedgerEntry) error {
+			if stream == nil {
+				if err := openStream(); err != nil {
+					return err
+				}
+			}
+			return stream.Write(entry)
+		})
+		if servErr != nil {
+			if stream == nil {
+				response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			}
+			// Headers are already sent, the client gets a truncated file.
+			return
+		}
+
+		if stream == nil {
+			if err := openStream(); err != nil {
+				log.Printf("export stream error: %s", err.Error())
+				return
+			}
+		}
+		if err := stream.Close(); err != nil {
+			log.Printf("export stream error: %s", err.Error())
+		}
+	}
+}
+
+func ledgerEntryToCSV(row any) []string {
+	entry := row.(*models.LedgerEntry)
+	return []string{
+		entry.ID,
+		entry.Kind,
+		entry.Username,
+		entry.Counterparty,
+		entry.Item,
+		strconv.Itoa(entry.Amount),
+		entry.Timing.Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"merch_shop/internal/models"
+	"merch_shop/pkg/response"
+	"net/http"
+)
+
+func (c *Controller) GetHistory() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		request := &models.TransferHistoryRequest{
+			Direction:    query.Get("direction"),
+			Counterparty: query.Get("counterparty"),
+			From:         query.Get("from"),
+			To:           query.Get("to"),
+			MinAmount:    query.Get("minAmount"),
+			MaxAmount:    query.Get("maxAmount"),
+			Cursor:       query.Get("cursor"),
+			Limit:        query.Get("limit"),
+		}
+
+		page, servErr := c.service.GetTransferHistory(r.Context(), request)
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, page)
+	}
+}
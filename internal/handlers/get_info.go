@@ -7,7 +7,7 @@ import (
 
 func (c *Controller) GetInfo() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		info, servErr := c.service.GetInfo(r.Context())
+		info, servErr := c.service.GetInfo(r.Context(), r.URL.Query().Get("grouped"))
 		if servErr != nil {
 			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
 			return
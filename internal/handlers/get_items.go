@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"merch_shop/pkg/response"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+func (c *Controller) GetItems() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		items, servErr := c.service.GetItems(r.Context())
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, items)
+	}
+}
+
+func (c *Controller) GetItem() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		itemID := mux.Vars(r)["id"]
+
+		item, servErr := c.service.GetItem(r.Context(), itemID)
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, item)
+	}
+}
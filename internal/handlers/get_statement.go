@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"merch_shop/pkg/response"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+func (c *Controller) GetStatement() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		month := mux.Vars(r)["month"]
+
+		statement, servErr := c.service.GetStatement(r.Context(), month)
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, statement)
+	}
+}
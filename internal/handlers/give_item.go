@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"encoding/json"
+	"merch_shop/internal/models"
+	"merch_shop/pkg/response"
+	"net/http"
+)
+
+func (c *Controller) GiveItem() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		request := &models.GiveItemRequest{}
+		err := json.NewDecoder(r.Body).Decode(request)
+		if err != nil {
+			response.MakeErrorResponseJSON(w, http.StatusBadRequest, err)
+			return
+		}
+
+		servErr := c.service.GiveItem(r.Context(), request)
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, nil)
+	}
+}
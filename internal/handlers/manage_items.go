@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"encoding/json"
+	"merch_shop/internal/models"
+	"merch_shop/pkg/response"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+func (c *Controller) GetAllItems() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		items, servErr := c.service.GetAllItems(r.Context())
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, items)
+	}
+}
+
+func (c *Controller) CreateItem() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		request := &models.ItemRequest{}
+		err := json.NewDecoder(r.Body).Decode(request)
+		if err != nil {
+			response.MakeErrorResponseJSON(w, http.StatusBadRequest, err)
+			return
+		}
+
+		item, servErr := c.service.CreateItem(r.Context(), request)
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusCreated, item)
+	}
+}
+
+func (c *Controller) UpdateItem() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		request := &models.ItemRequest{}
+		err := json.NewDecoder(r.Body).Decode(request)
+		if err != nil {
+			response.MakeErrorResponseJSON(w, http.StatusBadRequest, err)
+			return
+		}
+
+		item, servErr := c.service.UpdateItem(r.Context(), mux.Vars(r)["id"], request)
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, item)
+	}
+}
+
+func (c *Controller) CreateVariant() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		request := &models.VariantRequest{}
+		err := json.NewDecoder(r.Body).Decode(request)
+		if err != nil {
+			response.MakeErrorResponseJSON(w, http.StatusBadRequest, err)
+			return
+		}
+
+		item, servErr := c.service.CreateVariant(r.Context(), mux.Vars(r)["id"], request)
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusCreated, item)
+	}
+}
+
+func (c *Controller) UpdateVariant() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		request := &models.VariantRequest{}
+		err := json.NewDecoder(r.Body).Decode(request)
+		if err != nil {
+			response.MakeErrorResponseJSON(w, http.StatusBadRequest, err)
+			return
+		}
+
+		item, servErr := c.service.UpdateVariant(r.Context(), mux.Vars(r)["id"], request)
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, item)
+	}
+}
+
+func (c *Controller) RestockVariant() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		request := &models.RestockRequest{}
+		err := json.NewDecoder(r.Body).Decode(request)
+		if err != nil {
+			response.MakeErrorResponseJSON(w, http.StatusBadRequest, err)
+			return
+		}
+
+		item, servErr := c.service.RestockVariant(r.Context(), mux.Vars(r)["id"], request.Quantity)
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, item)
+	}
+}
+
+func (c *Controller) GetLowStockVariants() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		variants, servErr := c.service.GetLowStockVariants(r.Context(), r.URL.Query().Get("threshold"))
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, variants)
+	}
+}
+
+func (c *Controller) RetireItem() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		item, servErr := c.service.RetireItem(r.Context(), mux.Vars(r)["id"])
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, item)
+	}
+}
+
+func (c *Controller) RestoreItem() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		item, servErr := c.service.RestoreItem(r.Context(), mux.Vars(r)["id"])
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, item)
+	}
+}
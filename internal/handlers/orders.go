@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"merch_shop/pkg/response"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+func (c *Controller) GetOrders() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orders, servErr := c.service.GetOrders(r.Context(), r.URL.Query().Get("status"))
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, orders)
+	}
+}
+
+func (c *Controller) GetAllOrders() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orders, servErr := c.service.GetAllOrders(r.Context(), r.URL.Query().Get("status"))
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, orders)
+	}
+}
+
+func (c *Controller) CancelOrder() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		order, servErr := c.service.CancelOrder(r.Context(), mux.Vars(r)["id"])
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, order)
+	}
+}
+
+func (c *Controller) RefundOrder() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		order, servErr := c.service.RefundOrder(r.Context(), mux.Vars(r)["id"])
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, order)
+	}
+}
+
+func (c *Controller) AdvanceOrder() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		order, servErr := c.service.AdvanceOrder(r.Context(), mux.Vars(r)["id"])
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, order)
+	}
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"encoding/json"
+	"merch_shop/internal/models"
+	"merch_shop/pkg/response"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+func (c *Controller) SchedulePrice() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		request := &models.PriceRequest{}
+		err := json.NewDecoder(r.Body).Decode(request)
+		if err != nil {
+			response.MakeErrorResponseJSON(w, http.StatusBadRequest, err)
+			return
+		}
+
+		price, servErr := c.service.SchedulePrice(r.Context(), mux.Vars(r)["id"], request)
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusCreated, price)
+	}
+}
+
+func (c *Controller) GetPriceHistory() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		prices, servErr := c.service.GetPriceHistory(r.Context(), mux.Vars(r)["id"])
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, prices)
+	}
+}
+
+func (c *Controller) GetItemPrices() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		prices, servErr := c.service.GetItemPrices(r.Context(), mux.Vars(r)["id"])
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, prices)
+	}
+}
+
+func (c *Controller) CancelScheduledPrice() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		servErr := c.service.CancelScheduledPrice(r.Context(), mux.Vars(r)["id"])
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, nil)
+	}
+}
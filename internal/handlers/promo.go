@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"encoding/json"
+	"merch_shop/internal/models"
+	"merch_shop/pkg/response"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+func (c *Controller) CreatePromo() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		request := &models.PromoRequest{}
+		err := json.NewDecoder(r.Body).Decode(request)
+		if err != nil {
+			response.MakeErrorResponseJSON(w, http.StatusBadRequest, err)
+			return
+		}
+
+		promo, servErr := c.service.CreatePromo(r.Context(), request)
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusCreated, promo)
+	}
+}
+
+func (c *Controller) GetPromos() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		promos, servErr := c.service.GetPromos(r.Context())
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, promos)
+	}
+}
+
+func (c *Controller) DeactivatePromo() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		promo, servErr := c.service.DeactivatePromo(r.Context(), mux.Vars(r)["id"])
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, promo)
+	}
+}
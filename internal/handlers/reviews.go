@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"encoding/json"
+	"merch_shop/internal/models"
+	"merch_shop/pkg/response"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+func (c *Controller) GetItemReviews() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		reviews, servErr := c.service.GetItemReviews(r.Context(), mux.Vars(r)["id"])
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, reviews)
+	}
+}
+
+func (c *Controller) GetAllReviews() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		reviews, servErr := c.service.GetAllReviews(r.Context())
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, reviews)
+	}
+}
+
+func (c *Controller) PostReview() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		request := &models.ReviewRequest{}
+		err := json.NewDecoder(r.Body).Decode(request)
+		if err != nil {
+			response.MakeErrorResponseJSON(w, http.StatusBadRequest, err)
+			return
+		}
+
+		review, servErr := c.service.PostReview(r.Context(), request)
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, review)
+	}
+}
+
+func (c *Controller) HideReview() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		review, servErr := c.service.HideReview(r.Context(), mux.Vars(r)["id"])
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, review)
+	}
+}
+
+func (c *Controller) ShowReview() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		review, servErr := c.service.ShowReview(r.Context(), mux.Vars(r)["id"])
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, review)
+	}
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"encoding/json"
+	"merch_shop/internal/models"
+	"merch_shop/pkg/response"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+func (c *Controller) AddWishlistItem() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		request := &models.WishlistItemRequest{}
+		err := json.NewDecoder(r.Body).Decode(request)
+		if err != nil {
+			response.MakeErrorResponseJSON(w, http.StatusBadRequest, err)
+			return
+		}
+
+		servErr := c.service.AddWishlistItem(r.Context(), request)
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, nil)
+	}
+}
+
+func (c *Controller) RemoveWishlistItem() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		servErr := c.service.RemoveWishlistItem(r.Context(), mux.Vars(r)["id"])
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, nil)
+	}
+}
+
+func (c *Controller) GetWishlist() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		wishlist, servErr := c.service.GetWishlist(r.Context())
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, wishlist)
+	}
+}
+
+func (c *Controller) GetNotifications() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		notifications, servErr := c.service.GetNotifications(r.Context(), r.URL.Query().Get("unread"))
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, notifications)
+	}
+}
+
+func (c *Controller) MarkNotificationRead() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		servErr := c.service.MarkNotificationRead(r.Context(), mux.Vars(r)["id"])
+		if servErr != nil {
+			response.MakeErrorResponseJSON(w, servErr.Code(), servErr)
+			return
+		}
+
+		response.MakeResponseJSON(w, http.StatusOK, nil)
+	}
+}
@@ -0,0 +1,14 @@
+package jobs
+
+import (
+	"context"
+	"merch_shop/internal/db"
+	"time"
+)
+
+// Approvals rejects orders left pending approval for longer than timeout, releasing the held coins.
+func Approvals(storage db.DB, timeout time.Duration) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		return storage.ExpireApprovals(ctx, time.Now().Add(-timeout))
+	}
+}
@@ -0,0 +1,14 @@
+package jobs
+
+import (
+	"context"
+	"merch_shop/internal/db"
+	"time"
+)
+
+// Auctions closes ended auctions, charging the winners and returning unsold lots to stock.
+func Auctions(storage db.DB) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		return storage.CloseAuctions(ctx, time.Now())
+	}
+}
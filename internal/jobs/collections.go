@@ -0,0 +1,14 @@
+package jobs
+
+import (
+	"context"
+	"merch_shop/internal/db"
+	"time"
+)
+
+// Collections refunds contributions of collections whose deadline has passed before the target was reached.
+func Collections(storage db.DB) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		return storage.ExpireCollections(ctx, time.Now())
+	}
+}
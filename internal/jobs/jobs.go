@@ -0,0 +1,61 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Scheduler runs background tasks until it is stopped.
+type Scheduler struct {
+	logger *slog.Logger
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func New(logger *slog.Logger) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{
+		logger: logger,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Schedule runs task at every moment returned by next, which gets the current time.
+// Task errors are logged and do not stop the schedule.
+func (s *Scheduler) Schedule(name string, next func(now time.Time) time.Time, task func(ctx context.Context) error) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		for {
+			timer := time.NewTimer(time.Until(next(time.Now())))
+			select {
+			case <-s.ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+
+			if err := task(s.ctx); err != nil {
+				s.logger.Error(name + " job: " + err.Error())
+			}
+		}
+	}()
+}
+
+// Stop cancels running tasks and waits for them to return.
+func (s *Scheduler) Stop() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// Every schedules a task with a fixed interval.
+func Every(interval time.Duration) func(now time.Time) time.Time {
+	return func(now time.Time) time.Time {
+		return now.Add(interval)
+	}
+}
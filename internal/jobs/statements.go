@@ -0,0 +1,25 @@
+package jobs
+
+import (
+	"context"
+	"merch_shop/internal/db"
+	"time"
+)
+
+// Statements are generated shortly after a month ends, when late transactions are committed.
+const statementsDelay = 5 * time.Minute
+
+// NextMonthStart schedules a task right after the beginning of every month (UTC).
+func NextMonthStart(now time.Time) time.Time {
+	now = now.UTC()
+	return time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC).Add(statementsDelay)
+}
+
+// Statements precomputes statements of every user for the month that just ended.
+func Statements(storage db.DB) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		now := time.Now().UTC()
+		previousMonth := time.Date(now.Year(), now.Month()-1, 1, 0, 0, 0, 0, time.UTC)
+		return storage.SaveMonthlyStatements(ctx, previousMonth)
+	}
+}
@@ -0,0 +1,14 @@
+package jobs
+
+import (
+	"context"
+	"merch_shop/internal/db"
+	"time"
+)
+
+// Wishlists notifies users about price drops and restocks of wished items.
+func Wishlists(storage db.DB) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		return storage.NotifyWishlists(ctx, time.Now())
+	}
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Auction sells one unit of an item variant to the highest bidder once EndsAt has passed.
+type Auction struct {
+	ID           int    `json:"id"`
+	Item         string `json:"item"`
+	Variant      string `json:"variant"`
+	StartingBid  int    `json:"startingBid"`
+	MinIncrement int    `json:"minIncrement"`
+	// HighestBid is 0 until the first bid, Leader is the highest bidder and the winner of a closed auction.
+	HighestBid int    `json:"highestBid"`
+	Leader     string `json:"leader,omitempty"`
+	Bids       int    `json:"bids"`
+	// MinBid is the smallest bid accepted now.
+	MinBid    int        `json:"minBid"`
+	CreatedAt time.Time  `json:"createdAt"`
+	EndsAt    time.Time  `json:"endsAt"`
+	ClosedAt  *time.Time `json:"closedAt,omitempty"`
+}
+
+// AuctionRequest puts an item variant up for auction, a missing increment is 1.
+type AuctionRequest struct {
+	Item         string    `json:"item"`
+	Variant      string    `json:"variant,omitempty"`
+	StartingBid  int       `json:"startingBid"`
+	MinIncrement int       `json:"minIncrement,omitempty"`
+	EndsAt       time.Time `json:"endsAt"`
+}
+
+type BidRequest struct {
+	Amount int `json:"amount"`
+}
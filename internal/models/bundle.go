@@ -0,0 +1,31 @@
+package models
+
+// Bundle sells several item variants together at its own price.
+type Bundle struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Price       int    `json:"price"`
+	// RegularPrice is what the components cost now when bought separately.
+	RegularPrice int          `json:"regularPrice"`
+	Active       bool         `json:"active"`
+	Items        []BundleItem `json:"items"`
+	// Available is true while the bundle is active and every component can be bought in its quantity.
+	Available bool `json:"available"`
+}
+
+type BundleItem struct {
+	ItemID    int    `json:"itemId"`
+	Item      string `json:"item"`
+	VariantID int    `json:"variantId"`
+	Variant   string `json:"variant"`
+	Quantity  int    `json:"quantity"`
+}
+
+// BundleRequest describes a bundle created by an admin, components are selected as cart items.
+type BundleRequest struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Price       int               `json:"price"`
+	Items       []CartItemRequest `json:"items"`
+}
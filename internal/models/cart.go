@@ -0,0 +1,45 @@
+package models
+
+// CartLine is an item variant and its quantity to be bought.
+type CartLine struct {
+	VariantID int
+	Quantity  int
+}
+
+// CartItemRequest selects a variant by name or SKU, it may be omitted for items with a single variant.
+type CartItemRequest struct {
+	Item     string `json:"item"`
+	Variant  string `json:"variant,omitempty"`
+	Quantity int    `json:"quantity"`
+}
+
+// CartItem is a cart line priced with the current catalog price.
+type CartItem struct {
+	Item      string `json:"item"`
+	Variant   string `json:"variant"`
+	Quantity  int    `json:"quantity"`
+	Price     int    `json:"price"`
+	Total     int    `json:"total"`
+	Available bool   `json:"available"`
+}
+
+type Cart struct {
+	Items []CartItem `json:"items"`
+	Total int        `json:"total"`
+}
+
+// OrderLine is a bought variant, Total is the price of all units less the discount.
+type OrderLine struct {
+	Item     string `json:"item"`
+	Variant  string `json:"variant"`
+	Quantity int    `json:"quantity"`
+	Price    int    `json:"price"`
+	Discount int    `json:"discount"`
+	Total    int    `json:"total"`
+}
+
+// OrderSummary describes a placed order, Balance is left after it.
+type OrderSummary struct {
+	Order
+	Balance int `json:"balance"`
+}
@@ -0,0 +1,74 @@
+package models
+
+import "time"
+
+type CatalogItem struct {
+	ID   int    `json:"id"`
+	Type string `json:"type"`
+	// Price is the price in effect now, the sale price while a sale lasts.
+	Price int `json:"price"`
+	// PriceValidUntil is when the price may change next, as a scheduled price or a sale starts or ends.
+	// It is nil while nothing is scheduled.
+	PriceValidUntil *time.Time `json:"priceValidUntil,omitempty"`
+	Description     string     `json:"description"`
+	Category        string     `json:"category"`
+	// Active is false for retired items: they are no longer sold,
+	// but stay in inventories and purchase history.
+	Active   bool          `json:"active"`
+	Variants []ItemVariant `json:"variants"`
+	// Available is true while any variant of an active item can be bought.
+	Available     bool           `json:"available"`
+	PurchaseLimit *PurchaseLimit `json:"purchaseLimit,omitempty"`
+	// Rating is the average of visible reviews rounded to tenths, 0 while there are none.
+	Rating  float64 `json:"rating"`
+	Reviews int     `json:"reviews"`
+}
+
+// PurchaseLimit caps how many pieces of an item one user can buy within a rolling window of days.
+type PurchaseLimit struct {
+	Quantity   int `json:"quantity"`
+	WindowDays int `json:"windowDays"`
+}
+
+// ItemVariant is a version of an item that is actually bought, e.g. a size or a colour.
+type ItemVariant struct {
+	ID     int    `json:"id"`
+	ItemID int    `json:"itemId"`
+	Item   string `json:"item,omitempty"`
+	SKU    string `json:"sku"`
+	Name   string `json:"name"`
+	// Price is PriceOverride when set, the item price otherwise.
+	Price         int  `json:"price"`
+	PriceOverride *int `json:"priceOverride,omitempty"`
+	// Stock is nil for variants that are not tracked and never run out.
+	Stock     *int `json:"stock"`
+	Available bool `json:"available"`
+}
+
+// ItemRequest describes an item created or updated by an admin.
+type ItemRequest struct {
+	Type string `json:"type"`
+	// Price becomes the regular price from now on if it differs from the current one.
+	Price       int    `json:"price"`
+	Description string `json:"description"`
+	// Category groups items for promo codes, it may be empty.
+	Category string `json:"category,omitempty"`
+	// Stock of the default variant, only applied on creation.
+	Stock *int `json:"stock,omitempty"`
+	// PurchaseLimit is replaced on update, nil removes the limit.
+	PurchaseLimit *PurchaseLimit `json:"purchaseLimit,omitempty"`
+}
+
+// VariantRequest describes a variant created or updated by an admin.
+type VariantRequest struct {
+	SKU  string `json:"sku"`
+	Name string `json:"name"`
+	// Price overrides the item price, nil sells the variant at the item price.
+	Price *int `json:"price,omitempty"`
+	// Stock is only applied on creation, use restock to change it later.
+	Stock *int `json:"stock,omitempty"`
+}
+
+type RestockRequest struct {
+	Quantity int `json:"quantity"`
+}
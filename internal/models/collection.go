@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Collection pools coins of users to buy an item variant for the recipient.
+// Target is the price of the variant when the collection was started.
+type Collection struct {
+	ID           int       `json:"id"`
+	Creator      string    `json:"creator"`
+	Recipient    string    `json:"recipient"`
+	Item         string    `json:"item"`
+	Variant      string    `json:"variant"`
+	Target       int       `json:"target"`
+	Raised       int       `json:"raised"`
+	Contributors int       `json:"contributors"`
+	Deadline     time.Time `json:"deadline"`
+	CreatedAt    time.Time `json:"createdAt"`
+	// CompletedAt is set once the target is reached and OrderID is the order placed for the recipient.
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+	OrderID     *int       `json:"orderId,omitempty"`
+	// RefundedAt is set when the deadline passed before the target was reached.
+	RefundedAt *time.Time `json:"refundedAt,omitempty"`
+}
+
+// CollectionRequest starts a collection for the recipient, the variant may be omitted for items having only one.
+type CollectionRequest struct {
+	Recipient string    `json:"recipient"`
+	Item      string    `json:"item"`
+	Variant   string    `json:"variant,omitempty"`
+	Deadline  time.Time `json:"deadline"`
+}
+
+type ContributionRequest struct {
+	Amount int `json:"amount"`
+}
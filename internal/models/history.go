@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type CoinTransferHistory struct {
 	Recieved []IngoingCoinTransfer  `json:"recieved"`
 	Sent     []OutgoingCoinTransfer `json:"sent"`
@@ -14,3 +16,72 @@ type OutgoingCoinTransfer struct {
 	Username string `json:"toUser"`
 	Amount   int    `json:"amount"`
 }
+
+// ItemTransferHistory lists items given to and received from other users, oldest first.
+type ItemTransferHistory struct {
+	Received []IngoingItemTransfer  `json:"received"`
+	Sent     []OutgoingItemTransfer `json:"sent"`
+}
+
+type IngoingItemTransfer struct {
+	Username string `json:"fromUser"`
+	Type     string `json:"type"`
+	Variant  string `json:"variant"`
+	Quantity int    `json:"quantity"`
+}
+
+type OutgoingItemTransfer struct {
+	Username string `json:"toUser"`
+	Type     string `json:"type"`
+	Variant  string `json:"variant"`
+	Quantity int    `json:"quantity"`
+}
+
+const (
+	TransferDirectionSent     = "sent"
+	TransferDirectionReceived = "received"
+)
+
+type Transfer struct {
+	ID        int       `json:"id"`
+	Direction string    `json:"direction"`
+	Username  string    `json:"user"`
+	Amount    int       `json:"amount"`
+	Timing    time.Time `json:"timestamp"`
+}
+
+type TransferPage struct {
+	Transfers  []Transfer `json:"transfers"`
+	NextCursor string     `json:"nextCursor,omitempty"`
+}
+
+// TransferHistoryRequest holds raw query parameters of the history endpoint.
+type TransferHistoryRequest struct {
+	Direction    string
+	Counterparty string
+	From         string
+	To           string
+	MinAmount    string
+	MaxAmount    string
+	Cursor       string
+	Limit        string
+}
+
+// TransferFilter is a validated TransferHistoryRequest.
+// Since is inclusive, Until is exclusive, nil bounds are not applied.
+type TransferFilter struct {
+	Direction    string
+	Counterparty string
+	Since        *time.Time
+	Until        *time.Time
+	MinAmount    *int
+	MaxAmount    *int
+	After        *TransferCursor
+	Limit        int
+}
+
+// TransferCursor points to the last entry of the previous page.
+type TransferCursor struct {
+	Timing time.Time
+	ID     int
+}
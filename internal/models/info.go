@@ -4,4 +4,5 @@ type Info struct {
 	Balance         int                 `json:"coins"`
 	Inventory       []Item              `json:"inventory"`
 	TransferHistory CoinTransferHistory `json:"coinHistory"`
+	ItemHistory     ItemTransferHistory `json:"itemHistory"`
 }
@@ -2,5 +2,14 @@ package models
 
 type Item struct {
 	Type     string `json:"type"`
-	Quantity string `json:"quantity"`
+	Variant  string `json:"variant"`
+	Quantity int    `json:"quantity"`
+}
+
+// GiveItemRequest moves owned items to the inventory of another user.
+type GiveItemRequest struct {
+	ToUser   string `json:"toUser"`
+	Item     string `json:"item"`
+	Variant  string `json:"variant,omitempty"`
+	Quantity int    `json:"quantity"`
 }
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+const (
+	LedgerKindSent     = "sent"
+	LedgerKindReceived = "received"
+	LedgerKindPurchase = "purchase"
+	LedgerKindGift     = "gift"
+	LedgerKindRefund   = "refund"
+	// Coins of a bid are held until the bid is released: outbid, or charged as the winning order.
+	LedgerKindBid        = "bid"
+	LedgerKindBidRelease = "bid_release"
+	// Contributions to a group gift pay for its order, they are refunded if the deadline passes first.
+	LedgerKindContribution       = "contribution"
+	LedgerKindContributionRefund = "contribution_refund"
+
+	ExportFormatCSV  = "csv"
+	ExportFormatJSON = "json"
+)
+
+// LedgerEntry is a single change of a user balance. Amount is negative for spent coins.
+// ID is the kind followed by the id of the source row, e.g. "purchase-12", unique across the ledger.
+type LedgerEntry struct {
+	ID           string    `json:"id"`
+	Kind         string    `json:"kind"`
+	Username     string    `json:"user"`
+	Counterparty string    `json:"counterparty,omitempty"`
+	Item         string    `json:"item,omitempty"`
+	Amount       int       `json:"amount"`
+	Timing       time.Time `json:"timestamp"`
+}
+
+// ExportRequest holds raw query parameters of the export endpoints.
+type ExportRequest struct {
+	Format string
+	From   string
+	To     string
+}
+
+// LedgerFilter selects ledger entries of one user, or of everyone if UserID is nil.
+// Since is inclusive, Until is exclusive, nil bounds are not applied.
+type LedgerFilter struct {
+	UserID *int
+	Since  *time.Time
+	Until  *time.Time
+}
@@ -0,0 +1,92 @@
+package models
+
+import "time"
+
+const (
+	OrderStatusPlaced         = "placed"
+	OrderStatusPacked         = "packed"
+	OrderStatusReadyForPickup = "ready_for_pickup"
+	OrderStatusDelivered      = "delivered"
+	OrderStatusCancelled      = "cancelled"
+	// OrderStatusPendingApproval holds the coins of an expensive order until an approver decides on it.
+	// Approved orders are placed, rejected and expired ones are cancelled.
+	OrderStatusPendingApproval = "pending_approval"
+)
+
+// NextOrderStatus is the fulfilment lifecycle: each status is advanced to the next one
+// until the order is delivered. Delivered and cancelled orders are final.
+var NextOrderStatus = map[string]string{
+	OrderStatusPlaced:         OrderStatusPacked,
+	OrderStatusPacked:         OrderStatusReadyForPickup,
+	OrderStatusReadyForPickup: OrderStatusDelivered,
+}
+
+type Order struct {
+	ID     int         `json:"id"`
+	User   string      `json:"user,omitempty"`
+	Status string      `json:"status"`
+	Items  []OrderLine `json:"items"`
+	// Promo is the code applied to the order, Total is already discounted.
+	Promo           string    `json:"promo,omitempty"`
+	Discount        int       `json:"discount"`
+	Total           int       `json:"total"`
+	CreatedAt       time.Time `json:"createdAt"`
+	StatusUpdatedAt time.Time `json:"statusUpdatedAt"`
+	// Gift is set for orders bought for another user, User is the one who paid.
+	Gift *OrderGift `json:"gift,omitempty"`
+	// Bundle is the name of the bundle bought with the order, its saving is included in Discount.
+	Bundle string `json:"bundle,omitempty"`
+}
+
+type OrderGift struct {
+	To   string `json:"to"`
+	Note string `json:"note,omitempty"`
+}
+
+// GiftRequest buys an item into the inventory of another user.
+type GiftRequest struct {
+	ToUser   string `json:"toUser"`
+	Item     string `json:"item"`
+	Variant  string `json:"variant,omitempty"`
+	Quantity int    `json:"quantity"`
+	Note     string `json:"note,omitempty"`
+	Promo    string `json:"promo,omitempty"`
+}
+
+// OrderCancellation limits which orders can be cancelled, empty fields are not applied.
+// With both Status and PlacedAfter set an order matching either of them is cancelled.
+// Cancelled orders are never cancelled again.
+type OrderCancellation struct {
+	UserID      *int
+	Status      string
+	PlacedAfter *time.Time
+}
+
+// OrderFilter selects orders, empty fields are not applied.
+// UserID selects orders paid by the user, with ReceivedGifts also orders gifted to them.
+// ApproverID selects orders assigned to the approver.
+type OrderFilter struct {
+	OrderID       *int
+	UserID        *int
+	ReceivedGifts bool
+	ApproverID    *int
+	Status        string
+}
+
+// OrderApproval is a decision on an order pending approval. Approvers decide on orders assigned to them,
+// admins on any order. Nobody decides on their own orders.
+type OrderApproval struct {
+	UserID int
+	Admin  bool
+}
+
+type Approver struct {
+	Username string `json:"username"`
+	// Pending is the number of orders waiting for the approver's decision.
+	Pending int       `json:"pending"`
+	AddedAt time.Time `json:"addedAt"`
+}
+
+type ApproverRequest struct {
+	Username string `json:"username"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// ItemPrice is an effective-dated item price. A price without end is a regular price,
+// the latest started one applies; a price with end is a sale, it wins while it lasts.
+type ItemPrice struct {
+	ID     int `json:"id"`
+	ItemID int `json:"itemId"`
+	Price  int `json:"price"`
+	// StartsAt is nil for a price set before price history was recorded.
+	StartsAt *time.Time `json:"startsAt"`
+	EndsAt   *time.Time `json:"endsAt,omitempty"`
+}
+
+// PriceRequest schedules a price change, or a sale when EndsAt is set.
+type PriceRequest struct {
+	Price int `json:"price"`
+	// StartsAt is nil to apply the price right away.
+	StartsAt *time.Time `json:"startsAt,omitempty"`
+	EndsAt   *time.Time `json:"endsAt,omitempty"`
+}
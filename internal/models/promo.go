@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+const (
+	PromoKindPercent = "percent"
+	PromoKindFixed   = "fixed"
+)
+
+// PromoCode is a discount rule applied to a purchase by its code.
+// A code without item and category applies to every item.
+type PromoCode struct {
+	ID       int    `json:"id"`
+	Code     string `json:"code"`
+	Kind     string `json:"kind"`
+	Value    int    `json:"value"`
+	ItemID   *int   `json:"itemId,omitempty"`
+	Category string `json:"category,omitempty"`
+	// The code is valid from StartsAt inclusive until EndsAt exclusive, nil bounds are open.
+	StartsAt *time.Time `json:"startsAt,omitempty"`
+	EndsAt   *time.Time `json:"endsAt,omitempty"`
+	// Nil limits are not applied. Uses counts orders placed with the code, cancelled ones excluded.
+	MaxUses        *int `json:"maxUses,omitempty"`
+	MaxUsesPerUser *int `json:"maxUsesPerUser,omitempty"`
+	Uses           int  `json:"uses"`
+	Active         bool `json:"active"`
+}
+
+// PromoRequest describes a promo code created by an admin.
+type PromoRequest struct {
+	Code           string     `json:"code"`
+	Kind           string     `json:"kind"`
+	Value          int        `json:"value"`
+	ItemID         *int       `json:"itemId,omitempty"`
+	Category       string     `json:"category,omitempty"`
+	StartsAt       *time.Time `json:"startsAt,omitempty"`
+	EndsAt         *time.Time `json:"endsAt,omitempty"`
+	MaxUses        *int       `json:"maxUses,omitempty"`
+	MaxUsesPerUser *int       `json:"maxUsesPerUser,omitempty"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Review is a rating of an item from 1 to 5 stars by a user owning it.
+type Review struct {
+	ID        int       `json:"id"`
+	ItemID    int       `json:"itemId"`
+	Item      string    `json:"item"`
+	User      string    `json:"user"`
+	Rating    int       `json:"rating"`
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	// Hidden reviews are shown to admins only and are not counted in the item rating.
+	Hidden bool `json:"hidden,omitempty"`
+}
+
+// ReviewRequest posts a review of an item or replaces the review posted before.
+type ReviewRequest struct {
+	Item   string `json:"item"`
+	Rating int    `json:"rating"`
+	Text   string `json:"text"`
+}
+
+// ReviewFilter selects reviews of one item, or of every item if ItemID is nil.
+type ReviewFilter struct {
+	ItemID        *int
+	IncludeHidden bool
+}
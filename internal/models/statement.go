@@ -0,0 +1,17 @@
+package models
+
+// Statement sums balance movements of a user over a calendar month.
+// Closing balance equals opening balance plus grants, received coins and refunds
+// minus sent coins and purchases.
+type Statement struct {
+	Month          string `json:"month"`
+	OpeningBalance int    `json:"openingBalance"`
+	Granted        int    `json:"granted"`
+	Sent           int    `json:"sent"`
+	Received       int    `json:"received"`
+	Purchases      int    `json:"purchases"`
+	Refunds        int    `json:"refunds"`
+	ClosingBalance int    `json:"closingBalance"`
+	// Final is false for the current month which is still in progress.
+	Final bool `json:"final"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+const (
+	NotificationKindPriceDrop = "price_drop"
+	NotificationKindRestock   = "restock"
+)
+
+// WishlistItemRequest selects an item by name or id.
+type WishlistItemRequest struct {
+	Item string `json:"item"`
+}
+
+// WishlistItem is a wished item with its current catalog price and availability.
+type WishlistItem struct {
+	ItemID    int    `json:"itemId"`
+	Item      string `json:"item"`
+	Price     int    `json:"price"`
+	Available bool   `json:"available"`
+	// Affordable is true while the balance covers the price.
+	Affordable bool      `json:"affordable"`
+	AddedAt    time.Time `json:"addedAt"`
+}
+
+type Wishlist struct {
+	Items   []WishlistItem `json:"items"`
+	Balance int            `json:"balance"`
+}
+
+// Notification is an in-app message to a user, ReadAt is nil until the user reads it.
+type Notification struct {
+	ID        int        `json:"id"`
+	Kind      string     `json:"kind"`
+	Item      string     `json:"item,omitempty"`
+	Message   string     `json:"message"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ReadAt    *time.Time `json:"readAt,omitempty"`
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"merch_shop/internal/db"
+	"merch_shop/internal/models"
+	"merch_shop/pkg/middleware"
+	"merch_shop/pkg/xerrors"
+	"net/http"
+	"strconv"
+)
+
+// GetApprovals lists orders waiting for the user's decision, admins see every order pending approval.
+func (s *merchShopService) GetApprovals(ctx context.Context) ([]models.Order, xerrors.Xerror) {
+	approval, servErr := s.orderApproval(ctx)
+	if servErr != nil {
+		return nil, servErr
+	}
+
+	filter := &models.OrderFilter{Status: models.OrderStatusPendingApproval}
+	if !approval.Admin {
+		filter.ApproverID = &approval.UserID
+	}
+
+	return s.getOrders(ctx, filter)
+}
+
+// ApproveOrder places an order pending approval, the held coins pay for it.
+func (s *merchShopService) ApproveOrder(ctx context.Context, orderIDStr string) (*models.Order, xerrors.Xerror) {
+	approval, servErr := s.orderApproval(ctx)
+	if servErr != nil {
+		return nil, servErr
+	}
+
+	orderID, err := strconv.Atoi(orderIDStr)
+	if err != nil {
+		return nil, xerrors.New(db.ErrNoOrder, http.StatusNotFound)
+	}
+
+	err = s.storage.ApproveOrder(ctx, orderID, approval)
+	if err != nil {
+		return nil, s.orderError("approve order", err)
+	}
+
+	return s.getOrder(ctx, orderID)
+}
+
+// RejectOrder cancels an order pending approval and releases the held coins.
+func (s *merchShopService) RejectOrder(ctx context.Context, orderIDStr string) (*models.Order, xerrors.Xerror) {
+	approval, servErr := s.orderApproval(ctx)
+	if servErr != nil {
+		return nil, servErr
+	}
+
+	orderID, err := strconv.Atoi(orderIDStr)
+	if err != nil {
+		return nil, xerrors.New(db.ErrNoOrder, http.StatusNotFound)
+	}
+
+	err = s.storage.RejectOrder(ctx, orderID, approval)
+	if err != nil {
+		return nil, s.orderError("reject order", err)
+	}
+
+	return s.getOrder(ctx, orderID)
+}
+
+func (s *merchShopService) GetApprovers(ctx context.Context) ([]models.Approver, xerrors.Xerror) {
+	if _, servErr := s.requireAdmin(ctx); servErr != nil {
+		return nil, servErr
+	}
+
+	approvers, err := s.storage.GetApprovers(ctx)
+	if err != nil {
+		s.logger.Error("get approvers: " + err.Error())
+		return nil, xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+
+	return approvers, nil
+}
+
+func (s *merchShopService) AddApprover(ctx context.Context, request *models.ApproverRequest) xerrors.Xerror {
+	if _, servErr := s.requireAdmin(ctx); servErr != nil {
+		return servErr
+	}
+
+	err := s.storage.AddApprover(ctx, request.Username)
+	if err != nil {
+		switch err {
+		case db.ErrNoUser:
+			return xerrors.New(err, http.StatusBadRequest)
+		case db.ErrApproverExists:
+			return xerrors.New(err, http.StatusConflict)
+		}
+		s.logger.Error("add approver: " + err.Error())
+		return xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+
+	return nil
+}
+
+// RemoveApprover takes a user off the approvers, orders still waiting for them are left to admins.
+func (s *merchShopService) RemoveApprover(ctx context.Context, username string) xerrors.Xerror {
+	if _, servErr := s.requireAdmin(ctx); servErr != nil {
+		return servErr
+	}
+
+	err := s.storage.RemoveApprover(ctx, username)
+	if err != nil {
+		if err == db.ErrNoApprover {
+			return xerrors.New(err, http.StatusNotFound)
+		}
+		s.logger.Error("remove approver: " + err.Error())
+		return xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+
+	return nil
+}
+
+// orderApproval is a decision of the current user, approvers are not required to be admins.
+func (s *merchShopService) orderApproval(ctx context.Context) (*models.OrderApproval, xerrors.Xerror) {
+	userID, ok := ctx.Value(middleware.UserIDKey).(int)
+	if !ok {
+		return nil, xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+
+	isAdmin, err := s.storage.IsAdmin(ctx, userID)
+	if err != nil && err != db.ErrNoUser {
+		s.logger.Error("check admin: " + err.Error())
+		return nil, xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+
+	return &models.OrderApproval{UserID: userID, Admin: isAdmin}, nil
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"merch_shop/internal/db"
+	dbmock "merch_shop/internal/db/mocks"
+	"merch_shop/internal/models"
+	cryptormock "merch_shop/pkg/cryptor/mocks"
+	"merch_shop/pkg/middleware"
+	tokenizermock "merch_shop/pkg/tokenizer/mocks"
+	"merch_shop/pkg/xerrors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuyItemApprovalThreshold(t *testing.T) {
+	ctxWithUserID := context.WithValue(context.Background(), middleware.UserIDKey, 1)
+	cfg := testShopConfig
+	cfg.ApprovalThreshold = 500
+
+	database := dbmock.NewDB(t)
+	service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), cfg)
+
+	summary := &models.OrderSummary{Order: models.Order{ID: 7, Status: models.OrderStatusPendingApproval}, Balance: 400}
+	database.On("GetItems", mock.Anything).Return([]models.CatalogItem{{ID: 1, Type: "hoody", Price: 300, Active: true,
+		Variants: []models.ItemVariant{{ID: 2, Name: "default", Price: 300}}}}, nil)
+	database.On("BuyItems", mock.Anything, 1, []models.CartLine{{VariantID: 2, Quantity: 2}}, "", 500).Return(summary, nil)
+
+	res, err := service.BuyItem(ctxWithUserID, "hoody", "", "2", "")
+	require.Nil(t, err)
+	require.Equal(t, summary, res)
+}
+
+func TestGetApprovals(t *testing.T) {
+	ctxWithUserID := context.WithValue(context.Background(), middleware.UserIDKey, 4)
+	pending := []models.Order{{ID: 7, User: "alice", Status: models.OrderStatusPendingApproval, Total: 600}}
+
+	t.Run("approver sees orders assigned to them", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		approverID := 4
+		database.On("IsAdmin", mock.Anything, 4).Return(false, nil)
+		database.On("GetOrders", mock.Anything,
+			&models.OrderFilter{ApproverID: &approverID, Status: models.OrderStatusPendingApproval}).Return(pending, nil)
+
+		orders, err := service.GetApprovals(ctxWithUserID)
+		require.Nil(t, err)
+		require.Equal(t, pending, orders)
+	})
+
+	t.Run("admin sees every pending order", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("IsAdmin", mock.Anything, 4).Return(true, nil)
+		database.On("GetOrders", mock.Anything, &models.OrderFilter{Status: models.OrderStatusPendingApproval}).
+			Return(pending, nil)
+
+		orders, err := service.GetApprovals(ctxWithUserID)
+		require.Nil(t, err)
+		require.Equal(t, pending, orders)
+	})
+}
+
+func TestApproveOrder(t *testing.T) {
+	ctxWithUserID := context.WithValue(context.Background(), middleware.UserIDKey, 4)
+	approval := &models.OrderApproval{UserID: 4}
+
+	t.Run("storage errors", func(t *testing.T) {
+		testCases := []struct {
+			dbErr    error
+			expected xerrors.Xerror
+		}{
+			{dbErr: db.ErrNoOrder, expected: xerrors.New(db.ErrNoOrder, http.StatusNotFound)},
+			{dbErr: db.ErrNotPending, expected: xerrors.New(db.ErrNotPending, http.StatusConflict)},
+		}
+
+		for _, tc := range testCases {
+			database := dbmock.NewDB(t)
+			service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+			database.On("IsAdmin", mock.Anything, 4).Return(false, nil)
+			database.On("ApproveOrder", mock.Anything, 7, approval).Return(tc.dbErr)
+
+			_, err := service.ApproveOrder(ctxWithUserID, "7")
+			require.Equal(t, tc.expected, err)
+		}
+	})
+
+	t.Run("positive result", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		orderID := 7
+		placed := []models.Order{{ID: 7, User: "alice", Status: models.OrderStatusPlaced, Total: 600}}
+		database.On("IsAdmin", mock.Anything, 4).Return(false, nil)
+		database.On("ApproveOrder", mock.Anything, 7, approval).Return(nil)
+		database.On("GetOrders", mock.Anything, &models.OrderFilter{OrderID: &orderID}).Return(placed, nil)
+
+		order, err := service.ApproveOrder(ctxWithUserID, "7")
+		require.Nil(t, err)
+		require.Equal(t, &placed[0], order)
+	})
+}
+
+func TestAddApprover(t *testing.T) {
+	ctxWithUserID := context.WithValue(context.Background(), middleware.UserIDKey, 1)
+
+	testCases := []struct {
+		name     string
+		dbErr    error
+		expected xerrors.Xerror
+	}{
+		{name: "unknown user", dbErr: db.ErrNoUser, expected: xerrors.New(db.ErrNoUser, http.StatusBadRequest)},
+		{name: "approver already", dbErr: db.ErrApproverExists, expected: xerrors.New(db.ErrApproverExists, http.StatusConflict)},
+		{name: "positive result"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			database := dbmock.NewDB(t)
+			service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+			database.On("IsAdmin", mock.Anything, 1).Return(true, nil)
+			database.On("AddApprover", mock.Anything, "bob").Return(tc.dbErr)
+
+			err := service.AddApprover(ctxWithUserID, &models.ApproverRequest{Username: "bob"})
+			if tc.expected == nil {
+				require.Nil(t, err)
+				return
+			}
+			require.Equal(t, tc.expected, err)
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"merch_shop/internal/db"
+	"merch_shop/internal/models"
+	"merch_shop/pkg/middleware"
+	"merch_shop/pkg/xerrors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	minStartingBid  = 1
+	minBidIncrement = 1
+)
+
+var (
+	errStartingBidInvalid  = fmt.Errorf("starting bid is invalid: min %d", minStartingBid)
+	errBidIncrementInvalid = fmt.Errorf("minimal increment is invalid: min %d", minBidIncrement)
+	errAuctionEndInvalid   = errors.New("auction end is invalid: must be in the future")
+	errBidAmountInvalid    = fmt.Errorf("bid amount is invalid: min %d", minStartingBid)
+)
+
+// GetAuctions returns auctions still accepting bids, ending first first.
+func (s *merchShopService) GetAuctions(ctx context.Context) ([]models.Auction, xerrors.Xerror) {
+	return s.getAuctions(ctx, true)
+}
+
+// GetAllAuctions returns auctions including closed ones.
+func (s *merchShopService) GetAllAuctions(ctx context.Context) ([]models.Auction, xerrors.Xerror) {
+	if _, servErr := s.requireAdmin(ctx); servErr != nil {
+		return nil, servErr
+	}
+
+	return s.getAuctions(ctx, false)
+}
+
+func (s *merchShopService) getAuctions(ctx context.Context, openOnly bool) ([]models.Auction, xerrors.Xerror) {
+	auctions, err := s.storage.GetAuctions(ctx, openOnly)
+	if err != nil {
+		s.logger.Error("get auctions: " + err.Error())
+		return nil, xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+
+	return auctions, nil
+}
+
+func (s *merchShopService) GetAuction(ctx context.Context, auctionIDStr string) (*models.Auction, xerrors.Xerror) {
+	auctionID, err := strconv.Atoi(auctionIDStr)
+	if err != nil {
+		return nil, xerrors.New(db.ErrNoAuction, http.StatusNotFound)
+	}
+
+	auction, err := s.storage.GetAuction(ctx, auctionID)
+	if err != nil {
+		return nil, s.auctionError("get auction", err)
+	}
+
+	return auction, nil
+}
+
+// CreateAuction puts one unit of a variant up for auction. Retired items may be auctioned,
+// one-off merch is usually not sold in the catalog at all.
+func (s *merchShopService) CreateAuction(ctx context.Context, auction *models.AuctionRequest) (*models.Auction, xerrors.Xerror) {
+	if _, servErr := s.requireAdmin(ctx); servErr != nil {
+		return nil, servErr
+	}
+
+	if auction.MinIncrement == 0 {
+		auction.MinIncrement = minBidIncrement
+	}
+	if auction.StartingBid < minStartingBid {
+		return nil, xerrors.New(errStartingBidInvalid, http.StatusBadRequest)
+	}
+	if auction.MinIncrement < minBidIncrement {
+		return nil, xerrors.New(errBidIncrementInvalid, http.StatusBadRequest)
+	}
+	if !auction.EndsAt.After(time.Now()) {
+		return nil, xerrors.New(errAuctionEndInvalid, http.StatusBadRequest)
+	}
+
+	catalog, servErr := s.getCatalog(ctx)
+	if servErr != nil {
+		return nil, servErr
+	}
+
+	item, ok := catalog.lookup(auction.Item)
+	if !ok {
+		return nil, xerrors.New(db.ErrNoItem, http.StatusBadRequest)
+	}
+
+	variant, servErr := itemVariant(&item, auction.Variant)
+	if servErr != nil {
+		return nil, servErr
+	}
+
+	created, err := s.storage.CreateAuction(ctx, variant.ID, auction)
+	if err != nil {
+		if err == db.ErrOutOfStock {
+			return nil, xerrors.New(err, http.StatusConflict)
+		}
+		s.logger.Error("create auction: " + err.Error())
+		return nil, xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+	s.catalog.invalidate()
+
+	return created, nil
+}
+
+// PlaceBid holds the coins of the bid until it is outbid or the auction is closed.
+func (s *merchShopService) PlaceBid(ctx context.Context, auctionIDStr string,
+	bid *models.BidRequest) (*models.Auction, xerrors.Xerror) {
+	userID, ok := ctx.Value(middleware.UserIDKey).(int)
+	if !ok {
+		return nil, xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+
+	auctionID, err := strconv.Atoi(auctionIDStr)
+	if err != nil {
+		return nil, xerrors.New(db.ErrNoAuction, http.StatusNotFound)
+	}
+
+	if bid.Amount < minStartingBid {
+		return nil, xerrors.New(errBidAmountInvalid, http.StatusBadRequest)
+	}
+
+	err = s.storage.PlaceBid(ctx, userID, auctionID, bid.Amount)
+	if err != nil {
+		return nil, s.auctionError("place bid", err)
+	}
+
+	return s.GetAuction(ctx, auctionIDStr)
+}
+
+func (s *merchShopService) auctionError(operation string, err error) xerrors.Xerror {
+	switch err {
+	case db.ErrNoAuction:
+		return xerrors.New(err, http.StatusNotFound)
+	case db.ErrBidTooLow, db.ErrNotEnoughCoins:
+		return xerrors.New(err, http.StatusBadRequest)
+	case db.ErrAuctionClosed, db.ErrAlreadyLeading:
+		return xerrors.New(err, http.StatusConflict)
+	}
+	s.logger.Error(operation + ": " + err.Error())
+	return xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+}
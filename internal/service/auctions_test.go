@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"merch_shop/internal/db"
+	dbmock "merch_shop/internal/db/mocks"
+	"merch_shop/internal/models"
+	cryptormock "merch_shop/pkg/cryptor/mocks"
+	"merch_shop/pkg/middleware"
+	tokenizermock "merch_shop/pkg/tokenizer/mocks"
+	"merch_shop/pkg/xerrors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateAuction(t *testing.T) {
+	ctxWithUserID := context.WithValue(context.Background(), middleware.UserIDKey, 1)
+	endsAt := time.Now().Add(24 * time.Hour)
+	catalogItems := []models.CatalogItem{{ID: 1, Type: "signed book", Price: 500, Active: false,
+		Variants: []models.ItemVariant{{ID: 2, Name: "default", Price: 500}}}}
+
+	testCases := []struct {
+		name       string
+		request    *models.AuctionRequest
+		dbBehavior func(database *dbmock.DB)
+
+		expected *models.Auction
+		err      xerrors.Xerror
+	}{
+		{
+			name:    "starting bid is invalid",
+			request: &models.AuctionRequest{Item: "signed book", EndsAt: endsAt},
+			err:     xerrors.New(errStartingBidInvalid, http.StatusBadRequest),
+		},
+		{
+			name:    "auction has ended already",
+			request: &models.AuctionRequest{Item: "signed book", StartingBid: 100, EndsAt: time.Now().Add(-time.Minute)},
+			err:     xerrors.New(errAuctionEndInvalid, http.StatusBadRequest),
+		},
+		{
+			name:    "variant out of stock",
+			request: &models.AuctionRequest{Item: "signed book", StartingBid: 100, EndsAt: endsAt},
+			dbBehavior: func(database *dbmock.DB) {
+				database.On("GetItems", mock.Anything).Return(catalogItems, nil)
+				database.On("CreateAuction", mock.Anything, 2, mock.Anything).Return(nil, db.ErrOutOfStock)
+			},
+			err: xerrors.New(db.ErrOutOfStock, http.StatusConflict),
+		},
+		{
+			name:    "retired item is auctioned with the default increment",
+			request: &models.AuctionRequest{Item: "signed book", StartingBid: 100, EndsAt: endsAt},
+			dbBehavior: func(database *dbmock.DB) {
+				database.On("GetItems", mock.Anything).Return(catalogItems, nil)
+				database.On("CreateAuction", mock.Anything, 2,
+					&models.AuctionRequest{Item: "signed book", StartingBid: 100, MinIncrement: 1, EndsAt: endsAt}).
+					Return(&models.Auction{ID: 3, Item: "signed book", StartingBid: 100, MinIncrement: 1, MinBid: 100}, nil)
+			},
+			expected: &models.Auction{ID: 3, Item: "signed book", StartingBid: 100, MinIncrement: 1, MinBid: 100},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			database := dbmock.NewDB(t)
+			service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+			database.On("IsAdmin", mock.Anything, 1).Return(true, nil)
+			if tc.dbBehavior != nil {
+				tc.dbBehavior(database)
+			}
+
+			auction, err := service.CreateAuction(ctxWithUserID, tc.request)
+			if tc.err != nil {
+				require.Equal(t, tc.err, err)
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, tc.expected, auction)
+		})
+	}
+}
+
+func TestPlaceBid(t *testing.T) {
+	ctxWithUserID := context.WithValue(context.Background(), middleware.UserIDKey, 5)
+
+	t.Run("storage errors", func(t *testing.T) {
+		testCases := []struct {
+			dbErr    error
+			expected xerrors.Xerror
+		}{
+			{dbErr: db.ErrNoAuction, expected: xerrors.New(db.ErrNoAuction, http.StatusNotFound)},
+			{dbErr: db.ErrBidTooLow, expected: xerrors.New(db.ErrBidTooLow, http.StatusBadRequest)},
+			{dbErr: db.ErrNotEnoughCoins, expected: xerrors.New(db.ErrNotEnoughCoins, http.StatusBadRequest)},
+			{dbErr: db.ErrAuctionClosed, expected: xerrors.New(db.ErrAuctionClosed, http.StatusConflict)},
+			{dbErr: db.ErrAlreadyLeading, expected: xerrors.New(db.ErrAlreadyLeading, http.StatusConflict)},
+		}
+
+		for _, tc := range testCases {
+			database := dbmock.NewDB(t)
+			service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+			database.On("PlaceBid", mock.Anything, 5, 3, 120).Return(tc.dbErr)
+
+			_, err := service.PlaceBid(ctxWithUserID, "3", &models.BidRequest{Amount: 120})
+			require.Equal(t, tc.expected, err)
+		}
+	})
+
+	t.Run("amount is invalid", func(t *testing.T) {
+		service := New(dbmock.NewDB(t), slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		_, err := service.PlaceBid(ctxWithUserID, "3", &models.BidRequest{Amount: 0})
+		require.Equal(t, xerrors.New(errBidAmountInvalid, http.StatusBadRequest), err)
+	})
+
+	t.Run("positive result", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		auction := &models.Auction{ID: 3, Item: "signed book", HighestBid: 120, Leader: "bob", Bids: 2, MinBid: 130}
+		database.On("PlaceBid", mock.Anything, 5, 3, 120).Return(nil)
+		database.On("GetAuction", mock.Anything, 3).Return(auction, nil)
+
+		res, err := service.PlaceBid(ctxWithUserID, "3", &models.BidRequest{Amount: 120})
+		require.Nil(t, err)
+		require.Equal(t, auction, res)
+	})
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"merch_shop/internal/db"
+	"merch_shop/internal/models"
+	"merch_shop/pkg/middleware"
+	"merch_shop/pkg/xerrors"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// Matches bundles.name VARCHAR(50).
+	maxBundleNameLength = 50
+	minBundlePrice      = 1
+)
+
+var (
+	errBundleNameInvalid  = fmt.Errorf("bundle name is invalid: length min 1 max %d, no spaces around", maxBundleNameLength)
+	errBundlePriceInvalid = fmt.Errorf("bundle price is invalid: min %d", minBundlePrice)
+	errBundleItemsInvalid = errors.New("bundle items are invalid: at least one item is required")
+)
+
+// GetBundles returns bundles on sale, they are read from the storage on every call.
+func (s *merchShopService) GetBundles(ctx context.Context) ([]models.Bundle, xerrors.Xerror) {
+	bundles, err := s.storage.GetBundles(ctx)
+	if err != nil {
+		s.logger.Error("get bundles: " + err.Error())
+		return nil, xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+
+	active := make([]models.Bundle, 0, len(bundles))
+	for _, bundle := range bundles {
+		if bundle.Active {
+			active = append(active, bundle)
+		}
+	}
+
+	return active, nil
+}
+
+// GetAllBundles returns bundles including inactive ones.
+func (s *merchShopService) GetAllBundles(ctx context.Context) ([]models.Bundle, xerrors.Xerror) {
+	if _, servErr := s.requireAdmin(ctx); servErr != nil {
+		return nil, servErr
+	}
+
+	bundles, err := s.storage.GetBundles(ctx)
+	if err != nil {
+		s.logger.Error("get bundles: " + err.Error())
+		return nil, xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+
+	return bundles, nil
+}
+
+// CreateBundle adds a bundle of active items, components are resolved like cart items.
+func (s *merchShopService) CreateBundle(ctx context.Context, bundle *models.BundleRequest) (*models.Bundle, xerrors.Xerror) {
+	if _, servErr := s.requireAdmin(ctx); servErr != nil {
+		return nil, servErr
+	}
+
+	nameLength := utf8.RuneCountInString(bundle.Name)
+	if nameLength == 0 || nameLength > maxBundleNameLength || strings.TrimSpace(bundle.Name) != bundle.Name {
+		return nil, xerrors.New(errBundleNameInvalid, http.StatusBadRequest)
+	}
+	if bundle.Price < minBundlePrice {
+		return nil, xerrors.New(errBundlePriceInvalid, http.StatusBadRequest)
+	}
+	if utf8.RuneCountInString(bundle.Description) > maxItemDescriptionLength {
+		return nil, xerrors.New(errItemDescriptionInvalid, http.StatusBadRequest)
+	}
+	if len(bundle.Items) == 0 {
+		return nil, xerrors.New(errBundleItemsInvalid, http.StatusBadRequest)
+	}
+
+	lines := make([]models.CartLine, 0, len(bundle.Items))
+	for _, item := range bundle.Items {
+		line, servErr := s.cartLine(ctx, item.Item, item.Variant, item.Quantity)
+		if servErr != nil {
+			return nil, servErr
+		}
+		lines = append(lines, *line)
+	}
+
+	created, err := s.storage.CreateBundle(ctx, bundle, lines)
+	if err != nil {
+		return nil, s.bundleWriteError("create bundle", err)
+	}
+
+	return created, nil
+}
+
+// DeactivateBundle stops selling a bundle, orders placed for it are kept.
+func (s *merchShopService) DeactivateBundle(ctx context.Context, bundleIDStr string) (*models.Bundle, xerrors.Xerror) {
+	if _, servErr := s.requireAdmin(ctx); servErr != nil {
+		return nil, servErr
+	}
+
+	bundleID, err := strconv.Atoi(bundleIDStr)
+	if err != nil {
+		return nil, xerrors.New(db.ErrNoBundle, http.StatusNotFound)
+	}
+
+	updated, err := s.storage.SetBundleActive(ctx, bundleID, false)
+	if err != nil {
+		return nil, s.bundleWriteError("deactivate bundle", err)
+	}
+
+	return updated, nil
+}
+
+// BuyBundle buys bundles as one order of their components, a missing quantity buys one bundle.
+func (s *merchShopService) BuyBundle(ctx context.Context, bundleIDStr,
+	quantityStr string) (*models.OrderSummary, xerrors.Xerror) {
+	userID, ok := ctx.Value(middleware.UserIDKey).(int)
+	if !ok {
+		return nil, xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+
+	bundleID, err := strconv.Atoi(bundleIDStr)
+	if err != nil {
+		return nil, xerrors.New(db.ErrNoBundle, http.StatusNotFound)
+	}
+
+	quantity := minPurchaseQuantity
+	if quantityStr != "" {
+		quantity, err = strconv.Atoi(quantityStr)
+		if err != nil {
+			return nil, xerrors.New(errQuantityInvalid, http.StatusBadRequest)
+		}
+	}
+	if quantity < minPurchaseQuantity || quantity > maxPurchaseQuantity {
+		return nil, xerrors.New(errQuantityInvalid, http.StatusBadRequest)
+	}
+
+	summary, err := s.storage.BuyBundle(ctx, userID, bundleID, quantity, s.cfg.ApprovalThreshold)
+	if err != nil {
+		switch err {
+		case db.ErrNoBundle:
+			return nil, xerrors.New(err, http.StatusNotFound)
+		case db.ErrBundleRetired, db.ErrBundleDearer:
+			return nil, xerrors.New(err, http.StatusConflict)
+		}
+		return nil, s.purchaseError("buy bundle", err)
+	}
+
+	return summary, nil
+}
+
+func (s *merchShopService) bundleWriteError(operation string, err error) xerrors.Xerror {
+	switch err {
+	case db.ErrNoBundle:
+		return xerrors.New(err, http.StatusNotFound)
+	case db.ErrNoVariant, db.ErrBundleDearer:
+		return xerrors.New(err, http.StatusBadRequest)
+	case db.ErrBundleExists:
+		return xerrors.New(err, http.StatusConflict)
+	}
+	s.logger.Error(operation + ": " + err.Error())
+	return xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"merch_shop/internal/db"
+	dbmock "merch_shop/internal/db/mocks"
+	"merch_shop/internal/models"
+	cryptormock "merch_shop/pkg/cryptor/mocks"
+	"merch_shop/pkg/middleware"
+	tokenizermock "merch_shop/pkg/tokenizer/mocks"
+	"merch_shop/pkg/xerrors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateBundle(t *testing.T) {
+	ctxWithUserID := context.WithValue(context.Background(), middleware.UserIDKey, 1)
+	catalog := []models.CatalogItem{
+		{ID: 1, Type: "cup", Price: 20, Active: true, Available: true, Variants: []models.ItemVariant{
+			{ID: 2, ItemID: 1, SKU: "cup", Name: "default", Price: 20, Available: true},
+		}},
+		{ID: 3, Type: "pen", Price: 10, Active: true, Available: true, Variants: []models.ItemVariant{
+			{ID: 4, ItemID: 3, SKU: "pen-blue", Name: "blue", Price: 10, Available: true},
+			{ID: 5, ItemID: 3, SKU: "pen-red", Name: "red", Price: 10, Available: true},
+		}},
+		{ID: 6, Type: "old-pen", Price: 5, Variants: []models.ItemVariant{
+			{ID: 7, ItemID: 6, SKU: "old-pen", Name: "default", Price: 5},
+		}},
+	}
+	cup := models.CartItemRequest{Item: "cup", Quantity: 1}
+
+	t.Run("not admin error", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("IsAdmin", mock.Anything, 1).Return(false, nil)
+
+		_, err := service.CreateBundle(ctxWithUserID, &models.BundleRequest{Name: "onboarding"})
+		require.Equal(t, xerrors.New(errAdminRequired, http.StatusForbidden), err)
+	})
+
+	t.Run("invalid request validation", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("IsAdmin", mock.Anything, 1).Return(true, nil)
+		database.On("GetItems", mock.Anything).Return(catalog, nil).Maybe()
+
+		testCases := []struct {
+			name        string
+			request     models.BundleRequest
+			expectedErr error
+		}{
+			{name: "empty name", request: models.BundleRequest{Price: 100, Items: []models.CartItemRequest{cup}},
+				expectedErr: errBundleNameInvalid},
+			{name: "long name", request: models.BundleRequest{Name: strings.Repeat("a", 51), Price: 100,
+				Items: []models.CartItemRequest{cup}}, expectedErr: errBundleNameInvalid},
+			{name: "zero price", request: models.BundleRequest{Name: "onboarding", Items: []models.CartItemRequest{cup}},
+				expectedErr: errBundlePriceInvalid},
+			{name: "no items", request: models.BundleRequest{Name: "onboarding", Price: 100},
+				expectedErr: errBundleItemsInvalid},
+			{name: "zero quantity", request: models.BundleRequest{Name: "onboarding", Price: 100,
+				Items: []models.CartItemRequest{{Item: "cup"}}}, expectedErr: errQuantityInvalid},
+			{name: "variant missing", request: models.BundleRequest{Name: "onboarding", Price: 100,
+				Items: []models.CartItemRequest{cup, {Item: "pen", Quantity: 1}}}, expectedErr: errVariantRequired},
+			{name: "retired item", request: models.BundleRequest{Name: "onboarding", Price: 100,
+				Items: []models.CartItemRequest{{Item: "old-pen", Quantity: 1}}}, expectedErr: errItemRetired},
+		}
+
+		for _, tc := range testCases {
+			_, err := service.CreateBundle(ctxWithUserID, &tc.request)
+			require.Equal(t, xerrors.New(tc.expectedErr, http.StatusBadRequest), err, tc.name)
+		}
+	})
+
+	t.Run("name already exists", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("IsAdmin", mock.Anything, 1).Return(true, nil)
+		database.On("GetItems", mock.Anything).Return(catalog, nil)
+		database.On("CreateBundle", mock.Anything, mock.Anything, mock.Anything).Return(nil, db.ErrBundleExists)
+
+		_, err := service.CreateBundle(ctxWithUserID, &models.BundleRequest{Name: "onboarding", Price: 100,
+			Items: []models.CartItemRequest{cup}})
+		require.Equal(t, xerrors.New(db.ErrBundleExists, http.StatusConflict), err)
+	})
+
+	t.Run("bundle dearer than its items", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("IsAdmin", mock.Anything, 1).Return(true, nil)
+		database.On("GetItems", mock.Anything).Return(catalog, nil)
+		database.On("CreateBundle", mock.Anything, mock.Anything, mock.Anything).Return(nil, db.ErrBundleDearer)
+
+		_, err := service.CreateBundle(ctxWithUserID, &models.BundleRequest{Name: "onboarding", Price: 100,
+			Items: []models.CartItemRequest{cup}})
+		require.Equal(t, xerrors.New(db.ErrBundleDearer, http.StatusBadRequest), err)
+	})
+
+	t.Run("positive result", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		request := &models.BundleRequest{Name: "onboarding", Price: 25,
+			Items: []models.CartItemRequest{cup, {Item: "pen", Variant: "pen-red", Quantity: 2}}}
+		created := &models.Bundle{ID: 3, Name: "onboarding", Price: 25, RegularPrice: 40, Active: true, Available: true}
+
+		database.On("IsAdmin", mock.Anything, 1).Return(true, nil)
+		database.On("GetItems", mock.Anything).Return(catalog, nil)
+		database.On("CreateBundle", mock.Anything, request,
+			[]models.CartLine{{VariantID: 2, Quantity: 1}, {VariantID: 5, Quantity: 2}}).Return(created, nil)
+
+		bundle, err := service.CreateBundle(ctxWithUserID, request)
+		require.Nil(t, err)
+		require.Equal(t, created, bundle)
+	})
+}
+
+func TestGetBundles(t *testing.T) {
+	database := dbmock.NewDB(t)
+	service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+	database.On("GetBundles", mock.Anything).Return([]models.Bundle{
+		{ID: 3, Name: "onboarding", Active: true},
+		{ID: 4, Name: "farewell"},
+	}, nil)
+
+	bundles, err := service.GetBundles(context.Background())
+	require.Nil(t, err)
+	require.Equal(t, []models.Bundle{{ID: 3, Name: "onboarding", Active: true}}, bundles)
+}
+
+func TestBuyBundle(t *testing.T) {
+	ctxWithUserID := context.WithValue(context.Background(), middleware.UserIDKey, 1)
+
+	t.Run("invalid quantity", func(t *testing.T) {
+		service := New(dbmock.NewDB(t), slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		for _, quantity := range []string{"0", "101", "two"} {
+			_, err := service.BuyBundle(ctxWithUserID, "3", quantity)
+			require.Equal(t, xerrors.New(errQuantityInvalid, http.StatusBadRequest), err, quantity)
+		}
+	})
+
+	t.Run("storage errors", func(t *testing.T) {
+		testCases := []struct {
+			name     string
+			dbErr    error
+			expected xerrors.Xerror
+		}{
+			{name: "unknown bundle", dbErr: db.ErrNoBundle, expected: xerrors.New(db.ErrNoBundle, http.StatusNotFound)},
+			{name: "retired component", dbErr: db.ErrBundleRetired,
+				expected: xerrors.New(db.ErrBundleRetired, http.StatusConflict)},
+			{name: "out of stock", dbErr: db.ErrOutOfStock, expected: xerrors.New(db.ErrOutOfStock, http.StatusConflict)},
+			{name: "not enough coins", dbErr: db.ErrNotEnoughCoins,
+				expected: xerrors.New(db.ErrNotEnoughCoins, http.StatusBadRequest)},
+			{name: "unexpected", dbErr: errors.New("some error"),
+				expected: xerrors.New(errSmthWentWrong, http.StatusInternalServerError)},
+		}
+
+		for _, tc := range testCases {
+			database := dbmock.NewDB(t)
+			service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+			database.On("BuyBundle", mock.Anything, 1, 3, 1, 0).Return(nil, tc.dbErr)
+
+			_, err := service.BuyBundle(ctxWithUserID, "3", "")
+			require.Equal(t, tc.expected, err, tc.name)
+		}
+	})
+
+	t.Run("positive result", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		summary := &models.OrderSummary{Order: models.Order{ID: 7, Bundle: "onboarding", Total: 50}, Balance: 950}
+		database.On("BuyBundle", mock.Anything, 1, 3, 2, 0).Return(summary, nil)
+
+		result, err := service.BuyBundle(ctxWithUserID, "3", "2")
+		require.Nil(t, err)
+		require.Equal(t, summary, result)
+	})
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"merch_shop/internal/db"
+	"merch_shop/internal/models"
+	"merch_shop/pkg/middleware"
+	"merch_shop/pkg/xerrors"
+	"net/http"
+)
+
+const (
+	minPurchaseQuantity = 1
+	maxPurchaseQuantity = 100
+)
+
+var (
+	errQuantityInvalid = fmt.Errorf("quantity is invalid: min %d max %d", minPurchaseQuantity, maxPurchaseQuantity)
+	errVariantRequired = errors.New("item has several variants, variant is required")
+)
+
+func (s *merchShopService) AddCartItem(ctx context.Context, request *models.CartItemRequest) xerrors.Xerror {
+	userID, ok := ctx.Value(middleware.UserIDKey).(int)
+	if !ok {
+		return xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+
+	line, servErr := s.cartLine(ctx, request.Item, request.Variant, request.Quantity)
+	if servErr != nil {
+		return servErr
+	}
+
+	err := s.storage.AddCartItem(ctx, userID, *line)
+	if err != nil {
+		s.logger.Error("add cart item: " + err.Error())
+		return xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+
+	return nil
+}
+
+func (s *merchShopService) GetCart(ctx context.Context) (*models.Cart, xerrors.Xerror) {
+	userID, ok := ctx.Value(middleware.UserIDKey).(int)
+	if !ok {
+		return nil, xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+
+	items, err := s.storage.GetCart(ctx, userID)
+	if err != nil {
+		s.logger.Error("get cart: " + err.Error())
+		return nil, xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+
+	cart := &models.Cart{Items: items}
+	for _, item := range items {
+		cart.Total += item.Total
+	}
+
+	return cart, nil
+}
+
+func (s *merchShopService) CheckoutCart(ctx context.Context, promo string) (*models.OrderSummary, xerrors.Xerror) {
+	userID, ok := ctx.Value(middleware.UserIDKey).(int)
+	if !ok {
+		return nil, xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+
+	summary, err := s.storage.CheckoutCart(ctx, userID, normalizePromoCode(promo), s.cfg.ApprovalThreshold)
+	if err != nil {
+		return nil, s.purchaseError("checkout cart", err)
+	}
+
+	return summary, nil
+}
+
+// cartLine resolves an item by name or id and its variant by name or SKU, then validates the quantity to buy.
+func (s *merchShopService) cartLine(ctx context.Context, itemName, variantName string,
+	quantity int) (*models.CartLine, xerrors.Xerror) {
+	if quantity < minPurchaseQuantity || quantity > maxPurchaseQuantity {
+		return nil, xerrors.New(errQuantityInvalid, http.StatusBadRequest)
+	}
+
+	catalog, servErr := s.getCatalog(ctx)
+	if servErr != nil {
+		return nil, servErr
+	}
+
+	item, ok := catalog.lookup(itemName)
+	if !ok {
+		return nil, xerrors.New(db.ErrNoItem, http.StatusBadRequest)
+	}
+	if !item.Active {
+		return nil, xerrors.New(errItemRetired, http.StatusBadRequest)
+	}
+
+	variant, servErr := itemVariant(&item, variantName)
+	if servErr != nil {
+		return nil, servErr
+	}
+
+	return &models.CartLine{VariantID: variant.ID, Quantity: quantity}, nil
+}
+
+// itemVariant finds a variant of the item by name or SKU. The variant may be omitted
+// for items having only one, so clients buying by item name keep working.
+func itemVariant(item *models.CatalogItem, name string) (*models.ItemVariant, xerrors.Xerror) {
+	if name == "" {
+		if len(item.Variants) != 1 {
+			return nil, xerrors.New(errVariantRequired, http.StatusBadRequest)
+		}
+		return &item.Variants[0], nil
+	}
+
+	for i := range item.Variants {
+		if item.Variants[i].Name == name || item.Variants[i].SKU == name {
+			return &item.Variants[i], nil
+		}
+	}
+
+	return nil, xerrors.New(db.ErrNoVariant, http.StatusBadRequest)
+}
+
+func (s *merchShopService) purchaseError(operation string, err error) xerrors.Xerror {
+	var limitErr *db.PurchaseLimitError
+	if errors.As(err, &limitErr) {
+		return xerrors.New(err, http.StatusConflict)
+	}
+
+	switch err {
+	case db.ErrNoItem, db.ErrNoVariant, db.ErrNotEnoughCoins, db.ErrCartEmpty,
+		db.ErrNoPromo, db.ErrPromoInactive, db.ErrPromoNotApply:
+		return xerrors.New(err, http.StatusBadRequest)
+	case db.ErrOutOfStock, db.ErrPromoExhausted:
+		return xerrors.New(err, http.StatusConflict)
+	}
+	s.logger.Error(operation + ": " + err.Error())
+	return xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"merch_shop/internal/db"
+	dbmock "merch_shop/internal/db/mocks"
+	"merch_shop/internal/models"
+	cryptormock "merch_shop/pkg/cryptor/mocks"
+	"merch_shop/pkg/middleware"
+	tokenizermock "merch_shop/pkg/tokenizer/mocks"
+	"merch_shop/pkg/xerrors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddCartItem(t *testing.T) {
+	ctxWithUserID := context.WithValue(context.Background(), middleware.UserIDKey, 1)
+	catalog := []models.CatalogItem{
+		{ID: 4, Type: "pen", Price: 10, Active: true, Available: true, Variants: []models.ItemVariant{
+			{ID: 7, ItemID: 4, SKU: "pen-blue", Name: "blue", Price: 10, Available: true},
+			{ID: 8, ItemID: 4, SKU: "pen-red", Name: "red", Price: 10, Available: true},
+		}},
+		{ID: 5, Type: "old-pen", Price: 5, Variants: []models.ItemVariant{
+			{ID: 9, ItemID: 5, SKU: "old-pen", Name: "default", Price: 5},
+		}},
+	}
+
+	t.Run("userID missing error", func(t *testing.T) {
+		service := New(dbmock.NewDB(t), slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		err := service.AddCartItem(context.Background(), &models.CartItemRequest{Item: "pen", Variant: "blue", Quantity: 1})
+		require.Equal(t, xerrors.New(errSmthWentWrong, http.StatusInternalServerError), err)
+	})
+
+	t.Run("invalid params validation", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("GetItems", mock.Anything).Return(catalog, nil)
+
+		testCases := []struct {
+			name        string
+			request     models.CartItemRequest
+			expectedErr error
+		}{
+			{name: "quantity < min", request: models.CartItemRequest{Item: "pen", Variant: "blue"}, expectedErr: errQuantityInvalid},
+			{name: "quantity > max", request: models.CartItemRequest{Item: "pen", Variant: "blue", Quantity: maxPurchaseQuantity + 1},
+				expectedErr: errQuantityInvalid},
+			{name: "variant missing", request: models.CartItemRequest{Item: "pen", Quantity: 1}, expectedErr: errVariantRequired},
+			{name: "unknown variant", request: models.CartItemRequest{Item: "pen", Variant: "green", Quantity: 1},
+				expectedErr: db.ErrNoVariant},
+			{name: "unknown item", request: models.CartItemRequest{Item: "car", Quantity: 1}, expectedErr: db.ErrNoItem},
+			{name: "retired item", request: models.CartItemRequest{Item: "old-pen", Quantity: 1}, expectedErr: errItemRetired},
+		}
+
+		for _, tc := range testCases {
+			err := service.AddCartItem(ctxWithUserID, &tc.request)
+			require.Equal(t, xerrors.New(tc.expectedErr, http.StatusBadRequest), err, tc.name)
+		}
+	})
+
+	t.Run("positive result", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("GetItems", mock.Anything).Return(catalog, nil)
+		database.On("AddCartItem", mock.Anything, 1, models.CartLine{VariantID: 8, Quantity: 3}).Return(nil).Twice()
+
+		// Variants are resolved by name as well as by SKU.
+		err := service.AddCartItem(ctxWithUserID, &models.CartItemRequest{Item: "pen", Variant: "red", Quantity: 3})
+		require.Nil(t, err)
+
+		err = service.AddCartItem(ctxWithUserID, &models.CartItemRequest{Item: "pen", Variant: "pen-red", Quantity: 3})
+		require.Nil(t, err)
+	})
+}
+
+func TestGetCart(t *testing.T) {
+	ctxWithUserID := context.WithValue(context.Background(), middleware.UserIDKey, 1)
+
+	t.Run("get cart db error", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("GetCart", mock.Anything, 1).Return(nil, errors.New("some error"))
+
+		_, err := service.GetCart(ctxWithUserID)
+		require.Equal(t, xerrors.New(errSmthWentWrong, http.StatusInternalServerError), err)
+	})
+
+	t.Run("total sums all lines", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		items := []models.CartItem{
+			{Item: "cup", Quantity: 2, Price: 20, Total: 40, Available: true},
+			{Item: "pen", Quantity: 3, Price: 10, Total: 30, Available: true},
+		}
+		database.On("GetCart", mock.Anything, 1).Return(items, nil)
+
+		cart, err := service.GetCart(ctxWithUserID)
+		require.Nil(t, err)
+		require.Equal(t, &models.Cart{Items: items, Total: 70}, cart)
+	})
+}
+
+func TestCheckoutCart(t *testing.T) {
+	ctxWithUserID := context.WithValue(context.Background(), middleware.UserIDKey, 1)
+
+	t.Run("checkout user side error", func(t *testing.T) {
+		testCases := []struct {
+			err  error
+			code int
+		}{
+			{err: db.ErrCartEmpty, code: http.StatusBadRequest},
+			{err: db.ErrNoItem, code: http.StatusBadRequest},
+			{err: db.ErrNotEnoughCoins, code: http.StatusBadRequest},
+			{err: db.ErrOutOfStock, code: http.StatusConflict},
+		}
+
+		for _, tc := range testCases {
+			database := dbmock.NewDB(t)
+			service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+			database.On("CheckoutCart", mock.Anything, 1, "", 0).Return(nil, tc.err)
+
+			_, err := service.CheckoutCart(ctxWithUserID, "")
+			require.Equal(t, xerrors.New(tc.err, tc.code), err)
+		}
+	})
+
+	t.Run("positive result", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		summary := &models.OrderSummary{
+			Order: models.Order{
+				ID: 7, Status: models.OrderStatusPlaced,
+				Items: []models.OrderLine{{Item: "cup", Quantity: 2, Price: 20, Total: 40}}, Total: 40,
+			},
+			Balance: 960,
+		}
+		database.On("CheckoutCart", mock.Anything, 1, "", 0).Return(summary, nil)
+
+		result, err := service.CheckoutCart(ctxWithUserID, "")
+		require.Nil(t, err)
+		require.Equal(t, summary, result)
+	})
+}
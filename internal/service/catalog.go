@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"merch_shop/internal/db"
+	"merch_shop/internal/models"
+	"merch_shop/pkg/xerrors"
+	"net/http"
+	"strconv"
+)
+
+func (s *merchShopService) GetItems(ctx context.Context) ([]models.CatalogItem, xerrors.Xerror) {
+	catalog, servErr := s.getCatalog(ctx)
+	if servErr != nil {
+		return nil, servErr
+	}
+
+	return s.withStock(ctx, catalog.items)
+}
+
+func (s *merchShopService) GetItem(ctx context.Context, itemIDStr string) (*models.CatalogItem, xerrors.Xerror) {
+	item, servErr := s.catalogItem(ctx, itemIDStr)
+	if servErr != nil {
+		return nil, servErr
+	}
+
+	items, servErr := s.withStock(ctx, []models.CatalogItem{*item})
+	if servErr != nil {
+		return nil, servErr
+	}
+
+	return &items[0], nil
+}
+
+// catalogItem finds an item in the cached catalog by id, its stock may be stale.
+func (s *merchShopService) catalogItem(ctx context.Context, itemIDStr string) (*models.CatalogItem, xerrors.Xerror) {
+	itemID, err := strconv.Atoi(itemIDStr)
+	if err != nil {
+		return nil, xerrors.New(db.ErrNoItem, http.StatusNotFound)
+	}
+
+	catalog, servErr := s.getCatalog(ctx)
+	if servErr != nil {
+		return nil, servErr
+	}
+
+	item, ok := catalog.byID[itemID]
+	if !ok {
+		return nil, xerrors.New(db.ErrNoItem, http.StatusNotFound)
+	}
+
+	return &item, nil
+}
+
+// withStock copies cached items with the current stock of tracked variants. Purchases, cancellations
+// and jobs change stock without reloading the catalog, so availability is never read from the cache.
+func (s *merchShopService) withStock(ctx context.Context, items []models.CatalogItem) ([]models.CatalogItem, xerrors.Xerror) {
+	stock, err := s.storage.GetVariantStock(ctx)
+	if err != nil {
+		s.logger.Error("get variant stock: " + err.Error())
+		return nil, xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+
+	stocked := make([]models.CatalogItem, len(items))
+	for i, item := range items {
+		stocked[i] = item
+		if !hasTrackedVariants(&item) {
+			continue
+		}
+
+		// Variants of the snapshot are shared with other requests and are never modified.
+		stocked[i].Variants = append([]models.ItemVariant(nil), item.Variants...)
+		stocked[i].Available = false
+		for j := range stocked[i].Variants {
+			variant := &stocked[i].Variants[j]
+			if quantity, ok := stock[variant.ID]; ok && variant.Stock != nil {
+				variant.Stock = &quantity
+			}
+			variant.Available = item.Active && (variant.Stock == nil || *variant.Stock > 0)
+			stocked[i].Available = stocked[i].Available || variant.Available
+		}
+	}
+
+	return stocked, nil
+}
+
+func (s *merchShopService) getCatalog(ctx context.Context) (*catalogSnapshot, xerrors.Xerror) {
+	catalog, err := s.catalog.get(ctx, s.storage.GetItems)
+	if err != nil {
+		s.logger.Error("get items: " + err.Error())
+		return nil, xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+
+	return catalog, nil
+}
+
+func hasTrackedVariants(item *models.CatalogItem) bool {
+	for _, variant := range item.Variants {
+		if variant.Stock != nil {
+			return true
+		}
+	}
+	return false
+}
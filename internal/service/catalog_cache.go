@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"merch_shop/internal/models"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// catalogCache keeps the item catalog in process memory. The catalog is small
+// and read on every listing, so it is reloaded as a whole once ttl expires
+// or any price changes, whichever comes first. Stock changes with every purchase,
+// listings read it from the storage instead, see withStock.
+type catalogCache struct {
+	ttl time.Duration
+
+	mu        sync.RWMutex
+	snapshot  *catalogSnapshot
+	expiresAt time.Time
+}
+
+// catalogSnapshot is never modified after creation and can be shared between requests.
+type catalogSnapshot struct {
+	// items hold only active items, retired ones are resolvable by id and type.
+	items  []models.CatalogItem
+	byID   map[int]models.CatalogItem
+	byType map[string]models.CatalogItem
+}
+
+func newCatalogCache(ttl time.Duration) *catalogCache {
+	return &catalogCache{ttl: ttl}
+}
+
+// get returns cached catalog, loading it with fetch when missing or expired.
+func (c *catalogCache) get(ctx context.Context,
+	fetch func(ctx context.Context) ([]models.CatalogItem, error)) (*catalogSnapshot, error) {
+	c.mu.RLock()
+	snapshot, expiresAt := c.snapshot, c.expiresAt
+	c.mu.RUnlock()
+	if snapshot != nil && time.Now().Before(expiresAt) {
+		return snapshot, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Another request may have reloaded the catalog while this one waited for the lock.
+	if c.snapshot != nil && time.Now().Before(c.expiresAt) {
+		return c.snapshot, nil
+	}
+
+	items, err := fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt = time.Now().Add(c.ttl)
+	snapshot = &catalogSnapshot{
+		items:  make([]models.CatalogItem, 0, len(items)),
+		byID:   make(map[int]models.CatalogItem, len(items)),
+		byType: make(map[string]models.CatalogItem, len(items)),
+	}
+	for _, item := range items {
+		snapshot.byID[item.ID] = item
+		snapshot.byType[item.Type] = item
+		if item.Active {
+			snapshot.items = append(snapshot.items, item)
+		}
+		if item.PriceValidUntil != nil && item.PriceValidUntil.Before(expiresAt) {
+			expiresAt = *item.PriceValidUntil
+		}
+	}
+
+	c.snapshot, c.expiresAt = snapshot, expiresAt
+	return snapshot, nil
+}
+
+// invalidate drops the cached catalog, so the next request reads the storage.
+func (c *catalogCache) invalidate() {
+	c.mu.Lock()
+	c.snapshot = nil
+	c.mu.Unlock()
+}
+
+// lookup finds an item by its name, falling back to a numeric id.
+func (c *catalogSnapshot) lookup(item string) (models.CatalogItem, bool) {
+	if found, ok := c.byType[item]; ok {
+		return found, true
+	}
+
+	itemID, err := strconv.Atoi(item)
+	if err != nil {
+		return models.CatalogItem{}, false
+	}
+
+	found, ok := c.byID[itemID]
+	return found, ok
+}
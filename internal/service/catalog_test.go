@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"merch_shop/internal/db"
+	dbmock "merch_shop/internal/db/mocks"
+	"merch_shop/internal/models"
+	cryptormock "merch_shop/pkg/cryptor/mocks"
+	tokenizermock "merch_shop/pkg/tokenizer/mocks"
+	"merch_shop/pkg/xerrors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetItems(t *testing.T) {
+	items := []models.CatalogItem{
+		{ID: 1, Type: "t-shirt", Price: 80, Active: true, Available: true},
+		{ID: 2, Type: "cup", Price: 20, Active: true, Available: true},
+	}
+
+	t.Run("get items db error", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("GetItems", mock.Anything).Return(nil, errors.New("some error"))
+
+		_, err := service.GetItems(context.Background())
+		require.Equal(t, xerrors.New(errSmthWentWrong, http.StatusInternalServerError), err)
+	})
+
+	t.Run("catalog is cached", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("GetItems", mock.Anything).Return(items, nil).Once()
+		database.On("GetVariantStock", mock.Anything).Return(map[int]int{}, nil).Times(3)
+
+		for range 3 {
+			result, err := service.GetItems(context.Background())
+			require.Nil(t, err)
+			require.Equal(t, items, result)
+		}
+	})
+
+	t.Run("catalog is reloaded after ttl", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		cfg := testShopConfig
+		cfg.CatalogCacheTTL = 0
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), cfg)
+
+		database.On("GetItems", mock.Anything).Return(items, nil).Twice()
+		database.On("GetVariantStock", mock.Anything).Return(map[int]int{}, nil)
+
+		for range 2 {
+			_, err := service.GetItems(context.Background())
+			require.Nil(t, err)
+		}
+	})
+
+	t.Run("catalog is reloaded when a price changes", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		// The sale has ended by the time the catalog is read again.
+		saleEnd := time.Now().Add(-time.Second)
+		onSale := []models.CatalogItem{{ID: 1, Type: "t-shirt", Price: 60, PriceValidUntil: &saleEnd, Active: true}}
+		database.On("GetItems", mock.Anything).Return(onSale, nil).Twice()
+		database.On("GetVariantStock", mock.Anything).Return(map[int]int{}, nil)
+
+		for range 2 {
+			_, err := service.GetItems(context.Background())
+			require.Nil(t, err)
+		}
+	})
+
+	t.Run("stock is read past the cache", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		inStock, soldOut := 1, 0
+		cached := []models.CatalogItem{{ID: 1, Type: "t-shirt", Price: 80, Active: true, Available: true,
+			Variants: []models.ItemVariant{{ID: 5, Name: "default", Price: 80, Stock: &inStock, Available: true}}}}
+		database.On("GetItems", mock.Anything).Return(cached, nil).Once()
+		// The last piece is sold after the catalog is cached.
+		database.On("GetVariantStock", mock.Anything).Return(map[int]int{5: 1}, nil).Once()
+		database.On("GetVariantStock", mock.Anything).Return(map[int]int{5: 0}, nil).Once()
+
+		result, err := service.GetItems(context.Background())
+		require.Nil(t, err)
+		require.Equal(t, cached, result)
+
+		result, err = service.GetItems(context.Background())
+		require.Nil(t, err)
+		require.Equal(t, []models.CatalogItem{{ID: 1, Type: "t-shirt", Price: 80, Active: true,
+			Variants: []models.ItemVariant{{ID: 5, Name: "default", Price: 80, Stock: &soldOut}}}}, result)
+		// The cached snapshot is left as it was.
+		require.Equal(t, 1, *cached[0].Variants[0].Stock)
+	})
+}
+
+func TestGetItem(t *testing.T) {
+	database := dbmock.NewDB(t)
+	service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+	database.On("GetItems", mock.Anything).Return([]models.CatalogItem{
+		{ID: 1, Type: "t-shirt", Price: 80, Active: true, Available: true},
+	}, nil).Once()
+	database.On("GetVariantStock", mock.Anything).Return(map[int]int{}, nil)
+
+	t.Run("positive result", func(t *testing.T) {
+		item, err := service.GetItem(context.Background(), "1")
+		require.Nil(t, err)
+		require.Equal(t, &models.CatalogItem{ID: 1, Type: "t-shirt", Price: 80, Active: true, Available: true}, item)
+	})
+
+	t.Run("item not found", func(t *testing.T) {
+		_, err := service.GetItem(context.Background(), "2")
+		require.Equal(t, xerrors.New(db.ErrNoItem, http.StatusNotFound), err)
+	})
+}
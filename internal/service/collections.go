@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"merch_shop/internal/db"
+	"merch_shop/internal/models"
+	"merch_shop/pkg/middleware"
+	"merch_shop/pkg/xerrors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const minContribution = 1
+
+var (
+	errDeadlineInvalid     = errors.New("deadline is invalid: must be in the future")
+	errContributionInvalid = fmt.Errorf("contribution is invalid: min %d", minContribution)
+)
+
+// GetCollections returns collections still accepting contributions, the nearest deadline first.
+func (s *merchShopService) GetCollections(ctx context.Context) ([]models.Collection, xerrors.Xerror) {
+	return s.getCollections(ctx, true)
+}
+
+// GetAllCollections returns collections including completed and refunded ones.
+func (s *merchShopService) GetAllCollections(ctx context.Context) ([]models.Collection, xerrors.Xerror) {
+	if _, servErr := s.requireAdmin(ctx); servErr != nil {
+		return nil, servErr
+	}
+
+	return s.getCollections(ctx, false)
+}
+
+func (s *merchShopService) getCollections(ctx context.Context, openOnly bool) ([]models.Collection, xerrors.Xerror) {
+	collections, err := s.storage.GetCollections(ctx, openOnly)
+	if err != nil {
+		s.logger.Error("get collections: " + err.Error())
+		return nil, xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+
+	return collections, nil
+}
+
+func (s *merchShopService) GetCollection(ctx context.Context, collectionIDStr string) (*models.Collection, xerrors.Xerror) {
+	collectionID, err := strconv.Atoi(collectionIDStr)
+	if err != nil {
+		return nil, xerrors.New(db.ErrNoCollection, http.StatusNotFound)
+	}
+
+	collection, err := s.storage.GetCollection(ctx, collectionID)
+	if err != nil {
+		return nil, s.collectionError("get collection", err)
+	}
+
+	return collection, nil
+}
+
+// CreateCollection starts pooling coins for an item the recipient gets once its current price is collected.
+// A unit of the item is kept aside for the recipient until the collection is completed or refunded.
+func (s *merchShopService) CreateCollection(ctx context.Context,
+	request *models.CollectionRequest) (*models.Collection, xerrors.Xerror) {
+	userID, ok := ctx.Value(middleware.UserIDKey).(int)
+	if !ok {
+		return nil, xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+
+	if !request.Deadline.After(time.Now()) {
+		return nil, xerrors.New(errDeadlineInvalid, http.StatusBadRequest)
+	}
+
+	line, servErr := s.cartLine(ctx, request.Item, request.Variant, minPurchaseQuantity)
+	if servErr != nil {
+		return nil, servErr
+	}
+
+	created, err := s.storage.CreateCollection(ctx, userID, request.Recipient, line.VariantID, request.Deadline,
+		s.cfg.ApprovalThreshold)
+	if err != nil {
+		var limitErr *db.PurchaseLimitError
+		if errors.As(err, &limitErr) {
+			return nil, xerrors.New(err, http.StatusConflict)
+		}
+
+		switch err {
+		case db.ErrNoUser, db.ErrGiftToSelf, db.ErrNoItem, db.ErrOverThreshold:
+			return nil, xerrors.New(err, http.StatusBadRequest)
+		case db.ErrOutOfStock:
+			return nil, xerrors.New(err, http.StatusConflict)
+		}
+		s.logger.Error("create collection: " + err.Error())
+		return nil, xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+
+	return created, nil
+}
+
+// Contribute adds coins of the user to the collection, the contribution reaching the target buys the item.
+func (s *merchShopService) Contribute(ctx context.Context, collectionIDStr string,
+	contribution *models.ContributionRequest) (*models.Collection, xerrors.Xerror) {
+	userID, ok := ctx.Value(middleware.UserIDKey).(int)
+	if !ok {
+		return nil, xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+
+	collectionID, err := strconv.Atoi(collectionIDStr)
+	if err != nil {
+		return nil, xerrors.New(db.ErrNoCollection, http.StatusNotFound)
+	}
+
+	if contribution.Amount < minContribution {
+		return nil, xerrors.New(errContributionInvalid, http.StatusBadRequest)
+	}
+
+	err = s.storage.Contribute(ctx, userID, collectionID, contribution.Amount)
+	if err != nil {
+		return nil, s.collectionError("contribute", err)
+	}
+
+	return s.GetCollection(ctx, collectionIDStr)
+}
+
+func (s *merchShopService) collectionError(operation string, err error) xerrors.Xerror {
+	var limitErr *db.PurchaseLimitError
+	if errors.As(err, &limitErr) {
+		return xerrors.New(err, http.StatusConflict)
+	}
+
+	switch err {
+	case db.ErrNoCollection:
+		return xerrors.New(err, http.StatusNotFound)
+	case db.ErrOverTarget, db.ErrNotEnoughCoins:
+		return xerrors.New(err, http.StatusBadRequest)
+	case db.ErrCollectionOver:
+		return xerrors.New(err, http.StatusConflict)
+	}
+	s.logger.Error(operation + ": " + err.Error())
+	return xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"merch_shop/internal/db"
+	dbmock "merch_shop/internal/db/mocks"
+	"merch_shop/internal/models"
+	cryptormock "merch_shop/pkg/cryptor/mocks"
+	"merch_shop/pkg/middleware"
+	tokenizermock "merch_shop/pkg/tokenizer/mocks"
+	"merch_shop/pkg/xerrors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateCollection(t *testing.T) {
+	ctxWithUserID := context.WithValue(context.Background(), middleware.UserIDKey, 1)
+	deadline := time.Now().Add(7 * 24 * time.Hour)
+	threshold := testShopConfig.ApprovalThreshold
+	catalogItems := []models.CatalogItem{{ID: 1, Type: "hoody", Price: 300, Active: true,
+		Variants: []models.ItemVariant{{ID: 2, Name: "default", Price: 300}}}}
+	limitErr := &db.PurchaseLimitError{Item: "hoody", Limit: models.PurchaseLimit{Quantity: 1, WindowDays: 365}}
+
+	testCases := []struct {
+		name       string
+		request    *models.CollectionRequest
+		dbBehavior func(database *dbmock.DB)
+
+		expected *models.Collection
+		err      xerrors.Xerror
+	}{
+		{
+			name:    "deadline has passed",
+			request: &models.CollectionRequest{Recipient: "bob", Item: "hoody", Deadline: time.Now().Add(-time.Hour)},
+			err:     xerrors.New(errDeadlineInvalid, http.StatusBadRequest),
+		},
+		{
+			name:    "unknown recipient",
+			request: &models.CollectionRequest{Recipient: "bob", Item: "hoody", Deadline: deadline},
+			dbBehavior: func(database *dbmock.DB) {
+				database.On("GetItems", mock.Anything).Return(catalogItems, nil)
+				database.On("CreateCollection", mock.Anything, 1, "bob", 2, deadline, threshold).Return(nil, db.ErrNoUser)
+			},
+			err: xerrors.New(db.ErrNoUser, http.StatusBadRequest),
+		},
+		{
+			name:    "item is out of stock",
+			request: &models.CollectionRequest{Recipient: "bob", Item: "hoody", Deadline: deadline},
+			dbBehavior: func(database *dbmock.DB) {
+				database.On("GetItems", mock.Anything).Return(catalogItems, nil)
+				database.On("CreateCollection", mock.Anything, 1, "bob", 2, deadline, threshold).Return(nil, db.ErrOutOfStock)
+			},
+			err: xerrors.New(db.ErrOutOfStock, http.StatusConflict),
+		},
+		{
+			name:    "item above the approval threshold",
+			request: &models.CollectionRequest{Recipient: "bob", Item: "hoody", Deadline: deadline},
+			dbBehavior: func(database *dbmock.DB) {
+				database.On("GetItems", mock.Anything).Return(catalogItems, nil)
+				database.On("CreateCollection", mock.Anything, 1, "bob", 2, deadline, threshold).
+					Return(nil, db.ErrOverThreshold)
+			},
+			err: xerrors.New(db.ErrOverThreshold, http.StatusBadRequest),
+		},
+		{
+			name:    "purchase limit is reached",
+			request: &models.CollectionRequest{Recipient: "bob", Item: "hoody", Deadline: deadline},
+			dbBehavior: func(database *dbmock.DB) {
+				database.On("GetItems", mock.Anything).Return(catalogItems, nil)
+				database.On("CreateCollection", mock.Anything, 1, "bob", 2, deadline, threshold).Return(nil, limitErr)
+			},
+			err: xerrors.New(limitErr, http.StatusConflict),
+		},
+		{
+			name:    "positive result",
+			request: &models.CollectionRequest{Recipient: "bob", Item: "hoody", Deadline: deadline},
+			dbBehavior: func(database *dbmock.DB) {
+				database.On("GetItems", mock.Anything).Return(catalogItems, nil)
+				database.On("CreateCollection", mock.Anything, 1, "bob", 2, deadline, threshold).
+					Return(&models.Collection{ID: 3, Creator: "alice", Recipient: "bob", Item: "hoody", Target: 300}, nil)
+			},
+			expected: &models.Collection{ID: 3, Creator: "alice", Recipient: "bob", Item: "hoody", Target: 300},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			database := dbmock.NewDB(t)
+			service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+			if tc.dbBehavior != nil {
+				tc.dbBehavior(database)
+			}
+
+			collection, err := service.CreateCollection(ctxWithUserID, tc.request)
+			if tc.err != nil {
+				require.Equal(t, tc.err, err)
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, tc.expected, collection)
+		})
+	}
+}
+
+func TestContribute(t *testing.T) {
+	ctxWithUserID := context.WithValue(context.Background(), middleware.UserIDKey, 5)
+	limitErr := &db.PurchaseLimitError{Item: "hoody", Limit: models.PurchaseLimit{Quantity: 1, WindowDays: 365}}
+
+	t.Run("storage errors", func(t *testing.T) {
+		testCases := []struct {
+			dbErr    error
+			expected xerrors.Xerror
+		}{
+			{dbErr: db.ErrNoCollection, expected: xerrors.New(db.ErrNoCollection, http.StatusNotFound)},
+			{dbErr: db.ErrOverTarget, expected: xerrors.New(db.ErrOverTarget, http.StatusBadRequest)},
+			{dbErr: db.ErrNotEnoughCoins, expected: xerrors.New(db.ErrNotEnoughCoins, http.StatusBadRequest)},
+			{dbErr: db.ErrCollectionOver, expected: xerrors.New(db.ErrCollectionOver, http.StatusConflict)},
+			{dbErr: limitErr, expected: xerrors.New(limitErr, http.StatusConflict)},
+		}
+
+		for _, tc := range testCases {
+			database := dbmock.NewDB(t)
+			service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+			database.On("Contribute", mock.Anything, 5, 3, 100).Return(tc.dbErr)
+
+			_, err := service.Contribute(ctxWithUserID, "3", &models.ContributionRequest{Amount: 100})
+			require.Equal(t, tc.expected, err)
+		}
+	})
+
+	t.Run("amount is invalid", func(t *testing.T) {
+		service := New(dbmock.NewDB(t), slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		_, err := service.Contribute(ctxWithUserID, "3", &models.ContributionRequest{Amount: -5})
+		require.Equal(t, xerrors.New(errContributionInvalid, http.StatusBadRequest), err)
+	})
+
+	t.Run("positive result", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		orderID := 11
+		completedAt := time.Now()
+		collection := &models.Collection{ID: 3, Recipient: "bob", Target: 300, Raised: 300, Contributors: 2,
+			CompletedAt: &completedAt, OrderID: &orderID}
+		database.On("Contribute", mock.Anything, 5, 3, 100).Return(nil)
+		database.On("GetCollection", mock.Anything, 3).Return(collection, nil)
+
+		res, err := service.Contribute(ctxWithUserID, "3", &models.ContributionRequest{Amount: 100})
+		require.Nil(t, err)
+		require.Equal(t, collection, res)
+	})
+}
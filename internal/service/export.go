@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"merch_shop/internal/models"
+	"merch_shop/pkg/middleware"
+	"merch_shop/pkg/xerrors"
+	"net/http"
+)
+
+var (
+	errExportFormatInvalid = fmt.Errorf("format is invalid: expected %s or %s", models.ExportFormatCSV, models.ExportFormatJSON)
+	errExportPeriodMissing = errors.New("period is required: from and to must be set")
+)
+
+func (s *merchShopService) ExportHistory(ctx context.Context, request *models.ExportRequest,
+	write func(*models.LedgerEntry) error) xerrors.Xerror {
+	userID, ok := ctx.Value(middleware.UserIDKey).(int)
+	if !ok {
+		return xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+
+	filter, err := parseExportRequest(request)
+	if err != nil {
+		return xerrors.New(err, http.StatusBadRequest)
+	}
+	filter.UserID = &userID
+
+	return s.exportLedger(ctx, filter, write)
+}
+
+func (s *merchShopService) ExportAllHistory(ctx context.Context, request *models.ExportRequest,
+	write func(*models.LedgerEntry) error) xerrors.Xerror {
+	if _, servErr := s.requireAdmin(ctx); servErr != nil {
+		return servErr
+	}
+
+	filter, err := parseExportRequest(request)
+	if err != nil {
+		return xerrors.New(err, http.StatusBadRequest)
+	}
+	// Export of every user is bounded by period.
+	if filter.Since == nil || filter.Until == nil {
+		return xerrors.New(errExportPeriodMissing, http.StatusBadRequest)
+	}
+
+	return s.exportLedger(ctx, filter, write)
+}
+
+func (s *merchShopService) exportLedger(ctx context.Context, filter *models.LedgerFilter,
+	write func(*models.LedgerEntry) error) xerrors.Xerror {
+	err := s.storage.ExportLedger(ctx, filter, write)
+	if err != nil {
+		s.logger.Error("export ledger: " + err.Error())
+		return xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+
+	return nil
+}
+
+// parseExportRequest validates format and period, an empty format means csv.
+func parseExportRequest(request *models.ExportRequest) (*models.LedgerFilter, error) {
+	switch request.Format {
+	case "":
+		request.Format = models.ExportFormatCSV
+	case models.ExportFormatCSV, models.ExportFormatJSON:
+	default:
+		return nil, errExportFormatInvalid
+	}
+
+	since, until, err := parseHistoryPeriod(request.From, request.To)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.LedgerFilter{Since: since, Until: until}, nil
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	dbmock "merch_shop/internal/db/mocks"
+	"merch_shop/internal/models"
+	cryptormock "merch_shop/pkg/cryptor/mocks"
+	"merch_shop/pkg/middleware"
+	tokenizermock "merch_shop/pkg/tokenizer/mocks"
+	"merch_shop/pkg/xerrors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportHistory(t *testing.T) {
+	service := New(dbmock.NewDB(t), slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+	ctxEmpty := context.Background()
+	ctxWithUserID := context.WithValue(ctxEmpty, middleware.UserIDKey, 1)
+	discard := func(*models.LedgerEntry) error { return nil }
+
+	t.Run("userID missing error", func(t *testing.T) {
+		err := service.ExportHistory(ctxEmpty, &models.ExportRequest{}, discard)
+		require.Equal(t, xerrors.New(errSmthWentWrong, http.StatusInternalServerError), err)
+	})
+
+	t.Run("format invalid error", func(t *testing.T) {
+		err := service.ExportHistory(ctxWithUserID, &models.ExportRequest{Format: "xml"}, discard)
+		require.Equal(t, xerrors.New(errExportFormatInvalid, http.StatusBadRequest), err)
+	})
+
+	t.Run("period invalid error", func(t *testing.T) {
+		err := service.ExportHistory(ctxWithUserID, &models.ExportRequest{From: "2025-02-02", To: "2025-02-01"}, discard)
+		require.Equal(t, xerrors.New(errHistoryDateRange, http.StatusBadRequest), err)
+	})
+
+	t.Run("export db error", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("ExportLedger", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("some error"))
+
+		err := service.ExportHistory(ctxWithUserID, &models.ExportRequest{}, discard)
+		require.Equal(t, xerrors.New(errSmthWentWrong, http.StatusInternalServerError), err)
+	})
+
+	t.Run("positive result defaults to csv", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		entry := &models.LedgerEntry{ID: "purchase-1", Kind: models.LedgerKindPurchase, Username: "alice", Item: "cup", Amount: -20}
+		database.On("ExportLedger", mock.Anything, mock.MatchedBy(func(f *models.LedgerFilter) bool {
+			return f.UserID != nil && *f.UserID == 1
+		}), mock.Anything).Return(func(_ context.Context, _ *models.LedgerFilter, write func(*models.LedgerEntry) error) error {
+			return write(entry)
+		})
+
+		written := make([]*models.LedgerEntry, 0)
+		request := &models.ExportRequest{}
+		err := service.ExportHistory(ctxWithUserID, request, func(e *models.LedgerEntry) error {
+			written = append(written, e)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, []*models.LedgerEntry{entry}, written)
+		require.Equal(t, models.ExportFormatCSV, request.Format)
+	})
+}
+
+func TestExportAllHistory(t *testing.T) {
+	ctxWithUserID := context.WithValue(context.Background(), middleware.UserIDKey, 1)
+	discard := func(*models.LedgerEntry) error { return nil }
+	period := &models.ExportRequest{Format: models.ExportFormatJSON, From: "2025-01-01", To: "2025-01-31"}
+
+	t.Run("not admin error", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("IsAdmin", mock.Anything, 1).Return(false, nil)
+
+		err := service.ExportAllHistory(ctxWithUserID, period, discard)
+		require.Equal(t, xerrors.New(errAdminRequired, http.StatusForbidden), err)
+	})
+
+	t.Run("check admin db error", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("IsAdmin", mock.Anything, 1).Return(false, errors.New("some error"))
+
+		err := service.ExportAllHistory(ctxWithUserID, period, discard)
+		require.Equal(t, xerrors.New(errSmthWentWrong, http.StatusInternalServerError), err)
+	})
+
+	t.Run("period missing error", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("IsAdmin", mock.Anything, 1).Return(true, nil)
+
+		err := service.ExportAllHistory(ctxWithUserID, &models.ExportRequest{From: "2025-01-01"}, discard)
+		require.Equal(t, xerrors.New(errExportPeriodMissing, http.StatusBadRequest), err)
+	})
+
+	t.Run("positive result", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("IsAdmin", mock.Anything, 1).Return(true, nil)
+		database.On("ExportLedger", mock.Anything, mock.MatchedBy(func(f *models.LedgerFilter) bool {
+			return f.UserID == nil && f.Since != nil && f.Until != nil
+		}), mock.Anything).Return(nil)
+
+		err := service.ExportAllHistory(ctxWithUserID, period, discard)
+		require.NoError(t, err)
+	})
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"merch_shop/internal/db"
+	"merch_shop/internal/models"
+	"merch_shop/pkg/middleware"
+	"merch_shop/pkg/xerrors"
+	"net/http"
+)
+
+const maxGiftNoteLength = 200
+
+var errGiftNoteInvalid = fmt.Errorf("note is invalid: length max %d", maxGiftNoteLength)
+
+// BuyGift buys an item for another user, the payer is charged and the recipient gets the item.
+// A missing quantity buys one item.
+func (s *merchShopService) BuyGift(ctx context.Context, request *models.GiftRequest) (*models.OrderSummary, xerrors.Xerror) {
+	userID, ok := ctx.Value(middleware.UserIDKey).(int)
+	if !ok {
+		return nil, xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+
+	if len([]rune(request.Note)) > maxGiftNoteLength {
+		return nil, xerrors.New(errGiftNoteInvalid, http.StatusBadRequest)
+	}
+
+	quantity := request.Quantity
+	if quantity == 0 {
+		quantity = minPurchaseQuantity
+	}
+
+	line, servErr := s.cartLine(ctx, request.Item, request.Variant, quantity)
+	if servErr != nil {
+		return nil, servErr
+	}
+
+	summary, err := s.storage.BuyGift(ctx, userID, request.ToUser, []models.CartLine{*line},
+		normalizePromoCode(request.Promo), request.Note, s.cfg.ApprovalThreshold)
+	if err != nil {
+		if err == db.ErrNoUser || err == db.ErrGiftToSelf {
+			return nil, xerrors.New(err, http.StatusBadRequest)
+		}
+		return nil, s.purchaseError("buy gift", err)
+	}
+
+	return summary, nil
+}
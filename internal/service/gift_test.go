@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"merch_shop/internal/db"
+	dbmock "merch_shop/internal/db/mocks"
+	"merch_shop/internal/models"
+	cryptormock "merch_shop/pkg/cryptor/mocks"
+	"merch_shop/pkg/middleware"
+	tokenizermock "merch_shop/pkg/tokenizer/mocks"
+	"merch_shop/pkg/xerrors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuyGift(t *testing.T) {
+	ctxWithUserID := context.WithValue(context.Background(), middleware.UserIDKey, 1)
+	catalog := []models.CatalogItem{
+		{ID: 1, Type: "cup", Price: 20, Active: true, Available: true, Variants: []models.ItemVariant{
+			{ID: 2, ItemID: 1, SKU: "cup", Name: "default", Price: 20, Available: true},
+		}},
+	}
+
+	t.Run("userID missing error", func(t *testing.T) {
+		service := New(dbmock.NewDB(t), slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		_, err := service.BuyGift(context.Background(), &models.GiftRequest{ToUser: "bob", Item: "cup"})
+		require.Equal(t, xerrors.New(errSmthWentWrong, http.StatusInternalServerError), err)
+	})
+
+	t.Run("note too long", func(t *testing.T) {
+		service := New(dbmock.NewDB(t), slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		request := &models.GiftRequest{ToUser: "bob", Item: "cup", Note: strings.Repeat("a", maxGiftNoteLength+1)}
+		_, err := service.BuyGift(ctxWithUserID, request)
+		require.Equal(t, xerrors.New(errGiftNoteInvalid, http.StatusBadRequest), err)
+	})
+
+	t.Run("storage errors", func(t *testing.T) {
+		testCases := []struct {
+			name     string
+			dbErr    error
+			expected xerrors.Xerror
+		}{
+			{name: "unknown recipient", dbErr: db.ErrNoUser, expected: xerrors.New(db.ErrNoUser, http.StatusBadRequest)},
+			{name: "gift to yourself", dbErr: db.ErrGiftToSelf, expected: xerrors.New(db.ErrGiftToSelf, http.StatusBadRequest)},
+			{name: "not enough coins", dbErr: db.ErrNotEnoughCoins,
+				expected: xerrors.New(db.ErrNotEnoughCoins, http.StatusBadRequest)},
+			{name: "out of stock", dbErr: db.ErrOutOfStock, expected: xerrors.New(db.ErrOutOfStock, http.StatusConflict)},
+			{name: "unexpected", dbErr: errors.New("some error"),
+				expected: xerrors.New(errSmthWentWrong, http.StatusInternalServerError)},
+		}
+
+		for _, tc := range testCases {
+			database := dbmock.NewDB(t)
+			service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+			database.On("GetItems", mock.Anything).Return(catalog, nil)
+			database.On("BuyGift", mock.Anything, 1, "bob", mock.Anything, "", "", 0).Return(nil, tc.dbErr)
+
+			_, err := service.BuyGift(ctxWithUserID, &models.GiftRequest{ToUser: "bob", Item: "cup"})
+			require.Equal(t, tc.expected, err, tc.name)
+		}
+	})
+
+	t.Run("positive result", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		summary := &models.OrderSummary{Order: models.Order{ID: 7, Gift: &models.OrderGift{To: "bob", Note: "enjoy"}}}
+		database.On("GetItems", mock.Anything).Return(catalog, nil)
+		// A missing quantity buys one item, the promo code is normalized.
+		database.On("BuyGift", mock.Anything, 1, "bob", []models.CartLine{{VariantID: 2, Quantity: 1}}, "CUPS10", "enjoy", 0).
+			Return(summary, nil)
+
+		result, err := service.BuyGift(ctxWithUserID, &models.GiftRequest{ToUser: "bob", Item: "cup", Note: "enjoy", Promo: " cups10 "})
+		require.Nil(t, err)
+		require.Equal(t, summary, result)
+	})
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"merch_shop/internal/db"
+	"merch_shop/internal/models"
+	"merch_shop/pkg/middleware"
+	"merch_shop/pkg/xerrors"
+	"net/http"
+)
+
+const minItemsForTransfer = 1
+
+var errGiveQuantityInvalid = fmt.Errorf("quantity is invalid: min %d", minItemsForTransfer)
+
+// GiveItem moves owned items to another user. Retired items can be given away as well,
+// a missing quantity gives one item.
+func (s *merchShopService) GiveItem(ctx context.Context, request *models.GiveItemRequest) xerrors.Xerror {
+	userID, ok := ctx.Value(middleware.UserIDKey).(int)
+	if !ok {
+		return xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+
+	quantity := request.Quantity
+	if quantity == 0 {
+		quantity = minItemsForTransfer
+	}
+	if quantity < minItemsForTransfer {
+		return xerrors.New(errGiveQuantityInvalid, http.StatusBadRequest)
+	}
+
+	catalog, servErr := s.getCatalog(ctx)
+	if servErr != nil {
+		return servErr
+	}
+
+	item, ok := catalog.lookup(request.Item)
+	if !ok {
+		return xerrors.New(db.ErrNoItem, http.StatusBadRequest)
+	}
+
+	variant, servErr := itemVariant(&item, request.Variant)
+	if servErr != nil {
+		return servErr
+	}
+
+	err := s.storage.GiveItem(ctx, userID, request.ToUser, models.CartLine{VariantID: variant.ID, Quantity: quantity})
+	if err != nil {
+		switch err {
+		case db.ErrNoUser, db.ErrGiveToSelf, db.ErrNotEnoughItems:
+			return xerrors.New(err, http.StatusBadRequest)
+		}
+		s.logger.Error("give item: " + err.Error())
+		return xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+
+	return nil
+}
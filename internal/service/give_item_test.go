@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"merch_shop/internal/db"
+	dbmock "merch_shop/internal/db/mocks"
+	"merch_shop/internal/models"
+	cryptormock "merch_shop/pkg/cryptor/mocks"
+	"merch_shop/pkg/middleware"
+	tokenizermock "merch_shop/pkg/tokenizer/mocks"
+	"merch_shop/pkg/xerrors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGiveItem(t *testing.T) {
+	ctxWithUserID := context.WithValue(context.Background(), middleware.UserIDKey, 1)
+	catalog := []models.CatalogItem{
+		{ID: 4, Type: "pen", Price: 10, Active: true, Available: true, Variants: []models.ItemVariant{
+			{ID: 7, ItemID: 4, SKU: "pen-blue", Name: "blue", Price: 10, Available: true},
+			{ID: 8, ItemID: 4, SKU: "pen-red", Name: "red", Price: 10, Available: true},
+		}},
+		{ID: 5, Type: "old-pen", Price: 5, Variants: []models.ItemVariant{
+			{ID: 9, ItemID: 5, SKU: "old-pen", Name: "default", Price: 5},
+		}},
+	}
+
+	t.Run("userID missing error", func(t *testing.T) {
+		service := New(dbmock.NewDB(t), slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		err := service.GiveItem(context.Background(), &models.GiveItemRequest{ToUser: "bob", Item: "pen", Variant: "blue"})
+		require.Equal(t, xerrors.New(errSmthWentWrong, http.StatusInternalServerError), err)
+	})
+
+	t.Run("invalid params validation", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("GetItems", mock.Anything).Return(catalog, nil).Maybe()
+
+		testCases := []struct {
+			name        string
+			request     models.GiveItemRequest
+			expectedErr error
+		}{
+			{name: "negative quantity", request: models.GiveItemRequest{ToUser: "bob", Item: "pen", Variant: "blue", Quantity: -1},
+				expectedErr: errGiveQuantityInvalid},
+			{name: "variant missing", request: models.GiveItemRequest{ToUser: "bob", Item: "pen"}, expectedErr: errVariantRequired},
+			{name: "unknown item", request: models.GiveItemRequest{ToUser: "bob", Item: "car"}, expectedErr: db.ErrNoItem},
+		}
+
+		for _, tc := range testCases {
+			err := service.GiveItem(ctxWithUserID, &tc.request)
+			require.Equal(t, xerrors.New(tc.expectedErr, http.StatusBadRequest), err, tc.name)
+		}
+	})
+
+	t.Run("storage errors", func(t *testing.T) {
+		testCases := []struct {
+			name     string
+			dbErr    error
+			expected xerrors.Xerror
+		}{
+			{name: "unknown receiver", dbErr: db.ErrNoUser, expected: xerrors.New(db.ErrNoUser, http.StatusBadRequest)},
+			{name: "give to yourself", dbErr: db.ErrGiveToSelf, expected: xerrors.New(db.ErrGiveToSelf, http.StatusBadRequest)},
+			{name: "not enough items", dbErr: db.ErrNotEnoughItems,
+				expected: xerrors.New(db.ErrNotEnoughItems, http.StatusBadRequest)},
+			{name: "unexpected", dbErr: errors.New("some error"),
+				expected: xerrors.New(errSmthWentWrong, http.StatusInternalServerError)},
+		}
+
+		for _, tc := range testCases {
+			database := dbmock.NewDB(t)
+			service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+			database.On("GetItems", mock.Anything).Return(catalog, nil)
+			database.On("GiveItem", mock.Anything, 1, "bob", mock.Anything).Return(tc.dbErr)
+
+			err := service.GiveItem(ctxWithUserID, &models.GiveItemRequest{ToUser: "bob", Item: "pen", Variant: "red"})
+			require.Equal(t, tc.expected, err, tc.name)
+		}
+	})
+
+	t.Run("positive result", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("GetItems", mock.Anything).Return(catalog, nil)
+		database.On("GiveItem", mock.Anything, 1, "bob", models.CartLine{VariantID: 8, Quantity: 3}).Return(nil)
+		// Retired items stay in inventories and can be given away, a missing quantity gives one item.
+		database.On("GiveItem", mock.Anything, 1, "bob", models.CartLine{VariantID: 9, Quantity: 1}).Return(nil)
+
+		err := service.GiveItem(ctxWithUserID, &models.GiveItemRequest{ToUser: "bob", Item: "pen", Variant: "pen-red", Quantity: 3})
+		require.Nil(t, err)
+
+		err = service.GiveItem(ctxWithUserID, &models.GiveItemRequest{ToUser: "bob", Item: "old-pen"})
+		require.Nil(t, err)
+	})
+}
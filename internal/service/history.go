@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"merch_shop/internal/models"
+	"merch_shop/pkg/middleware"
+	"merch_shop/pkg/xerrors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 100
+
+	historyDateLayout = time.DateOnly
+)
+
+var (
+	errHistoryDirectionInvalid = fmt.Errorf("direction is invalid: expected %s or %s",
+		models.TransferDirectionSent, models.TransferDirectionReceived)
+	errHistoryDateInvalid = fmt.Errorf("date is invalid: expected %s or RFC 3339 format", historyDateLayout)
+	errHistoryDateRange   = errors.New("date range is invalid: from is after to")
+	errHistoryAmount      = fmt.Errorf("amount is invalid: min %d", minCoinsForTransfer)
+	errHistoryAmountRange = errors.New("amount range is invalid: min is greater than max")
+	errHistoryCursor      = errors.New("cursor is invalid")
+	errHistoryLimit       = fmt.Errorf("limit is invalid: min 1 max %d", maxHistoryLimit)
+)
+
+func (s *merchShopService) GetTransferHistory(ctx context.Context,
+	request *models.TransferHistoryRequest) (*models.TransferPage, xerrors.Xerror) {
+	userID, ok := ctx.Value(middleware.UserIDKey).(int)
+	if !ok {
+		return nil, xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+
+	filter, err := parseTransferHistoryRequest(request)
+	if err != nil {
+		return nil, xerrors.New(err, http.StatusBadRequest)
+	}
+
+	transfers, err := s.storage.GetTransferHistoryByUserID(ctx, userID, filter)
+	if err != nil {
+		s.logger.Error("get transfer history: " + err.Error())
+		return nil, xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+
+	page := &models.TransferPage{Transfers: transfers}
+	if len(transfers) > filter.Limit {
+		page.Transfers = transfers[:filter.Limit]
+		last := page.Transfers[filter.Limit-1]
+		page.NextCursor = encodeTransferCursor(&models.TransferCursor{Timing: last.Timing, ID: last.ID})
+	}
+
+	return page, nil
+}
+
+func parseTransferHistoryRequest(request *models.TransferHistoryRequest) (*models.TransferFilter, error) {
+	filter := &models.TransferFilter{
+		Counterparty: request.Counterparty,
+		Limit:        defaultHistoryLimit,
+	}
+
+	switch request.Direction {
+	case "", models.TransferDirectionSent, models.TransferDirectionReceived:
+		filter.Direction = request.Direction
+	default:
+		return nil, errHistoryDirectionInvalid
+	}
+
+	since, until, err := parseHistoryPeriod(request.From, request.To)
+	if err != nil {
+		return nil, err
+	}
+	filter.Since, filter.Until = since, until
+
+	if request.MinAmount != "" {
+		minAmount, err := strconv.Atoi(request.MinAmount)
+		if err != nil || minAmount < minCoinsForTransfer {
+			return nil, errHistoryAmount
+		}
+		filter.MinAmount = &minAmount
+	}
+	if request.MaxAmount != "" {
+		maxAmount, err := strconv.Atoi(request.MaxAmount)
+		if err != nil || maxAmount < minCoinsForTransfer {
+			return nil, errHistoryAmount
+		}
+		filter.MaxAmount = &maxAmount
+	}
+	if filter.MinAmount != nil && filter.MaxAmount != nil && *filter.MinAmount > *filter.MaxAmount {
+		return nil, errHistoryAmountRange
+	}
+
+	if request.Cursor != "" {
+		cursor, err := decodeTransferCursor(request.Cursor)
+		if err != nil {
+			return nil, errHistoryCursor
+		}
+		filter.After = cursor
+	}
+
+	if request.Limit != "" {
+		limit, err := strconv.Atoi(request.Limit)
+		if err != nil || limit < 1 || limit > maxHistoryLimit {
+			return nil, errHistoryLimit
+		}
+		filter.Limit = limit
+	}
+
+	return filter, nil
+}
+
+// parseHistoryPeriod turns optional from and to dates into an inclusive since
+// and exclusive until bound.
+func parseHistoryPeriod(from, to string) (*time.Time, *time.Time, error) {
+	var since, until *time.Time
+
+	if from != "" {
+		date, _, err := parseHistoryDate(from)
+		if err != nil {
+			return nil, nil, err
+		}
+		since = &date
+	}
+	if to != "" {
+		date, dateOnly, err := parseHistoryDate(to)
+		if err != nil {
+			return nil, nil, err
+		}
+		// A bare date includes the whole day.
+		if dateOnly {
+			date = date.AddDate(0, 0, 1)
+		}
+		until = &date
+	}
+	if since != nil && until != nil && !since.Before(*until) {
+		return nil, nil, errHistoryDateRange
+	}
+
+	return since, until, nil
+}
+
+// parseHistoryDate accepts either a bare date or a full RFC 3339 timestamp
+// and reports which one it got.
+func parseHistoryDate(value string) (time.Time, bool, error) {
+	if date, err := time.Parse(historyDateLayout, value); err == nil {
+		return date, true, nil
+	}
+	timestamp, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false, errHistoryDateInvalid
+	}
+	return timestamp, false, nil
+}
+
+// Cursor is opaque for clients: base64 of "<unix nanoseconds>:<transfer id>".
+func encodeTransferCursor(cursor *models.TransferCursor) string {
+	raw := fmt.Sprintf("%d:%d", cursor.Timing.UnixNano(), cursor.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeTransferCursor(value string) (*models.TransferCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	timing, id, found := strings.Cut(string(raw), ":")
+	if !found {
+		return nil, errHistoryCursor
+	}
+
+	nanos, err := strconv.ParseInt(timing, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	transferID, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TransferCursor{Timing: time.Unix(0, nanos).UTC(), ID: transferID}, nil
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	dbmock "merch_shop/internal/db/mocks"
+	"merch_shop/internal/models"
+	cryptormock "merch_shop/pkg/cryptor/mocks"
+	"merch_shop/pkg/middleware"
+	tokenizermock "merch_shop/pkg/tokenizer/mocks"
+	"merch_shop/pkg/xerrors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetTransferHistory(t *testing.T) {
+	service := New(dbmock.NewDB(t), slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+	ctxEmpty := context.Background()
+	ctxWithUserID := context.WithValue(ctxEmpty, middleware.UserIDKey, 1)
+
+	t.Run("userID missing error", func(t *testing.T) {
+		_, err := service.GetTransferHistory(ctxEmpty, &models.TransferHistoryRequest{})
+		require.Equal(t, xerrors.New(errSmthWentWrong, http.StatusInternalServerError), err)
+	})
+
+	t.Run("invalid params validation", func(t *testing.T) {
+		testCases := []struct {
+			name        string
+			request     models.TransferHistoryRequest
+			expectedErr error
+		}{
+			{name: "unknown direction", request: models.TransferHistoryRequest{Direction: "up"},
+				expectedErr: errHistoryDirectionInvalid},
+			{name: "malformed date", request: models.TransferHistoryRequest{From: "yesterday"},
+				expectedErr: errHistoryDateInvalid},
+			{name: "from after to", request: models.TransferHistoryRequest{From: "2025-02-10", To: "2025-02-01"},
+				expectedErr: errHistoryDateRange},
+			{name: "amount < min", request: models.TransferHistoryRequest{MinAmount: "0"},
+				expectedErr: errHistoryAmount},
+			{name: "min amount > max amount", request: models.TransferHistoryRequest{MinAmount: "10", MaxAmount: "5"},
+				expectedErr: errHistoryAmountRange},
+			{name: "malformed cursor", request: models.TransferHistoryRequest{Cursor: "!!!"},
+				expectedErr: errHistoryCursor},
+			{name: "limit > max", request: models.TransferHistoryRequest{Limit: "101"},
+				expectedErr: errHistoryLimit},
+		}
+
+		for _, tc := range testCases {
+			_, err := service.GetTransferHistory(ctxWithUserID, &tc.request)
+			require.Equal(t, xerrors.New(tc.expectedErr, http.StatusBadRequest), err, tc.name)
+		}
+	})
+
+	t.Run("get history db error", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("GetTransferHistoryByUserID", mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.New("some error"))
+
+		_, err := service.GetTransferHistory(ctxWithUserID, &models.TransferHistoryRequest{})
+		require.Equal(t, xerrors.New(errSmthWentWrong, http.StatusInternalServerError), err)
+	})
+
+	t.Run("filter is passed to db", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		since := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+		until := time.Date(2025, 2, 11, 0, 0, 0, 0, time.UTC)
+		minAmount, maxAmount := 10, 100
+		expectedFilter := &models.TransferFilter{
+			Direction:    models.TransferDirectionSent,
+			Counterparty: "bob",
+			Since:        &since,
+			Until:        &until,
+			MinAmount:    &minAmount,
+			MaxAmount:    &maxAmount,
+			Limit:        20,
+		}
+
+		database.On("GetTransferHistoryByUserID", mock.Anything, 1, expectedFilter).Return([]models.Transfer{}, nil)
+
+		page, err := service.GetTransferHistory(ctxWithUserID, &models.TransferHistoryRequest{
+			Direction:    models.TransferDirectionSent,
+			Counterparty: "bob",
+			From:         "2025-02-01",
+			To:           "2025-02-10",
+			MinAmount:    "10",
+			MaxAmount:    "100",
+			Limit:        "20",
+		})
+		require.NoError(t, err)
+		require.Empty(t, page.NextCursor)
+	})
+
+	t.Run("next page cursor round trip", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		timing := time.Date(2025, 2, 5, 12, 30, 0, 0, time.UTC)
+		transfers := []models.Transfer{
+			{ID: 3, Direction: models.TransferDirectionSent, Username: "bob", Amount: 10, Timing: timing.Add(time.Minute)},
+			{ID: 2, Direction: models.TransferDirectionReceived, Username: "eve", Amount: 20, Timing: timing},
+			{ID: 1, Direction: models.TransferDirectionSent, Username: "bob", Amount: 30, Timing: timing},
+		}
+
+		database.On("GetTransferHistoryByUserID", mock.Anything, 1, mock.MatchedBy(func(f *models.TransferFilter) bool {
+			return f.After == nil
+		})).Return(transfers, nil).Once()
+
+		page, err := service.GetTransferHistory(ctxWithUserID, &models.TransferHistoryRequest{Limit: "2"})
+		require.NoError(t, err)
+		require.Equal(t, transfers[:2], page.Transfers)
+		require.NotEmpty(t, page.NextCursor)
+
+		database.On("GetTransferHistoryByUserID", mock.Anything, 1, mock.MatchedBy(func(f *models.TransferFilter) bool {
+			return f.After != nil && f.After.ID == 2 && f.After.Timing.Equal(timing)
+		})).Return(transfers[2:], nil).Once()
+
+		page, err = service.GetTransferHistory(ctxWithUserID, &models.TransferHistoryRequest{Limit: "2", Cursor: page.NextCursor})
+		require.NoError(t, err)
+		require.Equal(t, transfers[2:], page.Transfers)
+		require.Empty(t, page.NextCursor)
+	})
+}
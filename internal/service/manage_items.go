@@ -0,0 +1,299 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"merch_shop/internal/db"
+	"merch_shop/internal/models"
+	"merch_shop/pkg/xerrors"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// Matches items.type VARCHAR(15).
+	maxItemTypeLength        = 15
+	minItemPrice             = 1
+	maxItemDescriptionLength = 500
+	// Matches items.category VARCHAR(30).
+	maxItemCategoryLength = 30
+	minRestockQuantity    = 1
+	minPurchaseLimit      = 1
+	minPurchaseLimitDays  = 1
+	// Match item_variants.sku VARCHAR(30) and item_variants.name VARCHAR(15).
+	maxVariantSKULength  = 30
+	maxVariantNameLength = 15
+)
+
+var (
+	errItemRetired = errors.New("item is retired and no longer sold")
+
+	errItemTypeInvalid = fmt.Errorf("item type is invalid: length min 1 max %d, "+
+		"no spaces around, no '/' and not a number", maxItemTypeLength)
+	errItemPriceInvalid       = fmt.Errorf("item price is invalid: min %d", minItemPrice)
+	errItemDescriptionInvalid = fmt.Errorf("item description is invalid: length max %d", maxItemDescriptionLength)
+	errItemCategoryInvalid    = fmt.Errorf("item category is invalid: length max %d, no spaces around", maxItemCategoryLength)
+	errItemStockInvalid       = errors.New("item stock is invalid: min 0")
+	errVariantSKUInvalid      = fmt.Errorf("variant sku is invalid: length min 1 max %d, no spaces", maxVariantSKULength)
+	errVariantNameInvalid     = fmt.Errorf("variant name is invalid: length min 1 max %d, no spaces around", maxVariantNameLength)
+	errRestockQuantity        = fmt.Errorf("restock quantity is invalid: min %d", minRestockQuantity)
+	errLowStockThreshold      = errors.New("threshold is invalid: expected a non-negative integer")
+	errPurchaseLimitInvalid   = fmt.Errorf("purchase limit is invalid: quantity min %d, window days min %d",
+		minPurchaseLimit, minPurchaseLimitDays)
+)
+
+// GetAllItems returns the catalog with retired items, bypassing the cache.
+func (s *merchShopService) GetAllItems(ctx context.Context) ([]models.CatalogItem, xerrors.Xerror) {
+	if _, servErr := s.requireAdmin(ctx); servErr != nil {
+		return nil, servErr
+	}
+
+	items, err := s.storage.GetItems(ctx)
+	if err != nil {
+		s.logger.Error("get items: " + err.Error())
+		return nil, xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+
+	return items, nil
+}
+
+func (s *merchShopService) CreateItem(ctx context.Context, item *models.ItemRequest) (*models.CatalogItem, xerrors.Xerror) {
+	if _, servErr := s.requireAdmin(ctx); servErr != nil {
+		return nil, servErr
+	}
+
+	if servErr := validateItemRequest(item); servErr != nil {
+		return nil, servErr
+	}
+
+	created, err := s.storage.CreateItem(ctx, item)
+	if err != nil {
+		return nil, s.itemWriteError("create item", err)
+	}
+
+	s.catalog.invalidate()
+	return created, nil
+}
+
+func (s *merchShopService) UpdateItem(ctx context.Context, itemIDStr string,
+	item *models.ItemRequest) (*models.CatalogItem, xerrors.Xerror) {
+	if _, servErr := s.requireAdmin(ctx); servErr != nil {
+		return nil, servErr
+	}
+
+	itemID, err := strconv.Atoi(itemIDStr)
+	if err != nil {
+		return nil, xerrors.New(db.ErrNoItem, http.StatusNotFound)
+	}
+
+	if servErr := validateItemRequest(item); servErr != nil {
+		return nil, servErr
+	}
+
+	updated, err := s.storage.UpdateItem(ctx, itemID, item)
+	if err != nil {
+		return nil, s.itemWriteError("update item", err)
+	}
+
+	s.catalog.invalidate()
+	return updated, nil
+}
+
+func (s *merchShopService) RetireItem(ctx context.Context, itemID string) (*models.CatalogItem, xerrors.Xerror) {
+	return s.setItemActive(ctx, itemID, false)
+}
+
+func (s *merchShopService) RestoreItem(ctx context.Context, itemID string) (*models.CatalogItem, xerrors.Xerror) {
+	return s.setItemActive(ctx, itemID, true)
+}
+
+func (s *merchShopService) setItemActive(ctx context.Context, itemIDStr string,
+	active bool) (*models.CatalogItem, xerrors.Xerror) {
+	if _, servErr := s.requireAdmin(ctx); servErr != nil {
+		return nil, servErr
+	}
+
+	itemID, err := strconv.Atoi(itemIDStr)
+	if err != nil {
+		return nil, xerrors.New(db.ErrNoItem, http.StatusNotFound)
+	}
+
+	updated, err := s.storage.SetItemActive(ctx, itemID, active)
+	if err != nil {
+		return nil, s.itemWriteError("set item active", err)
+	}
+
+	s.catalog.invalidate()
+	return updated, nil
+}
+
+func (s *merchShopService) CreateVariant(ctx context.Context, itemIDStr string,
+	variant *models.VariantRequest) (*models.CatalogItem, xerrors.Xerror) {
+	if _, servErr := s.requireAdmin(ctx); servErr != nil {
+		return nil, servErr
+	}
+
+	itemID, err := strconv.Atoi(itemIDStr)
+	if err != nil {
+		return nil, xerrors.New(db.ErrNoItem, http.StatusNotFound)
+	}
+
+	if servErr := validateVariantRequest(variant); servErr != nil {
+		return nil, servErr
+	}
+
+	updated, err := s.storage.CreateVariant(ctx, itemID, variant)
+	if err != nil {
+		return nil, s.itemWriteError("create variant", err)
+	}
+
+	s.catalog.invalidate()
+	return updated, nil
+}
+
+// UpdateVariant changes SKU, name and price override, the stock in the request is ignored.
+func (s *merchShopService) UpdateVariant(ctx context.Context, variantIDStr string,
+	variant *models.VariantRequest) (*models.CatalogItem, xerrors.Xerror) {
+	if _, servErr := s.requireAdmin(ctx); servErr != nil {
+		return nil, servErr
+	}
+
+	variantID, err := strconv.Atoi(variantIDStr)
+	if err != nil {
+		return nil, xerrors.New(db.ErrNoVariant, http.StatusNotFound)
+	}
+
+	if servErr := validateVariantRequest(variant); servErr != nil {
+		return nil, servErr
+	}
+
+	updated, err := s.storage.UpdateVariant(ctx, variantID, variant)
+	if err != nil {
+		return nil, s.itemWriteError("update variant", err)
+	}
+
+	s.catalog.invalidate()
+	return updated, nil
+}
+
+func (s *merchShopService) RestockVariant(ctx context.Context, variantIDStr string,
+	quantity int) (*models.CatalogItem, xerrors.Xerror) {
+	if _, servErr := s.requireAdmin(ctx); servErr != nil {
+		return nil, servErr
+	}
+
+	variantID, err := strconv.Atoi(variantIDStr)
+	if err != nil {
+		return nil, xerrors.New(db.ErrNoVariant, http.StatusNotFound)
+	}
+
+	if quantity < minRestockQuantity {
+		return nil, xerrors.New(errRestockQuantity, http.StatusBadRequest)
+	}
+
+	updated, err := s.storage.RestockVariant(ctx, variantID, quantity)
+	if err != nil {
+		return nil, s.itemWriteError("restock variant", err)
+	}
+
+	s.catalog.invalidate()
+	return updated, nil
+}
+
+// GetLowStockVariants reports variants running out, threshold defaults to shop.low_stock_threshold.
+func (s *merchShopService) GetLowStockVariants(ctx context.Context,
+	thresholdStr string) ([]models.ItemVariant, xerrors.Xerror) {
+	if _, servErr := s.requireAdmin(ctx); servErr != nil {
+		return nil, servErr
+	}
+
+	threshold := s.cfg.LowStockThreshold
+	if thresholdStr != "" {
+		var err error
+		threshold, err = strconv.Atoi(thresholdStr)
+		if err != nil || threshold < 0 {
+			return nil, xerrors.New(errLowStockThreshold, http.StatusBadRequest)
+		}
+	}
+
+	variants, err := s.storage.GetLowStockVariants(ctx, threshold)
+	if err != nil {
+		s.logger.Error("get low stock variants: " + err.Error())
+		return nil, xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+
+	return variants, nil
+}
+
+func (s *merchShopService) itemWriteError(operation string, err error) xerrors.Xerror {
+	switch err {
+	case db.ErrNoItem, db.ErrNoVariant:
+		return xerrors.New(err, http.StatusNotFound)
+	case db.ErrItemExists, db.ErrVariantExists:
+		return xerrors.New(err, http.StatusConflict)
+	}
+	s.logger.Error(operation + ": " + err.Error())
+	return xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+}
+
+// validateItemRequest checks an item against the items table constraints. Types are used
+// as names in /api/buy/{item}, so they can not look like an id or contain a slash.
+func validateItemRequest(item *models.ItemRequest) xerrors.Xerror {
+	typeLength := utf8.RuneCountInString(item.Type)
+	_, numErr := strconv.Atoi(item.Type)
+	if typeLength == 0 || typeLength > maxItemTypeLength || strings.TrimSpace(item.Type) != item.Type ||
+		strings.Contains(item.Type, "/") || numErr == nil {
+		return xerrors.New(errItemTypeInvalid, http.StatusBadRequest)
+	}
+
+	if item.Price < minItemPrice {
+		return xerrors.New(errItemPriceInvalid, http.StatusBadRequest)
+	}
+
+	if utf8.RuneCountInString(item.Description) > maxItemDescriptionLength {
+		return xerrors.New(errItemDescriptionInvalid, http.StatusBadRequest)
+	}
+
+	if utf8.RuneCountInString(item.Category) > maxItemCategoryLength || strings.TrimSpace(item.Category) != item.Category {
+		return xerrors.New(errItemCategoryInvalid, http.StatusBadRequest)
+	}
+
+	if item.Stock != nil && *item.Stock < 0 {
+		return xerrors.New(errItemStockInvalid, http.StatusBadRequest)
+	}
+
+	if limit := item.PurchaseLimit; limit != nil &&
+		(limit.Quantity < minPurchaseLimit || limit.WindowDays < minPurchaseLimitDays) {
+		return xerrors.New(errPurchaseLimitInvalid, http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+// validateVariantRequest checks a variant against the item_variants table constraints.
+// A variant without price is sold at the item price.
+func validateVariantRequest(variant *models.VariantRequest) xerrors.Xerror {
+	skuLength := utf8.RuneCountInString(variant.SKU)
+	if skuLength == 0 || skuLength > maxVariantSKULength || strings.ContainsFunc(variant.SKU, unicode.IsSpace) {
+		return xerrors.New(errVariantSKUInvalid, http.StatusBadRequest)
+	}
+
+	nameLength := utf8.RuneCountInString(variant.Name)
+	if nameLength == 0 || nameLength > maxVariantNameLength || strings.TrimSpace(variant.Name) != variant.Name {
+		return xerrors.New(errVariantNameInvalid, http.StatusBadRequest)
+	}
+
+	if variant.Price != nil && *variant.Price < minItemPrice {
+		return xerrors.New(errItemPriceInvalid, http.StatusBadRequest)
+	}
+
+	if variant.Stock != nil && *variant.Stock < 0 {
+		return xerrors.New(errItemStockInvalid, http.StatusBadRequest)
+	}
+
+	return nil
+}
@@ -0,0 +1,335 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"merch_shop/internal/db"
+	dbmock "merch_shop/internal/db/mocks"
+	"merch_shop/internal/models"
+	cryptormock "merch_shop/pkg/cryptor/mocks"
+	"merch_shop/pkg/middleware"
+	tokenizermock "merch_shop/pkg/tokenizer/mocks"
+	"merch_shop/pkg/xerrors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateItem(t *testing.T) {
+	ctxWithUserID := context.WithValue(context.Background(), middleware.UserIDKey, 1)
+	request := &models.ItemRequest{Type: "sticker", Price: 5, Description: "Logo sticker"}
+
+	t.Run("not admin error", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("IsAdmin", mock.Anything, 1).Return(false, nil)
+
+		_, err := service.CreateItem(ctxWithUserID, request)
+		require.Equal(t, xerrors.New(errAdminRequired, http.StatusForbidden), err)
+	})
+
+	t.Run("invalid params validation", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("IsAdmin", mock.Anything, 1).Return(true, nil)
+
+		testCases := []struct {
+			name        string
+			request     models.ItemRequest
+			expectedErr error
+		}{
+			{name: "empty type", request: models.ItemRequest{Price: 5}, expectedErr: errItemTypeInvalid},
+			{name: "type length > max", request: models.ItemRequest{Type: strings.Repeat("a", maxItemTypeLength+1), Price: 5},
+				expectedErr: errItemTypeInvalid},
+			{name: "type with spaces around", request: models.ItemRequest{Type: " cup", Price: 5}, expectedErr: errItemTypeInvalid},
+			{name: "numeric type", request: models.ItemRequest{Type: "42", Price: 5}, expectedErr: errItemTypeInvalid},
+			{name: "type with slash", request: models.ItemRequest{Type: "a/b", Price: 5}, expectedErr: errItemTypeInvalid},
+			{name: "price < min", request: models.ItemRequest{Type: "cup", Price: 0}, expectedErr: errItemPriceInvalid},
+			{name: "description length > max",
+				request:     models.ItemRequest{Type: "cup", Price: 5, Description: strings.Repeat("a", maxItemDescriptionLength+1)},
+				expectedErr: errItemDescriptionInvalid},
+			{name: "purchase limit < min",
+				request:     models.ItemRequest{Type: "cup", Price: 5, PurchaseLimit: &models.PurchaseLimit{WindowDays: 365}},
+				expectedErr: errPurchaseLimitInvalid},
+			{name: "purchase limit without window",
+				request:     models.ItemRequest{Type: "cup", Price: 5, PurchaseLimit: &models.PurchaseLimit{Quantity: 1}},
+				expectedErr: errPurchaseLimitInvalid},
+		}
+
+		for _, tc := range testCases {
+			_, err := service.CreateItem(ctxWithUserID, &tc.request)
+			require.Equal(t, xerrors.New(tc.expectedErr, http.StatusBadRequest), err, tc.name)
+		}
+	})
+
+	t.Run("multibyte type fits the column", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		request := &models.ItemRequest{Type: strings.Repeat("ф", maxItemTypeLength), Price: 5}
+		database.On("IsAdmin", mock.Anything, 1).Return(true, nil)
+		database.On("CreateItem", mock.Anything, request).Return(&models.CatalogItem{ID: 11}, nil)
+
+		_, err := service.CreateItem(ctxWithUserID, request)
+		require.Nil(t, err)
+	})
+
+	t.Run("type already exists error", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("IsAdmin", mock.Anything, 1).Return(true, nil)
+		database.On("CreateItem", mock.Anything, request).Return(nil, db.ErrItemExists)
+
+		_, err := service.CreateItem(ctxWithUserID, request)
+		require.Equal(t, xerrors.New(db.ErrItemExists, http.StatusConflict), err)
+	})
+
+	t.Run("create item db error", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("IsAdmin", mock.Anything, 1).Return(true, nil)
+		database.On("CreateItem", mock.Anything, request).Return(nil, errors.New("some error"))
+
+		_, err := service.CreateItem(ctxWithUserID, request)
+		require.Equal(t, xerrors.New(errSmthWentWrong, http.StatusInternalServerError), err)
+	})
+
+	t.Run("created item is visible in cached catalog", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		created := models.CatalogItem{ID: 11, Type: "sticker", Price: 5, Active: true, Available: true}
+		database.On("IsAdmin", mock.Anything, 1).Return(true, nil)
+		database.On("GetItems", mock.Anything).Return([]models.CatalogItem{}, nil).Once()
+		database.On("CreateItem", mock.Anything, request).Return(&created, nil)
+		database.On("GetItems", mock.Anything).Return([]models.CatalogItem{created}, nil).Once()
+		database.On("GetVariantStock", mock.Anything).Return(map[int]int{}, nil)
+
+		items, err := service.GetItems(context.Background())
+		require.Nil(t, err)
+		require.Empty(t, items)
+
+		_, err = service.CreateItem(ctxWithUserID, request)
+		require.Nil(t, err)
+
+		items, err = service.GetItems(context.Background())
+		require.Nil(t, err)
+		require.Equal(t, []models.CatalogItem{created}, items)
+	})
+}
+
+func TestRetireItem(t *testing.T) {
+	ctxWithUserID := context.WithValue(context.Background(), middleware.UserIDKey, 1)
+	retired := models.CatalogItem{ID: 2, Type: "cup", Price: 20}
+
+	t.Run("no item error", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("IsAdmin", mock.Anything, 1).Return(true, nil)
+		database.On("SetItemActive", mock.Anything, 3, false).Return(nil, db.ErrNoItem)
+
+		_, err := service.RetireItem(ctxWithUserID, "3")
+		require.Equal(t, xerrors.New(db.ErrNoItem, http.StatusNotFound), err)
+	})
+
+	t.Run("retired item is hidden from catalog but resolvable", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("IsAdmin", mock.Anything, 1).Return(true, nil)
+		database.On("SetItemActive", mock.Anything, 2, false).Return(&retired, nil)
+		database.On("GetItems", mock.Anything).Return([]models.CatalogItem{retired}, nil).Once()
+		database.On("GetVariantStock", mock.Anything).Return(map[int]int{}, nil)
+
+		_, err := service.RetireItem(ctxWithUserID, "2")
+		require.Nil(t, err)
+
+		items, err := service.GetItems(context.Background())
+		require.Nil(t, err)
+		require.Empty(t, items)
+
+		item, err := service.GetItem(context.Background(), "2")
+		require.Nil(t, err)
+		require.Equal(t, &retired, item)
+
+		_, err = service.BuyItem(ctxWithUserID, "cup", "", "", "")
+		require.Equal(t, xerrors.New(errItemRetired, http.StatusBadRequest), err)
+	})
+
+	t.Run("restore item", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		restored := models.CatalogItem{ID: 2, Type: "cup", Price: 20, Active: true, Available: true}
+		database.On("IsAdmin", mock.Anything, 1).Return(true, nil)
+		database.On("SetItemActive", mock.Anything, 2, true).Return(&restored, nil)
+
+		item, err := service.RestoreItem(ctxWithUserID, "2")
+		require.Nil(t, err)
+		require.Equal(t, &restored, item)
+	})
+}
+
+func TestCreateVariant(t *testing.T) {
+	ctxWithUserID := context.WithValue(context.Background(), middleware.UserIDKey, 1)
+	price, negative := 90, -1
+
+	t.Run("invalid request validation", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("IsAdmin", mock.Anything, 1).Return(true, nil)
+
+		testCases := []struct {
+			name        string
+			request     models.VariantRequest
+			expectedErr error
+		}{
+			{name: "empty sku", request: models.VariantRequest{Name: "XL"}, expectedErr: errVariantSKUInvalid},
+			{name: "sku with spaces", request: models.VariantRequest{SKU: "t-shirt xl", Name: "XL"}, expectedErr: errVariantSKUInvalid},
+			{name: "empty name", request: models.VariantRequest{SKU: "t-shirt-xl"}, expectedErr: errVariantNameInvalid},
+			{name: "name too long", request: models.VariantRequest{SKU: "t-shirt-xl", Name: strings.Repeat("x", maxVariantNameLength+1)},
+				expectedErr: errVariantNameInvalid},
+			{name: "price < min", request: models.VariantRequest{SKU: "t-shirt-xl", Name: "XL", Price: &negative},
+				expectedErr: errItemPriceInvalid},
+			{name: "negative stock", request: models.VariantRequest{SKU: "t-shirt-xl", Name: "XL", Stock: &negative},
+				expectedErr: errItemStockInvalid},
+		}
+
+		for _, tc := range testCases {
+			_, err := service.CreateVariant(ctxWithUserID, "1", &tc.request)
+			require.Equal(t, xerrors.New(tc.expectedErr, http.StatusBadRequest), err, tc.name)
+		}
+	})
+
+	t.Run("variant already exists", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		request := &models.VariantRequest{SKU: "t-shirt-xl", Name: "XL", Price: &price}
+		database.On("IsAdmin", mock.Anything, 1).Return(true, nil)
+		database.On("CreateVariant", mock.Anything, 1, request).Return(nil, db.ErrVariantExists)
+
+		_, err := service.CreateVariant(ctxWithUserID, "1", request)
+		require.Equal(t, xerrors.New(db.ErrVariantExists, http.StatusConflict), err)
+	})
+
+	t.Run("created variant can be bought", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		request := &models.VariantRequest{SKU: "t-shirt-xl", Name: "XL", Price: &price}
+		updated := models.CatalogItem{ID: 1, Type: "t-shirt", Price: 80, Active: true, Available: true, Variants: []models.ItemVariant{
+			{ID: 1, ItemID: 1, SKU: "t-shirt", Name: "default", Price: 80, Available: true},
+			{ID: 6, ItemID: 1, SKU: "t-shirt-xl", Name: "XL", Price: 90, PriceOverride: &price, Available: true},
+		}}
+		database.On("IsAdmin", mock.Anything, 1).Return(true, nil)
+		database.On("GetItems", mock.Anything).Return([]models.CatalogItem{updated}, nil).Once()
+		database.On("CreateVariant", mock.Anything, 1, request).Return(&updated, nil)
+		database.On("BuyItems", mock.Anything, 1, []models.CartLine{{VariantID: 6, Quantity: 1}}, "", 0).
+			Return(&models.OrderSummary{}, nil)
+
+		item, err := service.CreateVariant(ctxWithUserID, "1", request)
+		require.Nil(t, err)
+		require.Equal(t, &updated, item)
+
+		_, err = service.BuyItem(ctxWithUserID, "t-shirt", "XL", "", "")
+		require.Nil(t, err)
+	})
+}
+
+func TestRestockVariant(t *testing.T) {
+	ctxWithUserID := context.WithValue(context.Background(), middleware.UserIDKey, 1)
+
+	t.Run("quantity invalid error", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("IsAdmin", mock.Anything, 1).Return(true, nil)
+
+		_, err := service.RestockVariant(ctxWithUserID, "2", minRestockQuantity-1)
+		require.Equal(t, xerrors.New(errRestockQuantity, http.StatusBadRequest), err)
+	})
+
+	t.Run("no variant error", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("IsAdmin", mock.Anything, 1).Return(true, nil)
+		database.On("RestockVariant", mock.Anything, 9, 10).Return(nil, db.ErrNoVariant)
+
+		_, err := service.RestockVariant(ctxWithUserID, "9", 10)
+		require.Equal(t, xerrors.New(db.ErrNoVariant, http.StatusNotFound), err)
+	})
+
+	t.Run("restocked variant becomes available", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		empty, restocked := 0, 10
+		soldOut := models.CatalogItem{ID: 2, Type: "cup", Price: 20, Active: true, Variants: []models.ItemVariant{
+			{ID: 2, ItemID: 2, SKU: "cup", Name: "default", Price: 20, Stock: &empty},
+		}}
+		restockedItem := models.CatalogItem{
+			ID: 2, Type: "cup", Price: 20, Active: true, Available: true,
+			Variants: []models.ItemVariant{{ID: 2, ItemID: 2, SKU: "cup", Name: "default", Price: 20, Stock: &restocked, Available: true}},
+		}
+		database.On("IsAdmin", mock.Anything, 1).Return(true, nil)
+		database.On("GetItems", mock.Anything).Return([]models.CatalogItem{soldOut}, nil).Once()
+		database.On("RestockVariant", mock.Anything, 2, 10).Return(&restockedItem, nil)
+		database.On("GetItems", mock.Anything).Return([]models.CatalogItem{restockedItem}, nil).Once()
+		database.On("GetVariantStock", mock.Anything).Return(map[int]int{2: 0}, nil).Once()
+		database.On("GetVariantStock", mock.Anything).Return(map[int]int{2: 10}, nil).Once()
+
+		item, err := service.GetItem(context.Background(), "2")
+		require.Nil(t, err)
+		require.False(t, item.Available)
+
+		_, err = service.RestockVariant(ctxWithUserID, "2", 10)
+		require.Nil(t, err)
+
+		item, err = service.GetItem(context.Background(), "2")
+		require.Nil(t, err)
+		require.True(t, item.Available)
+	})
+}
+
+func TestGetLowStockVariants(t *testing.T) {
+	ctxWithUserID := context.WithValue(context.Background(), middleware.UserIDKey, 1)
+
+	t.Run("threshold invalid error", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("IsAdmin", mock.Anything, 1).Return(true, nil)
+
+		for _, threshold := range []string{"-1", "few"} {
+			_, err := service.GetLowStockVariants(ctxWithUserID, threshold)
+			require.Equal(t, xerrors.New(errLowStockThreshold, http.StatusBadRequest), err, threshold)
+		}
+	})
+
+	t.Run("default threshold from config", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("IsAdmin", mock.Anything, 1).Return(true, nil)
+		database.On("GetLowStockVariants", mock.Anything, testShopConfig.LowStockThreshold).Return([]models.ItemVariant{}, nil)
+		database.On("GetLowStockVariants", mock.Anything, 20).Return(nil, errors.New("some error"))
+
+		_, err := service.GetLowStockVariants(ctxWithUserID, "")
+		require.Nil(t, err)
+
+		_, err = service.GetLowStockVariants(ctxWithUserID, "20")
+		require.Equal(t, xerrors.New(errSmthWentWrong, http.StatusInternalServerError), err)
+	})
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"merch_shop/internal/db"
+	"merch_shop/internal/models"
+	"merch_shop/pkg/middleware"
+	"merch_shop/pkg/xerrors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var errOrderStatusInvalid = errors.New("status is invalid: " +
+	"expected pending_approval, placed, packed, ready_for_pickup, delivered or cancelled")
+
+var orderStatuses = map[string]struct{}{
+	models.OrderStatusPendingApproval: {},
+	models.OrderStatusPlaced:          {},
+	models.OrderStatusPacked:          {},
+	models.OrderStatusReadyForPickup:  {},
+	models.OrderStatusDelivered:       {},
+	models.OrderStatusCancelled:       {},
+}
+
+// GetOrders lists own orders together with gifts received from other users.
+func (s *merchShopService) GetOrders(ctx context.Context, status string) ([]models.Order, xerrors.Xerror) {
+	userID, ok := ctx.Value(middleware.UserIDKey).(int)
+	if !ok {
+		return nil, xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+
+	return s.getOrders(ctx, &models.OrderFilter{UserID: &userID, Status: status, ReceivedGifts: true})
+}
+
+// GetAllOrders lists orders of every user, e.g. placed ones waiting to be packed.
+func (s *merchShopService) GetAllOrders(ctx context.Context, status string) ([]models.Order, xerrors.Xerror) {
+	if _, servErr := s.requireAdmin(ctx); servErr != nil {
+		return nil, servErr
+	}
+
+	return s.getOrders(ctx, &models.OrderFilter{Status: status})
+}
+
+func (s *merchShopService) AdvanceOrder(ctx context.Context, orderIDStr string) (*models.Order, xerrors.Xerror) {
+	if _, servErr := s.requireAdmin(ctx); servErr != nil {
+		return nil, servErr
+	}
+
+	orderID, err := strconv.Atoi(orderIDStr)
+	if err != nil {
+		return nil, xerrors.New(db.ErrNoOrder, http.StatusNotFound)
+	}
+
+	err = s.storage.AdvanceOrder(ctx, orderID)
+	if err != nil {
+		return nil, s.orderError("advance order", err)
+	}
+
+	return s.getOrder(ctx, orderID)
+}
+
+// CancelOrder cancels an own order that is not packed yet or was placed within the cancellation window.
+// A zero window leaves only the first condition.
+func (s *merchShopService) CancelOrder(ctx context.Context, orderIDStr string) (*models.Order, xerrors.Xerror) {
+	userID, ok := ctx.Value(middleware.UserIDKey).(int)
+	if !ok {
+		return nil, xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+
+	orderID, err := strconv.Atoi(orderIDStr)
+	if err != nil {
+		return nil, xerrors.New(db.ErrNoOrder, http.StatusNotFound)
+	}
+
+	cancellation := &models.OrderCancellation{UserID: &userID, Status: models.OrderStatusPlaced}
+	if s.cfg.CancellationWindow > 0 {
+		placedAfter := time.Now().Add(-s.cfg.CancellationWindow)
+		cancellation.PlacedAfter = &placedAfter
+	}
+
+	err = s.storage.CancelOrder(ctx, orderID, cancellation)
+	if err != nil {
+		return nil, s.orderError("cancel order", err)
+	}
+
+	return s.getOrder(ctx, orderID)
+}
+
+// RefundOrder cancels any order that is not cancelled yet, whatever its status and age.
+// Orders pending approval are rejected instead.
+func (s *merchShopService) RefundOrder(ctx context.Context, orderIDStr string) (*models.Order, xerrors.Xerror) {
+	if _, servErr := s.requireAdmin(ctx); servErr != nil {
+		return nil, servErr
+	}
+
+	orderID, err := strconv.Atoi(orderIDStr)
+	if err != nil {
+		return nil, xerrors.New(db.ErrNoOrder, http.StatusNotFound)
+	}
+
+	err = s.storage.CancelOrder(ctx, orderID, &models.OrderCancellation{})
+	if err != nil {
+		return nil, s.orderError("refund order", err)
+	}
+
+	return s.getOrder(ctx, orderID)
+}
+
+func (s *merchShopService) getOrders(ctx context.Context, filter *models.OrderFilter) ([]models.Order, xerrors.Xerror) {
+	if _, ok := orderStatuses[filter.Status]; filter.Status != "" && !ok {
+		return nil, xerrors.New(errOrderStatusInvalid, http.StatusBadRequest)
+	}
+
+	orders, err := s.storage.GetOrders(ctx, filter)
+	if err != nil {
+		s.logger.Error("get orders: " + err.Error())
+		return nil, xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+
+	return orders, nil
+}
+
+func (s *merchShopService) getOrder(ctx context.Context, orderID int) (*models.Order, xerrors.Xerror) {
+	orders, servErr := s.getOrders(ctx, &models.OrderFilter{OrderID: &orderID})
+	if servErr != nil {
+		return nil, servErr
+	}
+	if len(orders) == 0 {
+		return nil, xerrors.New(db.ErrNoOrder, http.StatusNotFound)
+	}
+
+	return &orders[0], nil
+}
+
+func (s *merchShopService) orderError(operation string, err error) xerrors.Xerror {
+	switch err {
+	case db.ErrNoOrder:
+		return xerrors.New(err, http.StatusNotFound)
+	case db.ErrOrderFinal, db.ErrNotCancellable, db.ErrNotPending:
+		return xerrors.New(err, http.StatusConflict)
+	}
+	s.logger.Error(operation + ": " + err.Error())
+	return xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"merch_shop/internal/db"
+	dbmock "merch_shop/internal/db/mocks"
+	"merch_shop/internal/models"
+	cryptormock "merch_shop/pkg/cryptor/mocks"
+	"merch_shop/pkg/middleware"
+	tokenizermock "merch_shop/pkg/tokenizer/mocks"
+	"merch_shop/pkg/xerrors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetOrders(t *testing.T) {
+	ctxWithUserID := context.WithValue(context.Background(), middleware.UserIDKey, 1)
+
+	t.Run("userID missing error", func(t *testing.T) {
+		service := New(dbmock.NewDB(t), slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		_, err := service.GetOrders(context.Background(), "")
+		require.Equal(t, xerrors.New(errSmthWentWrong, http.StatusInternalServerError), err)
+	})
+
+	t.Run("status invalid error", func(t *testing.T) {
+		service := New(dbmock.NewDB(t), slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		_, err := service.GetOrders(ctxWithUserID, "lost")
+		require.Equal(t, xerrors.New(errOrderStatusInvalid, http.StatusBadRequest), err)
+	})
+
+	t.Run("get orders db error", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("GetOrders", mock.Anything, mock.Anything).Return(nil, errors.New("some error"))
+
+		_, err := service.GetOrders(ctxWithUserID, "")
+		require.Equal(t, xerrors.New(errSmthWentWrong, http.StatusInternalServerError), err)
+	})
+
+	t.Run("only own orders are requested", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("GetOrders", mock.Anything, mock.MatchedBy(func(f *models.OrderFilter) bool {
+			return f.UserID != nil && *f.UserID == 1 && f.Status == models.OrderStatusPlaced && f.ReceivedGifts
+		})).Return([]models.Order{}, nil)
+
+		orders, err := service.GetOrders(ctxWithUserID, models.OrderStatusPlaced)
+		require.Nil(t, err)
+		require.Empty(t, orders)
+	})
+}
+
+func TestAdvanceOrder(t *testing.T) {
+	ctxWithUserID := context.WithValue(context.Background(), middleware.UserIDKey, 1)
+
+	t.Run("not admin error", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("IsAdmin", mock.Anything, 1).Return(false, nil)
+
+		_, err := service.AdvanceOrder(ctxWithUserID, "3")
+		require.Equal(t, xerrors.New(errAdminRequired, http.StatusForbidden), err)
+	})
+
+	t.Run("advance user side error", func(t *testing.T) {
+		testCases := []struct {
+			err  error
+			code int
+		}{
+			{err: db.ErrNoOrder, code: http.StatusNotFound},
+			{err: db.ErrOrderFinal, code: http.StatusConflict},
+		}
+
+		for _, tc := range testCases {
+			database := dbmock.NewDB(t)
+			service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+			database.On("IsAdmin", mock.Anything, 1).Return(true, nil)
+			database.On("AdvanceOrder", mock.Anything, 3).Return(tc.err)
+
+			_, err := service.AdvanceOrder(ctxWithUserID, "3")
+			require.Equal(t, xerrors.New(tc.err, tc.code), err)
+		}
+	})
+
+	t.Run("positive result", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		order := models.Order{ID: 3, User: "alice", Status: models.OrderStatusPacked}
+		database.On("IsAdmin", mock.Anything, 1).Return(true, nil)
+		database.On("AdvanceOrder", mock.Anything, 3).Return(nil)
+		database.On("GetOrders", mock.Anything, mock.MatchedBy(func(f *models.OrderFilter) bool {
+			return f.OrderID != nil && *f.OrderID == 3 && f.UserID == nil
+		})).Return([]models.Order{order}, nil)
+
+		result, err := service.AdvanceOrder(ctxWithUserID, "3")
+		require.Nil(t, err)
+		require.Equal(t, &order, result)
+	})
+}
+
+func TestCancelOrder(t *testing.T) {
+	ctxWithUserID := context.WithValue(context.Background(), middleware.UserIDKey, 1)
+
+	t.Run("userID missing error", func(t *testing.T) {
+		service := New(dbmock.NewDB(t), slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		_, err := service.CancelOrder(context.Background(), "3")
+		require.Equal(t, xerrors.New(errSmthWentWrong, http.StatusInternalServerError), err)
+	})
+
+	t.Run("cancel user side error", func(t *testing.T) {
+		testCases := []struct {
+			err  error
+			code int
+		}{
+			{err: db.ErrNoOrder, code: http.StatusNotFound},
+			{err: db.ErrNotCancellable, code: http.StatusConflict},
+		}
+
+		for _, tc := range testCases {
+			database := dbmock.NewDB(t)
+			service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+			database.On("CancelOrder", mock.Anything, 3, mock.Anything).Return(tc.err)
+
+			_, err := service.CancelOrder(ctxWithUserID, "3")
+			require.Equal(t, xerrors.New(tc.err, tc.code), err)
+		}
+	})
+
+	t.Run("own orders not packed yet or placed within the window are cancelled", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		order := models.Order{ID: 3, Status: models.OrderStatusCancelled}
+		windowStart := time.Now().Add(-testShopConfig.CancellationWindow)
+		database.On("CancelOrder", mock.Anything, 3, mock.MatchedBy(func(c *models.OrderCancellation) bool {
+			return c.UserID != nil && *c.UserID == 1 && c.Status == models.OrderStatusPlaced &&
+				c.PlacedAfter != nil && !c.PlacedAfter.Before(windowStart)
+		})).Return(nil)
+		database.On("GetOrders", mock.Anything, mock.Anything).Return([]models.Order{order}, nil)
+
+		result, err := service.CancelOrder(ctxWithUserID, "3")
+		require.Nil(t, err)
+		require.Equal(t, &order, result)
+	})
+
+	t.Run("zero window cancels until packed", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		cfg := testShopConfig
+		cfg.CancellationWindow = 0
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), cfg)
+
+		database.On("CancelOrder", mock.Anything, 3, mock.MatchedBy(func(c *models.OrderCancellation) bool {
+			return c.Status == models.OrderStatusPlaced && c.PlacedAfter == nil
+		})).Return(nil)
+		database.On("GetOrders", mock.Anything, mock.Anything).Return([]models.Order{{ID: 3}}, nil)
+
+		_, err := service.CancelOrder(ctxWithUserID, "3")
+		require.Nil(t, err)
+	})
+}
+
+func TestRefundOrder(t *testing.T) {
+	ctxWithUserID := context.WithValue(context.Background(), middleware.UserIDKey, 1)
+
+	t.Run("not admin error", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("IsAdmin", mock.Anything, 1).Return(false, nil)
+
+		_, err := service.RefundOrder(ctxWithUserID, "3")
+		require.Equal(t, xerrors.New(errAdminRequired, http.StatusForbidden), err)
+	})
+
+	t.Run("already cancelled error", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("IsAdmin", mock.Anything, 1).Return(true, nil)
+		database.On("CancelOrder", mock.Anything, 3, mock.Anything).Return(db.ErrNotCancellable)
+
+		_, err := service.RefundOrder(ctxWithUserID, "3")
+		require.Equal(t, xerrors.New(db.ErrNotCancellable, http.StatusConflict), err)
+	})
+
+	t.Run("any order of any user is refunded", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		order := models.Order{ID: 3, User: "alice", Status: models.OrderStatusCancelled}
+		database.On("IsAdmin", mock.Anything, 1).Return(true, nil)
+		database.On("CancelOrder", mock.Anything, 3, &models.OrderCancellation{}).Return(nil)
+		database.On("GetOrders", mock.Anything, mock.Anything).Return([]models.Order{order}, nil)
+
+		result, err := service.RefundOrder(ctxWithUserID, "3")
+		require.Nil(t, err)
+		require.Equal(t, &order, result)
+	})
+}
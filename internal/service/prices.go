@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"merch_shop/internal/db"
+	"merch_shop/internal/models"
+	"merch_shop/pkg/xerrors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	errPriceStartInvalid = errors.New("price start is invalid: can not be in the past")
+	errPriceEndInvalid   = errors.New("price end is invalid: must be after start")
+)
+
+// SchedulePrice adds a regular price or, with an end, a sale of an item. A price without start
+// takes effect right away, past starts are rejected so the price history is never rewritten.
+func (s *merchShopService) SchedulePrice(ctx context.Context, itemIDStr string,
+	price *models.PriceRequest) (*models.ItemPrice, xerrors.Xerror) {
+	if _, servErr := s.requireAdmin(ctx); servErr != nil {
+		return nil, servErr
+	}
+
+	itemID, err := strconv.Atoi(itemIDStr)
+	if err != nil {
+		return nil, xerrors.New(db.ErrNoItem, http.StatusNotFound)
+	}
+
+	if servErr := validatePriceRequest(price, time.Now()); servErr != nil {
+		return nil, servErr
+	}
+
+	scheduled, err := s.storage.SchedulePrice(ctx, itemID, price)
+	if err != nil {
+		return nil, s.priceWriteError("schedule price", err)
+	}
+
+	s.catalog.invalidate()
+	return scheduled, nil
+}
+
+// GetPriceHistory returns prices of an item that have taken effect, scheduled ones are not disclosed.
+func (s *merchShopService) GetPriceHistory(ctx context.Context, itemIDStr string) ([]models.ItemPrice, xerrors.Xerror) {
+	item, servErr := s.catalogItem(ctx, itemIDStr)
+	if servErr != nil {
+		return nil, servErr
+	}
+
+	prices, servErr := s.getItemPrices(ctx, item.ID)
+	if servErr != nil {
+		return nil, servErr
+	}
+
+	now := time.Now()
+	history := make([]models.ItemPrice, 0, len(prices))
+	for _, price := range prices {
+		if price.StartsAt == nil || !price.StartsAt.After(now) {
+			history = append(history, price)
+		}
+	}
+
+	return history, nil
+}
+
+// GetItemPrices returns all prices of an item including scheduled ones.
+func (s *merchShopService) GetItemPrices(ctx context.Context, itemIDStr string) ([]models.ItemPrice, xerrors.Xerror) {
+	if _, servErr := s.requireAdmin(ctx); servErr != nil {
+		return nil, servErr
+	}
+
+	item, servErr := s.catalogItem(ctx, itemIDStr)
+	if servErr != nil {
+		return nil, servErr
+	}
+
+	return s.getItemPrices(ctx, item.ID)
+}
+
+func (s *merchShopService) getItemPrices(ctx context.Context, itemID int) ([]models.ItemPrice, xerrors.Xerror) {
+	prices, err := s.storage.GetItemPrices(ctx, itemID)
+	if err != nil {
+		s.logger.Error("get item prices: " + err.Error())
+		return nil, xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+
+	return prices, nil
+}
+
+// CancelScheduledPrice deletes a price that has not taken effect yet.
+func (s *merchShopService) CancelScheduledPrice(ctx context.Context, priceIDStr string) xerrors.Xerror {
+	if _, servErr := s.requireAdmin(ctx); servErr != nil {
+		return servErr
+	}
+
+	priceID, err := strconv.Atoi(priceIDStr)
+	if err != nil {
+		return xerrors.New(db.ErrNoPrice, http.StatusNotFound)
+	}
+
+	err = s.storage.CancelScheduledPrice(ctx, priceID, time.Now())
+	if err != nil {
+		return s.priceWriteError("cancel scheduled price", err)
+	}
+
+	s.catalog.invalidate()
+	return nil
+}
+
+func (s *merchShopService) priceWriteError(operation string, err error) xerrors.Xerror {
+	switch err {
+	case db.ErrNoItem, db.ErrNoPrice:
+		return xerrors.New(err, http.StatusNotFound)
+	case db.ErrPriceStarted:
+		return xerrors.New(err, http.StatusConflict)
+	}
+	s.logger.Error(operation + ": " + err.Error())
+	return xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+}
+
+// validatePriceRequest checks a price against the item_prices table constraints,
+// a missing start is set to now.
+func validatePriceRequest(price *models.PriceRequest, now time.Time) xerrors.Xerror {
+	if price.Price < minItemPrice {
+		return xerrors.New(errItemPriceInvalid, http.StatusBadRequest)
+	}
+
+	if price.StartsAt == nil {
+		price.StartsAt = &now
+	} else if price.StartsAt.Before(now) {
+		return xerrors.New(errPriceStartInvalid, http.StatusBadRequest)
+	}
+
+	if price.EndsAt != nil && !price.EndsAt.After(*price.StartsAt) {
+		return xerrors.New(errPriceEndInvalid, http.StatusBadRequest)
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"merch_shop/internal/db"
+	dbmock "merch_shop/internal/db/mocks"
+	"merch_shop/internal/models"
+	cryptormock "merch_shop/pkg/cryptor/mocks"
+	"merch_shop/pkg/middleware"
+	tokenizermock "merch_shop/pkg/tokenizer/mocks"
+	"merch_shop/pkg/xerrors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchedulePrice(t *testing.T) {
+	ctxWithUserID := context.WithValue(context.Background(), middleware.UserIDKey, 1)
+	tomorrow := time.Now().Add(24 * time.Hour)
+	yesterday := time.Now().Add(-24 * time.Hour)
+
+	t.Run("invalid request validation", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("IsAdmin", mock.Anything, 1).Return(true, nil)
+
+		testCases := []struct {
+			name        string
+			request     models.PriceRequest
+			expectedErr error
+		}{
+			{name: "zero price", request: models.PriceRequest{}, expectedErr: errItemPriceInvalid},
+			{name: "start in the past", request: models.PriceRequest{Price: 60, StartsAt: &yesterday},
+				expectedErr: errPriceStartInvalid},
+			{name: "sale ends before start", request: models.PriceRequest{Price: 60, StartsAt: &tomorrow, EndsAt: &yesterday},
+				expectedErr: errPriceEndInvalid},
+			{name: "immediate sale already ended", request: models.PriceRequest{Price: 60, EndsAt: &yesterday},
+				expectedErr: errPriceEndInvalid},
+		}
+
+		for _, tc := range testCases {
+			_, err := service.SchedulePrice(ctxWithUserID, "1", &tc.request)
+			require.Equal(t, xerrors.New(tc.expectedErr, http.StatusBadRequest), err, tc.name)
+		}
+	})
+
+	t.Run("price without start takes effect now", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("IsAdmin", mock.Anything, 1).Return(true, nil)
+		database.On("SchedulePrice", mock.Anything, 1, mock.MatchedBy(func(price *models.PriceRequest) bool {
+			return price.StartsAt != nil && time.Since(*price.StartsAt) < time.Minute
+		})).Return(&models.ItemPrice{ID: 3, ItemID: 1, Price: 60}, nil)
+
+		price, err := service.SchedulePrice(ctxWithUserID, "1", &models.PriceRequest{Price: 60, EndsAt: &tomorrow})
+		require.Nil(t, err)
+		require.Equal(t, 3, price.ID)
+	})
+
+	t.Run("no item", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("IsAdmin", mock.Anything, 1).Return(true, nil)
+		database.On("SchedulePrice", mock.Anything, 9, mock.Anything).Return(nil, db.ErrNoItem)
+
+		_, err := service.SchedulePrice(ctxWithUserID, "9", &models.PriceRequest{Price: 60, StartsAt: &tomorrow})
+		require.Equal(t, xerrors.New(db.ErrNoItem, http.StatusNotFound), err)
+	})
+}
+
+func TestGetPriceHistory(t *testing.T) {
+	database := dbmock.NewDB(t)
+	service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+	started := time.Now().Add(-time.Hour)
+	scheduled := time.Now().Add(time.Hour)
+	prices := []models.ItemPrice{
+		{ID: 1, ItemID: 1, Price: 80},
+		{ID: 2, ItemID: 1, Price: 90, StartsAt: &started},
+		{ID: 3, ItemID: 1, Price: 100, StartsAt: &scheduled},
+	}
+	database.On("GetItems", mock.Anything).Return([]models.CatalogItem{{ID: 1, Type: "t-shirt", Price: 90, Active: true}}, nil)
+	database.On("GetItemPrices", mock.Anything, 1).Return(prices, nil)
+
+	history, err := service.GetPriceHistory(context.Background(), "1")
+	require.Nil(t, err)
+	require.Equal(t, prices[:2], history)
+}
+
+func TestCancelScheduledPrice(t *testing.T) {
+	ctxWithUserID := context.WithValue(context.Background(), middleware.UserIDKey, 1)
+
+	database := dbmock.NewDB(t)
+	service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+	database.On("IsAdmin", mock.Anything, 1).Return(true, nil)
+	database.On("CancelScheduledPrice", mock.Anything, 2, mock.Anything).Return(db.ErrPriceStarted)
+	database.On("CancelScheduledPrice", mock.Anything, 3, mock.Anything).Return(nil)
+
+	err := service.CancelScheduledPrice(ctxWithUserID, "2")
+	require.Equal(t, xerrors.New(db.ErrPriceStarted, http.StatusConflict), err)
+
+	err = service.CancelScheduledPrice(ctxWithUserID, "3")
+	require.Nil(t, err)
+}
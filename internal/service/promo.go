@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"merch_shop/internal/db"
+	"merch_shop/internal/models"
+	"merch_shop/pkg/xerrors"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// Matches promo_codes.code VARCHAR(30).
+	maxPromoCodeLength = 30
+	maxPromoPercent    = 100
+)
+
+var (
+	errPromoCodeInvalid   = fmt.Errorf("promo code is invalid: length min 1 max %d, no spaces", maxPromoCodeLength)
+	errPromoKindInvalid   = fmt.Errorf("promo kind is invalid: expected %s or %s", models.PromoKindPercent, models.PromoKindFixed)
+	errPromoValueInvalid  = fmt.Errorf("promo value is invalid: min 1, percent max %d", maxPromoPercent)
+	errPromoTargetInvalid = errors.New("promo target is invalid: item and category can not be both set")
+	errPromoWindowInvalid = errors.New("promo validity window is invalid: start must be before end")
+	errPromoLimitInvalid  = errors.New("promo usage limit is invalid: min 1")
+)
+
+func (s *merchShopService) CreatePromo(ctx context.Context, promo *models.PromoRequest) (*models.PromoCode, xerrors.Xerror) {
+	if _, servErr := s.requireAdmin(ctx); servErr != nil {
+		return nil, servErr
+	}
+
+	promo.Code = normalizePromoCode(promo.Code)
+	if servErr := validatePromoRequest(promo); servErr != nil {
+		return nil, servErr
+	}
+
+	created, err := s.storage.CreatePromo(ctx, promo)
+	if err != nil {
+		return nil, s.promoWriteError("create promo", err)
+	}
+
+	return created, nil
+}
+
+func (s *merchShopService) GetPromos(ctx context.Context) ([]models.PromoCode, xerrors.Xerror) {
+	if _, servErr := s.requireAdmin(ctx); servErr != nil {
+		return nil, servErr
+	}
+
+	promos, err := s.storage.GetPromos(ctx)
+	if err != nil {
+		s.logger.Error("get promos: " + err.Error())
+		return nil, xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+
+	return promos, nil
+}
+
+// DeactivatePromo stops a promo code, orders placed with it keep their discount.
+func (s *merchShopService) DeactivatePromo(ctx context.Context, promoIDStr string) (*models.PromoCode, xerrors.Xerror) {
+	if _, servErr := s.requireAdmin(ctx); servErr != nil {
+		return nil, servErr
+	}
+
+	promoID, err := strconv.Atoi(promoIDStr)
+	if err != nil {
+		return nil, xerrors.New(db.ErrNoPromo, http.StatusNotFound)
+	}
+
+	updated, err := s.storage.SetPromoActive(ctx, promoID, false)
+	if err != nil {
+		return nil, s.promoWriteError("deactivate promo", err)
+	}
+
+	return updated, nil
+}
+
+func (s *merchShopService) promoWriteError(operation string, err error) xerrors.Xerror {
+	switch err {
+	case db.ErrNoPromo:
+		return xerrors.New(err, http.StatusNotFound)
+	case db.ErrNoItem:
+		return xerrors.New(err, http.StatusBadRequest)
+	case db.ErrPromoExists:
+		return xerrors.New(err, http.StatusConflict)
+	}
+	s.logger.Error(operation + ": " + err.Error())
+	return xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+}
+
+// normalizePromoCode makes codes case insensitive, they are stored in upper case.
+func normalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// validatePromoRequest checks a promo code against the promo_codes table constraints.
+func validatePromoRequest(promo *models.PromoRequest) xerrors.Xerror {
+	codeLength := utf8.RuneCountInString(promo.Code)
+	if codeLength == 0 || codeLength > maxPromoCodeLength || strings.ContainsFunc(promo.Code, unicode.IsSpace) {
+		return xerrors.New(errPromoCodeInvalid, http.StatusBadRequest)
+	}
+
+	if promo.Kind != models.PromoKindPercent && promo.Kind != models.PromoKindFixed {
+		return xerrors.New(errPromoKindInvalid, http.StatusBadRequest)
+	}
+
+	if promo.Value < 1 || promo.Kind == models.PromoKindPercent && promo.Value > maxPromoPercent {
+		return xerrors.New(errPromoValueInvalid, http.StatusBadRequest)
+	}
+
+	if promo.ItemID != nil && promo.Category != "" {
+		return xerrors.New(errPromoTargetInvalid, http.StatusBadRequest)
+	}
+
+	if utf8.RuneCountInString(promo.Category) > maxItemCategoryLength {
+		return xerrors.New(errItemCategoryInvalid, http.StatusBadRequest)
+	}
+
+	if promo.StartsAt != nil && promo.EndsAt != nil && !promo.StartsAt.Before(*promo.EndsAt) {
+		return xerrors.New(errPromoWindowInvalid, http.StatusBadRequest)
+	}
+
+	if promo.MaxUses != nil && *promo.MaxUses < 1 || promo.MaxUsesPerUser != nil && *promo.MaxUsesPerUser < 1 {
+		return xerrors.New(errPromoLimitInvalid, http.StatusBadRequest)
+	}
+
+	return nil
+}
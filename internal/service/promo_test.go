@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"merch_shop/internal/db"
+	dbmock "merch_shop/internal/db/mocks"
+	"merch_shop/internal/models"
+	cryptormock "merch_shop/pkg/cryptor/mocks"
+	"merch_shop/pkg/middleware"
+	tokenizermock "merch_shop/pkg/tokenizer/mocks"
+	"merch_shop/pkg/xerrors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreatePromo(t *testing.T) {
+	ctxWithUserID := context.WithValue(context.Background(), middleware.UserIDKey, 1)
+	itemID, zero := 3, 0
+	start := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 7)
+
+	t.Run("not admin error", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("IsAdmin", mock.Anything, 1).Return(false, nil)
+
+		_, err := service.CreatePromo(ctxWithUserID, &models.PromoRequest{Code: "HOODIES20"})
+		require.Equal(t, xerrors.New(errAdminRequired, http.StatusForbidden), err)
+	})
+
+	t.Run("invalid request validation", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("IsAdmin", mock.Anything, 1).Return(true, nil)
+
+		testCases := []struct {
+			name        string
+			request     models.PromoRequest
+			expectedErr error
+		}{
+			{name: "empty code", request: models.PromoRequest{Kind: models.PromoKindFixed, Value: 10},
+				expectedErr: errPromoCodeInvalid},
+			{name: "code with spaces", request: models.PromoRequest{Code: "HOODIES 20", Kind: models.PromoKindFixed, Value: 10},
+				expectedErr: errPromoCodeInvalid},
+			{name: "unknown kind", request: models.PromoRequest{Code: "HOODIES20", Kind: "gift", Value: 10},
+				expectedErr: errPromoKindInvalid},
+			{name: "percent over 100", request: models.PromoRequest{Code: "HOODIES20", Kind: models.PromoKindPercent, Value: 120},
+				expectedErr: errPromoValueInvalid},
+			{name: "zero value", request: models.PromoRequest{Code: "HOODIES20", Kind: models.PromoKindFixed},
+				expectedErr: errPromoValueInvalid},
+			{name: "item and category", request: models.PromoRequest{Code: "HOODIES20", Kind: models.PromoKindFixed, Value: 10,
+				ItemID: &itemID, Category: "clothes"}, expectedErr: errPromoTargetInvalid},
+			{name: "window ends before start", request: models.PromoRequest{Code: "HOODIES20", Kind: models.PromoKindFixed,
+				Value: 10, StartsAt: &end, EndsAt: &start}, expectedErr: errPromoWindowInvalid},
+			{name: "zero limit", request: models.PromoRequest{Code: "HOODIES20", Kind: models.PromoKindFixed, Value: 10,
+				MaxUsesPerUser: &zero}, expectedErr: errPromoLimitInvalid},
+		}
+
+		for _, tc := range testCases {
+			_, err := service.CreatePromo(ctxWithUserID, &tc.request)
+			require.Equal(t, xerrors.New(tc.expectedErr, http.StatusBadRequest), err, tc.name)
+		}
+	})
+
+	t.Run("code already exists", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("IsAdmin", mock.Anything, 1).Return(true, nil)
+		database.On("CreatePromo", mock.Anything, mock.Anything).Return(nil, db.ErrPromoExists)
+
+		_, err := service.CreatePromo(ctxWithUserID, &models.PromoRequest{Code: "HOODIES20", Kind: models.PromoKindPercent, Value: 20})
+		require.Equal(t, xerrors.New(db.ErrPromoExists, http.StatusConflict), err)
+	})
+
+	t.Run("code is stored in upper case", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		request := &models.PromoRequest{Code: "hoodies20", Kind: models.PromoKindPercent, Value: 20, Category: "clothes",
+			StartsAt: &start, EndsAt: &end}
+		stored := *request
+		stored.Code = "HOODIES20"
+		created := &models.PromoCode{ID: 1, Code: "HOODIES20", Kind: models.PromoKindPercent, Value: 20, Category: "clothes",
+			StartsAt: &start, EndsAt: &end, Active: true}
+		database.On("IsAdmin", mock.Anything, 1).Return(true, nil)
+		database.On("CreatePromo", mock.Anything, &stored).Return(created, nil)
+
+		promo, err := service.CreatePromo(ctxWithUserID, request)
+		require.Nil(t, err)
+		require.Equal(t, created, promo)
+	})
+}
+
+func TestDeactivatePromo(t *testing.T) {
+	ctxWithUserID := context.WithValue(context.Background(), middleware.UserIDKey, 1)
+
+	database := dbmock.NewDB(t)
+	service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+	database.On("IsAdmin", mock.Anything, 1).Return(true, nil)
+	database.On("SetPromoActive", mock.Anything, 2, false).Return(nil, db.ErrNoPromo)
+	database.On("SetPromoActive", mock.Anything, 3, false).Return(nil, errors.New("some error"))
+
+	_, err := service.DeactivatePromo(ctxWithUserID, "2")
+	require.Equal(t, xerrors.New(db.ErrNoPromo, http.StatusNotFound), err)
+
+	_, err = service.DeactivatePromo(ctxWithUserID, "3")
+	require.Equal(t, xerrors.New(errSmthWentWrong, http.StatusInternalServerError), err)
+}
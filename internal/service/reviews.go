@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"merch_shop/internal/db"
+	"merch_shop/internal/models"
+	"merch_shop/pkg/middleware"
+	"merch_shop/pkg/xerrors"
+	"net/http"
+	"strconv"
+	"unicode/utf8"
+)
+
+const (
+	minReviewRating     = 1
+	maxReviewRating     = 5
+	maxReviewTextLength = 1000
+)
+
+var (
+	errRatingInvalid     = fmt.Errorf("rating is invalid: min %d, max %d", minReviewRating, maxReviewRating)
+	errReviewTextTooLong = fmt.Errorf("review text is too long: max %d", maxReviewTextLength)
+)
+
+// GetItemReviews returns visible reviews of the item, the latest edited first.
+func (s *merchShopService) GetItemReviews(ctx context.Context, itemIDStr string) ([]models.Review, xerrors.Xerror) {
+	item, servErr := s.catalogItem(ctx, itemIDStr)
+	if servErr != nil {
+		return nil, servErr
+	}
+
+	return s.getReviews(ctx, &models.ReviewFilter{ItemID: &item.ID})
+}
+
+// GetAllReviews returns reviews of every item including hidden ones.
+func (s *merchShopService) GetAllReviews(ctx context.Context) ([]models.Review, xerrors.Xerror) {
+	if _, servErr := s.requireAdmin(ctx); servErr != nil {
+		return nil, servErr
+	}
+
+	return s.getReviews(ctx, &models.ReviewFilter{IncludeHidden: true})
+}
+
+func (s *merchShopService) getReviews(ctx context.Context, filter *models.ReviewFilter) ([]models.Review, xerrors.Xerror) {
+	reviews, err := s.storage.GetReviews(ctx, filter)
+	if err != nil {
+		s.logger.Error("get reviews: " + err.Error())
+		return nil, xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+
+	return reviews, nil
+}
+
+// PostReview saves the review of the user, replacing the one they posted for the item before.
+// Retired items can still be reviewed by their owners.
+func (s *merchShopService) PostReview(ctx context.Context, review *models.ReviewRequest) (*models.Review, xerrors.Xerror) {
+	userID, ok := ctx.Value(middleware.UserIDKey).(int)
+	if !ok {
+		return nil, xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+
+	if review.Rating < minReviewRating || review.Rating > maxReviewRating {
+		return nil, xerrors.New(errRatingInvalid, http.StatusBadRequest)
+	}
+	if utf8.RuneCountInString(review.Text) > maxReviewTextLength {
+		return nil, xerrors.New(errReviewTextTooLong, http.StatusBadRequest)
+	}
+
+	catalog, servErr := s.getCatalog(ctx)
+	if servErr != nil {
+		return nil, servErr
+	}
+
+	item, ok := catalog.lookup(review.Item)
+	if !ok {
+		return nil, xerrors.New(db.ErrNoItem, http.StatusBadRequest)
+	}
+
+	posted, err := s.storage.UpsertReview(ctx, userID, item.ID, review)
+	if err != nil {
+		if err == db.ErrNotOwner {
+			return nil, xerrors.New(err, http.StatusForbidden)
+		}
+		s.logger.Error("post review: " + err.Error())
+		return nil, xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+	s.catalog.invalidate()
+
+	return posted, nil
+}
+
+// HideReview hides the review from the item page and excludes it from the item rating.
+func (s *merchShopService) HideReview(ctx context.Context, reviewIDStr string) (*models.Review, xerrors.Xerror) {
+	return s.setReviewHidden(ctx, reviewIDStr, true)
+}
+
+// ShowReview brings a hidden review back.
+func (s *merchShopService) ShowReview(ctx context.Context, reviewIDStr string) (*models.Review, xerrors.Xerror) {
+	return s.setReviewHidden(ctx, reviewIDStr, false)
+}
+
+func (s *merchShopService) setReviewHidden(ctx context.Context, reviewIDStr string,
+	hidden bool) (*models.Review, xerrors.Xerror) {
+	if _, servErr := s.requireAdmin(ctx); servErr != nil {
+		return nil, servErr
+	}
+
+	reviewID, err := strconv.Atoi(reviewIDStr)
+	if err != nil {
+		return nil, xerrors.New(db.ErrNoReview, http.StatusNotFound)
+	}
+
+	review, err := s.storage.SetReviewHidden(ctx, reviewID, hidden)
+	if err != nil {
+		if err == db.ErrNoReview {
+			return nil, xerrors.New(err, http.StatusNotFound)
+		}
+		s.logger.Error("set review hidden: " + err.Error())
+		return nil, xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+	s.catalog.invalidate()
+
+	return review, nil
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"merch_shop/internal/db"
+	dbmock "merch_shop/internal/db/mocks"
+	"merch_shop/internal/models"
+	cryptormock "merch_shop/pkg/cryptor/mocks"
+	"merch_shop/pkg/middleware"
+	tokenizermock "merch_shop/pkg/tokenizer/mocks"
+	"merch_shop/pkg/xerrors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostReview(t *testing.T) {
+	ctxWithUserID := context.WithValue(context.Background(), middleware.UserIDKey, 5)
+	catalogItems := []models.CatalogItem{{ID: 4, Type: "mug", Price: 20, Active: false,
+		Variants: []models.ItemVariant{{ID: 7, Name: "default", Price: 20}}}}
+
+	testCases := []struct {
+		name       string
+		request    *models.ReviewRequest
+		dbBehavior func(database *dbmock.DB)
+
+		expected *models.Review
+		err      xerrors.Xerror
+	}{
+		{
+			name:    "rating is out of range",
+			request: &models.ReviewRequest{Item: "mug", Rating: 6},
+			err:     xerrors.New(errRatingInvalid, http.StatusBadRequest),
+		},
+		{
+			name:    "text is too long",
+			request: &models.ReviewRequest{Item: "mug", Rating: 5, Text: strings.Repeat("ы", maxReviewTextLength+1)},
+			err:     xerrors.New(errReviewTextTooLong, http.StatusBadRequest),
+		},
+		{
+			name:    "unknown item",
+			request: &models.ReviewRequest{Item: "cup", Rating: 5},
+			dbBehavior: func(database *dbmock.DB) {
+				database.On("GetItems", mock.Anything).Return(catalogItems, nil)
+			},
+			err: xerrors.New(db.ErrNoItem, http.StatusBadRequest),
+		},
+		{
+			name:    "user does not own the item",
+			request: &models.ReviewRequest{Item: "mug", Rating: 5},
+			dbBehavior: func(database *dbmock.DB) {
+				database.On("GetItems", mock.Anything).Return(catalogItems, nil)
+				database.On("UpsertReview", mock.Anything, 5, 4, &models.ReviewRequest{Item: "mug", Rating: 5}).
+					Return(nil, db.ErrNotOwner)
+			},
+			err: xerrors.New(db.ErrNotOwner, http.StatusForbidden),
+		},
+		{
+			name:    "retired item is reviewed by its owner",
+			request: &models.ReviewRequest{Item: "mug", Rating: 5, Text: "great"},
+			dbBehavior: func(database *dbmock.DB) {
+				database.On("GetItems", mock.Anything).Return(catalogItems, nil)
+				database.On("UpsertReview", mock.Anything, 5, 4, &models.ReviewRequest{Item: "mug", Rating: 5, Text: "great"}).
+					Return(&models.Review{ID: 9, ItemID: 4, Item: "mug", User: "alice", Rating: 5, Text: "great"}, nil)
+			},
+			expected: &models.Review{ID: 9, ItemID: 4, Item: "mug", User: "alice", Rating: 5, Text: "great"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			database := dbmock.NewDB(t)
+			service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+			if tc.dbBehavior != nil {
+				tc.dbBehavior(database)
+			}
+
+			review, err := service.PostReview(ctxWithUserID, tc.request)
+			if tc.err != nil {
+				require.Equal(t, tc.err, err)
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, tc.expected, review)
+		})
+	}
+}
+
+func TestHideReview(t *testing.T) {
+	ctxWithUserID := context.WithValue(context.Background(), middleware.UserIDKey, 1)
+
+	t.Run("no review", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("IsAdmin", mock.Anything, 1).Return(true, nil)
+		database.On("SetReviewHidden", mock.Anything, 9, true).Return(nil, db.ErrNoReview)
+
+		_, err := service.HideReview(ctxWithUserID, "9")
+		require.Equal(t, xerrors.New(db.ErrNoReview, http.StatusNotFound), err)
+	})
+
+	t.Run("positive result", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("IsAdmin", mock.Anything, 1).Return(true, nil)
+		database.On("SetReviewHidden", mock.Anything, 9, true).
+			Return(&models.Review{ID: 9, ItemID: 4, Rating: 1, Hidden: true}, nil)
+
+		review, err := service.HideReview(ctxWithUserID, "9")
+		require.Nil(t, err)
+		require.Equal(t, &models.Review{ID: 9, ItemID: 4, Rating: 1, Hidden: true}, review)
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"merch_shop/internal/config"
 	"merch_shop/internal/db"
 	"merch_shop/internal/models"
 	"merch_shop/pkg/cryptor"
@@ -12,9 +13,7 @@ import (
 	"merch_shop/pkg/tokenizer"
 	"merch_shop/pkg/xerrors"
 	"net/http"
-	"sort"
 	"strconv"
-	"strings"
 )
 
 const (
@@ -25,31 +24,87 @@ const (
 
 	minUsernameLength = 1
 	maxUsernameLength = 10
-
-	emptyJSONB = "{}"
 )
 
 var (
 	errSmthWentWrong    = errors.New("something went wrong")
 	errPasswordMismatch = errors.New("incorrect password")
+	errAdminRequired    = errors.New("admin rights required")
 
+	errGroupedInvalid    = errors.New("grouped is invalid: expected true or false")
 	errCoinAmountInvalid = fmt.Errorf("coin amount is invalid: min %d", minCoinsForTransfer)
 	errPasswordInvalid   = fmt.Errorf("password is invalid: length min %d max %d", minPasswordLength, maxPasswordLength)
 	errUsernameInvalid   = fmt.Errorf("username is invalid: length min %d max %d", minUsernameLength, maxUsernameLength)
 )
 
-var jsonbFormatRunesToCut = map[rune]struct{}{
-	'{': {},
-	'"': {},
-	' ': {},
-	'}': {},
-}
-
 type MerchShopService interface {
 	AuthentificateUser(ctx context.Context, username, password string) (string, xerrors.Xerror)
-	GetInfo(ctx context.Context) (*models.Info, xerrors.Xerror)
-	BuyItem(ctx context.Context, itemID string) xerrors.Xerror
+	GetInfo(ctx context.Context, grouped string) (*models.Info, xerrors.Xerror)
+	BuyItem(ctx context.Context, itemName, variant, quantity, promo string) (*models.OrderSummary, xerrors.Xerror)
+	BuyGift(ctx context.Context, request *models.GiftRequest) (*models.OrderSummary, xerrors.Xerror)
+	AddCartItem(ctx context.Context, request *models.CartItemRequest) xerrors.Xerror
+	GetCart(ctx context.Context) (*models.Cart, xerrors.Xerror)
+	CheckoutCart(ctx context.Context, promo string) (*models.OrderSummary, xerrors.Xerror)
+	GetOrders(ctx context.Context, status string) ([]models.Order, xerrors.Xerror)
+	GetAllOrders(ctx context.Context, status string) ([]models.Order, xerrors.Xerror)
+	AdvanceOrder(ctx context.Context, orderID string) (*models.Order, xerrors.Xerror)
+	CancelOrder(ctx context.Context, orderID string) (*models.Order, xerrors.Xerror)
+	RefundOrder(ctx context.Context, orderID string) (*models.Order, xerrors.Xerror)
+	GetApprovals(ctx context.Context) ([]models.Order, xerrors.Xerror)
+	ApproveOrder(ctx context.Context, orderID string) (*models.Order, xerrors.Xerror)
+	RejectOrder(ctx context.Context, orderID string) (*models.Order, xerrors.Xerror)
+	GetApprovers(ctx context.Context) ([]models.Approver, xerrors.Xerror)
+	AddApprover(ctx context.Context, request *models.ApproverRequest) xerrors.Xerror
+	RemoveApprover(ctx context.Context, username string) xerrors.Xerror
 	SendCoin(ctx context.Context, destUsername string, amount int) xerrors.Xerror
+	GiveItem(ctx context.Context, request *models.GiveItemRequest) xerrors.Xerror
+	GetTransferHistory(ctx context.Context, request *models.TransferHistoryRequest) (*models.TransferPage, xerrors.Xerror)
+	ExportHistory(ctx context.Context, request *models.ExportRequest, write func(*models.LedgerEntry) error) xerrors.Xerror
+	ExportAllHistory(ctx context.Context, request *models.ExportRequest, write func(*models.LedgerEntry) error) xerrors.Xerror
+	GetStatement(ctx context.Context, month string) (*models.Statement, xerrors.Xerror)
+	GetItems(ctx context.Context) ([]models.CatalogItem, xerrors.Xerror)
+	GetItem(ctx context.Context, itemID string) (*models.CatalogItem, xerrors.Xerror)
+	GetAllItems(ctx context.Context) ([]models.CatalogItem, xerrors.Xerror)
+	CreateItem(ctx context.Context, item *models.ItemRequest) (*models.CatalogItem, xerrors.Xerror)
+	UpdateItem(ctx context.Context, itemID string, item *models.ItemRequest) (*models.CatalogItem, xerrors.Xerror)
+	RetireItem(ctx context.Context, itemID string) (*models.CatalogItem, xerrors.Xerror)
+	RestoreItem(ctx context.Context, itemID string) (*models.CatalogItem, xerrors.Xerror)
+	CreateVariant(ctx context.Context, itemID string, variant *models.VariantRequest) (*models.CatalogItem, xerrors.Xerror)
+	UpdateVariant(ctx context.Context, variantID string, variant *models.VariantRequest) (*models.CatalogItem, xerrors.Xerror)
+	RestockVariant(ctx context.Context, variantID string, quantity int) (*models.CatalogItem, xerrors.Xerror)
+	GetLowStockVariants(ctx context.Context, threshold string) ([]models.ItemVariant, xerrors.Xerror)
+	CreatePromo(ctx context.Context, promo *models.PromoRequest) (*models.PromoCode, xerrors.Xerror)
+	GetPromos(ctx context.Context) ([]models.PromoCode, xerrors.Xerror)
+	DeactivatePromo(ctx context.Context, promoID string) (*models.PromoCode, xerrors.Xerror)
+	SchedulePrice(ctx context.Context, itemID string, price *models.PriceRequest) (*models.ItemPrice, xerrors.Xerror)
+	GetPriceHistory(ctx context.Context, itemID string) ([]models.ItemPrice, xerrors.Xerror)
+	GetItemPrices(ctx context.Context, itemID string) ([]models.ItemPrice, xerrors.Xerror)
+	CancelScheduledPrice(ctx context.Context, priceID string) xerrors.Xerror
+	GetBundles(ctx context.Context) ([]models.Bundle, xerrors.Xerror)
+	GetAllBundles(ctx context.Context) ([]models.Bundle, xerrors.Xerror)
+	CreateBundle(ctx context.Context, bundle *models.BundleRequest) (*models.Bundle, xerrors.Xerror)
+	DeactivateBundle(ctx context.Context, bundleID string) (*models.Bundle, xerrors.Xerror)
+	BuyBundle(ctx context.Context, bundleID, quantity string) (*models.OrderSummary, xerrors.Xerror)
+	GetAuctions(ctx context.Context) ([]models.Auction, xerrors.Xerror)
+	GetAllAuctions(ctx context.Context) ([]models.Auction, xerrors.Xerror)
+	GetAuction(ctx context.Context, auctionID string) (*models.Auction, xerrors.Xerror)
+	CreateAuction(ctx context.Context, auction *models.AuctionRequest) (*models.Auction, xerrors.Xerror)
+	PlaceBid(ctx context.Context, auctionID string, bid *models.BidRequest) (*models.Auction, xerrors.Xerror)
+	GetCollections(ctx context.Context) ([]models.Collection, xerrors.Xerror)
+	GetAllCollections(ctx context.Context) ([]models.Collection, xerrors.Xerror)
+	GetCollection(ctx context.Context, collectionID string) (*models.Collection, xerrors.Xerror)
+	CreateCollection(ctx context.Context, request *models.CollectionRequest) (*models.Collection, xerrors.Xerror)
+	Contribute(ctx context.Context, collectionID string, request *models.ContributionRequest) (*models.Collection, xerrors.Xerror)
+	GetItemReviews(ctx context.Context, itemID string) ([]models.Review, xerrors.Xerror)
+	PostReview(ctx context.Context, review *models.ReviewRequest) (*models.Review, xerrors.Xerror)
+	GetAllReviews(ctx context.Context) ([]models.Review, xerrors.Xerror)
+	HideReview(ctx context.Context, reviewID string) (*models.Review, xerrors.Xerror)
+	ShowReview(ctx context.Context, reviewID string) (*models.Review, xerrors.Xerror)
+	AddWishlistItem(ctx context.Context, request *models.WishlistItemRequest) xerrors.Xerror
+	RemoveWishlistItem(ctx context.Context, itemID string) xerrors.Xerror
+	GetWishlist(ctx context.Context) (*models.Wishlist, xerrors.Xerror)
+	GetNotifications(ctx context.Context, unread string) ([]models.Notification, xerrors.Xerror)
+	MarkNotificationRead(ctx context.Context, notificationID string) xerrors.Xerror
 }
 
 type merchShopService struct {
@@ -57,17 +112,43 @@ type merchShopService struct {
 	logger    *slog.Logger
 	cryptor   cryptor.Cryptor
 	tokenizer tokenizer.Tokenizer
+	cfg       config.Shop
+	catalog   *catalogCache
 }
 
-func New(storage db.DB, log *slog.Logger, cr cryptor.Cryptor, t tokenizer.Tokenizer) MerchShopService {
+func New(storage db.DB, log *slog.Logger, cr cryptor.Cryptor, t tokenizer.Tokenizer, cfg config.Shop) MerchShopService {
 	return &merchShopService{
 		storage:   storage,
 		logger:    log,
 		cryptor:   cr,
 		tokenizer: t,
+		cfg:       cfg,
+		catalog:   newCatalogCache(cfg.CatalogCacheTTL),
 	}
 }
 
+// requireAdmin returns id of the calling user if they have admin rights.
+func (s *merchShopService) requireAdmin(ctx context.Context) (int, xerrors.Xerror) {
+	userID, ok := ctx.Value(middleware.UserIDKey).(int)
+	if !ok {
+		return 0, xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+
+	isAdmin, err := s.storage.IsAdmin(ctx, userID)
+	if err != nil {
+		if err == db.ErrNoUser {
+			return 0, xerrors.New(errAdminRequired, http.StatusForbidden)
+		}
+		s.logger.Error("check admin: " + err.Error())
+		return 0, xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+	if !isAdmin {
+		return 0, xerrors.New(errAdminRequired, http.StatusForbidden)
+	}
+
+	return userID, nil
+}
+
 func (s *merchShopService) AuthentificateUser(ctx context.Context, username, password string) (string, xerrors.Xerror) {
 	if len(password) > maxPasswordLength || len(password) < minPasswordLength {
 		return "", xerrors.New(errPasswordInvalid, http.StatusBadRequest)
@@ -110,73 +191,65 @@ func (s *merchShopService) AuthentificateUser(ctx context.Context, username, pas
 	return *token, nil
 }
 
-func (s *merchShopService) GetInfo(ctx context.Context) (*models.Info, xerrors.Xerror) {
+func (s *merchShopService) GetInfo(ctx context.Context, groupedStr string) (*models.Info, xerrors.Xerror) {
 	userID, ok := ctx.Value(middleware.UserIDKey).(int)
 	if !ok {
 		return nil, xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
 	}
 
-	balance, inventory, history, err := s.storage.GetUserInfoByUserID(ctx, userID)
+	// Coin history is grouped by counterparty unless the raw list is requested.
+	grouped := true
+	if groupedStr != "" {
+		var err error
+		grouped, err = strconv.ParseBool(groupedStr)
+		if err != nil {
+			return nil, xerrors.New(errGroupedInvalid, http.StatusBadRequest)
+		}
+	}
+
+	balance, inventory, history, itemHistory, err := s.storage.GetUserInfoByUserID(ctx, userID, grouped)
 	if err != nil {
 		s.logger.Error("get user info: " + err.Error())
 		return nil, xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
 	}
 
-	proccessedInventory := make([]models.Item, 0)
-	if string(inventory) != emptyJSONB {
-
-		cleanInventory := strings.Builder{}
-		for _, r := range string(inventory) {
-			if _, ok := jsonbFormatRunesToCut[r]; !ok {
-				cleanInventory.WriteRune(r)
-			}
-		}
-
-		splittedInventory := strings.Split(cleanInventory.String(), ",")
-		for _, val := range splittedInventory {
-			typeToAmountPair := strings.Split(val, ":")
-			proccessedInventory = append(proccessedInventory, models.Item{
-				Type:     typeToAmountPair[0],
-				Quantity: typeToAmountPair[1],
-			})
-		}
-
-		sort.Slice(proccessedInventory, func(i, j int) bool {
-			return proccessedInventory[i].Type < proccessedInventory[j].Type
-		})
-
-	}
-
 	info := &models.Info{
 		Balance:         *balance,
-		Inventory:       proccessedInventory,
+		Inventory:       inventory,
 		TransferHistory: *history,
+		ItemHistory:     *itemHistory,
 	}
 
 	return info, nil
 }
 
-func (s *merchShopService) BuyItem(ctx context.Context, itemIDStr string) xerrors.Xerror {
+func (s *merchShopService) BuyItem(ctx context.Context, itemName, variant,
+	quantityStr, promo string) (*models.OrderSummary, xerrors.Xerror) {
 	userID, ok := ctx.Value(middleware.UserIDKey).(int)
 	if !ok {
-		return xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+		return nil, xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
 	}
 
-	itemID, err := strconv.Atoi(itemIDStr)
-	if err != nil {
-		s.logger.Error("itemID string to int: " + err.Error())
-		return xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	quantity := minPurchaseQuantity
+	if quantityStr != "" {
+		var err error
+		quantity, err = strconv.Atoi(quantityStr)
+		if err != nil {
+			return nil, xerrors.New(errQuantityInvalid, http.StatusBadRequest)
+		}
+	}
+
+	line, servErr := s.cartLine(ctx, itemName, variant, quantity)
+	if servErr != nil {
+		return nil, servErr
 	}
 
-	err = s.storage.BuyItemByItemID(ctx, userID, itemID)
+	summary, err := s.storage.BuyItems(ctx, userID, []models.CartLine{*line}, normalizePromoCode(promo),
+		s.cfg.ApprovalThreshold)
 	if err != nil {
-		if err == db.ErrNoItem || err == db.ErrNotEnoughCoins {
-			return xerrors.New(err, http.StatusBadRequest)
-		}
-		s.logger.Error("buy item: " + err.Error())
-		return xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+		return nil, s.purchaseError("buy item", err)
 	}
-	return nil
+	return summary, nil
 }
 
 func (s *merchShopService) SendCoin(ctx context.Context, destUsername string, amount int) xerrors.Xerror {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"merch_shop/internal/config"
 	"merch_shop/internal/db"
 	dbmock "merch_shop/internal/db/mocks"
 	"merch_shop/internal/models"
@@ -14,14 +15,21 @@ import (
 	"net/http"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
 
+var testShopConfig = config.Shop{
+	CatalogCacheTTL:    time.Minute,
+	LowStockThreshold:  5,
+	CancellationWindow: 30 * time.Minute,
+}
+
 func TestAuth(t *testing.T) {
-	service := New(dbmock.NewDB(t), slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t))
+	service := New(dbmock.NewDB(t), slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
 
 	t.Run("default invalid parms validation", func(t *testing.T) {
 		testCases := []struct {
@@ -56,7 +64,7 @@ func TestAuth(t *testing.T) {
 		tokenizer := tokenizermock.NewTokenizer(t)
 		cryptor := cryptormock.NewCryptor(t)
 
-		service := New(datadase, slog.Default(), cryptor, tokenizer)
+		service := New(datadase, slog.Default(), cryptor, tokenizer, testShopConfig)
 
 		datadase.On("GetUser", mock.Anything, mock.Anything).Return(nil, "", errors.New("some error"))
 
@@ -69,7 +77,7 @@ func TestAuth(t *testing.T) {
 		tokenizer := tokenizermock.NewTokenizer(t)
 		cryptor := cryptormock.NewCryptor(t)
 
-		service := New(datadase, slog.Default(), cryptor, tokenizer)
+		service := New(datadase, slog.Default(), cryptor, tokenizer, testShopConfig)
 
 		datadase.On("GetUser", mock.Anything, mock.Anything).Return(nil, "", db.ErrNoUser)
 		cryptor.On("EncryptKeyword", mock.Anything).Return("", errors.New("some error"))
@@ -83,7 +91,7 @@ func TestAuth(t *testing.T) {
 		tokenizer := tokenizermock.NewTokenizer(t)
 		cryptor := cryptormock.NewCryptor(t)
 
-		service := New(datadase, slog.Default(), cryptor, tokenizer)
+		service := New(datadase, slog.Default(), cryptor, tokenizer, testShopConfig)
 
 		datadase.On("GetUser", mock.Anything, mock.Anything).Return(nil, "", db.ErrNoUser)
 		datadase.On("CreateUser", mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.New("some error"))
@@ -97,7 +105,7 @@ func TestAuth(t *testing.T) {
 		tokenizer := tokenizermock.NewTokenizer(t)
 		cryptor := cryptormock.NewCryptor(t)
 
-		service := New(datadase, slog.Default(), cryptor, tokenizer)
+		service := New(datadase, slog.Default(), cryptor, tokenizer, testShopConfig)
 
 		datadase.On("GetUser", mock.Anything, mock.Anything).Return(nil, "", nil)
 		cryptor.On("CompareHashAndPassword", mock.Anything, mock.Anything).Return(errors.New("some error"))
@@ -111,7 +119,7 @@ func TestAuth(t *testing.T) {
 		tokenizer := tokenizermock.NewTokenizer(t)
 		cryptor := cryptormock.NewCryptor(t)
 
-		service := New(datadase, slog.Default(), cryptor, tokenizer)
+		service := New(datadase, slog.Default(), cryptor, tokenizer, testShopConfig)
 
 		datadase.On("GetUser", mock.Anything, mock.Anything).Return(&userID, "", nil)
 		cryptor.On("CompareHashAndPassword", mock.Anything, mock.Anything).Return(nil)
@@ -126,7 +134,7 @@ func TestAuth(t *testing.T) {
 		tokenizer := tokenizermock.NewTokenizer(t)
 		cryptor := cryptormock.NewCryptor(t)
 
-		service := New(datadase, slog.Default(), cryptor, tokenizer)
+		service := New(datadase, slog.Default(), cryptor, tokenizer, testShopConfig)
 
 		datadase.On("GetUser", mock.Anything, mock.Anything).Return(&userID, "", nil)
 		cryptor.On("CompareHashAndPassword", mock.Anything, mock.Anything).Return(nil)
@@ -142,7 +150,7 @@ func TestAuth(t *testing.T) {
 		tokenizer := tokenizermock.NewTokenizer(t)
 		cryptor := cryptormock.NewCryptor(t)
 
-		service := New(datadase, slog.Default(), cryptor, tokenizer)
+		service := New(datadase, slog.Default(), cryptor, tokenizer, testShopConfig)
 
 		datadase.On("GetUser", mock.Anything, mock.Anything).Return(nil, "", db.ErrNoUser)
 		datadase.On("CreateUser", mock.Anything, mock.Anything, mock.Anything).Return(&userID, nil)
@@ -156,30 +164,47 @@ func TestAuth(t *testing.T) {
 }
 
 func TestGetInfo(t *testing.T) {
-	service := New(dbmock.NewDB(t), slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t))
+	service := New(dbmock.NewDB(t), slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
 
 	ctxEmpty := context.Background()
 	ctxWithUserID := context.WithValue(ctxEmpty, middleware.UserIDKey, 1)
 	balance := 1000
 
 	t.Run("userID missing error", func(t *testing.T) {
-		_, err := service.GetInfo(ctxEmpty)
+		_, err := service.GetInfo(ctxEmpty, "")
 		require.Equal(t, xerrors.New(errSmthWentWrong, http.StatusInternalServerError), err)
 	})
 
+	t.Run("grouped invalid error", func(t *testing.T) {
+		_, err := service.GetInfo(ctxWithUserID, "sometimes")
+		require.Equal(t, xerrors.New(errGroupedInvalid, http.StatusBadRequest), err)
+	})
+
+	t.Run("raw history requested", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("GetUserInfoByUserID", mock.Anything, mock.Anything, false).Return(
+			&balance, []models.Item{}, &models.CoinTransferHistory{}, &models.ItemTransferHistory{}, nil,
+		)
+
+		_, err := service.GetInfo(ctxWithUserID, "false")
+		require.NoError(t, err)
+	})
+
 	t.Run("get info db error", func(t *testing.T) {
 		database := dbmock.NewDB(t)
-		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t))
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
 
-		database.On("GetUserInfoByUserID", mock.Anything, mock.Anything).Return(nil, nil, nil, errors.New("some error"))
+		database.On("GetUserInfoByUserID", mock.Anything, mock.Anything, true).Return(nil, nil, nil, nil, errors.New("some error"))
 
-		_, err := service.GetInfo(ctxWithUserID)
+		_, err := service.GetInfo(ctxWithUserID, "")
 		require.Equal(t, xerrors.New(errSmthWentWrong, http.StatusInternalServerError), err)
 	})
 
 	t.Run("positive result with empty inventory", func(t *testing.T) {
 		database := dbmock.NewDB(t)
-		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t))
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
 
 		expectedInfo := models.Info{
 			Balance:         balance,
@@ -187,99 +212,209 @@ func TestGetInfo(t *testing.T) {
 			TransferHistory: models.CoinTransferHistory{},
 		}
 
-		database.On("GetUserInfoByUserID", mock.Anything, mock.Anything).Return(
-			&balance, []byte(emptyJSONB), &models.CoinTransferHistory{}, nil,
+		database.On("GetUserInfoByUserID", mock.Anything, mock.Anything, true).Return(
+			&balance, []models.Item{}, &models.CoinTransferHistory{}, &models.ItemTransferHistory{}, nil,
 		)
 
-		info, err := service.GetInfo(ctxWithUserID)
+		info, err := service.GetInfo(ctxWithUserID, "")
 		require.NoError(t, err)
 		require.Equal(t, expectedInfo, *info)
 	})
 
 	t.Run("positive result with non empty inventory", func(t *testing.T) {
 		database := dbmock.NewDB(t)
-		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t))
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
 
 		expectedInfo := models.Info{
 			Balance: balance,
 			Inventory: []models.Item{
 				{
-					Type:     "testing",
-					Quantity: "100",
+					Type:     "t-shirt, \"red\": {xl}",
+					Quantity: 100,
 				},
 				{
 					Type:     "wewewe",
-					Quantity: "1",
+					Quantity: 1,
 				},
 			},
 			TransferHistory: models.CoinTransferHistory{},
 		}
 
-		database.On("GetUserInfoByUserID", mock.Anything, mock.Anything).Return(
-			&balance, []byte(`{"wewewe":"1"}, {"testing":"100"}`), &models.CoinTransferHistory{}, nil)
+		database.On("GetUserInfoByUserID", mock.Anything, mock.Anything, true).Return(
+			&balance, expectedInfo.Inventory, &models.CoinTransferHistory{}, &models.ItemTransferHistory{}, nil)
 
-		info, err := service.GetInfo(ctxWithUserID)
+		info, err := service.GetInfo(ctxWithUserID, "")
 		require.NoError(t, err)
 		require.Equal(t, expectedInfo, *info)
 	})
 }
 
 func TestBuyItem(t *testing.T) {
-	service := New(dbmock.NewDB(t), slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t))
+	service := New(dbmock.NewDB(t), slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
 
 	ctxEmpty := context.Background()
 	ctxWithUserID := context.WithValue(ctxEmpty, middleware.UserIDKey, 1)
-	invalItemID := "invalid item id"
-	validItemID := "1"
+	catalog := []models.CatalogItem{
+		{ID: 1, Type: "t-shirt", Price: 80, Active: true, Available: true, Variants: []models.ItemVariant{
+			{ID: 1, ItemID: 1, SKU: "t-shirt-s", Name: "S", Price: 80, Available: true},
+			{ID: 3, ItemID: 1, SKU: "t-shirt-xl", Name: "XL", Price: 90, Available: true},
+		}},
+		{ID: 2, Type: "pink-hoody", Price: 500, Active: true, Available: true, Variants: []models.ItemVariant{
+			{ID: 2, ItemID: 2, SKU: "pink-hoody", Name: "default", Price: 500, Available: true},
+		}},
+	}
+	validItemName := "pink-hoody"
+	summary := &models.OrderSummary{
+		Order: models.Order{
+			ID: 7, Status: models.OrderStatusPlaced,
+			Items: []models.OrderLine{{Item: validItemName, Variant: "default", Quantity: 1, Price: 500, Total: 500}}, Total: 500,
+		},
+		Balance: 500,
+	}
 
 	t.Run("userID missing error", func(t *testing.T) {
-		err := service.BuyItem(ctxEmpty, "")
+		_, err := service.BuyItem(ctxEmpty, "", "", "", "")
 		require.Equal(t, xerrors.New(errSmthWentWrong, http.StatusInternalServerError), err)
 	})
 
-	t.Run("itemID invalid error", func(t *testing.T) {
-		err := service.BuyItem(ctxWithUserID, invalItemID)
+	t.Run("quantity invalid error", func(t *testing.T) {
+		for _, quantity := range []string{"zero", "0", "101"} {
+			_, err := service.BuyItem(ctxWithUserID, validItemName, "", quantity, "")
+			require.Equal(t, xerrors.New(errQuantityInvalid, http.StatusBadRequest), err, quantity)
+		}
+	})
+
+	t.Run("get items db error", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("GetItems", mock.Anything).Return(nil, errors.New("some error"))
+
+		_, err := service.BuyItem(ctxWithUserID, validItemName, "", "", "")
 		require.Equal(t, xerrors.New(errSmthWentWrong, http.StatusInternalServerError), err)
 	})
 
+	t.Run("unknown item error", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("GetItems", mock.Anything).Return(catalog, nil)
+
+		for _, item := range []string{"invalid item name", "3"} {
+			_, err := service.BuyItem(ctxWithUserID, item, "", "", "")
+			require.Equal(t, xerrors.New(db.ErrNoItem, http.StatusBadRequest), err, item)
+		}
+	})
+
+	t.Run("variant error", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("GetItems", mock.Anything).Return(catalog, nil)
+
+		_, err := service.BuyItem(ctxWithUserID, "t-shirt", "", "", "")
+		require.Equal(t, xerrors.New(errVariantRequired, http.StatusBadRequest), err)
+
+		_, err = service.BuyItem(ctxWithUserID, "t-shirt", "M", "", "")
+		require.Equal(t, xerrors.New(db.ErrNoVariant, http.StatusBadRequest), err)
+	})
+
 	t.Run("buy item db error", func(t *testing.T) {
 		database := dbmock.NewDB(t)
-		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t))
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
 
-		database.On("BuyItemByItemID", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("some error"))
+		database.On("GetItems", mock.Anything).Return(catalog, nil)
+		database.On("BuyItems", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(nil, errors.New("some error"))
 
-		err := service.BuyItem(ctxWithUserID, validItemID)
+		_, err := service.BuyItem(ctxWithUserID, validItemName, "", "", "")
 		require.Equal(t, xerrors.New(errSmthWentWrong, http.StatusInternalServerError), err)
 	})
 
 	t.Run("buy item user side error", func(t *testing.T) {
-		userErrors := []error{db.ErrNoItem, db.ErrNotEnoughCoins}
+		userErrors := []error{db.ErrNoItem, db.ErrNotEnoughCoins, db.ErrNoPromo, db.ErrPromoInactive, db.ErrPromoNotApply}
 
 		for _, e := range userErrors {
 			database := dbmock.NewDB(t)
-			service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t))
-			database.On("BuyItemByItemID", mock.Anything, mock.Anything, mock.Anything).Return(e)
+			service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+			database.On("GetItems", mock.Anything).Return(catalog, nil)
+			database.On("BuyItems", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, e)
 
-			err := service.BuyItem(ctxWithUserID, validItemID)
+			_, err := service.BuyItem(ctxWithUserID, validItemName, "", "", "")
 			require.Equal(t, xerrors.New(e, http.StatusBadRequest), err)
 		}
 
 	})
 
-	t.Run("positive result", func(t *testing.T) {
+	t.Run("out of stock error", func(t *testing.T) {
 		database := dbmock.NewDB(t)
-		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t))
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
 
-		database.On("BuyItemByItemID", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		database.On("GetItems", mock.Anything).Return(catalog, nil)
+		database.On("BuyItems", mock.Anything, 1, mock.Anything, "", 0).Return(nil, db.ErrOutOfStock)
 
-		err := service.BuyItem(ctxWithUserID, validItemID)
-		require.NoError(t, err)
+		_, err := service.BuyItem(ctxWithUserID, validItemName, "", "", "")
+		require.Equal(t, xerrors.New(db.ErrOutOfStock, http.StatusConflict), err)
+	})
+
+	t.Run("promo limit error", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("GetItems", mock.Anything).Return(catalog, nil)
+		// Codes are matched case insensitively.
+		database.On("BuyItems", mock.Anything, 1, mock.Anything, "HOODIES20", 0).Return(nil, db.ErrPromoExhausted)
+
+		_, err := service.BuyItem(ctxWithUserID, validItemName, "", "", " hoodies20")
+		require.Equal(t, xerrors.New(db.ErrPromoExhausted, http.StatusConflict), err)
+	})
+
+	t.Run("purchase limit error", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		nextPurchase := time.Date(2026, 2, 5, 12, 30, 0, 0, time.UTC)
+		limitErr := &db.PurchaseLimitError{
+			Item: validItemName, Limit: models.PurchaseLimit{Quantity: 1, WindowDays: 365}, NextPurchaseAt: &nextPurchase,
+		}
+		database.On("GetItems", mock.Anything).Return(catalog, nil)
+		database.On("BuyItems", mock.Anything, 1, mock.Anything, "", 0).Return(nil, limitErr)
+
+		_, err := service.BuyItem(ctxWithUserID, validItemName, "", "", "")
+		require.Equal(t, xerrors.New(limitErr, http.StatusConflict), err)
+		require.Contains(t, err.Error(), "2026-02-05T12:30:00Z")
+	})
+
+	t.Run("positive result by name and by id", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("GetItems", mock.Anything).Return(catalog, nil).Once()
+		database.On("BuyItems", mock.Anything, 1, []models.CartLine{{VariantID: 2, Quantity: 1}}, "", 0).Return(summary, nil).Twice()
+
+		result, err := service.BuyItem(ctxWithUserID, validItemName, "", "", "")
+		require.Nil(t, err)
+		require.Equal(t, summary, result)
+
+		_, err = service.BuyItem(ctxWithUserID, "2", "default", "", "")
+		require.Nil(t, err)
+	})
+
+	t.Run("positive result with quantity", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("GetItems", mock.Anything).Return(catalog, nil)
+		database.On("BuyItems", mock.Anything, 1, []models.CartLine{{VariantID: 3, Quantity: 3}}, "", 0).Return(summary, nil)
+
+		_, err := service.BuyItem(ctxWithUserID, "t-shirt", "XL", "3", "")
+		require.Nil(t, err)
 	})
 }
 
 func TestSendCoin(t *testing.T) {
 	database := dbmock.NewDB(t)
-	service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t))
+	service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
 
 	ctxEmpty := context.Background()
 	ctxWithUserID := context.WithValue(ctxEmpty, middleware.UserIDKey, 1)
@@ -297,7 +432,7 @@ func TestSendCoin(t *testing.T) {
 
 	t.Run("send coin db error", func(t *testing.T) {
 		database := dbmock.NewDB(t)
-		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t))
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
 
 		database.On("SendCoinByUsername", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(errors.New("some error"))
 
@@ -310,7 +445,7 @@ func TestSendCoin(t *testing.T) {
 
 		for _, e := range userErrors {
 			database := dbmock.NewDB(t)
-			service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t))
+			service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
 
 			database.On("SendCoinByUsername", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(e)
 
@@ -322,7 +457,7 @@ func TestSendCoin(t *testing.T) {
 
 	t.Run("positive result", func(t *testing.T) {
 		database := dbmock.NewDB(t)
-		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t))
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
 
 		database.On("SendCoinByUsername", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 
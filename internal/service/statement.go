@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"merch_shop/internal/db"
+	"merch_shop/internal/models"
+	"merch_shop/pkg/middleware"
+	"merch_shop/pkg/xerrors"
+	"net/http"
+	"time"
+)
+
+const statementMonthLayout = "2006-01"
+
+var (
+	errStatementMonthInvalid = errors.New("month is invalid: expected yyyy-mm format")
+	errStatementMonthFuture  = errors.New("month is invalid: statement for a future month")
+)
+
+func (s *merchShopService) GetStatement(ctx context.Context, monthStr string) (*models.Statement, xerrors.Xerror) {
+	userID, ok := ctx.Value(middleware.UserIDKey).(int)
+	if !ok {
+		return nil, xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+
+	month, err := time.Parse(statementMonthLayout, monthStr)
+	if err != nil {
+		return nil, xerrors.New(errStatementMonthInvalid, http.StatusBadRequest)
+	}
+
+	now := time.Now().UTC()
+	if month.After(now) {
+		return nil, xerrors.New(errStatementMonthFuture, http.StatusBadRequest)
+	}
+
+	// Current month is still in progress, so it is always computed on the fly.
+	if month.AddDate(0, 1, 0).After(now) {
+		statement, err := s.storage.ComputeMonthlyStatement(ctx, userID, month)
+		if err != nil {
+			s.logger.Error("compute statement: " + err.Error())
+			return nil, xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+		}
+		return statement, nil
+	}
+
+	statement, err := s.storage.GetMonthlyStatement(ctx, userID, month)
+	if err == db.ErrNoStatement {
+		// Month closed before the background job ran.
+		statement, err = s.storage.ComputeMonthlyStatement(ctx, userID, month)
+		if statement != nil {
+			statement.Final = true
+		}
+	}
+	if err != nil {
+		s.logger.Error("get statement: " + err.Error())
+		return nil, xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+
+	return statement, nil
+}
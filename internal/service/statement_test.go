@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"merch_shop/internal/db"
+	dbmock "merch_shop/internal/db/mocks"
+	"merch_shop/internal/models"
+	cryptormock "merch_shop/pkg/cryptor/mocks"
+	"merch_shop/pkg/middleware"
+	tokenizermock "merch_shop/pkg/tokenizer/mocks"
+	"merch_shop/pkg/xerrors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetStatement(t *testing.T) {
+	service := New(dbmock.NewDB(t), slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+	ctxEmpty := context.Background()
+	ctxWithUserID := context.WithValue(ctxEmpty, middleware.UserIDKey, 1)
+
+	pastMonth := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := time.Now().UTC()
+	currentMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("userID missing error", func(t *testing.T) {
+		_, err := service.GetStatement(ctxEmpty, "2025-01")
+		require.Equal(t, xerrors.New(errSmthWentWrong, http.StatusInternalServerError), err)
+	})
+
+	t.Run("month invalid error", func(t *testing.T) {
+		for _, month := range []string{"", "2025-13", "2025-1", "january"} {
+			_, err := service.GetStatement(ctxWithUserID, month)
+			require.Equal(t, xerrors.New(errStatementMonthInvalid, http.StatusBadRequest), err)
+		}
+	})
+
+	t.Run("future month error", func(t *testing.T) {
+		_, err := service.GetStatement(ctxWithUserID, currentMonth.AddDate(0, 1, 0).Format(statementMonthLayout))
+		require.Equal(t, xerrors.New(errStatementMonthFuture, http.StatusBadRequest), err)
+	})
+
+	t.Run("precomputed statement", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		expected := &models.Statement{Month: "2025-01", OpeningBalance: 1000, Sent: 100, ClosingBalance: 900, Final: true}
+		database.On("GetMonthlyStatement", mock.Anything, 1, pastMonth).Return(expected, nil)
+
+		statement, err := service.GetStatement(ctxWithUserID, "2025-01")
+		require.NoError(t, err)
+		require.Equal(t, expected, statement)
+	})
+
+	t.Run("closed month not precomputed yet", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("GetMonthlyStatement", mock.Anything, 1, pastMonth).Return(nil, db.ErrNoStatement)
+		database.On("ComputeMonthlyStatement", mock.Anything, 1, pastMonth).Return(&models.Statement{Month: "2025-01"}, nil)
+
+		statement, err := service.GetStatement(ctxWithUserID, "2025-01")
+		require.NoError(t, err)
+		require.True(t, statement.Final)
+	})
+
+	t.Run("current month is computed", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("ComputeMonthlyStatement", mock.Anything, 1, currentMonth).Return(&models.Statement{}, nil)
+
+		statement, err := service.GetStatement(ctxWithUserID, currentMonth.Format(statementMonthLayout))
+		require.NoError(t, err)
+		require.False(t, statement.Final)
+	})
+
+	t.Run("statement db error", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("GetMonthlyStatement", mock.Anything, 1, pastMonth).Return(nil, errors.New("some error"))
+
+		_, err := service.GetStatement(ctxWithUserID, "2025-01")
+		require.Equal(t, xerrors.New(errSmthWentWrong, http.StatusInternalServerError), err)
+	})
+}
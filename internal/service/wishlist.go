@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"merch_shop/internal/db"
+	"merch_shop/internal/models"
+	"merch_shop/pkg/middleware"
+	"merch_shop/pkg/xerrors"
+	"net/http"
+	"strconv"
+)
+
+var errUnreadInvalid = errors.New("unread is invalid: expected true or false")
+
+// AddWishlistItem puts an item found by name or id into the wishlist. Retired items can be wished too,
+// their users are notified once they are restored.
+func (s *merchShopService) AddWishlistItem(ctx context.Context, request *models.WishlistItemRequest) xerrors.Xerror {
+	userID, ok := ctx.Value(middleware.UserIDKey).(int)
+	if !ok {
+		return xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+
+	catalog, servErr := s.getCatalog(ctx)
+	if servErr != nil {
+		return servErr
+	}
+
+	item, ok := catalog.lookup(request.Item)
+	if !ok {
+		return xerrors.New(db.ErrNoItem, http.StatusBadRequest)
+	}
+
+	err := s.storage.AddWishlistItem(ctx, userID, item.ID)
+	if err != nil {
+		s.logger.Error("add wishlist item: " + err.Error())
+		return xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+
+	return nil
+}
+
+func (s *merchShopService) RemoveWishlistItem(ctx context.Context, itemIDStr string) xerrors.Xerror {
+	userID, ok := ctx.Value(middleware.UserIDKey).(int)
+	if !ok {
+		return xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+
+	itemID, err := strconv.Atoi(itemIDStr)
+	if err != nil {
+		return xerrors.New(db.ErrNotWished, http.StatusNotFound)
+	}
+
+	err = s.storage.RemoveWishlistItem(ctx, userID, itemID)
+	if err != nil {
+		if err == db.ErrNotWished {
+			return xerrors.New(err, http.StatusNotFound)
+		}
+		s.logger.Error("remove wishlist item: " + err.Error())
+		return xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+
+	return nil
+}
+
+// GetWishlist returns wished items marking the ones the current balance is enough for.
+func (s *merchShopService) GetWishlist(ctx context.Context) (*models.Wishlist, xerrors.Xerror) {
+	userID, ok := ctx.Value(middleware.UserIDKey).(int)
+	if !ok {
+		return nil, xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+
+	wishlist, err := s.storage.GetWishlist(ctx, userID)
+	if err != nil {
+		s.logger.Error("get wishlist: " + err.Error())
+		return nil, xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+
+	for i := range wishlist.Items {
+		wishlist.Items[i].Affordable = wishlist.Items[i].Price <= wishlist.Balance
+	}
+
+	return wishlist, nil
+}
+
+func (s *merchShopService) GetNotifications(ctx context.Context, unreadStr string) ([]models.Notification, xerrors.Xerror) {
+	userID, ok := ctx.Value(middleware.UserIDKey).(int)
+	if !ok {
+		return nil, xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+
+	unread := false
+	if unreadStr != "" {
+		var err error
+		unread, err = strconv.ParseBool(unreadStr)
+		if err != nil {
+			return nil, xerrors.New(errUnreadInvalid, http.StatusBadRequest)
+		}
+	}
+
+	notifications, err := s.storage.GetNotifications(ctx, userID, unread)
+	if err != nil {
+		s.logger.Error("get notifications: " + err.Error())
+		return nil, xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+
+	return notifications, nil
+}
+
+func (s *merchShopService) MarkNotificationRead(ctx context.Context, notificationIDStr string) xerrors.Xerror {
+	userID, ok := ctx.Value(middleware.UserIDKey).(int)
+	if !ok {
+		return xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+
+	notificationID, err := strconv.Atoi(notificationIDStr)
+	if err != nil {
+		return xerrors.New(db.ErrNoNotification, http.StatusNotFound)
+	}
+
+	err = s.storage.MarkNotificationRead(ctx, userID, notificationID)
+	if err != nil {
+		if err == db.ErrNoNotification {
+			return xerrors.New(err, http.StatusNotFound)
+		}
+		s.logger.Error("mark notification read: " + err.Error())
+		return xerrors.New(errSmthWentWrong, http.StatusInternalServerError)
+	}
+
+	return nil
+}
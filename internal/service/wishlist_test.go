@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"merch_shop/internal/db"
+	dbmock "merch_shop/internal/db/mocks"
+	"merch_shop/internal/models"
+	cryptormock "merch_shop/pkg/cryptor/mocks"
+	"merch_shop/pkg/middleware"
+	tokenizermock "merch_shop/pkg/tokenizer/mocks"
+	"merch_shop/pkg/xerrors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddWishlistItem(t *testing.T) {
+	ctxWithUserID := context.WithValue(context.Background(), middleware.UserIDKey, 1)
+	catalog := []models.CatalogItem{
+		{ID: 4, Type: "pen", Price: 10, Active: true, Available: true},
+		{ID: 5, Type: "old-pen", Price: 5},
+	}
+
+	t.Run("userID missing error", func(t *testing.T) {
+		service := New(dbmock.NewDB(t), slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		err := service.AddWishlistItem(context.Background(), &models.WishlistItemRequest{Item: "pen"})
+		require.Equal(t, xerrors.New(errSmthWentWrong, http.StatusInternalServerError), err)
+	})
+
+	t.Run("unknown item", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("GetItems", mock.Anything).Return(catalog, nil)
+
+		err := service.AddWishlistItem(ctxWithUserID, &models.WishlistItemRequest{Item: "car"})
+		require.Equal(t, xerrors.New(db.ErrNoItem, http.StatusBadRequest), err)
+	})
+
+	t.Run("positive result", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("GetItems", mock.Anything).Return(catalog, nil)
+		database.On("AddWishlistItem", mock.Anything, 1, 4).Return(nil)
+		// Retired items can be wished to be notified when they are restored.
+		database.On("AddWishlistItem", mock.Anything, 1, 5).Return(nil)
+
+		err := service.AddWishlistItem(ctxWithUserID, &models.WishlistItemRequest{Item: "pen"})
+		require.Nil(t, err)
+
+		err = service.AddWishlistItem(ctxWithUserID, &models.WishlistItemRequest{Item: "5"})
+		require.Nil(t, err)
+	})
+}
+
+func TestRemoveWishlistItem(t *testing.T) {
+	ctxWithUserID := context.WithValue(context.Background(), middleware.UserIDKey, 1)
+
+	testCases := []struct {
+		name     string
+		dbErr    error
+		expected xerrors.Xerror
+	}{
+		{name: "not wished", dbErr: db.ErrNotWished, expected: xerrors.New(db.ErrNotWished, http.StatusNotFound)},
+		{name: "unexpected", dbErr: errors.New("some error"),
+			expected: xerrors.New(errSmthWentWrong, http.StatusInternalServerError)},
+		{name: "positive result"},
+	}
+
+	for _, tc := range testCases {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("RemoveWishlistItem", mock.Anything, 1, 4).Return(tc.dbErr)
+
+		err := service.RemoveWishlistItem(ctxWithUserID, "4")
+		if tc.expected == nil {
+			require.Nil(t, err, tc.name)
+			continue
+		}
+		require.Equal(t, tc.expected, err, tc.name)
+	}
+}
+
+func TestGetWishlist(t *testing.T) {
+	ctxWithUserID := context.WithValue(context.Background(), middleware.UserIDKey, 1)
+
+	t.Run("storage error", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("GetWishlist", mock.Anything, 1).Return(nil, errors.New("some error"))
+
+		_, err := service.GetWishlist(ctxWithUserID)
+		require.Equal(t, xerrors.New(errSmthWentWrong, http.StatusInternalServerError), err)
+	})
+
+	t.Run("affordable items are marked", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("GetWishlist", mock.Anything, 1).Return(&models.Wishlist{Balance: 100, Items: []models.WishlistItem{
+			{ItemID: 4, Item: "pen", Price: 100, Available: true},
+			{ItemID: 5, Item: "hoody", Price: 300, Available: true},
+			{ItemID: 6, Item: "cup", Price: 20},
+		}}, nil)
+
+		wishlist, err := service.GetWishlist(ctxWithUserID)
+		require.Nil(t, err)
+		require.Equal(t, &models.Wishlist{Balance: 100, Items: []models.WishlistItem{
+			{ItemID: 4, Item: "pen", Price: 100, Available: true, Affordable: true},
+			{ItemID: 5, Item: "hoody", Price: 300, Available: true},
+			{ItemID: 6, Item: "cup", Price: 20, Affordable: true},
+		}}, wishlist)
+	})
+}
+
+func TestGetNotifications(t *testing.T) {
+	ctxWithUserID := context.WithValue(context.Background(), middleware.UserIDKey, 1)
+
+	t.Run("invalid unread", func(t *testing.T) {
+		service := New(dbmock.NewDB(t), slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		_, err := service.GetNotifications(ctxWithUserID, "maybe")
+		require.Equal(t, xerrors.New(errUnreadInvalid, http.StatusBadRequest), err)
+	})
+
+	t.Run("positive result", func(t *testing.T) {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		notifications := []models.Notification{{ID: 2, Kind: models.NotificationKindRestock, Item: "pen",
+			Message: "pen is back in stock for 8 coins"}}
+		database.On("GetNotifications", mock.Anything, 1, true).Return(notifications, nil)
+		database.On("GetNotifications", mock.Anything, 1, false).Return(notifications, nil)
+
+		result, err := service.GetNotifications(ctxWithUserID, "true")
+		require.Nil(t, err)
+		require.Equal(t, notifications, result)
+
+		result, err = service.GetNotifications(ctxWithUserID, "")
+		require.Nil(t, err)
+		require.Equal(t, notifications, result)
+	})
+}
+
+func TestMarkNotificationRead(t *testing.T) {
+	ctxWithUserID := context.WithValue(context.Background(), middleware.UserIDKey, 1)
+
+	testCases := []struct {
+		name     string
+		dbErr    error
+		expected xerrors.Xerror
+	}{
+		{name: "unknown notification", dbErr: db.ErrNoNotification,
+			expected: xerrors.New(db.ErrNoNotification, http.StatusNotFound)},
+		{name: "unexpected", dbErr: errors.New("some error"),
+			expected: xerrors.New(errSmthWentWrong, http.StatusInternalServerError)},
+		{name: "positive result"},
+	}
+
+	for _, tc := range testCases {
+		database := dbmock.NewDB(t)
+		service := New(database, slog.Default(), cryptormock.NewCryptor(t), tokenizermock.NewTokenizer(t), testShopConfig)
+
+		database.On("MarkNotificationRead", mock.Anything, 1, 7).Return(tc.dbErr)
+
+		err := service.MarkNotificationRead(ctxWithUserID, "7")
+		if tc.expected == nil {
+			require.Nil(t, err, tc.name)
+			continue
+		}
+		require.Equal(t, tc.expected, err, tc.name)
+	}
+}
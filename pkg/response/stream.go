@@ -0,0 +1,118 @@
+package response
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+const (
+	contentDispositionHeader = "Content-Disposition"
+	csvContentType           = "text/csv"
+
+	// Rows are flushed to the client in chunks of this size.
+	streamFlushRows = 100
+)
+
+// Stream writes a downloadable attachment row by row. Without Content-Length
+// net/http sends it with chunked transfer encoding.
+type Stream interface {
+	Write(row any) error
+	Close() error
+}
+
+type csvStream struct {
+	w       http.ResponseWriter
+	csv     *csv.Writer
+	toCSV   func(row any) []string
+	written int
+}
+
+type jsonStream struct {
+	w       http.ResponseWriter
+	enc     *json.Encoder
+	written int
+}
+
+// NewCSVStream writes the header record and converts rows with toCSV.
+func NewCSVStream(w http.ResponseWriter, filename string, header []string, toCSV func(row any) []string) (Stream, error) {
+	startAttachment(w, csvContentType, filename)
+
+	stream := &csvStream{w: w, csv: csv.NewWriter(w), toCSV: toCSV}
+	if err := stream.csv.Write(header); err != nil {
+		return nil, err
+	}
+
+	return stream, nil
+}
+
+func (s *csvStream) Write(row any) error {
+	if err := s.csv.Write(s.toCSV(row)); err != nil {
+		return err
+	}
+
+	s.written++
+	if s.written%streamFlushRows == 0 {
+		s.csv.Flush()
+		flush(s.w)
+	}
+
+	return s.csv.Error()
+}
+
+func (s *csvStream) Close() error {
+	s.csv.Flush()
+	flush(s.w)
+	return s.csv.Error()
+}
+
+// NewJSONStream writes rows as elements of a single JSON array.
+func NewJSONStream(w http.ResponseWriter, filename string) (Stream, error) {
+	startAttachment(w, contentType, filename)
+
+	if _, err := io.WriteString(w, "["); err != nil {
+		return nil, err
+	}
+
+	return &jsonStream{w: w, enc: json.NewEncoder(w)}, nil
+}
+
+func (s *jsonStream) Write(row any) error {
+	if s.written > 0 {
+		if _, err := io.WriteString(s.w, ","); err != nil {
+			return err
+		}
+	}
+	if err := s.enc.Encode(row); err != nil {
+		return err
+	}
+
+	s.written++
+	if s.written%streamFlushRows == 0 {
+		flush(s.w)
+	}
+
+	return nil
+}
+
+func (s *jsonStream) Close() error {
+	if _, err := io.WriteString(s.w, "]\n"); err != nil {
+		return err
+	}
+	flush(s.w)
+	return nil
+}
+
+func startAttachment(w http.ResponseWriter, contentType, filename string) {
+	w.Header().Set(contentTypeHeader, contentType)
+	w.Header().Set(contentDispositionHeader, fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+}
+
+func flush(w http.ResponseWriter) {
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}